@@ -2,10 +2,24 @@ package main
 
 /*
 #include <stdlib.h>
+
+// Why a factorial couldn't be calculated
+enum {
+	FACTORIAL_OK = 0,
+	FACTORIAL_NEGATIVE = 1, // n was negative
+	FACTORIAL_OVERFLOW = 2, // n! doesn't fit in 64 bits (n > 20)
+};
+
+typedef struct{
+	long long value; // The factorial of n, 0 if there was an error
+	int error;       // FACTORIAL_OK, or why the factorial couldn't be calculated
+} FactorialResult;
 */
 import "C"
 import (
+	"errors"
 	"fmt"
+	"math"
 )
 
 // A function to greet someone
@@ -15,6 +29,12 @@ func Greeting() {
 	fmt.Println("Hello from Go!")
 }
 
+// Returned when the factorial is too big to fit in an int (n > 20 on 64-bit platforms)
+var ErrFactorialOverflow = errors.New("factorial overflows a 64-bit integer")
+
+// Returned for the factorial of a negative number, which isn't defined
+var ErrNegativeFactorial = errors.New("factorial of a negative number")
+
 // A function to calculate the factorial of a number n
 //
 // # Parameters
@@ -23,35 +43,44 @@ func Greeting() {
 //
 // # Returns
 //
-// int: The factorial of n
-func Factorial(n int) int {
-	result := n
-	lastVal := n - 1
-	for range int(n) {
-		if lastVal > 0 {
-			result *= lastVal
-			lastVal -= 1
+// int: The factorial of n (0! is 1)
+//
+// error: ErrNegativeFactorial if n is negative, or ErrFactorialOverflow if the result doesn't fit in an int, instead of silently wrapping around
+func Factorial(n int) (int, error) {
+	if n < 0 {
+		return 0, fmt.Errorf("%w: %d!", ErrNegativeFactorial, n)
+	}
+	result := 1
+	for i := 2; i <= n; i++ {
+		if result > math.MaxInt/i {
+			return 0, fmt.Errorf("%w: %d!", ErrFactorialOverflow, n)
 		}
+		result *= i
 	}
-	return result
+	return result, nil
 }
 
 // The cgo binding to call the Factorial Function through
 //
 // # Parameters
 //
-// n (C.int): The integer to calculate the factorial of
+// n (C.longlong): The integer to calculate the factorial of
 //
 // # Returns
 //
-// C.int: The factorial of n
+// C.FactorialResult: The factorial of n, and an error code (FACTORIAL_NEGATIVE or FACTORIAL_OVERFLOW) if it couldn't be calculated, returned by value so there's nothing to free
 //
 //export factorial
-func factorial(n C.int) C.int {
-	goN := int(n)            // Convert to go integer
-	result := Factorial(goN) // Get go integer result
-	r := C.int(result)       // Convert to C integer
-	return r
+func factorial(n C.longlong) C.FactorialResult {
+	goN := int(n)                 // Convert to go integer
+	result, err := Factorial(goN) // Get go integer result
+	switch {
+	case errors.Is(err, ErrNegativeFactorial):
+		return C.FactorialResult{error: C.FACTORIAL_NEGATIVE}
+	case errors.Is(err, ErrFactorialOverflow):
+		return C.FactorialResult{error: C.FACTORIAL_OVERFLOW}
+	}
+	return C.FactorialResult{value: C.longlong(result), error: C.FACTORIAL_OK} // 64-bit, so it can't be truncated
 }
 
 func main() {
@@ -23,6 +23,18 @@ typedef struct { const char *p; ptrdiff_t n; } _GoString_;
 
 #include <stdlib.h>
 
+// Why a factorial couldn't be calculated
+enum {
+	FACTORIAL_OK = 0,
+	FACTORIAL_NEGATIVE = 1, // n was negative
+	FACTORIAL_OVERFLOW = 2, // n! doesn't fit in 64 bits (n > 20)
+};
+
+typedef struct{
+	long long value; // The factorial of n, 0 if there was an error
+	int error;       // FACTORIAL_OK, or why the factorial couldn't be calculated
+} FactorialResult;
+
 #line 1 "cgo-generated-wrapper"
 
 
@@ -88,13 +100,13 @@ extern __declspec(dllexport) void Greeting();
 //
 // # Parameters
 //
-// n (C.int): The integer to calculate the factorial of
+// n (C.longlong): The integer to calculate the factorial of
 //
 // # Returns
 //
-// C.int: The factorial of n
+// C.FactorialResult: The factorial of n, and an error code (FACTORIAL_NEGATIVE or FACTORIAL_OVERFLOW) if it couldn't be calculated, returned by value so there's nothing to free
 //
-extern __declspec(dllexport) int factorial(int n);
+extern __declspec(dllexport) FactorialResult factorial(long long int n);
 
 #ifdef __cplusplus
 }
@@ -1,4 +1,4 @@
-from ctypes import cdll, c_int
+from ctypes import Structure, cdll, c_int, c_longlong
 from platform import platform
 
 # import library
@@ -10,10 +10,28 @@ else:
 # Simple idempotent function call
 lib.Greeting()
 
+# Mirrors the FactorialResult struct in lib.go, which is returned by value
+class FactorialResult(Structure):
+    _fields_ = [("value", c_longlong), ("error", c_int)]
+
+# Error codes from lib.go
+FACTORIAL_OK = 0
+FACTORIAL_NEGATIVE = 1
+FACTORIAL_OVERFLOW = 2
+
 # Variadic function (with arguments/returns)
-lib.factorial.argtypes = [c_int]
-lib.factorial.restype = c_int
+lib.factorial.argtypes = [c_longlong]
+lib.factorial.restype = FactorialResult
 
 n = 10
 
-print(f"The factorial of {n} is {lib.factorial(n)} {type(lib.factorial(n))}")
+result = lib.factorial(n)
+print(f"The factorial of {n} is {result.value} {type(result.value)}")
+
+# Errors are reported in their own field, instead of a value that could also be a result
+for n in (25, -1):
+    result = lib.factorial(n)
+    if result.error == FACTORIAL_OVERFLOW:
+        print(f"The factorial of {n} is too big to fit in 64 bits")
+    elif result.error == FACTORIAL_NEGATIVE:
+        print(f"The factorial of {n} isn't defined")
@@ -0,0 +1,18 @@
+// The go.mod of a host library that includes the scraping and similarity examples, used in place of the helper's
+// go.mod when building it (go run ./cmd/cgohelper build -modfile ../examples/host/host.mod ...), so the replace
+// paths are relative to the helper's directory
+module github.com/Descent098/cgo-python-helpers
+
+go 1.22.0
+
+require (
+	example.com/scraping v0.0.0
+	example.com/similarity v0.0.0
+	lib v0.0.0
+)
+
+replace (
+	example.com/scraping => ../examples/scraping/with-helper/scraping/go
+	example.com/similarity => ../examples/similarity/with-helper/similarity/go
+	lib => ../examples/similarity/original-embedded/similarity/go // The similarity algorithms
+)
@@ -0,0 +1,7 @@
+package main
+
+// The packages that register the host's modules, built into the helper's package main (with -file modules.go)
+import (
+	_ "example.com/scraping/scraper"
+	_ "example.com/similarity"
+)
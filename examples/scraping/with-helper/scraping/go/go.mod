@@ -1,5 +1,8 @@
-module lib
+module example.com/scraping
 
 go 1.22.0
 
-require github.com/Descent098/cgo-python-helpers v0.0.0-20250519041537-7901e16b05ab
+require github.com/Descent098/cgo-python-helpers v0.0.0
+
+// The helper in this repository, so the example builds against the same version as the rest of it
+replace github.com/Descent098/cgo-python-helpers => ../../../../../helper
@@ -16,172 +16,11 @@ typedef struct{
 import "C"
 import (
 	"fmt"
-	"io"
-	"net"
-	"net/http"
-	"net/url"
-	"runtime"
-	"sync"
-	"time"
 	"unsafe"
 
-	helpers "github.com/Descent098/cgo-python-helpers"
+	"example.com/scraping/scraper"
 )
 
-type Site struct {
-	url         string // the raw URL
-	domain      string // The domain the URL is hosted at
-	server      string // The value of the server header
-	protocol    string // The protocl of the site (http or https)
-	contentType string // The content type of the body (i.e. "text/html")
-	body        string // The body of the url
-	port        int    // The port the url is on
-}
-
-// Retrieves a value from HTTP headers or returns a default if not found
-//
-// # Parameters
-//
-//	headers (http.Header): The HTTP headers from the response
-//	key (string): The header key to look for
-//	defaultValue (string): The default value to return if the key is not found
-//
-// # Returns
-//
-//	string: The value of the header or the default value
-func getFromHeaders(headers http.Header, key string, defaultValue string) string {
-	values, ok := headers[key]
-	if !ok || len(values) == 0 {
-		return defaultValue
-	}
-	return values[0]
-}
-
-// Scrape metadata from from a single URL
-//
-// # Parameters
-//
-//	rawUrl (string): The raw URL string to fetch
-//
-// # Returns
-//
-//	*Site: A pointer to a Site struct containing metadata
-//	error: An error if the request or parsing fails
-func scrapeSite(rawUrl string) (*Site, error) {
-	var result Site
-	result.url = rawUrl
-	parsedURL, err := url.Parse(rawUrl)
-	if err != nil {
-		return &result, err
-	}
-
-	protocol := parsedURL.Scheme
-	domain := parsedURL.Hostname()
-	port := 80
-	if protocol == "https" {
-		port = 443
-	}
-	if parsedURL.Port() != "" {
-		p, err := net.LookupPort("tcp", parsedURL.Port())
-		if err == nil {
-			port = p
-		}
-	}
-
-	result.protocol = protocol
-	result.domain = domain
-	result.port = port
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        50,
-			MaxIdleConnsPerHost: 5,
-			IdleConnTimeout:     5 * time.Second,
-			TLSHandshakeTimeout: 2 * time.Second,
-			DisableKeepAlives:   true,
-			ForceAttemptHTTP2:   false,
-		},
-	}
-
-	resp, err := client.Get(rawUrl)
-	if err != nil {
-		return &result, err
-	}
-	defer resp.Body.Close()
-
-	contentType := getFromHeaders(resp.Header, "Content-Type", "text/plain")
-	server := getFromHeaders(resp.Header, "Server", "")
-	result.contentType = contentType
-	result.server = server
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return &result, err
-	}
-
-	result.body = string(bodyBytes)
-
-	return &result, nil
-}
-
-// Takes in a list of URL's and parses their content to Site's
-//
-// # Parameters
-//
-//	urls ([]string): A slice of raw URLs to scrape
-//
-// # Notes
-//
-//   - Do not include repeat URL's or this function will panic
-//
-// # Returns
-//
-//	[]*Site: A slice of Site pointers containing parsed metadata
-func ParseURLs(urls []string) []*Site {
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
-	result := make([]*Site, len(urls))
-	const MAXCONCURRENTGOROUTINES = 50
-
-	var (
-		wg             sync.WaitGroup
-		semaphore      = make(chan int, MAXCONCURRENTGOROUTINES)
-		writeArrayLock sync.Mutex
-	)
-
-	for index, url := range urls {
-		semaphore <- 1
-		wg.Add(1)
-		go func(url string, index int) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
-
-			site, err := scrapeSite(url)
-			if err != nil {
-				fmt.Printf("Error while processing %s: %v\n", url, err)
-				site = &Site{
-					url,
-					"", "", "", "", "", 80,
-				}
-			}
-
-			writeArrayLock.Lock()
-			result[index] = site
-			writeArrayLock.Unlock()
-		}(url, index)
-
-	}
-	wg.Wait()
-
-	if len(result) != len(urls) {
-		// This should never happen
-		fmt.Printf("Incorrect number of sites %d/%d", len(result), len(urls))
-		panic("URL results may cause memory misalignment, exiting")
-	}
-	return result
-}
-
 // C-callable wrapper that parses multiple URLs and returns C structs
 //
 // # Parameters
@@ -195,9 +34,12 @@ func ParseURLs(urls []string) []*Site {
 //
 //export parse_urls
 func parse_urls(cUrls **C.char, cCount C.int) *C.Site {
-	goURLs := helpers.CStringArrayToSlice(unsafe.Pointer(cUrls), int(cCount))
+	goURLs := make([]string, 0, int(cCount))
+	for _, cUrl := range unsafe.Slice(cUrls, int(cCount)) {
+		goURLs = append(goURLs, C.GoString(cUrl))
+	}
 
-	sitesData := ParseURLs(goURLs)
+	sitesData := scraper.ParseURLs(goURLs)
 
 	sites := PrepareSitesForExport(sitesData)
 
@@ -208,12 +50,12 @@ func parse_urls(cUrls **C.char, cCount C.int) *C.Site {
 //
 // # Parameters
 //
-//	sitesData ([]*Site): An slice of pointers to Site instances
+//	sitesData ([]*scraper.Site): An slice of pointers to Site instances
 //
 // # Returns
 //
 //	*C.Site: A pointer to the first element of an array of C.Site structs
-func PrepareSitesForExport(sitesData []*Site) *C.Site {
+func PrepareSitesForExport(sitesData []*scraper.Site) *C.Site {
 	count := len(sitesData)
 	// Allocate one big C array for all Site structs
 	size := C.size_t(count) * C.size_t(unsafe.Sizeof(C.Site{}))
@@ -230,13 +72,13 @@ func PrepareSitesForExport(sitesData []*Site) *C.Site {
 			uintptr(unsafe.Pointer(sites)) + uintptr(i)*unsafe.Sizeof(C.Site{}),
 		))
 
-		slot.url = C.CString(site.url)
-		slot.domain = C.CString(site.domain)
-		slot.server = C.CString(site.server)
-		slot.protocol = C.CString(site.protocol)
-		slot.contentType = C.CString(site.contentType)
-		slot.body = C.CString(site.body)
-		slot.port = C.int(site.port)
+		slot.url = C.CString(site.URL)
+		slot.domain = C.CString(site.Domain)
+		slot.server = C.CString(site.Server)
+		slot.protocol = C.CString(site.Protocol)
+		slot.contentType = C.CString(site.ContentType)
+		slot.body = C.CString(site.Body)
+		slot.port = C.int(site.Port)
 	}
 	return sites
 }
@@ -253,8 +95,8 @@ func PrepareSitesForExport(sitesData []*Site) *C.Site {
 //
 //export scrape_single_url
 func scrape_single_url(cUrl *C.char) *C.Site {
-	url := C.GoString(cUrl)      // Convert string back to Go string
-	site, err := scrapeSite(url) // Get site data
+	url := C.GoString(cUrl)              // Convert string back to Go string
+	site, err := scraper.ScrapeSite(url) // Get site data
 	if err != nil {
 		fmt.Printf("Error scraping %s: %v\n", url, err)
 		return nil
@@ -265,13 +107,13 @@ func scrape_single_url(cUrl *C.char) *C.Site {
 	cSite := (*C.Site)(C.malloc(C.size_t(unsafe.Sizeof(C.Site{}))))
 
 	// Assign values
-	cSite.url = C.CString(site.url)
-	cSite.domain = C.CString(site.domain)
-	cSite.server = C.CString(site.server)
-	cSite.protocol = C.CString(site.protocol)
-	cSite.contentType = C.CString(site.contentType)
-	cSite.body = C.CString(site.body)
-	cSite.port = C.int(site.port)
+	cSite.url = C.CString(site.URL)
+	cSite.domain = C.CString(site.Domain)
+	cSite.server = C.CString(site.Server)
+	cSite.protocol = C.CString(site.Protocol)
+	cSite.contentType = C.CString(site.ContentType)
+	cSite.body = C.CString(site.Body)
+	cSite.port = C.int(site.Port)
 
 	return cSite
 }
@@ -0,0 +1,26 @@
+package scraper
+
+import (
+	"encoding/json"
+
+	"github.com/Descent098/cgo-python-helpers/registry"
+)
+
+// Registers the functions of the package as the "scraping" module, so a host library that imports it can call
+// them by name (i.e. host.scraping.parse_urls() from python)
+func init() {
+	registry.Register("scraping", "parse_urls", func(args []json.RawMessage) (any, error) {
+		var urls []string
+		if err := registry.DecodeArgs(args, &urls); err != nil {
+			return nil, err
+		}
+		return ParseURLs(urls), nil
+	})
+	registry.Register("scraping", "scrape_single_url", func(args []json.RawMessage) (any, error) {
+		var rawUrl string
+		if err := registry.DecodeArgs(args, &rawUrl); err != nil {
+			return nil, err
+		}
+		return ScrapeSite(rawUrl)
+	})
+}
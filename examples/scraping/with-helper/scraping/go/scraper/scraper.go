@@ -0,0 +1,157 @@
+// Scrapes the metadata of URLs on a worker pool, used by the library in the parent directory
+// and registered as the "scraping" module for host libraries (see register.go)
+//
+// # Functions
+//
+//	ScrapeSite(): Scrape metadata from from a single URL
+//	ParseURLs(): Takes in a list of URL's and parses their content to Site's
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Descent098/cgo-python-helpers/pool"
+)
+
+// The most requests ParseURLs() makes at once, scraping waits on the network so this is much more than the number of cores
+const MaxConcurrentScrapes = 50
+
+// The pool ParseURLs() scrapes on, separate from pool.Shared (which is sized for CPU bound work, one worker per core)
+var scrapePool = pool.NewWorkerPool(MaxConcurrentScrapes)
+
+type Site struct {
+	URL         string `json:"url"`         // the raw URL
+	Domain      string `json:"domain"`      // The domain the URL is hosted at
+	Server      string `json:"server"`      // The value of the server header
+	Protocol    string `json:"protocol"`    // The protocl of the site (http or https)
+	ContentType string `json:"contentType"` // The content type of the body (i.e. "text/html")
+	Body        string `json:"body"`        // The body of the url
+	Port        int    `json:"port"`        // The port the url is on
+}
+
+// Retrieves a value from HTTP headers or returns a default if not found
+//
+// # Parameters
+//
+//	headers (http.Header): The HTTP headers from the response
+//	key (string): The header key to look for
+//	defaultValue (string): The default value to return if the key is not found
+//
+// # Returns
+//
+//	string: The value of the header or the default value
+func getFromHeaders(headers http.Header, key string, defaultValue string) string {
+	values, ok := headers[key]
+	if !ok || len(values) == 0 {
+		return defaultValue
+	}
+	return values[0]
+}
+
+// Scrape metadata from from a single URL
+//
+// # Parameters
+//
+//	rawUrl (string): The raw URL string to fetch
+//
+// # Returns
+//
+//	*Site: A pointer to a Site struct containing metadata
+//	error: An error if the request or parsing fails
+func ScrapeSite(rawUrl string) (*Site, error) {
+	var result Site
+	result.URL = rawUrl
+	parsedURL, err := url.Parse(rawUrl)
+	if err != nil {
+		return &result, err
+	}
+
+	protocol := parsedURL.Scheme
+	domain := parsedURL.Hostname()
+	port := 80
+	if protocol == "https" {
+		port = 443
+	}
+	if parsedURL.Port() != "" {
+		p, err := net.LookupPort("tcp", parsedURL.Port())
+		if err == nil {
+			port = p
+		}
+	}
+
+	result.Protocol = protocol
+	result.Domain = domain
+	result.Port = port
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        50,
+			MaxIdleConnsPerHost: 5,
+			IdleConnTimeout:     5 * time.Second,
+			TLSHandshakeTimeout: 2 * time.Second,
+			DisableKeepAlives:   true,
+			ForceAttemptHTTP2:   false,
+		},
+	}
+
+	resp, err := client.Get(rawUrl)
+	if err != nil {
+		return &result, err
+	}
+	defer resp.Body.Close()
+
+	contentType := getFromHeaders(resp.Header, "Content-Type", "text/plain")
+	server := getFromHeaders(resp.Header, "Server", "")
+	result.ContentType = contentType
+	result.Server = server
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &result, err
+	}
+
+	result.Body = string(bodyBytes)
+
+	return &result, nil
+}
+
+// Takes in a list of URL's and parses their content to Site's
+//
+// # Parameters
+//
+//	urls ([]string): A slice of raw URLs to scrape
+//
+// # Notes
+//
+//   - The URLs are scraped on their own worker pool, so at most MaxConcurrentScrapes requests run at once. It's not
+//     pool.Shared, which has one worker per core (its size is set with CGOHELPER_POOL_SIZE, or GoPool.size from python)
+//     and would only run that many requests at once
+//
+// # Returns
+//
+//	[]*Site: A slice of Site pointers containing parsed metadata, in the same order as urls
+func ParseURLs(urls []string) []*Site {
+	result := make([]*Site, len(urls))
+
+	scrapePool.Map(context.Background(), pool.PriorityNormal, len(urls), func(ctx context.Context, index int) error {
+		site, err := ScrapeSite(urls[index])
+		if err != nil {
+			fmt.Printf("Error while processing %s: %v\n", urls[index], err)
+			site = &Site{
+				urls[index],
+				"", "", "", "", "", 80,
+			}
+		}
+
+		result[index] = site // Each index is written by one call, so no lock is needed
+		return nil
+	})
+	return result
+}
@@ -0,0 +1,17 @@
+"""The build backend of this project (see pyproject.toml), which runs the helper's backend (helper/backend.py)
+
+pip only loads backends from inside a project, so this loads the helper's one instead of being a copy of it
+"""
+import os
+import importlib.util
+
+_spec = importlib.util.spec_from_file_location(
+    "cgohelper_backend", os.path.join(os.path.dirname(os.path.realpath(__file__)), "..", "..", "..", "helper", "backend.py")
+)
+_backend = importlib.util.module_from_spec(_spec)
+_spec.loader.exec_module(_backend)
+
+get_requires_for_build_wheel = _backend.get_requires_for_build_wheel
+get_requires_for_build_sdist = _backend.get_requires_for_build_sdist
+build_wheel = _backend.build_wheel
+build_sdist = _backend.build_sdist
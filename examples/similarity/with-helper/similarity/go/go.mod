@@ -0,0 +1,15 @@
+module example.com/similarity
+
+go 1.22.0
+
+require (
+	github.com/Descent098/cgo-python-helpers v0.0.0
+	lib v0.0.0
+)
+
+replace (
+	// The helper in this repository, for the shared worker pool
+	github.com/Descent098/cgo-python-helpers => ../../../../../helper
+	// The original example's module, for its algorithms package (only the algorithms are imported, not its package main)
+	lib => ../../../original-embedded/similarity/go
+)
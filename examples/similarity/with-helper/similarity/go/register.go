@@ -0,0 +1,27 @@
+package similarity
+
+import (
+	"encoding/json"
+
+	"github.com/Descent098/cgo-python-helpers/registry"
+	"lib/algorithms"
+)
+
+// Registers the functions of the package as the "similarity" module, so a host library that imports it can call
+// them by name (i.e. host.similarity.check() from python)
+func init() {
+	register := func(name string, algorithm algorithms.SimilarityAlgorithm) {
+		registry.Register("similarity", name, func(args []json.RawMessage) (any, error) {
+			var (
+				word       string
+				validWords []string
+			)
+			if err := registry.DecodeArgs(args, &word, &validWords); err != nil {
+				return nil, err
+			}
+			return CheckSimilarity(word, algorithm, validWords), nil
+		})
+	}
+	register("check", algorithms.IndelSimilarity)
+	register("check_levenstein", algorithms.LevensteinSimilarity)
+}
@@ -0,0 +1,61 @@
+// A library to help calculate the similarity between two strings, that searches the corpus on the helper's
+// shared worker pool, and is built into a host library instead of a library of it's own. The algorithms are the
+// original example's (lib/algorithms in original-embedded)
+//
+// # Functions
+//
+//	CheckSimilarity(): Check the similarity of a word to a corpus of validWords
+package similarity
+
+import (
+	"context"
+
+	"github.com/Descent098/cgo-python-helpers/pool"
+	"lib/algorithms"
+)
+
+// The most similar word to a search, algorithms.Suggestion with json tags for host libraries
+type Suggestion struct {
+	Likelihood float32 `json:"likelihood"`
+	Word       string  `json:"word"`
+}
+
+// The number of words each task in CheckSimilarity() compares, large enough that queueing a task costs little next to it
+const wordsPerTask = 4096
+
+// Utility function that does the heavy lifting, essentially a general solution that will take in any SimilarityAlgorithm
+//
+// # Parameters
+//
+//	word (string): The word to find a similar word for
+//	algorithm (algorithms.SimilarityAlgorithm): The algorithm to use to calculate the similarity of the words
+//	validWords ([]string): A slice with the words in the corpus
+//
+// # Notes
+//
+//   - The corpus is split into chunks that algorithms.SuggestWord() searches on the helper's shared pool (pool.Shared),
+//     so a search uses as many cores as the pool has workers
+//
+// # Returns
+//
+//	Suggestion: The most similar word and it's likelihood
+func CheckSimilarity(word string, algorithm algorithms.SimilarityAlgorithm, validWords []string) Suggestion {
+	tasks := (len(validWords) + wordsPerTask - 1) / wordsPerTask
+	chunkResults := make([]algorithms.Suggestion, tasks)
+
+	pool.Shared.Map(context.Background(), pool.PriorityNormal, tasks, func(ctx context.Context, task int) error {
+		start := task * wordsPerTask
+		end := min(start+wordsPerTask, len(validWords))
+		chunkResults[task] = algorithms.SuggestWord(word, validWords[start:end], algorithm)
+		return nil
+	})
+
+	// Compared in order, so a tie goes to the first word in validWords (the same as checking them one at a time)
+	var result algorithms.Suggestion
+	for _, suggestion := range chunkResults {
+		if suggestion.Likelihood > result.Likelihood {
+			result = suggestion
+		}
+	}
+	return Suggestion(result)
+}
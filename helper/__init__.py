@@ -2,7 +2,84 @@
 
 Helper Functions
 ----------------
-- get_library(dll_path:str,source_path:str="", compile:bool=False) -> CDLL: Get's the DLL specified, will compile if not found and flag is specified
+- get_library(dll_path:str,source_path:str="", compile:bool=False, abi_version:int|None=None, required_features:int=0, profile:str="release", tags:list[str]|None=None, cc:str|None=None) -> GoLibrary: Get's the DLL specified, will build it (or rebuild it when the sources changed) if flag is specified
+
+Building
+--------
+- build_library(output_path: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, build_mode: str = "c-shared", env: dict[str, str] | None = None, force: bool = False) -> dict: Builds a package with the Go build driver, only if its sources changed
+- BUILD_PROFILES: The build profiles ("release", "debug", "race", "cgocheck")
+- build_targets(output_path: str, source_path: str, targets: list[str] | None = None, profile: str = "release", tags: list[str] | None = None, force: bool = False) -> list[dict]: Cross-compiles a library for several GOOS/GOARCH/libc targets with zig cc, into a directory per platform that get_library() picks from
+- BUILD_TARGETS: The targets build_targets() builds by default (linux amd64/arm64, glibc and musl)
+- platform_tag() -> str: The platform directory cross-compiled libraries for this python are in (i.e. "linux-amd64-gnu")
+- find_platform_library(dll_path: str) -> str | None: Finds the library cross-compiled for this platform next to dll_path
+- is_bundled_library(dll_path: str) -> bool: Checks if a library was bundled in a wheel by the build backend (backend.py), get_library() loads bundled libraries without building them
+- BuildError: Raised when a library can't be built, the message has the compiler output
+- new_project(name: str, directory: str, structs: list[str] | None = None, force: bool = False) -> str: Creates a new Go library with python bindings, struct typedefs and round-trip tests (go run ./cmd/cgohelper new)
+- doctor(python: str | None = None, cc: str | None = None, source_path: str = "") -> dict: Checks go, cgo, the C compiler, python's architecture and a c-shared build, with a fix for each failed check (get_library() prints them when a build fails)
+- build_extension(module: str, output_directory: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, python: str | None = None, force: bool = False) -> dict: Builds a package into a CPython extension module that converts arguments and results straight to python objects, instead of a library for ctypes
+- load_extension(path: str) -> ModuleType: Imports an extension module built by build_extension()
+- compare_latency(functions: dict[str, Callable[[], object]], number: int = 10000, repeat: int = 5) -> dict[str, float]: Times the seconds per call of each function (i.e. ctypes against an extension module)
+- generate_cffi(module: str, output_directory: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, force: bool = False, compile: bool = False, python: str | None = None) -> dict: Generates a cffi (API mode) binding of a package from it's cgo header (a cdef, a build script and a python module that converts and frees results), and optionally compiles it
+
+ABI Versioning
+--------------
+- check_abi(library: CDLL, dll_path: str, abi_version: int, required_features: int = 0): Checks that a loaded library matches the ABI version, and has the features the bindings expect
+- ABIMismatchError: Raised when a shared library was built from a different version of the helper than the python bindings (i.e. a stale lib.so)
+- ABI_VERSION, REQUIRED_FEATURES, FEATURE_*: The ABI version/feature bits these bindings were written for (pass to get_library() to check on load)
+
+Fork Safety
+-----------
+- GoLibrary: A fork-aware wrapper around a loaded library, raises ForkedProcessError when used after os.fork()
+- get_process_context() -> multiprocessing.context.BaseContext: Get's a spawn-based multiprocessing context that is safe to use with Go libraries
+- spawn_pool(processes: int | None = None, initializer=None, initargs: tuple = ()) -> Pool: Creates a process pool whose workers are safe to call Go libraries from
+
+Out-of-process Workers
+----------------------
+- get_worker(executable_path:str, source_path:str="", compile:bool=False, socket_path:str="") -> GoWorker: Get's a worker process for the Go library, will compile if not found and flag is specified
+- GoWorker: A proxy that calls functions in a Go library running as a separate (restartable) worker process
+- WorkerCrashedError: Raised when a worker process dies while handling a call
+- WorkerError: Raised when a function called in a worker returns an error
+
+Module Host
+-----------
+- get_host(dll_path:str, source_path:str="", compile:bool=False) -> GoHost: Get's a host library, will compile if not found and flag is specified
+- GoHost: A host library that many Go packages registered their functions in, with a namespaced view per module (i.e. host.scraping.parse_urls)
+- HostError: Raised when a function called through a host library returns an error
+
+Runtime Tuning
+--------------
+- GoRuntime(library: GoLibrary | CDLL): Reads and sets the tuning knobs (gomaxprocs, gc_percent, memory_limit) of the Go runtime embedded in a library, and triggers a gc()/free_os_memory()
+- go_runtime: The GoRuntime of the helper's own library
+- GoRuntime.metrics() -> dict[str, float]: Takes a snapshot of runtime/metrics (heap, goroutines, GC cycles, cgo calls etc.)
+- GoRuntime.cpu_profile(path)/trace(path)/mutex_profile(path)/block_profile(path): Context managers that profile the Go side of the code in a with block (open the results with go tool pprof/go tool trace)
+- GoRuntime.write_profile(name: str, path: str, debug: int = 0): Writes a named runtime profile ("heap", "goroutine", "mutex" etc.) to a file
+- ProfilingError: Raised when a profile or trace can't be started, stopped or written
+- metrics_to_prometheus(metrics: dict[str, float], prefix: str = "go", labels: dict[str, str] | None = None) -> str: Formats a metrics snapshot as Prometheus text
+
+Worker Pool
+-----------
+- GoPool(library: GoLibrary | CDLL): Resizes (size) and inspects (stats()) the bounded worker pool a library's exported functions run their parallel work on
+- go_pool: The GoPool of the helper's own library
+
+Buffer Pool
+-----------
+- GoBufferPool(library: GoLibrary | CDLL): Sets the cap (cap), frees the idle memory (trim()) and inspects (stats()) the pool of C memory a library's array conversions allocate from
+- go_buffer_pool: The GoBufferPool of the helper's own library
+
+Self-test
+---------
+- self_test(library: GoLibrary | CDLL | None = None, check: bool = False) -> dict: Runs the conversion round-trips (empty arrays, NaN/Inf, int extremes, multi-byte UTF-8, very long strings) against buffers allocated by python, to check the ABI on the target machine
+- SelfTestError: Raised by self_test(check=True) when a case fails
+- SELF_TEST_INTS, SELF_TEST_FLOATS, SELF_TEST_TEXT: The values python writes into the self-test buffers (and expects back)
+
+Memory Layout
+-------------
+- compare_struct_layouts(structures: dict[str, type[Structure]] | None = None, library: GoLibrary | CDLL | None = None, check: bool = False) -> list[LayoutMismatch]: Compares the size, alignment and field offsets of ctypes Structures against the C structs cgo compiled (by default the helper's own, HELPER_STRUCTURES)
+- struct_layouts(library: GoLibrary | CDLL | None = None) -> dict[str, dict]: Gets the layout of every struct registered in a library, as cgo laid it out
+- structure_layout(structure: type[Structure]) -> dict: Gets the layout of a ctypes Structure, in the same form as struct_layouts()
+- hexdump(data: int | Structure | Array, length: int | None = None, library: GoLibrary | CDLL | None = None) -> str: Dumps memory the way Go sees it (in the format of hexdump -C)
+- LayoutMismatch: A difference between how Go and ctypes lay out a struct, str() it for a readable message
+- StructLayoutError: Raised by compare_struct_layouts(check=True) when a layout differs
 
 Converting to ctypes
 --------------------
@@ -10,6 +87,48 @@ Converting to ctypes
 - prepare_string_array(data:list[str|bytes]) -> tuple[Array[c_char_p], int]: Takes in a string list, and converts it to a C-compatible array
 - prepare_int_array(data:list[int]) -> tuple[Array[c_int], int]: Takes in a int list, and converts it to a C-compatible array
 - prepare_float_array(data:list[float]) -> tuple[Array[c_float], int]: Takes in a float list, and converts it to a C-compatible array
+- check_c_int(value: int) -> int: Raises an OverflowError if a python int doesn't fit in a C int (instead of ctypes silently wrapping it)
+
+Caller-allocated Buffers
+------------------------
+- prepare_int_buffer(capacity: int) -> tuple[Array[c_int], int]: Allocates a C int array for Go to fill in place (reuse it across calls instead of having Go malloc a result)
+- prepare_float_buffer(capacity: int) -> tuple[Array[c_float], int]: Allocates a C float array for Go to fill in place
+- prepare_byte_buffer(capacity: int) -> tuple[Array[c_char], int]: Allocates a C char buffer for Go to fill in place with bytes or a null terminated string
+- check_fill(result: int, capacity: int) -> int: Checks the return value of a fill function, returns the number of elements written
+- BufferTooSmallError: Raised by check_fill() when the buffer was too small, error.required is the capacity needed
+
+Nullable Values
+---------------
+- prepare_nullable_int_array(data: list[int | None]) -> tuple[Array[c_int], Array[c_ubyte], int]: Converts a list of ints/Nones to a C array and validity bitmap (None stays different from 0)
+- prepare_nullable_float_array(data: list[float | None]) -> tuple[Array[c_float], Array[c_ubyte], int]: Converts a list of floats/Nones to a C array and validity bitmap
+- prepare_validity_bitmap(data: list) -> Array[c_ubyte]: Builds the validity bitmap (Apache Arrow layout) for a list
+- is_valid(validity, i: int) -> bool: Checks if item i has a value in a validity bitmap
+- nullable_int_array_result_to_list(pointer: _CNullableIntArrayResult) -> list[int | None]: Converts a nullable int result to a list, and frees it
+- nullable_float_array_result_to_list(pointer: _CNullableFloatArrayResult) -> list[float | None]: Converts a nullable float result to a list, and frees it
+
+Time Conversions
+----------------
+- prepare_timestamp(value: datetime) -> _CTimestamp: Converts a datetime to a C Timestamp (Unix nanoseconds plus timezone offset), naive datetimes are local time
+- timestamp_to_datetime(timestamp: _CTimestamp) -> datetime: Converts a Timestamp from Go to a timezone-aware datetime
+- prepare_duration(value: timedelta) -> int: Converts a timedelta to a C Duration (nanoseconds)
+- duration_to_timedelta(nanoseconds: int) -> timedelta: Converts a Duration from Go to a timedelta
+- go_now() -> datetime: The current time according to Go
+
+Arbitrary-precision Numbers
+---------------------------
+- prepare_big_int(value: int) -> tuple[bytes, int]: Converts an int of any size to two's-complement bytes for Go's big.Int
+- big_int_result_to_int(pointer: _CBigIntResult) -> int: Converts a BigIntResult to an int, and frees it
+- decimal_result_to_int(pointer: _CDecimalResult) -> int: Converts a DecimalResult holding a decimal integer to an int, and frees it
+- decimal_result_to_decimal(pointer: _CDecimalResult) -> Decimal: Converts a DecimalResult (i.e. a big.Float) to a decimal.Decimal, and frees it
+- decimal_result_to_fraction(pointer: _CDecimalResult) -> Fraction: Converts a DecimalResult holding a fraction (i.e. a big.Rat) to a fractions.Fraction, and frees it
+
+Versioned Structs
+-----------------
+- StructHeader: The (size, version) header at the start of a versioned struct
+- VersionedStructure: Base class for versioned structs, with new(), check(), has_field() and get() (fields Go didn't write are missing)
+- fill_versioned_struct(function, struct: VersionedStructure) -> VersionedStructure: Calls a Go function that fills a versioned struct, and checks the header
+- StructVersionError: Raised when Go rejects a versioned struct, or its header doesn't make sense
+- library_info() -> LibraryInfo: Information about the loaded library, read through a versioned struct
 
 Converting from ctypes
 ----------------------
@@ -18,12 +137,35 @@ Converting from ctypes
 - int_array_result_to_list(pointer: _CIntArrayResult) -> list[int]: 
 - float_array_result_to_list(pointer: _CFloatArrayResult) -> list[float]: 
 
+Text Encodings
+--------------
+- decode_utf8(data: bytes, errors: str = "strict") -> DecodedText: Decodes UTF-8 in Go with a "strict", "replace" or "surrogateescape" policy, and reports where invalid sequences are
+- decode_utf16(data: bytes, errors: str = "strict", big_endian: bool = False) -> DecodedText: Decodes UTF-16 in Go, and reports where unpaired surrogates are
+- decode_latin1(data: bytes) -> str: Decodes Latin-1 in Go
+- DecodedText: A (text, invalid_offsets) named tuple
+- InvalidEncodingError: Raised by the "strict" policy, error.invalid_offsets has where the invalid sequences are
+- ENCODING_STRICT, ENCODING_REPLACE, ENCODING_SURROGATEESCAPE: The policy values passed to the Go functions
+
 Debugging Functions
 -------------------
 - return_string(text: str | bytes) -> str: Debugging function that shows you the Go representation of a C string and returns the python string version
 - return_string_array(c_array:CStringArray, number_of_elements:int) ->list[str]: Debugging function that shows you the Go representation of a C array and returns the python list version (does not free)
 - return_int_array(c_array: CIntArray, number_of_elements: int) -> list[int]: Debugging function that shows you the Go representation of a C int array and returns a Python list
 - return_float_array(c_array: CFloatArray, number_of_elements: int) -> list[float]: Debugging function that shows you the Go representation of a C float array and returns a Python list
+- return_nullable_int_array(c_array: CIntArray, validity: CValidityBitmap, number_of_elements: int) -> list[int | None]: Debugging function that round trips a nullable int array through Go
+- return_nullable_float_array(c_array: CFloatArray, validity: CValidityBitmap, number_of_elements: int) -> list[float | None]: Debugging function that round trips a nullable float array through Go
+- return_nullable_string_array(c_array: CStringArray, number_of_elements: int) -> list[str | None]: Debugging function that round trips a string array with None entries through Go
+- return_timestamp(value: datetime) -> datetime: Debugging function that round trips a datetime through Go's time.Time
+- return_duration(value: timedelta) -> timedelta: Debugging function that round trips a timedelta through Go's time.Duration
+- return_big_int(value: int) -> int: Debugging function that round trips an int through Go's big.Int
+- return_big_int_string(value: int | str) -> int: Debugging function that has Go parse a decimal integer string to a big.Int
+- format_big_int(value: int) -> str: Debugging function that has Go format an int as a decimal string
+- return_big_float(value: Decimal | str, precision: int = 0) -> Decimal: Debugging function that round trips a Decimal through Go's big.Float
+- return_big_rat(value: Fraction | Decimal | str) -> Fraction: Debugging function that round trips a Fraction through Go's big.Rat
+- fill_int_array(c_array: CIntArray, number_of_elements: int, buffer: CIntArray, capacity: int) -> list[int]: Debugging function that has Go copy a C int array into a caller-allocated buffer
+- fill_float_array(c_array: CFloatArray, number_of_elements: int, buffer: CFloatArray, capacity: int) -> list[float]: Debugging function that has Go copy a C float array into a caller-allocated buffer
+- fill_bytes(data: bytes, buffer: CByteBuffer, capacity: int) -> bytes: Debugging function that has Go copy bytes into a caller-allocated buffer
+- fill_string(text: str | bytes, buffer: CByteBuffer, capacity: int) -> str: Debugging function that has Go copy a string into a caller-allocated buffer
 - print_string(text: str | bytes): Prints a string's go representation, useful to look for encoding issues
 - print_string_array(data:list[str|bytes]): Prints a string array's go representation, useful to look for encoding issues
 - print_int_array(data:list[int]): Prints a int array's go representation, useful to look for rounding/conversion issues
@@ -45,17 +187,132 @@ from platform import platform
 # Exported functions
 from .lib import (
     get_library,
+    build_library,
+    BUILD_PROFILES,
+    build_targets,
+    BUILD_TARGETS,
+    platform_tag,
+    find_platform_library,
+    is_bundled_library,
+    BuildError,
+    new_project,
+    doctor,
+    build_extension,
+    load_extension,
+    compare_latency,
+    generate_cffi,
+    check_abi,
+    ABIMismatchError,
+    ABI_VERSION,
+    REQUIRED_FEATURES,
+    FEATURE_FORK_DETECTION,
+    FEATURE_WORKER,
+    FEATURE_HOST,
+    FEATURE_RUNTIME_TUNING,
+    FEATURE_METRICS,
+    FEATURE_PROFILING,
+    FEATURE_FILL_BUFFERS,
+    FEATURE_ENCODINGS,
+    FEATURE_NULLABLE,
+    FEATURE_TIME,
+    FEATURE_BIG_NUMBERS,
+    FEATURE_VERSIONED_STRUCTS,
+    FEATURE_WORKER_POOL,
+    FEATURE_BUFFER_POOL,
+    FEATURE_SELF_TEST,
+    FEATURE_LAYOUT,
+    GoLibrary,
+    ForkedProcessError,
+    get_process_context,
+    spawn_pool,
+    get_worker,
+    GoWorker,
+    WorkerCrashedError,
+    WorkerError,
+    get_host,
+    GoHost,
+    HostError,
+    GoRuntime,
+    go_runtime,
+    metrics_to_prometheus,
+    ProfilingError,
+    GoPool,
+    go_pool,
+    GoBufferPool,
+    go_buffer_pool,
+    self_test,
+    SelfTestError,
+    SELF_TEST_INTS,
+    SELF_TEST_FLOATS,
+    SELF_TEST_TEXT,
+    HELPER_STRUCTURES,
+    LayoutMismatch,
+    StructLayoutError,
+    struct_layouts,
+    structure_layout,
+    compare_struct_layouts,
+    hexdump,
     prepare_string,
     prepare_string_array,
     prepare_int_array,
     prepare_float_array,
+    check_c_int,
+    prepare_int_buffer,
+    prepare_float_buffer,
+    prepare_byte_buffer,
+    check_fill,
+    BufferTooSmallError,
+    prepare_nullable_int_array,
+    prepare_nullable_float_array,
+    prepare_validity_bitmap,
+    is_valid,
+    nullable_int_array_result_to_list,
+    nullable_float_array_result_to_list,
+    prepare_timestamp,
+    timestamp_to_datetime,
+    prepare_duration,
+    duration_to_timedelta,
+    go_now,
+    prepare_big_int,
+    big_int_result_to_int,
+    decimal_result_to_int,
+    decimal_result_to_decimal,
+    decimal_result_to_fraction,
+    StructHeader,
+    VersionedStructure,
+    fill_versioned_struct,
+    StructVersionError,
+    LibraryInfo,
+    library_info,
     string_array_result_to_list,
     int_array_result_to_list,
     float_array_result_to_list,
+    decode_utf8,
+    decode_utf16,
+    decode_latin1,
+    DecodedText,
+    InvalidEncodingError,
+    ENCODING_STRICT,
+    ENCODING_REPLACE,
+    ENCODING_SURROGATEESCAPE,
     return_string,
     return_string_array,
     return_int_array,
     return_float_array,
+    return_nullable_int_array,
+    return_nullable_float_array,
+    return_nullable_string_array,
+    return_timestamp,
+    return_duration,
+    return_big_int,
+    return_big_int_string,
+    format_big_int,
+    return_big_float,
+    return_big_rat,
+    fill_int_array,
+    fill_float_array,
+    fill_bytes,
+    fill_string,
     print_string,
     print_string_array,
     print_int_array,
@@ -70,11 +327,11 @@ from .lib import (
 )
 
 # Check if library exists, and if it doesn't compile it
-dll_source_file = os.path.join(os.path.dirname(os.path.realpath(__file__)), "lib.go")
+dll_source_file = os.path.dirname(os.path.realpath(__file__))
 if platform().lower().startswith("windows"):
     dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.dll")
-    get_library(dll_file, dll_source_file, True)
+    get_library(dll_file, dll_source_file, True, ABI_VERSION, REQUIRED_FEATURES)
 else:
     dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.so")
-    get_library(dll_file, dll_source_file, True)
+    get_library(dll_file, dll_source_file, True, ABI_VERSION, REQUIRED_FEATURES)
 
@@ -0,0 +1,51 @@
+package main
+
+import "C"
+
+// The version of the helper's C ABI, bump it whenever an exported signature or struct layout changes
+const ABIVersion = 2
+
+// Bit flags for the optional features compiled into the library, check them with helper_abi_features()
+const (
+	FeatureForkDetection    uint64 = 1 << iota // helper_get_init_pid()/helper_is_forked_process()
+	FeatureWorker                              // The worker protocol (RunWorkerMain())
+	FeatureHost                                // helper_host_call()/helper_host_modules()
+	FeatureRuntimeTuning                       // helper_set_gomaxprocs()/helper_set_gc_percent()/helper_set_memory_limit() etc.
+	FeatureMetrics                             // helper_runtime_metrics()
+	FeatureProfiling                           // helper_start_cpu_profile()/helper_write_profile()/helper_start_trace() etc.
+	FeatureFillBuffers                         // helper_fill_int_array()/helper_fill_string() etc. (caller-allocated output buffers)
+	FeatureEncodings                           // helper_decode_utf8()/helper_decode_utf16()/helper_decode_latin1()
+	FeatureNullable                            // NullableIntArrayResult/NullableFloatArrayResult and NULL entries in string arrays
+	FeatureTime                                // Timestamp/Duration and helper_return_timestamp()/helper_now()
+	FeatureBigNumbers                          // BigIntResult/DecimalResult and helper_return_big_int()/helper_return_big_float() etc.
+	FeatureVersionedStructs                    // StructHeader and helper_library_info()
+	FeatureWorkerPool                          // SharedPool and helper_pool_size()/helper_pool_resize()/helper_pool_stats()
+	FeatureBufferPool                          // SharedBufferPool and helper_buffer_pool_set_cap()/helper_buffer_pool_stats() etc.
+	FeatureSelfTest                            // helper_self_test()
+	FeatureLayout                              // helper_struct_layouts()/helper_hexdump()
+)
+
+// The features compiled into this build of the library
+const ABIFeatures = FeatureForkDetection | FeatureWorker | FeatureHost | FeatureRuntimeTuning | FeatureMetrics | FeatureProfiling | FeatureFillBuffers | FeatureEncodings | FeatureNullable | FeatureTime | FeatureBigNumbers | FeatureVersionedStructs | FeatureWorkerPool | FeatureBufferPool | FeatureSelfTest | FeatureLayout
+
+// ======== ABI Versioning ========
+
+// Returns the version of the helper's C ABI, the python loader compares it to the version it was written for
+//
+// Returns:
+//   - ABIVersion (C.int).
+//
+//export helper_abi_version
+func helper_abi_version() C.int {
+	return C.int(ABIVersion)
+}
+
+// Returns a bitmap of the optional features compiled into the library
+//
+// Returns:
+//   - ABIFeatures (C.ulonglong), each bit is one of the Feature* constants.
+//
+//export helper_abi_features
+func helper_abi_features() C.ulonglong {
+	return C.ulonglong(ABIFeatures)
+}
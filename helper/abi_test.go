@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestABI(t *testing.T) {
+	if int(helper_abi_version()) != ABIVersion {
+		t.Errorf("TestABI:helper_abi_version(): %d!=%d", helper_abi_version(), ABIVersion)
+	}
+	features := uint64(helper_abi_features())
+	for _, feature := range []uint64{FeatureForkDetection, FeatureWorker, FeatureHost, FeatureRuntimeTuning, FeatureMetrics, FeatureProfiling, FeatureFillBuffers, FeatureEncodings, FeatureNullable, FeatureTime, FeatureBigNumbers, FeatureVersionedStructs, FeatureWorkerPool, FeatureBufferPool, FeatureSelfTest, FeatureLayout} {
+		if features&feature == 0 {
+			t.Errorf("TestABI:helper_abi_features(): %b is missing feature %b", features, feature)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+"""A PEP 517 build backend that builds the Go shared libraries of a package, and bundles them in a platform-tagged wheel
+
+Notes
+-----
+- Only uses the standard library (and tomli on python 3.10), libraries are built with the helper's build driver
+  (go run ./cmd/cgohelper build), so this file has to stay in the helper's directory
+- pip only loads backends from inside a project (backend-path can't point outside it), so projects have a backend.py that
+  loads this one (see examples/scraping/original/backend.py)
+- Each library is marked as bundled (<library>.bundled.json), and get_library() loads bundled libraries as they are instead of
+  building them, so the wheel installs and runs with plain pip on machines without Go
+- Go sources aren't put in wheels (only the built libraries), sdists have everything
+
+Configuration
+-------------
+In pyproject.toml (next to the backend.py that loads this one):
+
+    [build-system]
+    requires = []
+    build-backend = "backend"
+    backend-path = ["."]
+
+    [project]
+    name = "scraping"
+    version = "0.1.0"
+
+    [tool.cgohelper]
+    packages = ["scraping"]                         # The python packages (directories) to put in the wheel
+    libraries = [                                   # The Go packages to build, the extension (.so/.dll/.dylib) is added
+        {source = "scraping/go", output = "scraping/go/lib"},
+        {source = "scraping/helpers", output = "scraping/helpers/lib"},
+    ]
+    cc = "zig cc"                                   # Optional C compiler (or pip wheel --config-settings cc="zig cc")
+    exclude = ["*.go", "go.mod", "go.sum", "*.h"]   # Optional, file patterns left out of wheels (the default)
+
+pip wheel --config-settings platform-tag=manylinux2014_x86_64 overrides the platform tag (i.e. when building with
+cc = "zig cc -target x86_64-linux-gnu.2.17")
+"""
+import os
+import io
+import re
+import sys
+import json
+import base64
+import fnmatch
+import hashlib
+import tarfile
+import zipfile
+import tempfile
+import sysconfig
+import subprocess
+
+try:
+    import tomllib
+except ModuleNotFoundError: # python 3.10, get_requires_for_build_*() asks for tomli
+    tomllib = None
+
+DEFAULT_EXCLUDE = ["*.go", "go.mod", "go.sum", "*.h", "*.build.json", "*.bundled.json", "*.pyc", "test_*.py"]
+
+# Directories never put in wheels or sdists
+_SKIPPED_DIRECTORIES = {"__pycache__", ".git", ".venv", "build", "dist"}
+
+_HELPER_DIRECTORY = os.path.dirname(os.path.realpath(__file__))
+
+class BackendError(RuntimeError):
+    """Raised when the project can't be built (i.e. missing configuration, or go build failed)"""
+
+def _load_config() -> dict:
+    """Reads pyproject.toml in the current directory (pip runs backends in the project's directory)"""
+    if tomllib is None:
+        import tomli as toml # Installed by get_requires_for_build_*()
+    else:
+        toml = tomllib
+    try:
+        with open("pyproject.toml", "rb") as file:
+            pyproject = toml.load(file)
+    except FileNotFoundError:
+        raise BackendError(f"No pyproject.toml in {os.getcwd()}")
+    project = pyproject.get("project", {})
+    if "name" not in project or "version" not in project:
+        raise BackendError("pyproject.toml needs a [project] table with a name and version")
+    config = pyproject.get("tool", {}).get("cgohelper", {})
+    if not config.get("packages"):
+        raise BackendError("pyproject.toml needs [tool.cgohelper] packages = [...], the python packages to put in the wheel")
+    return {"project": project, "config": config}
+
+def _library_extension() -> str:
+    if sys.platform.startswith("win"):
+        return ".dll"
+    if sys.platform == "darwin":
+        return ".dylib"
+    return ".so"
+
+def wheel_platform_tag() -> str:
+    """The wheel platform tag of this python (i.e. linux_x86_64, win_amd64, macosx_11_0_arm64)"""
+    return re.sub(r"[-.]", "_", sysconfig.get_platform())
+
+def _normalize(name: str) -> str:
+    """Normalizes a distribution name for file names (PEP 427)"""
+    return re.sub(r"[-_.]+", "_", name).lower()
+
+def _files(directory: str, exclude: list[str]):
+    """Yields the files in a directory (relative to the current directory), except excluded ones"""
+    for root, directories, files in os.walk(directory):
+        directories[:] = sorted(d for d in directories if d not in _SKIPPED_DIRECTORIES)
+        for name in sorted(files):
+            if not any(fnmatch.fnmatch(name, pattern) for pattern in exclude):
+                yield os.path.relpath(os.path.join(root, name)).replace(os.sep, "/")
+
+def _build_library(source: str, output: str, cc: str | None):
+    """Builds a Go package into a shared library with the build driver (go run ./cmd/cgohelper build), with the release profile"""
+    command = ["go", "run", "./cmd/cgohelper", "build", "-o", output]
+    if cc:
+        command += ["-cc", cc]
+    command.append(os.path.abspath(source))
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BackendError("Unable to find Go install, building a wheel needs go (installing the wheel doesn't)")
+    if completed.returncode != 0:
+        raise BackendError(f"go build failed in {source}:\n{completed.stderr.strip()}")
+
+def _metadata(project: dict) -> str:
+    """The METADATA (and PKG-INFO) of a project"""
+    lines = ["Metadata-Version: 2.1", f"Name: {project['name']}", f"Version: {project['version']}"]
+    if "description" in project:
+        lines.append(f"Summary: {project['description']}")
+    if "requires-python" in project:
+        lines.append(f"Requires-Python: {project['requires-python']}")
+    for dependency in project.get("dependencies", []):
+        lines.append(f"Requires-Dist: {dependency}")
+    return "\n".join(lines) + "\n"
+
+def _record_hash(data: bytes) -> str:
+    return "sha256=" + base64.urlsafe_b64encode(hashlib.sha256(data).digest()).rstrip(b"=").decode()
+
+def get_requires_for_build_wheel(config_settings=None) -> list[str]:
+    """PEP 517 hook, nothing is needed except tomli on python 3.10"""
+    return [] if tomllib is not None else ["tomli"]
+
+def get_requires_for_build_sdist(config_settings=None) -> list[str]:
+    """PEP 517 hook, nothing is needed except tomli on python 3.10"""
+    return get_requires_for_build_wheel(config_settings)
+
+def build_wheel(wheel_directory: str, config_settings: dict | None = None, metadata_directory: str | None = None) -> str:
+    """PEP 517 hook, builds each library and writes a platform-tagged wheel with them in it
+
+    Parameters
+    ----------
+    wheel_directory : str
+        The directory to write the wheel to
+
+    config_settings : dict | None, optional
+        "cc" overrides the C compiler, "platform-tag" overrides the platform tag, by default None
+
+    metadata_directory : str | None, optional
+        Unused, the metadata is always generated from pyproject.toml
+
+    Returns
+    -------
+    str
+        The file name of the wheel
+
+    Raises
+    ------
+    BackendError
+        If pyproject.toml isn't configured, or a library fails to build
+    """
+    config_settings = config_settings or {}
+    loaded = _load_config()
+    project, config = loaded["project"], loaded["config"]
+    cc = config_settings.get("cc") or config.get("cc")
+    tag = f"py3-none-{config_settings.get('platform-tag') or wheel_platform_tag()}"
+    name, version = _normalize(project["name"]), project["version"]
+    dist_info = f"{name}-{version}.dist-info"
+    wheel_name = f"{name}-{version}-{tag}.whl"
+    exclude = config.get("exclude", DEFAULT_EXCLUDE)
+
+    records = []
+    with tempfile.TemporaryDirectory() as build_directory, zipfile.ZipFile(os.path.join(wheel_directory, wheel_name), "w", zipfile.ZIP_DEFLATED) as wheel:
+        def add(path: str, data: bytes):
+            wheel.writestr(path, data)
+            records.append(f"{path},{_record_hash(data)},{len(data)}")
+
+        libraries = config.get("libraries", [])
+        outputs = [os.path.normpath(library["output"]).replace(os.sep, "/") + _library_extension() for library in libraries]
+        for package in config["packages"]:
+            for path in _files(package, exclude):
+                # A library built in the source tree is replaced by the fresh build
+                if path in outputs:
+                    continue
+                with open(path, "rb") as file:
+                    add(path, file.read())
+
+        for i, (library, output) in enumerate(zip(libraries, outputs)):
+            built = os.path.join(build_directory, str(i), os.path.basename(output))
+            _build_library(library["source"], os.path.abspath(built), cc)
+            with open(built, "rb") as file:
+                add(output, file.read())
+            # Tells get_library() to use the library as is, instead of building it
+            add(output + ".bundled.json", json.dumps({"platform": tag, "cc": cc or ""}).encode())
+
+        add(f"{dist_info}/METADATA", _metadata(project).encode())
+        add(f"{dist_info}/WHEEL", f"Wheel-Version: 1.0\nGenerator: cgohelper-backend\nRoot-Is-Purelib: false\nTag: {tag}\n".encode())
+        records.append(f"{dist_info}/RECORD,,")
+        wheel.writestr(f"{dist_info}/RECORD", "\n".join(records) + "\n")
+    return wheel_name
+
+def build_sdist(sdist_directory: str, config_settings: dict | None = None) -> str:
+    """PEP 517 hook, writes an sdist with pyproject.toml, the packages (including the Go sources) and this backend
+
+    Parameters
+    ----------
+    sdist_directory : str
+        The directory to write the sdist to
+
+    config_settings : dict | None, optional
+        Unused
+
+    Returns
+    -------
+    str
+        The file name of the sdist
+    """
+    loaded = _load_config()
+    project, config = loaded["project"], loaded["config"]
+    base = f"{_normalize(project['name'])}-{project['version']}"
+    exclude = ["*.pyc", "*.so", "*.dll", "*.dylib", "*.h", "*.build.json", "*.bundled.json"]
+    paths = ["pyproject.toml"] + [p for p in ("README.md", "LICENSE") if os.path.exists(p)]
+    for package in config["packages"]:
+        paths.extend(_files(package, exclude))
+    backend = os.path.relpath(__file__).replace(os.sep, "/")
+    if backend not in paths and not backend.startswith(".."):
+        paths.append(backend)
+
+    sdist_name = f"{base}.tar.gz"
+    with tarfile.open(os.path.join(sdist_directory, sdist_name), "w:gz", format=tarfile.PAX_FORMAT) as sdist:
+        for path in paths:
+            sdist.add(path, arcname=f"{base}/{path}")
+        info = _metadata(project).encode()
+        entry = tarfile.TarInfo(f"{base}/PKG-INFO")
+        entry.size = len(info)
+        sdist.addfile(entry, io.BytesIO(info))
+    return sdist_name
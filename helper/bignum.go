@@ -0,0 +1,347 @@
+package main
+
+/*
+#include <stdlib.h>
+
+typedef struct {
+    size_t length;          // Number of bytes in data
+    unsigned char* data;    // Big-endian two's-complement bytes (python's int.to_bytes(..., "big", signed=True))
+    char* error;            // NULL on success, otherwise why the conversion failed
+} BigIntResult;
+
+typedef struct {
+    char* data;             // The number as a string ("-123", "1.5e+100", "Infinity" or "22/7")
+    char* error;            // NULL on success, otherwise why the conversion failed
+} DecimalResult;
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unsafe"
+)
+
+// Returned (wrapped) when a string isn't a valid number
+var ErrInvalidNumber = errors.New("invalid number")
+
+func init() {
+	RegisterStructLayout("BigIntResult", C.BigIntResult{})
+	RegisterStructLayout("DecimalResult", C.DecimalResult{})
+}
+
+// ======== Arbitrary-precision Numbers ========
+
+// Converts a big.Int to big-endian two's-complement bytes, the same as python's int.to_bytes(length, "big", signed=True)
+//
+// Parameters:
+//   - x: The integer to convert.
+//
+// Returns:
+//   - The shortest two's-complement representation of x (0 is a single 0x00 byte).
+func BigIntToTwosComplement(x *big.Int) []byte {
+	if x.Sign() >= 0 {
+		result := x.Bytes()
+		// Add a leading 0 if the top bit is set, or it would read back as negative
+		if len(result) == 0 || result[0]&0x80 != 0 {
+			result = append([]byte{0}, result...)
+		}
+		return result
+	}
+	// -x = ^(x - 1), so get the magnitude of x + 1 and invert the bits
+	magnitude := new(big.Int).Add(x, big.NewInt(1))
+	result := magnitude.Neg(magnitude).Bytes()
+	if len(result) == 0 || result[0]&0x80 != 0 {
+		result = append([]byte{0}, result...)
+	}
+	for i := range result {
+		result[i] = ^result[i]
+	}
+	return result
+}
+
+// Converts big-endian two's-complement bytes to a big.Int, the same as python's int.from_bytes(data, "big", signed=True)
+//
+// Parameters:
+//   - data: The bytes to convert (empty is 0).
+//
+// Returns:
+//   - The integer.
+func TwosComplementToBigInt(data []byte) *big.Int {
+	if len(data) == 0 || data[0]&0x80 == 0 {
+		return new(big.Int).SetBytes(data)
+	}
+	inverted := make([]byte, len(data))
+	for i, b := range data {
+		inverted[i] = ^b
+	}
+	result := new(big.Int).SetBytes(inverted)
+	return result.Neg(result.Add(result, big.NewInt(1)))
+}
+
+// Parses a decimal integer string (i.e. one from python's str(int)) to a big.Int
+//
+// Parameters:
+//   - text: The decimal string, with an optional sign.
+//
+// Returns:
+//   - The integer.
+//   - An error wrapping ErrInvalidNumber if text isn't a decimal integer.
+func ParseBigInt(text string) (*big.Int, error) {
+	result, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a decimal integer", ErrInvalidNumber, text)
+	}
+	return result, nil
+}
+
+// Formats a big.Float as a decimal string that python's decimal.Decimal() can parse
+//
+// Parameters:
+//   - x: The float to format.
+//
+// Returns:
+//   - The shortest decimal string that reads back as x at its precision ("Infinity"/"-Infinity" for infinities).
+func BigFloatToDecimalString(x *big.Float) string {
+	if x.IsInf() {
+		if x.Signbit() {
+			return "-Infinity"
+		}
+		return "Infinity"
+	}
+	return x.Text('g', -1)
+}
+
+// Parses a decimal string (i.e. one from python's str(Decimal)) to a big.Float
+//
+// Parameters:
+//   - text: The decimal string, can use exponents ("1.5E+100") and be "Infinity"/"-Infinity".
+//   - precision: The precision of the result in bits, 0 picks enough bits to hold every digit of text.
+//
+// Returns:
+//   - The float, rounded to the nearest even value if it can't be represented exactly.
+//   - An error wrapping ErrInvalidNumber if text isn't a decimal number (python's "NaN" has no big.Float equivalent).
+func ParseBigFloat(text string, precision uint) (*big.Float, error) {
+	if precision == 0 {
+		// ~3.33 bits per digit, plus room so the last digit is exact
+		precision = uint(len(text))*4 + 64
+	}
+	normalized := strings.Replace(strings.TrimPrefix(text, "+"), "Infinity", "Inf", 1)
+	result, _, err := big.ParseFloat(normalized, 10, precision, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a decimal number", ErrInvalidNumber, text)
+	}
+	return result, nil
+}
+
+// Parses a rational string ("22/7") or a decimal string ("3.125") to a big.Rat
+//
+// Parameters:
+//   - text: The fraction or decimal string.
+//
+// Returns:
+//   - The exact rational.
+//   - An error wrapping ErrInvalidNumber if text isn't a fraction or a finite decimal (or the denominator is 0).
+func ParseBigRat(text string) (*big.Rat, error) {
+	result, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a fraction or decimal", ErrInvalidNumber, text)
+	}
+	return result, nil
+}
+
+// Converts a big.Int to a C-compatible two's-complement byte buffer
+//
+// Parameters:
+//   - x: The integer to convert.
+//
+// Returns:
+//   - Pointer to a C.BigIntResult.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_big_int_result.
+func BigIntToCBytes(x *big.Int) *C.BigIntResult {
+	return bigIntToCResult(x, nil)
+}
+
+// Converts the output of a big.Int conversion to a BigIntResult to be returned to C code
+func bigIntToCResult(x *big.Int, err error) *C.BigIntResult {
+	result := (*C.BigIntResult)(C.malloc(C.size_t(unsafe.Sizeof(C.BigIntResult{}))))
+	result.length = 0
+	result.data = nil
+	if err == nil {
+		data := BigIntToTwosComplement(x)
+		result.length = C.size_t(len(data))
+		result.data = (*C.uchar)(C.CBytes(data))
+	}
+	result.error = (*C.char)(errorToCString(err))
+	return result
+}
+
+// Converts a number string (or the error from producing one) to a DecimalResult to be returned to C code
+//
+// Parameters:
+//   - text: The number as a string.
+//   - err: The error from the conversion (or nil).
+//
+// Returns:
+//   - Pointer to a C.DecimalResult.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decimal_result.
+func DecimalStringToCResult(text string, err error) *C.DecimalResult {
+	result := (*C.DecimalResult)(C.malloc(C.size_t(unsafe.Sizeof(C.DecimalResult{}))))
+	result.data = nil
+	if err == nil {
+		result.data = C.CString(text)
+	}
+	result.error = (*C.char)(errorToCString(err))
+	return result
+}
+
+// Takes a C two's-complement byte buffer, and converts it to a big.Int
+//
+// Parameters:
+//   - data: Pointer to the bytes (*C.uchar), big-endian.
+//   - length: Number of bytes in data.
+//
+// Returns:
+//   - The integer.
+func CBytesToBigInt(data unsafe.Pointer, length int) *big.Int {
+	return TwosComplementToBigInt(unsafe.Slice((*byte)(data), length))
+}
+
+// Used to convert a C-compatible two's-complement integer to a big.Int and back
+//
+// Parameters:
+//   - data: Pointer to the bytes (*C.uchar), big-endian two's-complement.
+//   - length: Number of bytes in data (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.BigIntResult with the shortest representation of the same integer.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_big_int_result.
+//
+//export helper_return_big_int
+func helper_return_big_int(data unsafe.Pointer, length C.size_t) *C.BigIntResult {
+	if err := CheckNotForked(); err != nil {
+		return bigIntToCResult(nil, err)
+	}
+	goLength, err := CSizeToInt(length)
+	if err != nil {
+		return bigIntToCResult(nil, err)
+	}
+	return BigIntToCBytes(CBytesToBigInt(data, goLength))
+}
+
+// Used to convert a decimal integer string to a big.Int, and return it as two's-complement bytes
+//
+// Parameters:
+//   - cString: The decimal integer (*C.char).
+//
+// Returns:
+//   - Pointer to a C.BigIntResult, with an error if the string isn't a decimal integer.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_big_int_result.
+//
+//export helper_big_int_from_string
+func helper_big_int_from_string(cString unsafe.Pointer) *C.BigIntResult {
+	if err := CheckNotForked(); err != nil {
+		return bigIntToCResult(nil, err)
+	}
+	return bigIntToCResult(ParseBigInt(C.GoString((*C.char)(cString))))
+}
+
+// Used to convert two's-complement bytes to a big.Int, and return it as a decimal string
+//
+// Parameters:
+//   - data: Pointer to the bytes (*C.uchar), big-endian two's-complement.
+//   - length: Number of bytes in data (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.DecimalResult with the decimal integer.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decimal_result.
+//
+//export helper_big_int_to_string
+func helper_big_int_to_string(data unsafe.Pointer, length C.size_t) *C.DecimalResult {
+	if err := CheckNotForked(); err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	goLength, err := CSizeToInt(length)
+	if err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	return DecimalStringToCResult(CBytesToBigInt(data, goLength).String(), nil)
+}
+
+// Used to convert a decimal string to a big.Float and back, good for debugging precision issues
+//
+// Parameters:
+//   - cString: The decimal number (*C.char).
+//   - precision: The precision of the big.Float in bits (C.uint), 0 keeps every digit.
+//
+// Returns:
+//   - Pointer to a C.DecimalResult with the number as the big.Float saw it, with an error if the string isn't a number.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decimal_result.
+//
+//export helper_return_big_float
+func helper_return_big_float(cString unsafe.Pointer, precision C.uint) *C.DecimalResult {
+	if err := CheckNotForked(); err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	x, err := ParseBigFloat(C.GoString((*C.char)(cString)), uint(precision))
+	if err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	return DecimalStringToCResult(BigFloatToDecimalString(x), nil)
+}
+
+// Used to convert a fraction or decimal string to a big.Rat and back
+//
+// Parameters:
+//   - cString: The fraction ("22/7") or decimal ("3.125") (*C.char).
+//
+// Returns:
+//   - Pointer to a C.DecimalResult with the reduced fraction ("25/8", or "3" for integers), with an error if the string isn't a number.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decimal_result.
+//
+//export helper_return_big_rat
+func helper_return_big_rat(cString unsafe.Pointer) *C.DecimalResult {
+	if err := CheckNotForked(); err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	x, err := ParseBigRat(C.GoString((*C.char)(cString)))
+	if err != nil {
+		return DecimalStringToCResult("", err)
+	}
+	return DecimalStringToCResult(x.RatString(), nil)
+}
+
+// Free a *C.BigIntResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.BigIntResult to be freed (*C.BigIntResult).
+//
+//export helper_free_big_int_result
+func helper_free_big_int_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	if ptr == nil {
+		return
+	}
+	temp := (*C.BigIntResult)(ptr)
+	C.free(unsafe.Pointer(temp.data))
+	C.free(unsafe.Pointer(temp.error))
+	C.free(ptr)
+}
+
+// Free a *C.DecimalResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.DecimalResult to be freed (*C.DecimalResult).
+//
+//export helper_free_decimal_result
+func helper_free_decimal_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	if ptr == nil {
+		return
+	}
+	temp := (*C.DecimalResult)(ptr)
+	C.free(unsafe.Pointer(temp.data))
+	C.free(unsafe.Pointer(temp.error))
+	C.free(ptr)
+}
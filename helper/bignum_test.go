@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"unsafe"
+)
+
+func TestTwosComplement(t *testing.T) {
+	// Expected values are from python's int.to_bytes(..., "big", signed=True)
+	for test_input, expected := range map[int64][]byte{
+		0:    {0x00},
+		1:    {0x01},
+		127:  {0x7f},
+		128:  {0x00, 0x80},
+		-1:   {0xff},
+		-128: {0x80},
+		-129: {0xff, 0x7f},
+		-256: {0xff, 0x00},
+	} {
+		temp := BigIntToTwosComplement(big.NewInt(test_input))
+		if !bytes.Equal(temp, expected) {
+			t.Errorf("TestTwosComplement:BigIntToTwosComplement(%d): %x!=%x", test_input, temp, expected)
+		}
+		if back := TwosComplementToBigInt(temp); back.Int64() != test_input {
+			t.Errorf("TestTwosComplement:TwosComplementToBigInt(%x): %v!=%d", temp, back, test_input)
+		}
+	}
+
+	// 25! overflows an int64, Factorial() in easy-part can only go up to 20!
+	test_input := new(big.Int).MulRange(1, 25)
+	for _, x := range []*big.Int{test_input, new(big.Int).Neg(test_input)} {
+		if temp := TwosComplementToBigInt(BigIntToTwosComplement(x)); temp.Cmp(x) != 0 {
+			t.Errorf("TestTwosComplement:TwosComplementToBigInt(BigIntToTwosComplement(%v)): %v!=%v", x, temp, x)
+		}
+	}
+	if temp := TwosComplementToBigInt(nil); temp.Sign() != 0 {
+		t.Errorf("TestTwosComplement:TwosComplementToBigInt(nil): %v!=0", temp)
+	}
+
+	r := helper_return_big_int(unsafe.Pointer(&[]byte{0xff, 0xff, 0x80}[0]), 3)
+	defer helper_free_big_int_result(unsafe.Pointer(r))
+	if temp := unsafe.Slice((*byte)(unsafe.Pointer(r.data)), r.length); !bytes.Equal(temp, []byte{0x80}) {
+		t.Errorf("TestTwosComplement:helper_return_big_int(ffff80): %x!=80", temp)
+	}
+}
+
+func TestParseNumbers(t *testing.T) {
+	if x, err := ParseBigInt("-15511210043330985984000000"); err != nil || x.Cmp(new(big.Int).Neg(new(big.Int).MulRange(1, 25))) != 0 {
+		t.Errorf("TestParseNumbers:ParseBigInt(-25!): %v, %v", x, err)
+	}
+	for _, test_input := range []string{"", "1.5", "12abc"} {
+		if _, err := ParseBigInt(test_input); !errors.Is(err, ErrInvalidNumber) {
+			t.Errorf("TestParseNumbers:ParseBigInt(%q): expected ErrInvalidNumber, got %v", test_input, err)
+		}
+	}
+
+	for test_input, expected := range map[string]string{
+		"0.1":                             "0.1",
+		"1.5E+100":                        "1.5e+100",
+		"-Infinity":                       "-Infinity",
+		"+Infinity":                       "Infinity",
+		"3.14159265358979323846264338327": "3.14159265358979323846264338327",
+	} {
+		x, err := ParseBigFloat(test_input, 0)
+		if err != nil {
+			t.Errorf("TestParseNumbers:ParseBigFloat(%q): %v", test_input, err)
+			continue
+		}
+		if temp := BigFloatToDecimalString(x); temp != expected {
+			t.Errorf("TestParseNumbers:BigFloatToDecimalString(ParseBigFloat(%q)): %v!=%v", test_input, temp, expected)
+		}
+	}
+	// At float32 precision the extra digits are rounded away
+	if x, _ := ParseBigFloat("0.1", 24); BigFloatToDecimalString(x) != "0.1" || x.Prec() != 24 {
+		t.Errorf("TestParseNumbers:ParseBigFloat(0.1, 24): %v (precision %d)", BigFloatToDecimalString(x), x.Prec())
+	}
+	if _, err := ParseBigFloat("NaN", 0); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("TestParseNumbers:ParseBigFloat(NaN): expected ErrInvalidNumber, got %v", err)
+	}
+
+	for test_input, expected := range map[string]string{"22/7": "22/7", "3.125": "25/8", "-10/5": "-2", "1E+3": "1000"} {
+		x, err := ParseBigRat(test_input)
+		if err != nil || x.RatString() != expected {
+			t.Errorf("TestParseNumbers:ParseBigRat(%q): %v!=%v (%v)", test_input, x, expected, err)
+		}
+	}
+	if _, err := ParseBigRat("1/0"); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("TestParseNumbers:ParseBigRat(1/0): expected ErrInvalidNumber, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"math/bits"
+	"os"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// ======== C Buffer Pool ========
+
+const (
+	minBufferClass   = 4  // The smallest size class is 1<<4 (16) bytes
+	maxBufferClass   = 20 // The largest is 1<<20 (1MiB), bigger allocations aren't pooled
+	numberOfClasses  = maxBufferClass - minBufferClass + 1
+	defaultBufferCap = 64 << 20
+	bufferHeaderSize = 16                 // Keeps the memory after the header aligned like malloc()'s, for any C type
+	bufferMagic      = 0x4c4f4f5046554243 // "CBUFPOOL"
+)
+
+// The start of every pooled block, the pointer Alloc() hands out is just past it
+//
+// Because of the header the pointer isn't the start of a malloc() allocation, so calling free() on it is an invalid
+// free (glibc aborts with "free(): invalid pointer") instead of freeing memory the pool still tracks. That also means
+// malloc() can never hand out an address that's in the pool's inUse map to anything else.
+type bufferHeader struct {
+	magic uint64
+	class uint64
+}
+
+// The environment variable that sets the cap of SharedBufferPool (the most idle bytes it keeps, not a limit on the
+// memory it hands out) when the library loads (defaults to 64MiB)
+const BufferPoolCapEnvironmentVariable = "CGOHELPER_BUFFER_POOL_CAP"
+
+// A snapshot of a CBufferPool
+type BufferPoolStats struct {
+	Cap         int64  // The most bytes of idle blocks the pool keeps in its free lists (in use blocks don't count)
+	IdleBytes   int64  // The bytes of the blocks in the free lists
+	IdleBlocks  int64  // The blocks in the free lists
+	InUseBytes  int64  // The bytes of the pooled blocks that have been handed out and not freed
+	InUseBlocks int64  // The pooled blocks that have been handed out and not freed
+	Hits        uint64 // Allocations that reused a block from a free list
+	Misses      uint64 // Allocations that had to malloc() a new block
+	Oversized   uint64 // Allocations bigger than the largest size class, which are malloc()'d and never pooled
+	Recycled    uint64 // Frees that put the block back in a free list
+	Released    uint64 // Frees (and trims) that free()'d the block because the pool was at it's cap, or didn't own it
+	Corrupted   uint64 // Frees of pooled blocks whose header had been overwritten, the block is free()'d instead of pooled
+}
+
+// Size-classed free lists of C memory, so converting arrays in a loop reuses the same blocks instead of calling
+// malloc()/free() for every result
+//
+// Notes:
+//   - Blocks are malloc() allocations rounded up to a power of two, with a bufferHeader in front, so memory from the
+//     pool can be read and written by C like any other
+//   - Memory from the pool must be released with Free() (i.e. through the helper's free functions), calling free()
+//     on it directly is an invalid free because of the header
+//   - Free() also accepts memory that didn't come from the pool (it's free()'d), so the free functions work for both
+//   - The cap only limits the idle bytes kept in the free lists, blocks that have been handed out aren't counted
+//     against it, so it doesn't limit how much memory the pool's callers hold at once
+type CBufferPool struct {
+	lock      sync.Mutex
+	free      [numberOfClasses][]unsafe.Pointer
+	inUse     map[unsafe.Pointer]uint8 // The size class of each pooled block that's been handed out (by the pointer after the header)
+	cap       int64
+	idleBytes int64
+	inUseSize int64
+	hits      uint64
+	misses    uint64
+	oversized uint64
+	recycled  uint64
+	released  uint64
+	corrupted uint64
+}
+
+// The pool the helper's conversion functions (IntSliceToCArray() etc.) allocate from
+var SharedBufferPool = NewCBufferPool(defaultBufferPoolCap())
+
+// The cap of SharedBufferPool from BufferPoolCapEnvironmentVariable, or 64MiB if it isn't set to a non-negative integer
+func defaultBufferPoolCap() int64 {
+	if capacity, err := strconv.ParseInt(os.Getenv(BufferPoolCapEnvironmentVariable), 10, 64); err == nil && capacity >= 0 {
+		return capacity
+	}
+	return defaultBufferCap
+}
+
+// Creates an empty buffer pool
+//
+// Parameters:
+//   - capacity: The most bytes of idle blocks to keep in the free lists (not a limit on what's handed out), 0 free()'s
+//     every block as soon as it's freed.
+//
+// Returns:
+//   - A pointer to the new pool.
+func NewCBufferPool(capacity int64) *CBufferPool {
+	return &CBufferPool{inUse: map[unsafe.Pointer]uint8{}, cap: max(capacity, 0)}
+}
+
+// Returns the size class for an allocation, and if it's small enough to be pooled
+func bufferClass(size uintptr) (class int, ok bool) {
+	if size <= 1<<minBufferClass {
+		return 0, true
+	}
+	class = bits.Len64(uint64(size-1)) - minBufferClass
+	return class, class < numberOfClasses
+}
+
+// Returns the header of a pooled block from the pointer Alloc() handed out
+func blockHeader(ptr unsafe.Pointer) *bufferHeader {
+	return (*bufferHeader)(unsafe.Add(ptr, -bufferHeaderSize))
+}
+
+// Allocates C memory, reusing a freed block of the same size class when there is one
+//
+// Parameters:
+//   - size: The number of bytes needed, 0 still returns a (16 byte) block.
+//
+// Returns:
+//   - A pointer to uninitialized memory of at least size bytes.
+//     Note: The caller is responsible for freeing the allocated memory using Free, not free().
+func (p *CBufferPool) Alloc(size uintptr) unsafe.Pointer {
+	class, ok := bufferClass(size)
+	if !ok {
+		p.lock.Lock()
+		p.oversized++
+		p.lock.Unlock()
+		return C.malloc(C.size_t(size))
+	}
+	blockSize := int64(1) << (class + minBufferClass)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var block unsafe.Pointer
+	if list := p.free[class]; len(list) > 0 {
+		block = list[len(list)-1]
+		p.free[class] = list[:len(list)-1]
+		p.idleBytes -= blockSize
+		p.hits++
+	} else {
+		header := (*bufferHeader)(C.malloc(C.size_t(bufferHeaderSize + blockSize)))
+		header.magic, header.class = bufferMagic, uint64(class)
+		block = unsafe.Add(unsafe.Pointer(header), bufferHeaderSize)
+		p.misses++
+	}
+	p.inUse[block] = uint8(class)
+	p.inUseSize += blockSize
+	return block
+}
+
+// Returns memory to the pool, or free()'s it if the pool is at it's cap or the memory didn't come from the pool
+//
+// Parameters:
+//   - ptr: The memory to free, NULL is ignored.
+func (p *CBufferPool) Free(ptr unsafe.Pointer) {
+	if ptr == nil {
+		return
+	}
+	p.lock.Lock()
+	class, ok := p.inUse[ptr]
+	if !ok {
+		p.released++
+		p.lock.Unlock()
+		C.free(ptr)
+		return
+	}
+	delete(p.inUse, ptr)
+	blockSize := int64(1) << (int(class) + minBufferClass)
+	p.inUseSize -= blockSize
+	if header := blockHeader(ptr); header.magic != bufferMagic || header.class != uint64(class) {
+		// Something wrote before the start of the block, it can't be trusted to be the size of it's class any more
+		p.corrupted++
+		p.lock.Unlock()
+		C.free(unsafe.Pointer(header))
+		return
+	}
+	if p.idleBytes+blockSize > p.cap {
+		p.released++
+		p.lock.Unlock()
+		C.free(unsafe.Pointer(blockHeader(ptr)))
+		return
+	}
+	p.free[class] = append(p.free[class], ptr)
+	p.idleBytes += blockSize
+	p.recycled++
+	p.lock.Unlock()
+}
+
+// Changes the most bytes of idle blocks the pool keeps, free()ing idle blocks over the new cap (blocks that are in use
+// aren't counted, or freed)
+//
+// Parameters:
+//   - capacity: The new cap in bytes, 0 disables pooling, values < 0 leave the cap unchanged.
+//
+// Returns:
+//   - The previous cap.
+func (p *CBufferPool) SetCap(capacity int64) int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	previous := p.cap
+	if capacity >= 0 {
+		p.cap = capacity
+		p.trim(capacity)
+	}
+	return previous
+}
+
+// Returns the most bytes of idle blocks the pool keeps in its free lists (not a limit on the memory it hands out)
+func (p *CBufferPool) Cap() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cap
+}
+
+// Free's every idle block, blocks that are in use are still returned to the pool when they're freed
+//
+// Returns:
+//   - The number of bytes that were free()'d.
+func (p *CBufferPool) Trim() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.trim(0)
+}
+
+// Free's idle blocks (largest first) until there are at most limit bytes left, the lock must be held
+func (p *CBufferPool) trim(limit int64) int64 {
+	var freed int64
+	for class := numberOfClasses - 1; class >= 0 && p.idleBytes > limit; class-- {
+		blockSize := int64(1) << (class + minBufferClass)
+		for len(p.free[class]) > 0 && p.idleBytes > limit {
+			list := p.free[class]
+			C.free(unsafe.Pointer(blockHeader(list[len(list)-1])))
+			list[len(list)-1] = nil
+			p.free[class] = list[:len(list)-1]
+			p.idleBytes -= blockSize
+			p.released++
+			freed += blockSize
+		}
+	}
+	return freed
+}
+
+// Takes a snapshot of the pool's usage and counters
+func (p *CBufferPool) Stats() BufferPoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	stats := BufferPoolStats{
+		Cap: p.cap, IdleBytes: p.idleBytes, InUseBytes: p.inUseSize, InUseBlocks: int64(len(p.inUse)),
+		Hits: p.hits, Misses: p.misses, Oversized: p.oversized, Recycled: p.recycled, Released: p.released, Corrupted: p.corrupted,
+	}
+	for _, list := range p.free {
+		stats.IdleBlocks += int64(len(list))
+	}
+	return stats
+}
+
+// Flattens the stats for helper_buffer_pool_stats()
+//
+// Returns:
+//   - A map of "cap", "idle_bytes", "idle_blocks", "in_use_bytes", "in_use_blocks", "hits", "misses", "oversized",
+//     "recycled", "released" and "corrupted" to their values.
+func (s BufferPoolStats) Map() map[string]float64 {
+	return map[string]float64{
+		"cap":           float64(s.Cap),
+		"idle_bytes":    float64(s.IdleBytes),
+		"idle_blocks":   float64(s.IdleBlocks),
+		"in_use_bytes":  float64(s.InUseBytes),
+		"in_use_blocks": float64(s.InUseBlocks),
+		"hits":          float64(s.Hits),
+		"misses":        float64(s.Misses),
+		"oversized":     float64(s.Oversized),
+		"recycled":      float64(s.Recycled),
+		"released":      float64(s.Released),
+		"corrupted":     float64(s.Corrupted),
+	}
+}
+
+// Returns the cap of the shared buffer pool
+//
+// Returns:
+//   - The most bytes of idle blocks SharedBufferPool keeps in its free lists, in use blocks don't count (C.longlong).
+//
+//export helper_buffer_pool_get_cap
+func helper_buffer_pool_get_cap() C.longlong {
+	ExitIfForked()
+	return C.longlong(SharedBufferPool.Cap())
+}
+
+// Sets the cap of the shared buffer pool, the most idle bytes it keeps (not a limit on the memory it hands out), see
+// CBufferPool.SetCap()
+//
+// Parameters:
+//   - capacity: The new cap in bytes, 0 disables pooling, values < 0 leave the cap unchanged.
+//
+// Returns:
+//   - The previous cap (C.longlong).
+//
+//export helper_buffer_pool_set_cap
+func helper_buffer_pool_set_cap(capacity C.longlong) C.longlong {
+	ExitIfForked()
+	return C.longlong(SharedBufferPool.SetCap(int64(capacity)))
+}
+
+// Free's the idle blocks in the shared buffer pool, see CBufferPool.Trim()
+//
+// Returns:
+//   - The number of bytes that were free()'d (C.longlong).
+//
+//export helper_buffer_pool_trim
+func helper_buffer_pool_trim() C.longlong {
+	ExitIfForked()
+	return C.longlong(SharedBufferPool.Trim())
+}
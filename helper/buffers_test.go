@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+)
+
+func TestBufferClass(t *testing.T) {
+	for size, expected := range map[uintptr]int{0: 0, 1: 0, 16: 0, 17: 1, 32: 1, 33: 2, 4000: 8, 1 << 20: 16} {
+		if class, ok := bufferClass(size); class != expected || !ok {
+			t.Errorf("TestBufferClass:bufferClass(%d): %d, %v!=%d", size, class, ok, expected)
+		}
+	}
+	if _, ok := bufferClass(1<<20 + 1); ok {
+		t.Errorf("TestBufferClass:bufferClass(1MiB+1): should be too big to pool")
+	}
+}
+
+func TestCBufferPool(t *testing.T) {
+	pool := NewCBufferPool(1024)
+	defer pool.Trim()
+
+	// Freed blocks are reused for allocations in the same size class
+	first := pool.Alloc(100)
+	pool.Free(first)
+	if second := pool.Alloc(128); second != first {
+		t.Errorf("TestCBufferPool:Alloc(128): didn't reuse the freed 128 byte block")
+	} else {
+		pool.Free(second)
+	}
+	if stats := pool.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Recycled != 2 || stats.IdleBytes != 128 || stats.InUseBlocks != 0 {
+		t.Errorf("TestCBufferPool:Stats(): %+v", stats)
+	}
+
+	// Blocks that would put the idle bytes over the cap are free()'d
+	blocks := []unsafe.Pointer{pool.Alloc(1024), pool.Alloc(1024)}
+	if stats := pool.Stats(); stats.InUseBytes != 2048 || stats.InUseBlocks != 2 {
+		t.Errorf("TestCBufferPool:Stats() in use: %+v", stats)
+	}
+	for _, block := range blocks {
+		pool.Free(block)
+	}
+	if stats := pool.Stats(); stats.IdleBytes != 128 || stats.Released != 2 {
+		t.Errorf("TestCBufferPool:Free() over the cap: %+v", stats)
+	}
+
+	// Oversized and foreign memory is never pooled
+	pool.Free(pool.Alloc(2 << 20))
+	pool.Free(StringToCString("not from the pool"))
+	pool.Free(nil)
+	if stats := pool.Stats(); stats.Oversized != 1 || stats.Released != 4 {
+		t.Errorf("TestCBufferPool:Free() foreign memory: %+v", stats)
+	}
+
+	// Pooled blocks start after a header, so they're still aligned like malloc()'s and free() on them fails loudly
+	block := pool.Alloc(32)
+	if header := blockHeader(block); header.magic != bufferMagic || header.class != 1 || uintptr(block)%bufferHeaderSize != 0 {
+		t.Errorf("TestCBufferPool:Alloc(32): header %+v at %p", *header, block)
+	}
+	// A block whose header was overwritten isn't put back in a free list
+	blockHeader(block).magic = 0
+	pool.Free(block)
+	if stats := pool.Stats(); stats.Corrupted != 1 || stats.IdleBytes != 128 || stats.InUseBlocks != 0 {
+		t.Errorf("TestCBufferPool:Free() corrupted header: %+v", stats)
+	}
+
+	// Lowering the cap trims the free lists
+	if previous := pool.SetCap(0); previous != 1024 || pool.Stats().IdleBytes != 0 {
+		t.Errorf("TestCBufferPool:SetCap(0): previous %d, %+v", previous, pool.Stats())
+	}
+	if previous := pool.SetCap(-1); previous != 0 || pool.Cap() != 0 {
+		t.Errorf("TestCBufferPool:SetCap(-1): should not change the cap")
+	}
+	pool.SetCap(1 << 20)
+	pool.Free(pool.Alloc(64))
+	if freed := pool.Trim(); freed != 64 || pool.Stats().IdleBlocks != 0 {
+		t.Errorf("TestCBufferPool:Trim(): freed %d", freed)
+	}
+}
+
+func TestBufferPoolConversions(t *testing.T) {
+	before := SharedBufferPool.Stats()
+	for range 3 {
+		ints, _ := IntSliceToCArray([]int{1, 2, 3})
+		helper_free_int_array_result(unsafe.Pointer(ints))
+		floats := FloatSliceToCArray([]float32{1.5})
+		helper_free_float_array_result(unsafe.Pointer(floats))
+		strings := StringSliceToCArray([]string{"a", "b"})
+		helper_free_string_array_result(unsafe.Pointer(strings))
+	}
+	after := SharedBufferPool.Stats()
+	if after.InUseBlocks != before.InUseBlocks {
+		t.Errorf("TestBufferPoolConversions: %d blocks weren't returned to the pool", after.InUseBlocks-before.InUseBlocks)
+	}
+	if after.Hits-before.Hits < 12 {
+		t.Errorf("TestBufferPoolConversions: the second and third round trips should reuse every block, %+v", after)
+	}
+
+	// Memory from other allocators still works with the free functions
+	helper_free_int_array(StringToCString("malloc'd"))
+	helper_free_string_array(nil, 0)
+}
+
+func BenchmarkBufferPoolRoundTrip(b *testing.B) {
+	for _, size := range []int{16, 1024, 64 * 1024} {
+		data := make([]int, size)
+		for _, capacity := range []int64{64 << 20, 0} {
+			name := map[bool]string{true: "pooled", false: "malloc"}[capacity > 0]
+			b.Run(fmt.Sprintf("%s/%d", name, size), func(b *testing.B) {
+				previous := SharedBufferPool.SetCap(capacity)
+				defer SharedBufferPool.SetCap(previous)
+				before := SharedBufferPool.Stats()
+				b.ResetTimer()
+				for range b.N {
+					input, _ := IntSliceToCArray(data)
+					result := helper_return_int_array(unsafe.Pointer(input.data), input.numberOfElements)
+					helper_free_int_array_result(unsafe.Pointer(result))
+					helper_free_int_array_result(unsafe.Pointer(input))
+				}
+				b.StopTimer()
+				after := SharedBufferPool.Stats()
+				b.ReportMetric(float64(after.Misses-before.Misses)/float64(b.N), "mallocs/op")
+			})
+		}
+	}
+}
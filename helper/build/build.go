@@ -0,0 +1,428 @@
+// Builds Go packages into shared libraries (or executables) for python bindings, skipping the build
+// when nothing that goes into it has changed since the last one
+//
+// # Functions
+//
+//	ParseProfile(name string) (Profile, error){} // Converts a profile name (i.e. "debug") to a Profile
+//	(c Config) Args() []string{} // The arguments to go for a build (i.e. ["build", "-buildmode=c-shared", ...])
+//	(c Config) Environ() []string{} // The environment variables to add for a build (i.e. CC="zig cc")
+//	HashSources(ctx context.Context, c Config) (string, error){} // Hashes the sources, dependencies, toolchain and configuration of a build
+//	Build(ctx context.Context, c Config) (Result, error){} // Builds if the hash changed (or the output is missing), and records the new hash
+//	ParseTarget(spec string) (Target, error){} // Converts a target (i.e. "linux/arm64/musl") to a Target
+//	(t Target) Config(base Config) Config{} // The configuration that cross-compiles a target with zig cc into it's platform directory
+//	BuildMatrix(ctx context.Context, base Config, targets []Target) ([]TargetResult, error){} // Builds a library for each target into per-platform directories
+//
+// # Examples
+//
+// Build a library with the race detector, using zig as the C compiler
+//
+//	result, err := build.Build(context.Background(), build.Config{
+//		Source:  "./mylib",
+//		Output:  "./mylib/lib.so",
+//		Profile: build.ProfileRace,
+//		CC:      "zig cc",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.Rebuilt) // false if lib.so was already up to date
+//
+// Build the helper as a host for the packages ../examples/host/modules.go imports (which ../examples/host/host.mod requires)
+//
+//	result, err := build.Build(context.Background(), build.Config{
+//		Source:  ".",
+//		Output:  "./host.so",
+//		ModFile: "../examples/host/host.mod",
+//		Files:   []string{"../examples/host/modules.go"},
+//	})
+//
+// Cross-compile for glibc and musl on amd64 and arm64, into ./mylib/linux-amd64-gnu/lib.so etc.
+//
+//	results, err := build.BuildMatrix(context.Background(), build.Config{Source: "./mylib", Output: "./mylib/lib.so"}, build.DefaultTargets)
+package build
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// A set of compiler/linker flags for a build
+type Profile string
+
+const (
+	ProfileRelease  Profile = "release"  // Stripped symbols and trimmed paths (the default)
+	ProfileDebug    Profile = "debug"    // Debug symbols with optimizations and inlining off, for gdb/dlv
+	ProfileRace     Profile = "race"     // Debug build with the race detector
+	ProfileCgoCheck Profile = "cgocheck" // Debug build that checks every pointer passed between Go and C (GOEXPERIMENT=cgocheck2)
+)
+
+// Every profile, in the order they're documented
+var Profiles = []Profile{ProfileRelease, ProfileDebug, ProfileRace, ProfileCgoCheck}
+
+// Returned (wrapped) for a profile name that isn't one of the Profile constants
+var ErrUnknownProfile = errors.New("unknown build profile")
+
+// Returned (wrapped) when go build fails, the error includes the compiler output
+var ErrBuildFailed = errors.New("build failed")
+
+// Converts a profile name to a Profile
+//
+// Parameters:
+//   - name: The name of the profile, "" is ProfileRelease.
+//
+// Returns:
+//   - The profile.
+//   - An error wrapping ErrUnknownProfile if name isn't a profile.
+func ParseProfile(name string) (Profile, error) {
+	if name == "" {
+		return ProfileRelease, nil
+	}
+	if !slices.Contains(Profiles, Profile(name)) {
+		return "", fmt.Errorf("%w: %q (expected one of %v)", ErrUnknownProfile, name, Profiles)
+	}
+	return Profile(name), nil
+}
+
+// The configuration of a build, everything in it is part of the hash
+type Config struct {
+	Source    string   // The package directory (or .go file) to build
+	Output    string   // The file to write (i.e. lib.so)
+	BuildMode string   // The -buildmode, "" is c-shared ("exe" for workers)
+	Profile   Profile  // The compiler/linker flags, "" is ProfileRelease
+	Tags      []string // Build tags (-tags)
+	CC        string   // The C compiler (i.e. "zig cc"), "" uses go's default
+	Env       []string // Extra environment variables (KEY=VALUE), i.e. GOOS/GOARCH to cross-compile
+	ModFile   string   // An alternate go.mod (-modfile), i.e. one that requires the modules of a host, it's replace paths are relative to Source
+	Files     []string // Extra .go files to build into Source's package (with an -overlay), i.e. a host's file that imports it's modules
+	Force     bool     // Build even if the hash hasn't changed (not part of the hash)
+}
+
+// The outcome of a Build
+type Result struct {
+	Output  string   `json:"output"`  // The file that was written (or was already up to date)
+	Hash    string   `json:"hash"`    // The hash of everything that went into the build
+	Rebuilt bool     `json:"rebuilt"` // Whether go build ran
+	Command []string `json:"command"` // The go command that builds the output (with the added environment variables first)
+}
+
+func (c Config) buildMode() string {
+	if c.BuildMode == "" {
+		return "c-shared"
+	}
+	return c.BuildMode
+}
+
+func (c Config) profile() Profile {
+	if c.Profile == "" {
+		return ProfileRelease
+	}
+	return c.Profile
+}
+
+// The directory go should run in, so the source's go.mod is used
+func (c Config) dir() string {
+	if info, err := os.Stat(c.Source); err == nil && info.IsDir() {
+		return c.Source
+	}
+	return filepath.Dir(c.Source)
+}
+
+// The package argument to go, relative to dir()
+func (c Config) pattern() string {
+	if info, err := os.Stat(c.Source); err == nil && info.IsDir() {
+		return "."
+	}
+	return filepath.Base(c.Source)
+}
+
+// Where each of Files appears in the package's directory, mapped to the file
+//
+// Returns:
+//   - The map, in the format of an -overlay's "Replace".
+//   - An error if a file has the same name as one in the package, it would replace it instead of being added.
+func (c Config) overlay() (map[string]string, error) {
+	replace := make(map[string]string, len(c.Files))
+	for _, file := range c.Files {
+		target := filepath.Join(absolutePath(c.dir()), filepath.Base(file))
+		if _, err := os.Stat(target); err == nil {
+			return nil, fmt.Errorf("%s has the same name as %s", file, target)
+		}
+		replace[target] = absolutePath(file)
+	}
+	return replace, nil
+}
+
+// The -overlay file for Files, in the temporary directory so nothing is written to the package's directory
+func (c Config) overlayPath() string {
+	name := sha256.Sum256([]byte(absolutePath(c.dir()) + "\n" + strings.Join(c.Files, "\n")))
+	return filepath.Join(os.TempDir(), "cgohelper-overlay-"+hex.EncodeToString(name[:8])+".json")
+}
+
+// Writes the -overlay file for Files, when there are any
+//
+// Returns:
+//   - The overlay's "Replace" map, nil without Files.
+//   - An error if a file can't be added to the package, or the overlay can't be written.
+func (c Config) writeOverlay() (map[string]string, error) {
+	if len(c.Files) == 0 {
+		return nil, nil
+	}
+	replace, err := c.overlay()
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(map[string]map[string]string{"Replace": replace})
+	return replace, os.WriteFile(c.overlayPath(), data, 0o644)
+}
+
+// The flags go build and go list share (-tags, -modfile and -overlay)
+func (c Config) packageFlags() []string {
+	var flags []string
+	if len(c.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(c.Tags, ","))
+	}
+	if c.ModFile != "" {
+		flags = append(flags, "-modfile="+absolutePath(c.ModFile))
+	}
+	if len(c.Files) > 0 {
+		flags = append(flags, "-overlay="+c.overlayPath())
+	}
+	return flags
+}
+
+// Makes a path absolute, go runs in the source's directory, leaves it as is if the working directory is gone
+func absolutePath(path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return path
+}
+
+// The arguments to go for a build
+//
+// Returns:
+//   - The arguments, starting with "build".
+func (c Config) Args() []string {
+	args := []string{"build", "-buildmode=" + c.buildMode()}
+	switch c.profile() {
+	case ProfileRelease:
+		args = append(args, "-trimpath", "-ldflags=-s -w")
+	case ProfileRace:
+		args = append(args, "-race", "-gcflags=all=-N -l")
+	default:
+		args = append(args, "-gcflags=all=-N -l")
+	}
+	args = append(args, c.packageFlags()...)
+	return append(args, "-o", absolutePath(c.Output), c.pattern())
+}
+
+// The environment variables to add to the current environment for a build
+//
+// Returns:
+//   - The variables (KEY=VALUE), CGO_ENABLED is always on since every build mode the helper uses needs cgo, and
+//     GOTRACEBACK=system so a crash in the toolchain (i.e. cgo or the linker) prints every goroutine.
+func (c Config) Environ() []string {
+	env := []string{"CGO_ENABLED=1", "GOTRACEBACK=system"}
+	if c.CC != "" {
+		env = append(env, "CC="+c.CC)
+		// zig (and clang) also handle C++, so use the same driver for any C++ files
+		if fields := strings.Fields(c.CC); len(fields) == 2 && fields[1] == "cc" {
+			env = append(env, "CXX="+fields[0]+" c++")
+		}
+	}
+	if c.profile() == ProfileCgoCheck {
+		env = append(env, "GOEXPERIMENT=cgocheck2")
+	}
+	return append(env, c.Env...)
+}
+
+// The subset of go list -json output used for hashing
+type listedPackage struct {
+	Dir        string
+	ImportPath string
+	Standard   bool
+	Module     *struct {
+		Path    string
+		Version string
+		Main    bool
+		GoMod   string
+		Replace *struct {
+			Version string
+		}
+	}
+	GoFiles, CgoFiles, CFiles, CXXFiles, HFiles, SFiles, SysoFiles, EmbedFiles []string
+}
+
+// Runs go with the build's environment
+func (c Config) goCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = c.dir()
+	cmd.Env = append(os.Environ(), c.Environ()...)
+	return cmd
+}
+
+// Hashes everything that goes into a build: the configuration, the toolchain, and the files of every
+// package in the main module (or a local replace), other modules are covered by their version
+//
+// Parameters:
+//   - ctx: Cancels the go list/go env commands.
+//   - c: The build configuration.
+//
+// Returns:
+//   - The hex encoded SHA-256 hash.
+//   - An error if go can't list the package (i.e. a syntax error in an import, or go isn't installed).
+func HashSources(ctx context.Context, c Config) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "mode=%s\nprofile=%s\ntags=%v\ncc=%s\nenv=%v\nmodfile=%s\nfiles=%v\n", c.buildMode(), c.profile(), c.Tags, c.CC, c.Env, c.ModFile, c.Files)
+
+	toolchain, err := c.goCommand(ctx, "env", "GOVERSION", "GOOS", "GOARCH", "CC", "GOFLAGS").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+	hash.Write(toolchain)
+
+	overlay, err := c.writeOverlay()
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{"list", "-deps", "-json"}, c.packageFlags()...)
+	var stderr bytes.Buffer
+	cmd := c.goCommand(ctx, append(args, c.pattern())...)
+	cmd.Stderr = &stderr
+	listing, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// c-shared/c-archive builds write a header next to the output, which would change the hash of the next build
+	output, _ := filepath.Abs(c.Output)
+	generatedHeader := strings.TrimSuffix(output, filepath.Ext(output)) + ".h"
+
+	modFiles := map[string]bool{}
+	decoder := json.NewDecoder(bytes.NewReader(listing))
+	for {
+		var pkg listedPackage
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("go list: %w", err)
+		}
+		if pkg.Standard {
+			continue
+		}
+		local := pkg.Module == nil || pkg.Module.Main || (pkg.Module.Replace != nil && pkg.Module.Replace.Version == "")
+		if !local {
+			fmt.Fprintf(hash, "module %s@%s\n", pkg.Module.Path, pkg.Module.Version)
+			continue
+		}
+		if pkg.Module != nil && pkg.Module.GoMod != "" {
+			modFiles[pkg.Module.GoMod] = true
+		}
+		for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.CFiles, pkg.CXXFiles, pkg.HFiles, pkg.SFiles, pkg.SysoFiles, pkg.EmbedFiles} {
+			for _, name := range files {
+				path := filepath.Join(pkg.Dir, name)
+				if path == generatedHeader {
+					continue
+				}
+				if backing, ok := overlay[path]; ok {
+					path = backing // One of Files, which isn't really in the package's directory
+				}
+				if err := hashFile(hash, path); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if c.ModFile != "" {
+		modFiles[absolutePath(c.ModFile)] = true
+	}
+	goMods := make([]string, 0, len(modFiles))
+	for goMod := range modFiles {
+		goMods = append(goMods, goMod)
+	}
+	slices.Sort(goMods)
+	for _, goMod := range goMods {
+		if err := hashFile(hash, goMod); err != nil {
+			return "", err
+		}
+		// go.sum (host.sum for a ModFile called host.mod) is optional, a module without dependencies doesn't have one
+		if err := hashFile(hash, strings.TrimSuffix(goMod, ".mod")+".sum"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Adds the name and contents of a file to a hash
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fmt.Fprintf(hash, "file %s\n", path)
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// The file the hash of the last build is recorded in, next to the output
+func stampPath(output string) string {
+	return output + ".build.json"
+}
+
+// What's recorded about the last build
+type stamp struct {
+	Hash    string  `json:"hash"`
+	Profile Profile `json:"profile"`
+}
+
+// Builds the output if anything that goes into it changed since the last build (or the output is missing)
+//
+// Parameters:
+//   - ctx: Cancels the build.
+//   - c: The build configuration.
+//
+// Returns:
+//   - The result, Rebuilt is false if the output was already up to date.
+//   - An error wrapping ErrBuildFailed with the compiler output if go build fails, or an error from HashSources.
+func Build(ctx context.Context, c Config) (Result, error) {
+	result := Result{Output: c.Output, Command: append(c.Environ(), append([]string{"go"}, c.Args()...)...)}
+	hash, err := HashSources(ctx, c)
+	if err != nil {
+		return result, err
+	}
+	result.Hash = hash
+
+	if !c.Force {
+		var previous stamp
+		if data, err := os.ReadFile(stampPath(c.Output)); err == nil && json.Unmarshal(data, &previous) == nil && previous.Hash == hash {
+			if _, err := os.Stat(c.Output); err == nil {
+				return result, nil
+			}
+		}
+	}
+
+	var output bytes.Buffer
+	cmd := c.goCommand(ctx, c.Args()...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		// Don't leave a stamp for the old output behind
+		os.Remove(stampPath(c.Output))
+		return result, fmt.Errorf("%w: %v\n%s", ErrBuildFailed, err, strings.TrimSpace(output.String()))
+	}
+	result.Rebuilt = true
+	data, _ := json.Marshal(stamp{Hash: hash, Profile: c.profile()})
+	return result, os.WriteFile(stampPath(c.Output), data, 0o644)
+}
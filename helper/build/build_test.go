@@ -0,0 +1,169 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	for test_input, expected := range map[string]Profile{"": ProfileRelease, "release": ProfileRelease, "debug": ProfileDebug, "race": ProfileRace, "cgocheck": ProfileCgoCheck} {
+		if temp, err := ParseProfile(test_input); err != nil || temp != expected {
+			t.Errorf("TestParseProfile:ParseProfile(%q): %v!=%v (%v)", test_input, temp, expected, err)
+		}
+	}
+	if _, err := ParseProfile("fast"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("TestParseProfile:ParseProfile(fast): expected ErrUnknownProfile, got %v", err)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "lib.so")
+
+	temp := Config{Source: dir, Output: output}.Args()
+	expected := []string{"build", "-buildmode=c-shared", "-trimpath", "-ldflags=-s -w", "-o", output, "."}
+	if !slices.Equal(temp, expected) {
+		t.Errorf("TestArgs:Args(release): %q!=%q", temp, expected)
+	}
+
+	temp = Config{Source: dir, Output: output, BuildMode: "exe", Profile: ProfileRace, Tags: []string{"a", "b"}}.Args()
+	expected = []string{"build", "-buildmode=exe", "-race", "-gcflags=all=-N -l", "-tags=a,b", "-o", output, "."}
+	if !slices.Equal(temp, expected) {
+		t.Errorf("TestArgs:Args(race): %q!=%q", temp, expected)
+	}
+
+	hostConfig := Config{Source: dir, Output: output, ModFile: "host/host.mod", Files: []string{"host/modules.go"}}
+	temp = hostConfig.Args()
+	expected = []string{"build", "-buildmode=c-shared", "-trimpath", "-ldflags=-s -w", "-modfile=" + absolutePath("host/host.mod"), "-overlay=" + hostConfig.overlayPath(), "-o", output, "."}
+	if !slices.Equal(temp, expected) {
+		t.Errorf("TestArgs:Args(host): %q!=%q", temp, expected)
+	}
+
+	env := Config{Source: dir, Profile: ProfileCgoCheck, CC: "zig cc", Env: []string{"GOOS=linux"}}.Environ()
+	expected = []string{"CGO_ENABLED=1", "GOTRACEBACK=system", "CC=zig cc", "CXX=zig c++", "GOEXPERIMENT=cgocheck2", "GOOS=linux"}
+	if !slices.Equal(env, expected) {
+		t.Errorf("TestArgs:Environ(cgocheck): %q!=%q", env, expected)
+	}
+}
+
+// Writes a minimal c-shared package to dir
+func writePackage(t *testing.T, dir, body string) {
+	t.Helper()
+	files := map[string]string{
+		"go.mod": "module lib\n\ngo 1.22\n",
+		"lib.go": "package main\n\nimport \"C\"\n\n" + body + "\n\nfunc main() {}\n",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	dir := t.TempDir()
+	writePackage(t, dir, "//export answer\nfunc answer() C.int { return 42 }")
+	config := Config{Source: dir, Output: filepath.Join(dir, "lib.so")}
+	ctx := context.Background()
+
+	first, err := Build(ctx, config)
+	if err != nil || !first.Rebuilt {
+		t.Fatalf("TestBuild:Build(first): %+v, %v", first, err)
+	}
+	if _, err := os.Stat(config.Output); err != nil {
+		t.Fatalf("TestBuild:Build(first): no output, %v", err)
+	}
+
+	// Nothing changed
+	if temp, err := Build(ctx, config); err != nil || temp.Rebuilt || temp.Hash != first.Hash {
+		t.Errorf("TestBuild:Build(unchanged): %+v, %v", temp, err)
+	}
+
+	// A different profile is a different build
+	debug := config
+	debug.Profile = ProfileDebug
+	if hash, err := HashSources(ctx, debug); err != nil || hash == first.Hash {
+		t.Errorf("TestBuild:HashSources(debug): %v, %v (same as release)", hash, err)
+	}
+
+	// Changing the source rebuilds
+	writePackage(t, dir, "//export answer\nfunc answer() C.int { return 43 }")
+	if temp, err := Build(ctx, config); err != nil || !temp.Rebuilt || temp.Hash == first.Hash {
+		t.Errorf("TestBuild:Build(changed): %+v, %v", temp, err)
+	}
+
+	// So does a missing output
+	os.Remove(config.Output)
+	if temp, err := Build(ctx, config); err != nil || !temp.Rebuilt {
+		t.Errorf("TestBuild:Build(missing output): %+v, %v", temp, err)
+	}
+
+	writePackage(t, dir, "func broken() { return 1 }")
+	if _, err := Build(ctx, config); !errors.Is(err, ErrBuildFailed) {
+		t.Errorf("TestBuild:Build(broken): expected ErrBuildFailed, got %v", err)
+	}
+	if _, err := os.Stat(stampPath(config.Output)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("TestBuild:Build(broken): left the stamp of the previous build behind")
+	}
+}
+
+func TestBuildHost(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	root := t.TempDir()
+	source, module, host := filepath.Join(root, "lib"), filepath.Join(root, "module"), filepath.Join(root, "host")
+	for _, dir := range []string{source, module, host} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePackage(t, source, "//export answer\nfunc answer() C.int { return 42 }")
+	files := map[string]string{
+		filepath.Join(module, "go.mod"):     "module example.com/module\n\ngo 1.22\n",
+		filepath.Join(module, "module.go"):  "package module\n\nvar Registered = true\n",
+		filepath.Join(host, "host.mod"):     "module lib\n\ngo 1.22\n\nrequire example.com/module v0.0.0\n\nreplace example.com/module => ../module\n",
+		filepath.Join(host, "modules.go"):   "package main\n\nimport _ \"example.com/module\"\n",
+		filepath.Join(host, "lib_extra.go"): "package main\n",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(name, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := Config{Source: source, Output: filepath.Join(root, "host.so"), ModFile: filepath.Join(host, "host.mod"), Files: []string{filepath.Join(host, "modules.go")}}
+	ctx := context.Background()
+
+	first, err := Build(ctx, config)
+	if err != nil || !first.Rebuilt {
+		t.Fatalf("TestBuildHost:Build(first): %+v, %v", first, err)
+	}
+	if hash, err := HashSources(ctx, Config{Source: source, Output: config.Output}); err != nil || hash == first.Hash {
+		t.Errorf("TestBuildHost:HashSources(without the host): %v, %v (same as the host)", hash, err)
+	}
+
+	// Changing an imported module rebuilds
+	if err := os.WriteFile(filepath.Join(module, "module.go"), []byte("package module\n\nvar Registered = false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if temp, err := Build(ctx, config); err != nil || !temp.Rebuilt {
+		t.Errorf("TestBuildHost:Build(module changed): %+v, %v", temp, err)
+	}
+
+	// A file with the same name as one in the package would replace it
+	clash := config
+	clash.Files = []string{filepath.Join(host, "lib.go")}
+	if err := os.Rename(filepath.Join(host, "lib_extra.go"), filepath.Join(host, "lib.go")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(ctx, clash); err == nil {
+		t.Errorf("TestBuildHost:Build(lib.go): expected an error for a file that replaces one in the package")
+	}
+}
@@ -0,0 +1,154 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Returned (wrapped) for a target that can't be parsed, or that zig can't cross-compile a c-shared library for
+var ErrUnsupportedTarget = errors.New("unsupported target")
+
+// A platform to cross-compile for
+type Target struct {
+	GOOS   string `json:"goos"`   // Only linux is supported
+	GOARCH string `json:"goarch"` // The architecture (i.e. "amd64", "arm64")
+	Libc   string `json:"libc"`   // "gnu" (glibc) or "musl", gnu can pin the minimum glibc version (i.e. "gnu.2.17")
+}
+
+// The targets built when none are specified, the linux hosts we ship to
+var DefaultTargets = []Target{
+	{"linux", "amd64", "gnu"},
+	{"linux", "arm64", "gnu"},
+	{"linux", "amd64", "musl"},
+	{"linux", "arm64", "musl"},
+}
+
+// zig's name for each GOARCH, and the suffix of it's libc for 32-bit arm (hard float)
+var zigArches = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"386":     "x86",
+	"arm":     "arm",
+	"riscv64": "riscv64",
+	"ppc64le": "powerpc64le",
+	"s390x":   "s390x",
+}
+
+// Parses a target
+//
+// Parameters:
+//   - spec: GOOS/GOARCH/libc (i.e. "linux/arm64/musl" or "linux/amd64/gnu.2.17"), the libc defaults to gnu.
+//
+// Returns:
+//   - The target.
+//   - An error wrapping ErrUnsupportedTarget if spec isn't a target zig can build for.
+func ParseTarget(spec string) (Target, error) {
+	parts := strings.Split(spec, "/")
+	if len(parts) == 2 {
+		parts = append(parts, "gnu")
+	}
+	if len(parts) != 3 {
+		return Target{}, fmt.Errorf("%w: %q, expected GOOS/GOARCH/libc (i.e. linux/arm64/musl)", ErrUnsupportedTarget, spec)
+	}
+	target := Target{parts[0], parts[1], parts[2]}
+	if target.GOOS != "linux" {
+		return Target{}, fmt.Errorf("%w: %q, only linux can be cross-compiled", ErrUnsupportedTarget, spec)
+	}
+	if _, ok := zigArches[target.GOARCH]; !ok {
+		return Target{}, fmt.Errorf("%w: %q, GOARCH must be one of %v", ErrUnsupportedTarget, spec, sortedKeys(zigArches))
+	}
+	if target.libc() != "gnu" && target.libc() != "musl" {
+		return Target{}, fmt.Errorf("%w: %q, libc must be gnu (optionally with a glibc version, i.e. gnu.2.17) or musl", ErrUnsupportedTarget, spec)
+	}
+	return target, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// The libc without a glibc version (i.e. "gnu" for "gnu.2.17")
+func (t Target) libc() string {
+	libc, _, _ := strings.Cut(t.Libc, ".")
+	return libc
+}
+
+func (t Target) String() string {
+	return t.GOOS + "/" + t.GOARCH + "/" + t.Libc
+}
+
+// The directory the target's library goes in, which the python loader looks for (i.e. "linux-arm64-musl")
+func (t Target) Platform() string {
+	return t.GOOS + "-" + t.GOARCH + "-" + t.libc()
+}
+
+// The target triple for zig cc -target (i.e. "aarch64-linux-musl")
+func (t Target) ZigTarget() string {
+	libc := t.Libc
+	if t.GOARCH == "arm" {
+		// GOARM defaults to 7 with hardware floating point
+		libc = strings.Replace(libc, t.libc(), t.libc()+"eabihf", 1)
+	}
+	return zigArches[t.GOARCH] + "-" + t.GOOS + "-" + libc
+}
+
+// The configuration that builds a target
+//
+// Parameters:
+//   - base: The configuration to start from, its output is written to <dir of base.Output>/<t.Platform()>/<name of base.Output>.
+//
+// Returns:
+//   - The configuration, with GOOS/GOARCH set and zig cc -target as the C compiler (unless base.CC is another compiler).
+func (t Target) Config(base Config) Config {
+	config := base
+	config.Output = filepath.Join(filepath.Dir(base.Output), t.Platform(), filepath.Base(base.Output))
+	if base.CC == "" || base.CC == "zig cc" {
+		config.CC = "zig cc -target " + t.ZigTarget()
+	}
+	config.Env = append(slices.Clip(base.Env), "GOOS="+t.GOOS, "GOARCH="+t.GOARCH)
+	return config
+}
+
+// The outcome of building one target
+type TargetResult struct {
+	Target Target `json:"target"`
+	Result
+	Error string `json:"error,omitempty"` // Why the build failed, "" on success
+}
+
+// Builds a library for each target into per-platform directories (see Target.Config())
+//
+// Parameters:
+//   - ctx: Cancels the builds.
+//   - base: The configuration shared by every target.
+//   - targets: The targets to build, DefaultTargets if empty.
+//
+// Returns:
+//   - The result of each target, in order (targets that failed have Error set).
+//   - An error joining the errors of every target that failed.
+func BuildMatrix(ctx context.Context, base Config, targets []Target) ([]TargetResult, error) {
+	if len(targets) == 0 {
+		targets = DefaultTargets
+	}
+	results := make([]TargetResult, 0, len(targets))
+	var errs []error
+	for _, target := range targets {
+		result, err := Build(ctx, target.Config(base))
+		targetResult := TargetResult{Target: target, Result: result}
+		if err != nil {
+			targetResult.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		}
+		results = append(results, targetResult)
+	}
+	return results, errors.Join(errs...)
+}
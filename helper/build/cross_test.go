@@ -0,0 +1,97 @@
+package build
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	for test_input, expected := range map[string]Target{
+		"linux/amd64":          {"linux", "amd64", "gnu"},
+		"linux/arm64/musl":     {"linux", "arm64", "musl"},
+		"linux/amd64/gnu.2.17": {"linux", "amd64", "gnu.2.17"},
+	} {
+		if temp, err := ParseTarget(test_input); err != nil || temp != expected {
+			t.Errorf("TestParseTarget:ParseTarget(%q): %+v!=%+v (%v)", test_input, temp, expected, err)
+		}
+	}
+	for _, test_input := range []string{"linux", "darwin/arm64", "linux/sparc64/gnu", "linux/amd64/uclibc", "linux/amd64/gnu/extra"} {
+		if _, err := ParseTarget(test_input); !errors.Is(err, ErrUnsupportedTarget) {
+			t.Errorf("TestParseTarget:ParseTarget(%q): expected ErrUnsupportedTarget, got %v", test_input, err)
+		}
+	}
+}
+
+func TestTargetConfig(t *testing.T) {
+	for target, expected := range map[Target][2]string{
+		{"linux", "amd64", "gnu.2.17"}: {"linux-amd64-gnu", "x86_64-linux-gnu.2.17"},
+		{"linux", "arm64", "musl"}:     {"linux-arm64-musl", "aarch64-linux-musl"},
+		{"linux", "arm", "gnu"}:        {"linux-arm-gnu", "arm-linux-gnueabihf"},
+	} {
+		if target.Platform() != expected[0] || target.ZigTarget() != expected[1] {
+			t.Errorf("TestTargetConfig:%v: %q, %q!=%q", target, target.Platform(), target.ZigTarget(), expected)
+		}
+	}
+
+	base := Config{Source: ".", Output: filepath.Join("out", "lib.so"), Env: []string{"GOFLAGS=-mod=mod"}}
+	config := Target{"linux", "arm64", "musl"}.Config(base)
+	if config.Output != filepath.Join("out", "linux-arm64-musl", "lib.so") || config.CC != "zig cc -target aarch64-linux-musl" {
+		t.Errorf("TestTargetConfig:Config(): %+v", config)
+	}
+	if !slices.Equal(config.Env, []string{"GOFLAGS=-mod=mod", "GOOS=linux", "GOARCH=arm64"}) || len(base.Env) != 1 {
+		t.Errorf("TestTargetConfig:Config(): env %q (base %q)", config.Env, base.Env)
+	}
+	// Another cross compiler is kept
+	base.CC = "aarch64-linux-gnu-gcc"
+	if config := (Target{"linux", "arm64", "gnu"}).Config(base); config.CC != base.CC {
+		t.Errorf("TestTargetConfig:Config(): CC %q!=%q", config.CC, base.CC)
+	}
+}
+
+func TestBuildMatrix(t *testing.T) {
+	if testing.Short() || runtime.GOOS != "linux" {
+		t.Skip("builds a linux shared library")
+	}
+	dir := t.TempDir()
+	writePackage(t, dir, "//export answer\nfunc answer() C.int { return 42 }")
+	ctx := context.Background()
+
+	// The host's compiler can build the host's target
+	output, err := exec.Command("go", "env", "CC").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	native := Target{"linux", runtime.GOARCH, "gnu"}
+	results, err := BuildMatrix(ctx, Config{Source: dir, Output: filepath.Join(dir, "lib.so"), CC: strings.TrimSpace(string(output))}, []Target{native})
+	if err != nil || len(results) != 1 || !results[0].Rebuilt || results[0].Target != native {
+		t.Fatalf("TestBuildMatrix:BuildMatrix(native): %+v, %v", results, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, native.Platform(), "lib.so")); err != nil {
+		t.Errorf("TestBuildMatrix:BuildMatrix(native): %v", err)
+	}
+
+	if _, err := exec.LookPath("zig"); err != nil {
+		// Without zig every target fails, but each one is still attempted
+		results, err := BuildMatrix(ctx, Config{Source: dir, Output: filepath.Join(dir, "lib.so")}, nil)
+		if err == nil || len(results) != len(DefaultTargets) || results[3].Error == "" {
+			t.Errorf("TestBuildMatrix:BuildMatrix(no zig): %+v, %v", results, err)
+		}
+		return
+	}
+	results, err = BuildMatrix(ctx, Config{Source: dir, Output: filepath.Join(dir, "lib.so")}, nil)
+	if err != nil {
+		t.Fatalf("TestBuildMatrix:BuildMatrix(zig): %v", err)
+	}
+	for _, result := range results {
+		if _, err := os.Stat(result.Output); err != nil {
+			t.Errorf("TestBuildMatrix:BuildMatrix(zig): %s has no output, %v", result.Target, err)
+		}
+	}
+}
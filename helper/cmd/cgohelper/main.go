@@ -0,0 +1,487 @@
+// Command line tools for Go libraries with python bindings, the python loader (get_library()) runs it with go run
+//
+// # Commands
+//
+//	cgohelper build [-o lib.so] [-profile release|debug|race|cgocheck] [-tags a,b] [-cc "zig cc"] [-buildmode c-shared] [-env KEY=VALUE] [-modfile host.mod] [-file modules.go] [-targets linux/amd64/gnu,...] [-force] [-json] <source>
+//	cgohelper new [-o directory] [-struct Name:field=type,...] [-helper directory] [-force] <name>
+//	cgohelper doctor [-python python3] [-cc "zig cc"] [-json] [directory]
+//	cgohelper extension -module name [-o directory] [-python python3] [-profile release|debug|race|cgocheck] [-tags a,b] [-cc "zig cc"] [-force] [-json] <source>
+//	cgohelper cffi -module name [-o directory] [-profile release|debug|race|cgocheck] [-tags a,b] [-cc "zig cc"] [-force] [-json] <source>
+//
+// # Examples
+//
+// Build the library in the current directory with debug symbols, only if it changed since the last build
+//
+//	go run github.com/Descent098/cgo-python-helpers/cmd/cgohelper build -profile debug -o lib.so .
+//
+// Cross-compile with zig cc into linux-amd64-gnu/lib.so, linux-arm64-musl/lib.so etc. (the python loader picks the one for it's platform)
+//
+//	go run ./cmd/cgohelper build -targets linux/amd64/gnu.2.17,linux/arm64/gnu,linux/amd64/musl,linux/arm64/musl -o lib.so .
+//
+// Build the helper into a host library for the example modules (see ../examples/host)
+//
+//	go run ./cmd/cgohelper build -modfile ../examples/host/host.mod -file ../examples/host/modules.go -o host.so .
+//
+// Create a new project in ./geometry with a Point struct (run from the helper's directory so it can be found)
+//
+//	go run ./cmd/cgohelper new -o .. -struct Point:x=int,y=float,label=string geometry
+//
+// Check why builds fail, for the python in a virtual environment
+//
+//	go run ./cmd/cgohelper doctor -python .venv/bin/python
+//
+// Build the scraping example into a CPython extension module (import scraping_ext) instead of a library for ctypes
+//
+//	go run ./cmd/cgohelper extension -module scraping_ext -o ../scraping ../scraping/go
+//
+// Generate a cffi binding of the scraping example (then compile it with python ../scraping/scraping_cffi_build.py)
+//
+//	go run ./cmd/cgohelper cffi -module scraping_cffi -o ../scraping ../scraping/go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Descent098/cgo-python-helpers/build"
+	"github.com/Descent098/cgo-python-helpers/doctor"
+	"github.com/Descent098/cgo-python-helpers/pyext"
+	"github.com/Descent098/cgo-python-helpers/scaffold"
+)
+
+// A command's flags and positional arguments couldn't be parsed (the usage has already been printed)
+var errUsage = errors.New("usage")
+
+// Repeatable string flag (i.e. -env GOOS=linux -env GOARCH=arm64)
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+// A subcommand, run with the arguments after its name
+type command struct {
+	summary string
+	run     func(ctx context.Context, args []string, stdout, stderr io.Writer) error
+}
+
+var commands = map[string]command{
+	"build":     {"Build a package into a shared library (or executable) if its sources changed", runBuild},
+	"cffi":      {"Build a package into an archive and generate a cffi (API mode) binding of it", runCFFI},
+	"doctor":    {"Check go, cgo, the C compiler and python, and print how to fix what's broken", runDoctor},
+	"extension": {"Build a package into a CPython extension module that converts straight to python objects", runExtension},
+	"new":       {"Create a new Go library with python bindings, struct typedefs and round-trip tests", runNew},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Runs a subcommand
+//
+// Parameters:
+//   - ctx: Cancels the command.
+//   - args: The command line arguments (without the program name).
+//   - stdout, stderr: Where to write output and errors.
+//
+// Returns:
+//   - The exit code, 0 on success, 1 if the command failed, 2 for usage errors.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || commands[args[0]].run == nil {
+		fmt.Fprintln(stderr, "usage: cgohelper <command> [arguments]\n\ncommands:")
+		for _, name := range sortedCommandNames() {
+			fmt.Fprintf(stderr, "  %-10s %s\n", name, commands[name].summary)
+		}
+		return 2
+	}
+	if err := commands[args[0]].run(ctx, args[1:], stdout, stderr); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "cgohelper %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func sortedCommandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Parses the arguments of the build command into a build.Config
+//
+// Parameters:
+//   - args: The arguments after "build".
+//   - stderr: Where to write the usage on errors.
+//
+// Returns:
+//   - The configuration.
+//   - The targets to cross-compile for, nil to build for the host.
+//   - Whether to print the result as JSON.
+//   - An error wrapping errUsage (or flag.ErrHelp) if the arguments are invalid.
+func parseBuildArgs(args []string, stderr io.Writer) (build.Config, []build.Target, bool, error) {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "lib.so", "The file to write")
+	profile := flags.String("profile", string(build.ProfileRelease), "The build profile: release, debug, race or cgocheck")
+	tags := flags.String("tags", "", "Comma separated build tags")
+	cc := flags.String("cc", "", `The C compiler, i.e. "zig cc" (defaults to go's)`)
+	buildMode := flags.String("buildmode", "c-shared", "The -buildmode passed to go build (i.e. exe for workers)")
+	force := flags.Bool("force", false, "Build even if the sources haven't changed")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	targets := flags.String("targets", "", `Comma separated GOOS/GOARCH/libc targets to cross-compile for with zig cc (i.e. linux/arm64/musl), into a directory per platform next to -o, "default" builds linux amd64/arm64 with glibc and musl`)
+	modFile := flags.String("modfile", "", "An alternate go.mod for the build, i.e. one that requires the modules of a host (replace paths are relative to the source)")
+	var env, files listFlag
+	flags.Var(&env, "env", "An extra environment variable for the build (KEY=VALUE), can be repeated")
+	flags.Var(&files, "file", "An extra .go file to build into the source's package (i.e. a host's file that imports it's modules), can be repeated")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cgohelper build [flags] <source>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return build.Config{}, nil, false, err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return build.Config{}, nil, false, fmt.Errorf("%w: expected one source, got %d", errUsage, flags.NArg())
+	}
+	parsedProfile, err := build.ParseProfile(*profile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return build.Config{}, nil, false, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	var parsedTargets []build.Target
+	if *targets == "default" {
+		parsedTargets = build.DefaultTargets
+	} else if *targets != "" {
+		for _, spec := range strings.Split(*targets, ",") {
+			target, err := build.ParseTarget(strings.TrimSpace(spec))
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return build.Config{}, nil, false, fmt.Errorf("%w: %w", errUsage, err)
+			}
+			parsedTargets = append(parsedTargets, target)
+		}
+	}
+	config := build.Config{
+		Source:    flags.Arg(0),
+		Output:    *output,
+		BuildMode: *buildMode,
+		Profile:   parsedProfile,
+		CC:        *cc,
+		Env:       env,
+		ModFile:   *modFile,
+		Files:     files,
+		Force:     *force,
+	}
+	if *tags != "" {
+		config.Tags = strings.Split(*tags, ",")
+	}
+	return config, parsedTargets, *asJSON, nil
+}
+
+// Builds a package if its sources changed since the last build
+func runBuild(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	config, targets, asJSON, err := parseBuildArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		return runBuildMatrix(ctx, config, targets, asJSON, stdout)
+	}
+	result, err := build.Build(ctx, config)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return json.NewEncoder(stdout).Encode(result)
+	}
+	if result.Rebuilt {
+		fmt.Fprintf(stdout, "built %s (%s)\n", result.Output, config.Profile)
+	} else {
+		fmt.Fprintf(stdout, "%s is up to date\n", result.Output)
+	}
+	return nil
+}
+
+// Parses the arguments of the new command into a scaffold.Config
+//
+// Parameters:
+//   - args: The arguments after "new".
+//   - stderr: Where to write the usage on errors.
+//
+// Returns:
+//   - The configuration, HelperDir is "" if it wasn't specified.
+//   - An error wrapping errUsage (or flag.ErrHelp) if the arguments are invalid.
+func parseNewArgs(args []string, stderr io.Writer) (scaffold.Config, error) {
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	directory := flags.String("o", ".", "The directory to create the project in")
+	helperDir := flags.String("helper", "", "The helper module to copy (defaults to the one go resolves from the current directory)")
+	force := flags.Bool("force", false, "Overwrite the files of an existing project")
+	var structs listFlag
+	flags.Var(&structs, "struct", fmt.Sprintf("A struct as Name:field=type,... (types are int, int64, float, string and bool), can be repeated (default %q)", scaffold.DefaultStruct))
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cgohelper new [flags] <name>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return scaffold.Config{}, err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return scaffold.Config{}, fmt.Errorf("%w: expected one name, got %d", errUsage, flags.NArg())
+	}
+	config := scaffold.Config{Name: flags.Arg(0), Directory: *directory, HelperDir: *helperDir, Force: *force}
+	for _, spec := range structs {
+		parsed, err := scaffold.ParseStruct(spec)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return scaffold.Config{}, fmt.Errorf("%w: %w", errUsage, err)
+		}
+		config.Structs = append(config.Structs, parsed)
+	}
+	return config, nil
+}
+
+// Cross-compiles for each target, reporting every target (even when some of them failed)
+func runBuildMatrix(ctx context.Context, config build.Config, targets []build.Target, asJSON bool, stdout io.Writer) error {
+	results, err := build.BuildMatrix(ctx, config, targets)
+	if asJSON {
+		if encodeErr := json.NewEncoder(stdout).Encode(results); encodeErr != nil {
+			return encodeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%d of %d targets failed", countFailed(results), len(results))
+		}
+		return nil
+	}
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(stdout, "%-22s failed\n", result.Target)
+		case result.Rebuilt:
+			fmt.Fprintf(stdout, "%-22s built %s (%s)\n", result.Target, result.Output, config.Profile)
+		default:
+			fmt.Fprintf(stdout, "%-22s %s is up to date\n", result.Target, result.Output)
+		}
+	}
+	return err
+}
+
+func countFailed(results []build.TargetResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Creates a new project
+func runNew(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	config, err := parseNewArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+	if config.HelperDir == "" {
+		if config.HelperDir, err = scaffold.FindHelper(ctx); err != nil {
+			return err
+		}
+	}
+	files, err := scaffold.Generate(config)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "created %s (%d files), test it with:\n\tcd %s && go test ./...\n\tcd %s && python -m pytest test_%s.py\n",
+		filepath.Join(config.Directory, config.Name), len(files), filepath.Join(config.Directory, config.Name, "go"), config.Directory, config.Name)
+	return nil
+}
+
+// Checks the toolchain, exits with 1 if any check has an error
+func runDoctor(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	python := flags.String("python", "", "The python that will load the library (defaults to python3, python on windows)")
+	cc := flags.String("cc", "", `The C compiler to check, i.e. "zig cc" (defaults to go's)`)
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cgohelper doctor [flags] [directory]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("%w: expected at most one directory, got %d", errUsage, flags.NArg())
+	}
+	report := doctor.Run(ctx, doctor.Options{Dir: flags.Arg(0), Python: *python, CC: *cc})
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, check := range report.Checks {
+			fmt.Fprintf(stdout, "%-8s %-11s %s\n", check.Status, check.Name, check.Detail)
+			if check.Fix != "" {
+				fmt.Fprintf(stdout, "%-8s %-11s fix: %s\n", "", "", check.Fix)
+			}
+		}
+	}
+	if !report.OK {
+		return errors.New("some checks failed")
+	}
+	return nil
+}
+
+// Parses the arguments of the extension command into a pyext.Config
+//
+// Parameters:
+//   - args: The arguments after "extension".
+//   - stderr: Where to write the usage on errors.
+//
+// Returns:
+//   - The configuration.
+//   - Whether to print the result as JSON.
+//   - An error wrapping errUsage (or flag.ErrHelp) if the arguments are invalid.
+func parseExtensionArgs(args []string, stderr io.Writer) (pyext.Config, bool, error) {
+	flags := flag.NewFlagSet("extension", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	module := flags.String("module", "", "The name python imports the module as (i.e. scraping_ext)")
+	output := flags.String("o", ".", "The directory to write the module to")
+	python := flags.String("python", "", "The python the module is for (defaults to python3, python on windows)")
+	profile := flags.String("profile", string(build.ProfileRelease), "The build profile: release, debug, race or cgocheck")
+	tags := flags.String("tags", "", "Comma separated build tags")
+	cc := flags.String("cc", "", `The C compiler, i.e. "zig cc" (defaults to go's)`)
+	force := flags.Bool("force", false, "Build even if nothing changed")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cgohelper extension -module name [flags] <source>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return pyext.Config{}, false, err
+	}
+	if flags.NArg() != 1 || *module == "" {
+		flags.Usage()
+		return pyext.Config{}, false, fmt.Errorf("%w: expected -module and one source", errUsage)
+	}
+	parsedProfile, err := build.ParseProfile(*profile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return pyext.Config{}, false, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	config := pyext.Config{Module: *module, Source: flags.Arg(0), Output: *output, Python: *python, CC: *cc, Profile: parsedProfile, Force: *force}
+	if *tags != "" {
+		config.Tags = strings.Split(*tags, ",")
+	}
+	return config, *asJSON, nil
+}
+
+// Builds a package into an extension module, listing the functions that couldn't be wrapped
+func runExtension(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	config, asJSON, err := parseExtensionArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+	result, err := pyext.Build(ctx, config)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return json.NewEncoder(stdout).Encode(result)
+	}
+	if result.Rebuilt {
+		fmt.Fprintf(stdout, "built %s (%d functions)\n", result.Output, len(result.Wrapped))
+	} else {
+		fmt.Fprintf(stdout, "%s is up to date (%d functions)\n", result.Output, len(result.Wrapped))
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(stdout, "skipped %s: %s\n", skipped.Name, skipped.Reason)
+	}
+	return nil
+}
+
+// Parses the arguments of the cffi command into a pyext.Config
+//
+// Parameters:
+//   - args: The arguments after "cffi".
+//   - stderr: Where to write the usage on errors.
+//
+// Returns:
+//   - The configuration.
+//   - Whether to print the result as JSON.
+//   - An error wrapping errUsage (or flag.ErrHelp) if the arguments are invalid.
+func parseCFFIArgs(args []string, stderr io.Writer) (pyext.Config, bool, error) {
+	flags := flag.NewFlagSet("cffi", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	module := flags.String("module", "", "The name python imports the binding as (i.e. scraping_cffi), the extension is _<module>")
+	output := flags.String("o", ".", "The directory to write the binding to")
+	profile := flags.String("profile", string(build.ProfileRelease), "The build profile: release, debug, race or cgocheck")
+	tags := flags.String("tags", "", "Comma separated build tags")
+	cc := flags.String("cc", "", `The C compiler, i.e. "zig cc" (defaults to go's)`)
+	force := flags.Bool("force", false, "Build even if nothing changed")
+	asJSON := flags.Bool("json", false, "Print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: cgohelper cffi -module name [flags] <source>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return pyext.Config{}, false, err
+	}
+	if flags.NArg() != 1 || *module == "" {
+		flags.Usage()
+		return pyext.Config{}, false, fmt.Errorf("%w: expected -module and one source", errUsage)
+	}
+	parsedProfile, err := build.ParseProfile(*profile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return pyext.Config{}, false, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	config := pyext.Config{Module: *module, Source: flags.Arg(0), Output: *output, CC: *cc, Profile: parsedProfile, Force: *force}
+	if *tags != "" {
+		config.Tags = strings.Split(*tags, ",")
+	}
+	return config, *asJSON, nil
+}
+
+// Builds a package into an archive and generates a cffi binding of it, listing the functions that couldn't be wrapped
+func runCFFI(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	config, asJSON, err := parseCFFIArgs(args, stderr)
+	if err != nil {
+		return err
+	}
+	result, err := pyext.BuildCFFI(ctx, config)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return json.NewEncoder(stdout).Encode(result)
+	}
+	if result.Rebuilt {
+		fmt.Fprintf(stdout, "generated %s (%d functions), compile it with python %s\n", result.Wrapper, len(result.Wrapped), result.BuildScript)
+	} else {
+		fmt.Fprintf(stdout, "%s is up to date (%d functions)\n", result.Wrapper, len(result.Wrapped))
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(stdout, "skipped %s: %s\n", skipped.Name, skipped.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Descent098/cgo-python-helpers/build"
+	"github.com/Descent098/cgo-python-helpers/scaffold"
+)
+
+func TestParseBuildArgs(t *testing.T) {
+	var stderr bytes.Buffer
+	config, targets, asJSON, err := parseBuildArgs([]string{"-o", "out/lib.so", "-profile", "race", "-tags", "a,b", "-cc", "zig cc", "-env", "GOOS=linux", "-env", "GOARCH=arm64", "-json", "./src"}, &stderr)
+	if err != nil {
+		t.Fatalf("TestParseBuildArgs:parseBuildArgs(): %v", err)
+	}
+	if !asJSON || targets != nil || config.Source != "./src" || config.Output != "out/lib.so" || config.Profile != build.ProfileRace || config.CC != "zig cc" || config.BuildMode != "c-shared" {
+		t.Errorf("TestParseBuildArgs:parseBuildArgs(): %+v", config)
+	}
+	if !slices.Equal(config.Tags, []string{"a", "b"}) || !slices.Equal(config.Env, []string{"GOOS=linux", "GOARCH=arm64"}) {
+		t.Errorf("TestParseBuildArgs:parseBuildArgs(): tags %q env %q", config.Tags, config.Env)
+	}
+
+	config, _, _, err = parseBuildArgs([]string{"-modfile", "host/host.mod", "-file", "host/a.go", "-file", "host/b.go", "."}, &stderr)
+	if err != nil || config.ModFile != "host/host.mod" || !slices.Equal(config.Files, []string{"host/a.go", "host/b.go"}) {
+		t.Errorf("TestParseBuildArgs:parseBuildArgs(-modfile -file): %+v, %v", config, err)
+	}
+
+	_, targets, _, err = parseBuildArgs([]string{"-targets", "linux/amd64/gnu.2.17, linux/arm64/musl", "."}, &stderr)
+	if err != nil || len(targets) != 2 || targets[0].Libc != "gnu.2.17" || targets[1].GOARCH != "arm64" {
+		t.Errorf("TestParseBuildArgs:parseBuildArgs(-targets): %+v, %v", targets, err)
+	}
+	if _, targets, _, err = parseBuildArgs([]string{"-targets", "default", "."}, &stderr); err != nil || !slices.Equal(targets, build.DefaultTargets) {
+		t.Errorf("TestParseBuildArgs:parseBuildArgs(-targets default): %+v, %v", targets, err)
+	}
+
+	for _, test_input := range [][]string{{}, {"a", "b"}, {"-profile", "fast", "."}, {"-targets", "darwin/arm64", "."}} {
+		if _, _, _, err := parseBuildArgs(test_input, &stderr); err == nil {
+			t.Errorf("TestParseBuildArgs:parseBuildArgs(%q): expected an error", test_input)
+		}
+	}
+}
+
+func TestParseNewArgs(t *testing.T) {
+	var stderr bytes.Buffer
+	config, err := parseNewArgs([]string{"-o", "out", "-struct", "Point:x=int,y=float", "-struct", "Label:text=string", "-force", "geometry"}, &stderr)
+	if err != nil {
+		t.Fatalf("TestParseNewArgs:parseNewArgs(): %v", err)
+	}
+	if config.Name != "geometry" || config.Directory != "out" || !config.Force || config.HelperDir != "" || len(config.Structs) != 2 || config.Structs[1].Name != "Label" {
+		t.Errorf("TestParseNewArgs:parseNewArgs(): %+v", config)
+	}
+
+	for _, test_input := range [][]string{{}, {"a", "b"}, {"-struct", "Point", "geometry"}} {
+		if _, err := parseNewArgs(test_input, &stderr); err == nil {
+			t.Errorf("TestParseNewArgs:parseNewArgs(%q): expected an error", test_input)
+		}
+	}
+}
+
+func TestParseExtensionArgs(t *testing.T) {
+	var stderr bytes.Buffer
+	config, asJSON, err := parseExtensionArgs([]string{"-module", "scraping_ext", "-o", "out", "-profile", "debug", "-tags", "a,b", "-json", "./src"}, &stderr)
+	if err != nil {
+		t.Fatalf("TestParseExtensionArgs:parseExtensionArgs(): %v", err)
+	}
+	if !asJSON || config.Module != "scraping_ext" || config.Source != "./src" || config.Output != "out" || config.Profile != build.ProfileDebug || !slices.Equal(config.Tags, []string{"a", "b"}) {
+		t.Errorf("TestParseExtensionArgs:parseExtensionArgs(): %+v", config)
+	}
+
+	for _, test_input := range [][]string{{"."}, {"-module", "m"}, {"-module", "m", "-profile", "fast", "."}} {
+		if _, _, err := parseExtensionArgs(test_input, &stderr); err == nil {
+			t.Errorf("TestParseExtensionArgs:parseExtensionArgs(%q): expected an error", test_input)
+		}
+	}
+}
+
+func TestParseCFFIArgs(t *testing.T) {
+	var stderr bytes.Buffer
+	config, asJSON, err := parseCFFIArgs([]string{"-module", "scraping_cffi", "-o", "out", "-cc", "zig cc", "./src"}, &stderr)
+	if err != nil {
+		t.Fatalf("TestParseCFFIArgs:parseCFFIArgs(): %v", err)
+	}
+	if asJSON || config.Module != "scraping_cffi" || config.Source != "./src" || config.Output != "out" || config.CC != "zig cc" || config.Profile != build.ProfileRelease {
+		t.Errorf("TestParseCFFIArgs:parseCFFIArgs(): %+v", config)
+	}
+
+	for _, test_input := range [][]string{{"."}, {"-module", "m"}, {"-module", "m", "-python", "python3", "."}} {
+		if _, _, err := parseCFFIArgs(test_input, &stderr); err == nil {
+			t.Errorf("TestParseCFFIArgs:parseCFFIArgs(%q): expected an error", test_input)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"does-not-exist"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "build") {
+		t.Errorf("TestRun:run(does-not-exist): %d, %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := run(context.Background(), []string{"build"}, &stdout, &stderr); code != 2 {
+		t.Errorf("TestRun:run(build): %d!=2", code)
+	}
+	stderr.Reset()
+	if code := run(context.Background(), []string{"build", t.TempDir()}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "cgohelper build:") {
+		t.Errorf("TestRun:run(build empty dir): %d, %q", code, stderr.String())
+	}
+
+	// The helper is found from the current directory (the helper's module)
+	dir := t.TempDir()
+	if code := run(context.Background(), []string{"new", "-o", dir, "geometry"}, &stdout, &stderr); code != 0 || !strings.Contains(stdout.String(), "created") {
+		t.Fatalf("TestRun:run(new): %d, %q", code, stderr.String())
+	}
+	stderr.Reset()
+	if code := run(context.Background(), []string{"new", "-o", dir, "geometry"}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), scaffold.ErrExists.Error()) {
+		t.Errorf("TestRun:run(new existing): %d, %q", code, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run(context.Background(), []string{"doctor", "-json", "-python", "does-not-exist-python"}, &stdout, &stderr); code != 1 || !strings.Contains(stdout.String(), `"ok":false`) {
+		t.Errorf("TestRun:run(doctor missing python): %d, %q, %q", code, stdout.String(), stderr.String())
+	}
+}
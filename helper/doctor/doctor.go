@@ -0,0 +1,495 @@
+// Diagnoses the toolchain needed to build Go libraries for python: Go (and it's version against go.mod),
+// cgo, a C compiler (or zig cc), the architecture of the python that will load the library, and
+// whether a -buildmode=c-shared library actually builds and loads. Every failed check has a fix
+//
+// # Functions
+//
+//	Run(ctx context.Context, o Options) Report{} // Runs every check, later checks are skipped when the ones they need failed
+//	CheckGoVersion(goVersion, required, toolchain string) Check{} // Compares the installed Go with the go directive of a go.mod
+//	CheckCgo(enabled string) Check{} // Checks the CGO_ENABLED setting
+//	CheckPython(info PythonInfo, goos, goarch string) Check{} // Checks that a python can load libraries built for GOOS/GOARCH
+//	GoArch(machine string) string{} // Converts a python platform.machine() to a GOARCH (i.e. "x86_64" to "amd64")
+//
+// # Examples
+//
+// Print the checks that failed, and how to fix them
+//
+//	report := doctor.Run(context.Background(), doctor.Options{Dir: ".", Python: "python3"})
+//	for _, check := range report.Checks {
+//		if check.Status == doctor.StatusError {
+//			fmt.Printf("%s: %s\n\tfix: %s\n", check.Name, check.Detail, check.Fix)
+//		}
+//	}
+package doctor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/Descent098/cgo-python-helpers/build"
+)
+
+// The outcome of a check
+type Status string
+
+const (
+	StatusOK      Status = "ok"      // Nothing to fix
+	StatusWarning Status = "warning" // Builds will work, but something is likely to cause problems (the fix says what)
+	StatusError   Status = "error"   // Builds (or loading the library) will fail
+	StatusSkipped Status = "skipped" // A check it depends on failed
+)
+
+// The result of a single check
+type Check struct {
+	Name   string `json:"name"`          // What was checked (i.e. "c compiler")
+	Status Status `json:"status"`        // The outcome
+	Detail string `json:"detail"`        // What was found (i.e. the compiler output)
+	Fix    string `json:"fix,omitempty"` // How to fix it, for warnings and errors
+}
+
+// The results of every check
+type Report struct {
+	OK     bool    `json:"ok"` // Whether no check had an error (warnings are ok)
+	Checks []Check `json:"checks"`
+}
+
+// What to check
+type Options struct {
+	Dir    string // The directory of the go.mod to check against, "" is the current directory
+	Python string // The python that will load the library (i.e. sys.executable), "" is python3 (python on windows)
+	CC     string // The C compiler to check (i.e. "zig cc"), "" is go env CC
+}
+
+// What a python reports about itself
+type PythonInfo struct {
+	Executable     string `json:"executable"`     // sys.executable
+	Version        string `json:"version"`        // platform.python_version()
+	Platform       string `json:"platform"`       // sys.platform (i.e. "linux", "darwin", "win32")
+	Machine        string `json:"machine"`        // platform.machine() (i.e. "x86_64", "arm64", "AMD64")
+	PointerBits    int    `json:"pointer_bits"`   // struct.calcsize("P") * 8
+	Implementation string `json:"implementation"` // platform.python_implementation()
+}
+
+// The go env variables the checks use
+type goEnv struct {
+	GOVERSION, GOOS, GOARCH, CGO_ENABLED, CC, GOMOD, GOTOOLCHAIN string
+}
+
+// Python's platform.machine() names for each GOARCH
+var goArches = map[string]string{
+	"x86_64": "amd64", "amd64": "amd64", "x64": "amd64",
+	"aarch64": "arm64", "arm64": "arm64", "armv8l": "arm64",
+	"i386": "386", "i486": "386", "i586": "386", "i686": "386", "x86": "386",
+	"armv6l": "arm", "armv7l": "arm", "arm": "arm",
+	"ppc64le": "ppc64le", "ppc64": "ppc64", "s390x": "s390x", "riscv64": "riscv64",
+	"loongarch64": "loong64", "mips64": "mips64", "mips64el": "mips64le",
+}
+
+// Pointer size of each GOARCH that has a python
+var goArchBits = map[string]int{
+	"amd64": 64, "arm64": 64, "ppc64le": 64, "ppc64": 64, "s390x": 64, "riscv64": 64, "loong64": 64, "mips64": 64, "mips64le": 64,
+	"386": 32, "arm": 32,
+}
+
+// sys.platform for each GOOS
+var pythonPlatforms = map[string]string{"linux": "linux", "darwin": "darwin", "windows": "win32", "freebsd": "freebsd"}
+
+// Converts a python platform.machine() to a GOARCH
+//
+// Parameters:
+//   - machine: The machine name (case insensitive), i.e. "x86_64" or "AMD64".
+//
+// Returns:
+//   - The GOARCH, or "" if it's not known.
+func GoArch(machine string) string {
+	return goArches[strings.ToLower(machine)]
+}
+
+var goVersionPattern = regexp.MustCompile(`^(?:go)?(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// Parses a Go version ("go1.22.0", "1.22", "go1.23rc1") into it's major, minor and patch numbers
+func parseGoVersion(version string) ([3]int, bool) {
+	match := goVersionPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return [3]int{}, false
+	}
+	var parsed [3]int
+	for i, part := range match[1:] {
+		parsed[i], _ = strconv.Atoi(part)
+	}
+	return parsed, true
+}
+
+// Compares two Go versions, -1 if a is older than b, 0 if they're the same, 1 if a is newer
+func compareGoVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Reads the go directive of a go.mod ("" if it doesn't have one)
+func readGoDirective(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "go" {
+			return fields[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// Compares the installed Go with the go directive of a go.mod
+//
+// Parameters:
+//   - goVersion: The installed version (go env GOVERSION, i.e. "go1.22.3").
+//   - required: The go directive of the go.mod (i.e. "1.22.0").
+//   - toolchain: GOTOOLCHAIN, older Go's only download the required version when it isn't "local".
+//
+// Returns:
+//   - The check, an error if the installed Go is older than required and won't switch to a newer one.
+func CheckGoVersion(goVersion, required, toolchain string) Check {
+	check := Check{Name: "go version", Status: StatusOK, Detail: fmt.Sprintf("%s (go.mod needs go%s)", goVersion, required)}
+	installed, ok := parseGoVersion(goVersion)
+	needed, neededOK := parseGoVersion(required)
+	if !ok || !neededOK {
+		check.Status = StatusWarning
+		check.Detail = fmt.Sprintf("unable to compare %q with the go.mod's go %q", goVersion, required)
+		check.Fix = "check that go version is at least the go line of go.mod"
+		return check
+	}
+	if compareGoVersions(installed, needed) >= 0 {
+		return check
+	}
+	check.Fix = fmt.Sprintf("install go%s or newer from https://go.dev/dl/", required)
+	// Since go1.21 a newer toolchain is downloaded automatically (unless GOTOOLCHAIN=local)
+	if compareGoVersions(installed, [3]int{1, 21, 0}) >= 0 && !strings.HasPrefix(toolchain, "local") {
+		check.Status = StatusWarning
+		check.Detail += ", go will download the newer toolchain on the first build"
+		check.Fix += ", or let go download it once with: go version (in the module's directory)"
+		return check
+	}
+	check.Status = StatusError
+	if strings.HasPrefix(toolchain, "local") {
+		check.Fix += ", or allow go to download it with: go env -w GOTOOLCHAIN=auto"
+	}
+	return check
+}
+
+// Checks the CGO_ENABLED setting
+//
+// Parameters:
+//   - enabled: go env CGO_ENABLED ("1" or "0").
+//
+// Returns:
+//   - The check, a warning if cgo is off (the build driver turns it on, but a plain go build won't).
+func CheckCgo(enabled string) Check {
+	if enabled == "1" {
+		return Check{Name: "cgo", Status: StatusOK, Detail: "CGO_ENABLED=1"}
+	}
+	return Check{
+		Name:   "cgo",
+		Status: StatusWarning,
+		Detail: fmt.Sprintf("CGO_ENABLED=%q, go turns cgo off when it can't find a C compiler (or it was set to 0), get_library() turns it on for it's builds but go build won't", enabled),
+		Fix:    "fix the c compiler check, then turn cgo on with: go env -w CGO_ENABLED=1",
+	}
+}
+
+// Checks that a python can load libraries built for GOOS/GOARCH
+//
+// Parameters:
+//   - info: What the python reports about itself.
+//   - goos, goarch: The target of the builds (go env GOOS/GOARCH).
+//
+// Returns:
+//   - The check, an error if the python's platform, architecture or pointer size doesn't match.
+func CheckPython(info PythonInfo, goos, goarch string) Check {
+	check := Check{
+		Name:   "python",
+		Status: StatusOK,
+		Detail: fmt.Sprintf("%s %s (%s, %s, %d-bit) at %s", info.Implementation, info.Version, info.Platform, info.Machine, info.PointerBits, info.Executable),
+	}
+	pythonArch := GoArch(info.Machine)
+	// 32-bit python on a 64-bit windows/linux reports the machine of the OS, so trust the pointer size
+	if pythonArch == "amd64" && info.PointerBits == 32 {
+		pythonArch = "386"
+	} else if pythonArch == "arm64" && info.PointerBits == 32 {
+		pythonArch = "arm"
+	}
+	if platform, ok := pythonPlatforms[goos]; ok && !strings.HasPrefix(info.Platform, platform) {
+		check.Status = StatusError
+		check.Detail += fmt.Sprintf(", but go builds for %s", goos)
+		check.Fix = fmt.Sprintf("build on the same OS as python, or cross-compile with GOOS=%s (and a C compiler for it, i.e. zig cc)", goosForPlatform(info.Platform))
+		return check
+	}
+	if pythonArch == "" {
+		check.Status = StatusWarning
+		check.Detail += fmt.Sprintf(", unknown machine %q", info.Machine)
+		check.Fix = fmt.Sprintf("check that python is running on %s", goarch)
+		return check
+	}
+	if pythonArch != goarch || (goArchBits[goarch] != 0 && goArchBits[goarch] != info.PointerBits) {
+		check.Status = StatusError
+		check.Detail += fmt.Sprintf(", but go builds for %s (%d-bit), so the library won't load", goarch, goArchBits[goarch])
+		check.Fix = fmt.Sprintf("use a python for %s, or build with GOARCH=%s and a C compiler for it (i.e. cc=\"zig cc -target ...\")", goarch, pythonArch)
+	}
+	return check
+}
+
+func goosForPlatform(platform string) string {
+	for goos, prefix := range pythonPlatforms {
+		if strings.HasPrefix(platform, prefix) {
+			return goos
+		}
+	}
+	return platform
+}
+
+// Reports about a python, prints PythonInfo as JSON
+const pythonScript = `import json, platform, struct, sys
+print(json.dumps({"executable": sys.executable, "version": platform.python_version(), "platform": sys.platform, "machine": platform.machine(), "pointer_bits": struct.calcsize("P") * 8, "implementation": platform.python_implementation()}))`
+
+// Runs a python to find out about it
+func pythonInfo(ctx context.Context, python string) (PythonInfo, error) {
+	var info PythonInfo
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, python, "-c", pythonScript)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return info, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	} else if err != nil {
+		return info, err
+	}
+	return info, json.Unmarshal(output, &info)
+}
+
+// Compiles a C file with a compiler command (i.e. "gcc" or "zig cc"), returning the compiler output on errors
+func compileC(ctx context.Context, cc, dir string) error {
+	fields := strings.Fields(cc)
+	if len(fields) == 0 {
+		return fmt.Errorf("no compiler")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("%s not found on the PATH", fields[0])
+	}
+	source := filepath.Join(dir, "doctor.c")
+	if err := os.WriteFile(source, []byte("#include <stdlib.h>\nint doctor(void) { return (int)sizeof(void*); }\n"), 0o644); err != nil {
+		return err
+	}
+	args := append(fields[1:], "-c", source, "-o", filepath.Join(dir, "doctor.o"))
+	output, err := exec.CommandContext(ctx, fields[0], args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// The fix for a missing C compiler on each OS
+func installCompilerFix() string {
+	switch runtime.GOOS {
+	case "windows":
+		return `install a gcc for windows (i.e. https://www.msys2.org, then pacman -S mingw-w64-ucrt-x86_64-gcc) and add it to the PATH, or install zig (https://ziglang.org) and build with cc="zig cc"`
+	case "darwin":
+		return `install the command line tools with: xcode-select --install, or install zig (brew install zig) and build with cc="zig cc"`
+	default:
+		return `install gcc (i.e. apt install build-essential, dnf install gcc, apk add build-base), or install zig (https://ziglang.org) and build with cc="zig cc"`
+	}
+}
+
+// Checks the C compiler, falling back to zig cc, and returns the compiler the c-shared check should use ("" if none work)
+func checkCompiler(ctx context.Context, cc, dir string) (Check, string) {
+	check := Check{Name: "c compiler"}
+	err := compileC(ctx, cc, dir)
+	if err == nil {
+		check.Status, check.Detail = StatusOK, fmt.Sprintf("%s compiles C", cc)
+		return check, cc
+	}
+	check.Detail = fmt.Sprintf("%s doesn't work: %v", cc, err)
+	if cc != "zig cc" {
+		if zigErr := compileC(ctx, "zig cc", dir); zigErr == nil {
+			check.Status = StatusWarning
+			check.Detail += ", but zig cc does"
+			check.Fix = `build with zig as the C compiler: get_library(..., cc="zig cc") or cgohelper build -cc "zig cc"`
+			return check, "zig cc"
+		}
+	}
+	check.Status = StatusError
+	check.Fix = installCompilerFix()
+	return check, ""
+}
+
+// Builds a c-shared library, and loads it with the python (if there is one)
+func checkCShared(ctx context.Context, cc, python, dir string) Check {
+	check := Check{Name: "c-shared", Status: StatusOK}
+	source := filepath.Join(dir, "cshared")
+	if err := os.MkdirAll(source, 0o755); err != nil {
+		check.Status, check.Detail = StatusError, err.Error()
+		return check
+	}
+	files := map[string]string{
+		"go.mod": "module doctor\n\ngo 1.22\n",
+		"lib.go": "package main\n\nimport \"C\"\nimport \"unsafe\"\n\n//export doctor_pointer_bits\nfunc doctor_pointer_bits() C.int { return C.int(unsafe.Sizeof(uintptr(0)) * 8) }\n\nfunc main() {}\n",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(source, name), []byte(contents), 0o644); err != nil {
+			check.Status, check.Detail = StatusError, err.Error()
+			return check
+		}
+	}
+	output := filepath.Join(source, "lib.so")
+	if runtime.GOOS == "windows" {
+		output = filepath.Join(source, "lib.dll")
+	}
+	if _, err := build.Build(ctx, build.Config{Source: source, Output: output, CC: cc, Force: true}); err != nil {
+		check.Status = StatusError
+		check.Detail = err.Error()
+		check.Fix = "check the compiler output above, a -buildmode=c-shared build needs cgo and a C compiler for the target (GOOS/GOARCH)"
+		return check
+	}
+	check.Detail = "built a -buildmode=c-shared library"
+	if python == "" {
+		return check
+	}
+	script := "import ctypes, sys; print(ctypes.CDLL(sys.argv[1]).doctor_pointer_bits())"
+	loaded, err := exec.CommandContext(ctx, python, "-c", script, output).CombinedOutput()
+	if err != nil {
+		check.Status = StatusError
+		check.Detail += fmt.Sprintf(", but python can't load it: %s", strings.TrimSpace(string(loaded)))
+		check.Fix = "check that python and go have the same architecture (see the python check)"
+		return check
+	}
+	check.Detail += fmt.Sprintf(" and loaded it from python (%s-bit)", strings.TrimSpace(string(loaded)))
+	return check
+}
+
+// Runs every check, later checks are skipped when the ones they need failed
+//
+// Parameters:
+//   - ctx: Cancels the go, python and compiler commands.
+//   - o: What to check.
+//
+// Returns:
+//   - The report, OK is false if any check had an error.
+func Run(ctx context.Context, o Options) (report Report) {
+	add := func(check Check) Check {
+		report.Checks = append(report.Checks, check)
+		return check
+	}
+	skip := func(name, reason string) {
+		add(Check{Name: name, Status: StatusSkipped, Detail: reason})
+	}
+	defer func() {
+		report.OK = true
+		for _, check := range report.Checks {
+			if check.Status == StatusError {
+				report.OK = false
+			}
+		}
+	}()
+
+	python := o.Python
+	if python == "" {
+		python = "python3"
+		if runtime.GOOS == "windows" {
+			python = "python"
+		}
+	}
+	scratch, err := os.MkdirTemp("", "cgohelper-doctor")
+	if err != nil {
+		add(Check{Name: "go", Status: StatusError, Detail: err.Error(), Fix: "check that the temporary directory is writable"})
+		return report
+	}
+	defer os.RemoveAll(scratch)
+
+	// go
+	var env goEnv
+	cmd := exec.CommandContext(ctx, "go", "env", "-json", "GOVERSION", "GOOS", "GOARCH", "CGO_ENABLED", "CC", "GOMOD", "GOTOOLCHAIN")
+	cmd.Dir = o.Dir
+	output, err := cmd.Output()
+	if err == nil {
+		err = json.Unmarshal(output, &env)
+	}
+	goOK := err == nil
+	if goOK {
+		add(Check{Name: "go", Status: StatusOK, Detail: fmt.Sprintf("%s %s/%s", env.GOVERSION, env.GOOS, env.GOARCH)})
+	} else {
+		add(Check{Name: "go", Status: StatusError, Detail: fmt.Sprintf("go env failed: %v", err), Fix: "install go from https://go.dev/dl/ and make sure it's on the PATH"})
+	}
+
+	// go version
+	if !goOK {
+		skip("go version", "go isn't installed")
+	} else if env.GOMOD == "" || env.GOMOD == os.DevNull {
+		add(Check{Name: "go version", Status: StatusWarning, Detail: fmt.Sprintf("no go.mod in %q", o.Dir), Fix: "run the doctor in the directory of the library (or the helper)"})
+	} else if required, err := readGoDirective(env.GOMOD); err != nil || required == "" {
+		add(Check{Name: "go version", Status: StatusWarning, Detail: fmt.Sprintf("unable to read the go line of %s: %v", env.GOMOD, err), Fix: "add a go line to go.mod (i.e. go 1.22.0)"})
+	} else {
+		add(CheckGoVersion(env.GOVERSION, required, env.GOTOOLCHAIN))
+	}
+
+	// cgo
+	if goOK {
+		add(CheckCgo(env.CGO_ENABLED))
+	} else {
+		skip("cgo", "go isn't installed")
+	}
+
+	// c compiler
+	cc := o.CC
+	if cc == "" {
+		cc = env.CC
+	}
+	if cc == "" {
+		cc = "gcc"
+	}
+	compilerCheck, workingCC := checkCompiler(ctx, cc, scratch)
+	add(compilerCheck)
+
+	// python
+	info, err := pythonInfo(ctx, python)
+	pythonOK := err == nil
+	switch {
+	case !pythonOK:
+		add(Check{Name: "python", Status: StatusError, Detail: fmt.Sprintf("unable to run %s: %v", python, err), Fix: "install python 3, or pass the python that will load the library (i.e. -python /path/to/venv/bin/python)"})
+	case !goOK:
+		add(Check{Name: "python", Status: StatusSkipped, Detail: fmt.Sprintf("%s %s (%s, %d-bit), go isn't installed to compare with", info.Version, info.Machine, info.Platform, info.PointerBits)})
+	default:
+		pythonOK = add(CheckPython(info, env.GOOS, env.GOARCH)).Status != StatusError
+	}
+
+	// c-shared
+	switch {
+	case !goOK:
+		skip("c-shared", "go isn't installed")
+	case workingCC == "":
+		skip("c-shared", "no working C compiler")
+	default:
+		loader := ""
+		if pythonOK {
+			loader = python
+		}
+		add(checkCShared(ctx, workingCC, loader, scratch))
+	}
+	return report
+}
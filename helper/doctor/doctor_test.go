@@ -0,0 +1,106 @@
+package doctor
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGoArch(t *testing.T) {
+	for test_input, expected := range map[string]string{"x86_64": "amd64", "AMD64": "amd64", "aarch64": "arm64", "arm64": "arm64", "i686": "386", "armv7l": "arm", "sparc": ""} {
+		if temp := GoArch(test_input); temp != expected {
+			t.Errorf("TestGoArch:GoArch(%q): %q!=%q", test_input, temp, expected)
+		}
+	}
+}
+
+func TestCheckGoVersion(t *testing.T) {
+	for _, test_input := range []struct {
+		installed, required, toolchain string
+		expected                       Status
+	}{
+		{"go1.22.0", "1.22.0", "auto", StatusOK},
+		{"go1.23.4", "1.22", "auto", StatusOK},
+		{"go1.22rc1", "1.21.0", "auto", StatusOK},
+		{"go1.21.5", "1.22.0", "auto", StatusWarning},
+		{"go1.21.5", "1.22.0", "local", StatusError},
+		{"go1.20.1", "1.22.0", "auto", StatusError},
+		{"devel +abc", "1.22.0", "auto", StatusWarning},
+	} {
+		temp := CheckGoVersion(test_input.installed, test_input.required, test_input.toolchain)
+		if temp.Status != test_input.expected || (temp.Status != StatusOK && temp.Fix == "") {
+			t.Errorf("TestCheckGoVersion:CheckGoVersion(%q, %q, %q): %+v, expected %s", test_input.installed, test_input.required, test_input.toolchain, temp, test_input.expected)
+		}
+	}
+}
+
+func TestCheckCgo(t *testing.T) {
+	if temp := CheckCgo("1"); temp.Status != StatusOK {
+		t.Errorf("TestCheckCgo:CheckCgo(1): %+v", temp)
+	}
+	if temp := CheckCgo("0"); temp.Status != StatusWarning || !strings.Contains(temp.Fix, "CGO_ENABLED=1") {
+		t.Errorf("TestCheckCgo:CheckCgo(0): %+v", temp)
+	}
+}
+
+func TestCheckPython(t *testing.T) {
+	for _, test_input := range []struct {
+		info        PythonInfo
+		goos, arch  string
+		expected    Status
+		fixContains string
+	}{
+		{PythonInfo{Platform: "linux", Machine: "x86_64", PointerBits: 64}, "linux", "amd64", StatusOK, ""},
+		{PythonInfo{Platform: "win32", Machine: "AMD64", PointerBits: 64}, "windows", "amd64", StatusOK, ""},
+		{PythonInfo{Platform: "darwin", Machine: "arm64", PointerBits: 64}, "darwin", "amd64", StatusError, "GOARCH=arm64"},
+		{PythonInfo{Platform: "win32", Machine: "AMD64", PointerBits: 32}, "windows", "amd64", StatusError, "GOARCH=386"},
+		{PythonInfo{Platform: "linux", Machine: "x86_64", PointerBits: 64}, "windows", "amd64", StatusError, "GOOS=linux"},
+		{PythonInfo{Platform: "linux", Machine: "sparc64", PointerBits: 64}, "linux", "amd64", StatusWarning, "amd64"},
+	} {
+		temp := CheckPython(test_input.info, test_input.goos, test_input.arch)
+		if temp.Status != test_input.expected || !strings.Contains(temp.Fix, test_input.fixContains) {
+			t.Errorf("TestCheckPython:CheckPython(%+v, %s, %s): %+v, expected %s", test_input.info, test_input.goos, test_input.arch, temp, test_input.expected)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 isn't installed")
+	}
+	report := Run(context.Background(), Options{Dir: "..", Python: python})
+	names := []string{}
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+		if check.Status == StatusError && check.Fix == "" {
+			t.Errorf("TestRun:Run(): %s failed without a fix", check.Name)
+		}
+	}
+	if strings.Join(names, ",") != "go,go version,cgo,c compiler,python,c-shared" {
+		t.Errorf("TestRun:Run(): checks %q", names)
+	}
+	// This machine builds the helper, so everything should work
+	if !report.OK {
+		t.Errorf("TestRun:Run(): %+v", report.Checks)
+	}
+
+	report = Run(context.Background(), Options{Dir: "..", Python: python, CC: "does-not-exist-cc"})
+	for _, check := range report.Checks {
+		if check.Name == "c compiler" && (check.Status == StatusOK || !strings.Contains(check.Detail, "not found")) {
+			t.Errorf("TestRun:Run(missing cc): %+v", check)
+		}
+	}
+
+	report = Run(context.Background(), Options{Dir: "..", Python: "does-not-exist-python"})
+	if report.OK || report.Checks[4].Status != StatusError {
+		t.Errorf("TestRun:Run(missing python): %+v", report.Checks[4])
+	}
+	if !strings.Contains(report.Checks[5].Detail, "built") {
+		t.Errorf("TestRun:Run(missing python): c-shared should still build, %+v", report.Checks[5])
+	}
+}
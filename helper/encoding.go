@@ -0,0 +1,291 @@
+package main
+
+/*
+#include <stdlib.h>
+
+typedef struct {
+    char* data;                  // The decoded UTF-8 bytes, null terminated (but may contain nulls, use length)
+    size_t length;               // Number of bytes in data, not counting the null terminator
+    size_t numberOfInvalid;      // Number of invalid sequences found in the input
+    size_t* invalidOffsets;      // Offset of each invalid sequence in the input (in bytes for UTF-8/Latin-1, code units for UTF-16)
+    char* error;                 // NULL on success, otherwise why the input was rejected (strict policy)
+} DecodeResult;
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// What to do with invalid sequences when decoding text, the values match the python ENCODING_* constants
+type EncodingPolicy int
+
+const (
+	EncodingStrict          EncodingPolicy = iota // Reject the input with an error wrapping ErrInvalidEncoding
+	EncodingReplace                               // Replace each invalid sequence with U+FFFD
+	EncodingSurrogateEscape                       // Pass invalid bytes through untouched, so python can decode them with errors="surrogateescape"
+)
+
+// Returned (wrapped) by the strict policy when the input contains an invalid sequence
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// Returned for an EncodingPolicy that isn't one of the Encoding* constants
+var ErrUnknownEncodingPolicy = errors.New("unknown encoding policy")
+
+func init() {
+	RegisterStructLayout("DecodeResult", C.DecodeResult{})
+}
+
+// ======== Text Encodings ========
+
+// Finds the invalid sequences in UTF-8 input
+//
+// Parameters:
+//   - data: The bytes to check.
+//
+// Returns:
+//   - The byte offset of each invalid sequence (empty if the input is valid UTF-8).
+func FindInvalidUTF8(data []byte) []int {
+	invalid := []int{}
+	for offset := 0; offset < len(data); {
+		r, size := utf8.DecodeRune(data[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			invalid = append(invalid, offset)
+			size = 1
+		}
+		offset += size
+	}
+	return invalid
+}
+
+// Decodes UTF-8 input, handling invalid sequences according to policy
+//
+// Parameters:
+//   - data: The bytes to decode.
+//   - policy: What to do with invalid sequences.
+//
+// Returns:
+//   - The decoded string (with invalid bytes replaced or passed through, depending on policy).
+//   - The byte offset of each invalid sequence in data.
+//   - An error wrapping ErrInvalidEncoding if policy is EncodingStrict and the input is invalid.
+func DecodeUTF8(data []byte, policy EncodingPolicy) (string, []int, error) {
+	invalid := FindInvalidUTF8(data)
+	switch policy {
+	case EncodingStrict:
+		if len(invalid) > 0 {
+			return "", invalid, fmt.Errorf("%w: utf-8 byte 0x%02x at offset %d", ErrInvalidEncoding, data[invalid[0]], invalid[0])
+		}
+		return string(data), invalid, nil
+	case EncodingReplace:
+		return string(replaceInvalidUTF8(data)), invalid, nil
+	case EncodingSurrogateEscape:
+		return string(data), invalid, nil
+	}
+	return "", nil, fmt.Errorf("%w: %d", ErrUnknownEncodingPolicy, policy)
+}
+
+// Replaces every invalid byte in UTF-8 input with U+FFFD (so a truncated multi-byte sequence gets one per byte)
+func replaceInvalidUTF8(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	for offset := 0; offset < len(data); {
+		r, size := utf8.DecodeRune(data[offset:])
+		if r == utf8.RuneError && size <= 1 {
+			result = utf8.AppendRune(result, utf8.RuneError)
+			offset++
+			continue
+		}
+		result = append(result, data[offset:offset+size]...)
+		offset += size
+	}
+	return result
+}
+
+// Decodes UTF-16 input (i.e. from a Windows API), handling unpaired surrogates according to policy
+//
+// Parameters:
+//   - data: The UTF-16 code units to decode.
+//   - policy: What to do with unpaired surrogates.
+//
+// Returns:
+//   - The decoded string, with EncodingSurrogateEscape unpaired surrogates are kept as 3 byte sequences
+//     (WTF-8, decode them in python with errors="surrogatepass").
+//   - The index of each unpaired surrogate in data.
+//   - An error wrapping ErrInvalidEncoding if policy is EncodingStrict and the input is invalid.
+func DecodeUTF16(data []uint16, policy EncodingPolicy) (string, []int, error) {
+	if policy < EncodingStrict || policy > EncodingSurrogateEscape {
+		return "", nil, fmt.Errorf("%w: %d", ErrUnknownEncodingPolicy, policy)
+	}
+	invalid := []int{}
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		unit := rune(data[i])
+		if !utf16.IsSurrogate(unit) {
+			result = utf8.AppendRune(result, unit)
+			continue
+		}
+		if i+1 < len(data) {
+			if r := utf16.DecodeRune(unit, rune(data[i+1])); r != utf8.RuneError {
+				result = utf8.AppendRune(result, r)
+				i++
+				continue
+			}
+		}
+
+		invalid = append(invalid, i)
+		switch policy {
+		case EncodingStrict:
+			return "", invalid, fmt.Errorf("%w: unpaired utf-16 surrogate 0x%04x at index %d", ErrInvalidEncoding, unit, i)
+		case EncodingReplace:
+			result = utf8.AppendRune(result, utf8.RuneError)
+		case EncodingSurrogateEscape:
+			// utf8.AppendRune() refuses surrogates, so encode the 3 bytes by hand
+			result = append(result, byte(0xe0|unit>>12), byte(0x80|(unit>>6)&0x3f), byte(0x80|unit&0x3f))
+		}
+	}
+	return string(result), invalid, nil
+}
+
+// Decodes Latin-1 (ISO-8859-1) input, every byte is a valid character so this can't fail
+//
+// Parameters:
+//   - data: The bytes to decode.
+//
+// Returns:
+//   - The decoded string.
+func DecodeLatin1(data []byte) string {
+	result := make([]byte, 0, len(data))
+	for _, b := range data {
+		result = utf8.AppendRune(result, rune(b))
+	}
+	return string(result)
+}
+
+// Converts the output of a Decode* function to a DecodeResult to be returned to C code
+//
+// Parameters:
+//   - text: The decoded string.
+//   - invalid: The offsets of the invalid sequences.
+//   - err: The error from decoding (or nil).
+//
+// Returns:
+//   - Pointer to a C.DecodeResult.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decode_result.
+func decodedToCResult(text string, invalid []int, err error) *C.DecodeResult {
+	result := (*C.DecodeResult)(C.malloc(C.size_t(unsafe.Sizeof(C.DecodeResult{}))))
+	result.data = (*C.char)(C.CBytes(append([]byte(text), 0)))
+	result.length = C.size_t(len(text))
+	result.numberOfInvalid = C.size_t(len(invalid))
+	result.invalidOffsets = nil
+	if len(invalid) > 0 {
+		result.invalidOffsets = (*C.size_t)(C.malloc(C.size_t(len(invalid)) * C.size_t(unsafe.Sizeof(C.size_t(0)))))
+		offsets := unsafe.Slice(result.invalidOffsets, len(invalid))
+		for i, offset := range invalid {
+			offsets[i] = C.size_t(offset)
+		}
+	}
+	result.error = (*C.char)(errorToCString(err))
+	return result
+}
+
+// Decodes a UTF-8 buffer according to an encoding policy
+//
+// Parameters:
+//   - data: Pointer to the bytes to decode (*C.char), doesn't need to be null terminated.
+//   - length: Number of bytes in data (C.size_t).
+//   - policy: The EncodingPolicy (C.int), 0 strict, 1 replace, 2 surrogateescape passthrough.
+//
+// Returns:
+//   - Pointer to a C.DecodeResult with the text, the byte offsets of invalid sequences, and an error in strict mode.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decode_result.
+//
+//export helper_decode_utf8
+func helper_decode_utf8(data unsafe.Pointer, length C.size_t, policy C.int) *C.DecodeResult {
+	if err := CheckNotForked(); err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	goLength, err := CSizeToInt(length)
+	if err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	return decodedToCResult(DecodeUTF8(unsafe.Slice((*byte)(data), goLength), EncodingPolicy(policy)))
+}
+
+// Decodes a UTF-16 buffer according to an encoding policy
+//
+// Parameters:
+//   - data: Pointer to the UTF-16 code units to decode (*C.uint16_t), without a byte order mark.
+//   - length: Number of code units (not bytes) in data (C.size_t).
+//   - bigEndian: 1 if the code units are big endian, 0 for little endian (i.e. Windows wchar_t) (C.int).
+//   - policy: The EncodingPolicy (C.int), 0 strict, 1 replace, 2 passthrough (as WTF-8).
+//
+// Returns:
+//   - Pointer to a C.DecodeResult with the text, the code unit index of unpaired surrogates, and an error in strict mode.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decode_result.
+//
+//export helper_decode_utf16
+func helper_decode_utf16(data unsafe.Pointer, length C.size_t, bigEndian C.int, policy C.int) *C.DecodeResult {
+	if err := CheckNotForked(); err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	goLength, err := CSizeToInt(length)
+	if err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	if goLength > math.MaxInt/2 {
+		return decodedToCResult("", nil, fmt.Errorf("%w: %d code units don't fit in a Go int as bytes", ErrIntegerOverflow, goLength))
+	}
+	raw := unsafe.Slice((*byte)(data), goLength*2)
+	units := make([]uint16, goLength)
+	for i := range units {
+		if bigEndian != 0 {
+			units[i] = uint16(raw[2*i])<<8 | uint16(raw[2*i+1])
+		} else {
+			units[i] = uint16(raw[2*i+1])<<8 | uint16(raw[2*i])
+		}
+	}
+	return decodedToCResult(DecodeUTF16(units, EncodingPolicy(policy)))
+}
+
+// Decodes a Latin-1 (ISO-8859-1) buffer
+//
+// Parameters:
+//   - data: Pointer to the bytes to decode (*C.char), doesn't need to be null terminated.
+//   - length: Number of bytes in data (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.DecodeResult with the text (Latin-1 input is never invalid).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_decode_result.
+//
+//export helper_decode_latin1
+func helper_decode_latin1(data unsafe.Pointer, length C.size_t) *C.DecodeResult {
+	if err := CheckNotForked(); err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	goLength, err := CSizeToInt(length)
+	if err != nil {
+		return decodedToCResult("", nil, err)
+	}
+	return decodedToCResult(DecodeLatin1(unsafe.Slice((*byte)(data), goLength)), nil, nil)
+}
+
+// Free a *C.DecodeResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.DecodeResult to be freed (*C.DecodeResult).
+//
+//export helper_free_decode_result
+func helper_free_decode_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	if ptr == nil {
+		return
+	}
+	temp := (*C.DecodeResult)(ptr)
+	C.free(unsafe.Pointer(temp.data))
+	C.free(unsafe.Pointer(temp.invalidOffsets))
+	C.free(unsafe.Pointer(temp.error))
+	C.free(ptr)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestDecodeUTF8(t *testing.T) {
+	// Valid input is the same under every policy
+	for _, test_input := range []string{"", "Hello World", "!@$#^%!#@@%*!", "❤", "\n"} {
+		for _, policy := range []EncodingPolicy{EncodingStrict, EncodingReplace, EncodingSurrogateEscape} {
+			temp, invalid, err := DecodeUTF8([]byte(test_input), policy)
+			if temp != test_input || len(invalid) != 0 || err != nil {
+				t.Errorf("TestDecodeUTF8:DecodeUTF8(%q, %d): %q!=%q (%v, %v)", test_input, policy, temp, test_input, invalid, err)
+			}
+		}
+	}
+
+	test_input := []byte("ok\xffthen\xe2\x9dend") // A stray byte, and a truncated ❤
+	if _, invalid, err := DecodeUTF8(test_input, EncodingStrict); !errors.Is(err, ErrInvalidEncoding) || !slices.Equal(invalid, []int{2, 7, 8}) {
+		t.Errorf("TestDecodeUTF8:DecodeUTF8(strict): expected ErrInvalidEncoding at [2 7 8], got %v %v", invalid, err)
+	}
+	if temp, _, _ := DecodeUTF8(test_input, EncodingReplace); temp != "ok�then��end" {
+		t.Errorf("TestDecodeUTF8:DecodeUTF8(replace): %q", temp)
+	}
+	if temp, _, _ := DecodeUTF8(test_input, EncodingSurrogateEscape); temp != string(test_input) {
+		t.Errorf("TestDecodeUTF8:DecodeUTF8(surrogateescape): %q!=%q", temp, test_input)
+	}
+	if _, _, err := DecodeUTF8(test_input, 42); !errors.Is(err, ErrUnknownEncodingPolicy) {
+		t.Errorf("TestDecodeUTF8:DecodeUTF8(policy 42): expected ErrUnknownEncodingPolicy, got %v", err)
+	}
+}
+
+func TestDecodeUTF16(t *testing.T) {
+	for _, test_input := range []string{"", "Hello World", "❤", "😀 emoji need surrogate pairs"} {
+		temp, invalid, err := DecodeUTF16(utf16.Encode([]rune(test_input)), EncodingStrict)
+		if temp != test_input || len(invalid) != 0 || err != nil {
+			t.Errorf("TestDecodeUTF16:DecodeUTF16(%q): %q!=%q (%v, %v)", test_input, temp, test_input, invalid, err)
+		}
+	}
+
+	test_input := []uint16{'a', 0xd83d, 'b', 0xde00} // A lone high and a lone low surrogate
+	if _, invalid, err := DecodeUTF16(test_input, EncodingStrict); !errors.Is(err, ErrInvalidEncoding) || !slices.Equal(invalid, []int{1}) {
+		t.Errorf("TestDecodeUTF16:DecodeUTF16(strict): expected ErrInvalidEncoding at [1], got %v %v", invalid, err)
+	}
+	if temp, invalid, _ := DecodeUTF16(test_input, EncodingReplace); temp != "a�b�" || !slices.Equal(invalid, []int{1, 3}) {
+		t.Errorf("TestDecodeUTF16:DecodeUTF16(replace): %q %v", temp, invalid)
+	}
+	if temp, _, _ := DecodeUTF16(test_input, EncodingSurrogateEscape); temp != "a\xed\xa0\xbdb\xed\xb8\x80" {
+		t.Errorf("TestDecodeUTF16:DecodeUTF16(passthrough): %q", temp)
+	}
+}
+
+func TestDecodeLatin1(t *testing.T) {
+	if temp := DecodeLatin1([]byte("caf\xe9 \xa9\xff")); temp != "café ©ÿ" {
+		t.Errorf("TestDecodeLatin1:DecodeLatin1(): %q!=%q", temp, "café ©ÿ")
+	}
+}
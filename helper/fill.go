@@ -0,0 +1,195 @@
+package main
+
+import "C"
+import (
+	"math"
+	"slices"
+	"unsafe"
+)
+
+// ======== Fill Caller-allocated Buffers ========
+//
+// The Fill* functions write into memory the caller already owns (i.e. an array from prepare_int_array()),
+// so nothing is allocated with C.malloc and there's nothing to free afterwards. They all follow the
+// snprintf() convention, the return value is the number of elements the data needs:
+//   - If it's <= capacity the data was written, and the value is the number of elements written.
+//   - If it's > capacity the buffer was left untouched, and the value is the capacity needed, grow it and call again.
+
+// Writes a slice of ints into a caller-allocated C int array
+//
+// Parameters:
+//   - data: Slice of Go integers to write.
+//   - buffer: Pointer to the C array of integers (*C.int) to write into.
+//   - capacity: Number of elements the buffer can hold.
+//
+// Returns:
+//   - The number of elements written, or the required capacity if it's larger than capacity.
+//   - An error wrapping ErrIntegerOverflow if a value doesn't fit in a C int (the buffer is left untouched).
+func FillIntBuffer(data []int, buffer unsafe.Pointer, capacity int) (int, error) {
+	if len(data) > capacity || len(data) == 0 {
+		return len(data), nil
+	}
+	if err := CheckIntSliceFitsCInt(data); err != nil {
+		return 0, err
+	}
+	array := unsafe.Slice((*C.int)(buffer), len(data))
+	for i, val := range data {
+		array[i] = C.int(val)
+	}
+	return len(data), nil
+}
+
+// Writes a slice of floats into a caller-allocated C float array
+//
+// Parameters:
+//   - data: Slice of Go float32 values to write.
+//   - buffer: Pointer to the C array of floats (*C.float) to write into.
+//   - capacity: Number of elements the buffer can hold.
+//
+// Returns:
+//   - The number of elements written, or the required capacity if it's larger than capacity.
+func FillFloatBuffer(data []float32, buffer unsafe.Pointer, capacity int) int {
+	if len(data) > capacity || len(data) == 0 {
+		return len(data)
+	}
+	array := unsafe.Slice((*C.float)(buffer), len(data))
+	for i, val := range data {
+		array[i] = C.float(val)
+	}
+	return len(data)
+}
+
+// Writes a slice of bytes into a caller-allocated C byte array (not null terminated)
+//
+// Parameters:
+//   - data: Slice of bytes to write.
+//   - buffer: Pointer to the C array of bytes (*C.char) to write into.
+//   - capacity: Number of bytes the buffer can hold.
+//
+// Returns:
+//   - The number of bytes written, or the required capacity if it's larger than capacity.
+func FillByteBuffer(data []byte, buffer unsafe.Pointer, capacity int) int {
+	if len(data) > capacity || len(data) == 0 {
+		return len(data)
+	}
+	copy(unsafe.Slice((*byte)(buffer), len(data)), data)
+	return len(data)
+}
+
+// Writes a string into a caller-allocated C char buffer, including the null terminator
+//
+// Parameters:
+//   - data: The Go string to write.
+//   - buffer: Pointer to the C char buffer (*C.char) to write into.
+//   - capacity: Number of bytes the buffer can hold.
+//
+// Returns:
+//   - The number of bytes written including the null terminator (len(data)+1), or the required capacity if it's larger than capacity.
+//
+// Notes:
+//   - Unlike snprintf() the string is never truncated, a buffer that's too small is left untouched
+func FillStringBuffer(data string, buffer unsafe.Pointer, capacity int) int {
+	required := len(data) + 1
+	if required > capacity {
+		return required
+	}
+	array := unsafe.Slice((*byte)(buffer), required)
+	copy(array, data)
+	array[len(data)] = 0
+	return required
+}
+
+// Converts a buffer capacity passed in from C to a Go int, capacities too big for an int can hold anything so they're clamped
+func capacityToInt(capacity C.size_t) int {
+	if uint64(capacity) > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(capacity)
+}
+
+// Used to copy a C-compatible integer array into a caller-allocated buffer, good for debugging fill functions
+//
+// Parameters:
+//   - cArray: Pointer to the C array of integers (*C.int).
+//   - numberOfElements: Number of elements in the C array (C.size_t).
+//   - buffer: Pointer to the C array of integers (*C.int) to write into.
+//   - capacity: Number of elements the buffer can hold (C.size_t).
+//
+// Returns:
+//   - The number of elements written, or the required capacity if it's larger than capacity (C.size_t), 0 if numberOfElements is too large.
+//
+//export helper_fill_int_array
+func helper_fill_int_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return 0
+	}
+	internalRepresentation := CIntArrayToSlice(cArray, length)
+	written, err := FillIntBuffer(internalRepresentation, buffer, capacityToInt(capacity))
+	if err != nil {
+		return 0 // Can't happen, every value started out as a C int
+	}
+	return C.size_t(written)
+}
+
+// Used to copy a C-compatible float array into a caller-allocated buffer, good for debugging fill functions
+//
+// Parameters:
+//   - cArray: Pointer to the C array of floats (*C.float).
+//   - numberOfElements: Number of elements in the C array (C.size_t).
+//   - buffer: Pointer to the C array of floats (*C.float) to write into.
+//   - capacity: Number of elements the buffer can hold (C.size_t).
+//
+// Returns:
+//   - The number of elements written, or the required capacity if it's larger than capacity (C.size_t), 0 if numberOfElements is too large.
+//
+//export helper_fill_float_array
+func helper_fill_float_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return 0
+	}
+	internalRepresentation := CFloatArrayToSlice(cArray, length)
+	return C.size_t(FillFloatBuffer(internalRepresentation, buffer, capacityToInt(capacity)))
+}
+
+// Used to copy a C-compatible byte array into a caller-allocated buffer, good for debugging fill functions
+//
+// Parameters:
+//   - cArray: Pointer to the C array of bytes (*C.char).
+//   - numberOfElements: Number of bytes in the C array (C.size_t).
+//   - buffer: Pointer to the C array of bytes (*C.char) to write into.
+//   - capacity: Number of bytes the buffer can hold (C.size_t).
+//
+// Returns:
+//   - The number of bytes written, or the required capacity if it's larger than capacity (C.size_t), 0 if numberOfElements is too large.
+//
+//export helper_fill_byte_array
+func helper_fill_byte_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return 0
+	}
+	internalRepresentation := slices.Clone(unsafe.Slice((*byte)(cArray), length))
+	return C.size_t(FillByteBuffer(internalRepresentation, buffer, capacityToInt(capacity)))
+}
+
+// Used to copy a C-compatible string into a caller-allocated buffer, good for debugging fill functions
+//
+// Parameters:
+//   - cString: Pointer to the C string (*C.char).
+//   - buffer: Pointer to the C char buffer (*C.char) to write into.
+//   - capacity: Number of bytes the buffer can hold (C.size_t).
+//
+// Returns:
+//   - The number of bytes written including the null terminator, or the required capacity if it's larger than capacity (C.size_t).
+//
+//export helper_fill_string
+func helper_fill_string(cString unsafe.Pointer, buffer unsafe.Pointer, capacity C.size_t) C.size_t {
+	ExitIfForked()
+	internalRepresentation := C.GoString((*C.char)(cString))
+	return C.size_t(FillStringBuffer(internalRepresentation, buffer, capacityToInt(capacity)))
+}
@@ -0,0 +1,77 @@
+package main
+
+// Tests for the fill functions, the buffers are Go slices
+// standing in for memory allocated by the caller
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestFillNumberBuffers(t *testing.T) {
+	for _, test_input := range [][]int{{-10_000, -1, 0, 1, 10_000}, {42}} {
+		buffer := make([]int32, 5)
+		if temp, err := FillIntBuffer(test_input, unsafe.Pointer(&buffer[0]), len(buffer)); temp != len(test_input) || err != nil {
+			t.Errorf("TestFillNumberBuffers:FillIntBuffer(%v): %d!=%d (%v)", test_input, temp, len(test_input), err)
+		}
+		for i := range test_input {
+			if int(buffer[i]) != test_input[i] {
+				t.Errorf("TestFillNumberBuffers:FillIntBuffer(%v): %v!=%v", test_input, buffer, test_input)
+			}
+		}
+	}
+
+	floatInput := []float32{-790.5207, 0, 3.14159}
+	floatBuffer := make([]float32, 3)
+	if temp := FillFloatBuffer(floatInput, unsafe.Pointer(&floatBuffer[0]), len(floatBuffer)); temp != 3 || !slices.Equal(floatBuffer, floatInput) {
+		t.Errorf("TestFillNumberBuffers:FillFloatBuffer(%v): %v (%d)!=%v", floatInput, floatBuffer, temp, floatInput)
+	}
+
+	// Buffers that are too small report the required size and are left untouched
+	buffer := []int32{7, 7}
+	if temp, _ := FillIntBuffer([]int{1, 2, 3}, unsafe.Pointer(&buffer[0]), len(buffer)); temp != 3 || buffer[0] != 7 {
+		t.Errorf("TestFillNumberBuffers:FillIntBuffer(too small): returned %d, buffer %v", temp, buffer)
+	}
+	if temp := FillFloatBuffer([]float32{1, 2}, nil, 0); temp != 2 {
+		t.Errorf("TestFillNumberBuffers:FillFloatBuffer(nil): %d!=2", temp)
+	}
+	if temp, _ := FillIntBuffer(nil, nil, 0); temp != 0 {
+		t.Errorf("TestFillNumberBuffers:FillIntBuffer(empty): %d!=0", temp)
+	}
+
+	// Values that don't fit in a C int are reported instead of wrapping
+	if _, err := FillIntBuffer([]int{1, 1 << 40}, unsafe.Pointer(&buffer[0]), len(buffer)); !errors.Is(err, ErrIntegerOverflow) || buffer[0] != 7 {
+		t.Errorf("TestFillNumberBuffers:FillIntBuffer(1<<40): expected ErrIntegerOverflow, got %v, buffer %v", err, buffer)
+	}
+}
+
+func TestFillByteBuffers(t *testing.T) {
+	for _, test_input := range []string{"", "Hello World", "!@$#^%!#@@%*!", "❤", "\n"} {
+		buffer := make([]byte, 32)
+		for i := range buffer {
+			buffer[i] = 0xff
+		}
+		temp := FillStringBuffer(test_input, unsafe.Pointer(&buffer[0]), len(buffer))
+		if temp != len(test_input)+1 {
+			t.Errorf("TestFillByteBuffers:FillStringBuffer(%q): %d!=%d", test_input, temp, len(test_input)+1)
+		}
+		if result := CStringToString(unsafe.Pointer(&buffer[0])); result != test_input {
+			t.Errorf("TestFillByteBuffers:FillStringBuffer(%q): %q!=%q", test_input, result, test_input)
+		}
+
+		if temp := FillByteBuffer([]byte(test_input), unsafe.Pointer(&buffer[0]), len(buffer)); temp != len(test_input) || string(buffer[:temp]) != test_input {
+			t.Errorf("TestFillByteBuffers:FillByteBuffer(%q): %q!=%q", test_input, buffer[:temp], test_input)
+		}
+	}
+
+	// The null terminator counts towards the required size
+	buffer := []byte("xxxxx")
+	if temp := FillStringBuffer("Hello", unsafe.Pointer(&buffer[0]), len(buffer)); temp != 6 || string(buffer) != "xxxxx" {
+		t.Errorf("TestFillByteBuffers:FillStringBuffer(too small): returned %d, buffer %q", temp, buffer)
+	}
+	if temp := FillByteBuffer([]byte("Hello"), unsafe.Pointer(&buffer[0]), len(buffer)); temp != 5 || string(buffer) != "Hello" {
+		t.Errorf("TestFillByteBuffers:FillByteBuffer(exact): returned %d, buffer %q", temp, buffer)
+	}
+}
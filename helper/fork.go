@@ -0,0 +1,85 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The pid of the process that initialized the Go runtime, used to detect use after a fork()
+var initPID = os.Getpid()
+
+// Returned when the library is used from a process forked after the Go runtime started
+var ErrForkedProcess = errors.New("go runtime was initialized in a parent process and is not usable after fork(), use a spawn-based worker instead")
+
+// The exit status of a forked process that called an exported function with no way to return an error (EX_OSERR)
+const ForkedExitCode = 71
+
+// ======== Fork Detection ========
+
+// Checks if the current process is a fork of the process that initialized the Go runtime
+//
+// Returns:
+//   - true if the current pid differs from the pid recorded when the runtime started.
+func ForkedSinceInit() bool {
+	return os.Getpid() != initPID
+}
+
+// Used at the entrypoint of exported functions that can return an error to fail fast after a fork()
+//
+// Returns:
+//   - ErrForkedProcess if the runtime was started in a different process, otherwise nil.
+//
+// Usage:
+//
+//	if err := CheckNotForked(); err != nil {
+//		fmt.Println(err)
+//		return nil
+//	}
+func CheckNotForked() error {
+	if ForkedSinceInit() {
+		return ErrForkedProcess
+	}
+	return nil
+}
+
+// Used at the entrypoint of exported functions that have no way to return an error, exits the process after a fork()
+// (with ErrForkedProcess on stderr) instead of carrying on with a runtime that can hang or corrupt memory
+//
+// Usage:
+//
+//	//export parse_urls
+//	func parse_urls(cArray unsafe.Pointer, numberOfStrings C.size_t) *C.StringArrayResult {
+//		ExitIfForked()
+//		...
+//	}
+func ExitIfForked() {
+	if err := CheckNotForked(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v (process %d, initialized in %d)\n", err, os.Getpid(), initPID)
+		os.Exit(ForkedExitCode)
+	}
+}
+
+// Returns the pid of the process that initialized the Go runtime
+//
+// Returns:
+//   - The pid recorded at init (C.longlong), compare against os.getpid() on the caller side.
+//
+//export helper_get_init_pid
+func helper_get_init_pid() C.longlong {
+	return C.longlong(initPID)
+}
+
+// Checks if the current process was forked after the Go runtime started
+//
+// Returns:
+//   - 1 if the process was forked after init, 0 otherwise (C.int).
+//
+//export helper_is_forked_process
+func helper_is_forked_process() C.int {
+	if ForkedSinceInit() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+// Tests for fork detection, the actual fork() scenario is
+// covered in test_lib.py since Go can't fork itself safely
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestForkDetection(t *testing.T) {
+	if ForkedSinceInit() {
+		t.Errorf("TestForkDetection:ForkedSinceInit(): reported fork in the initializing process")
+	}
+	if err := CheckNotForked(); err != nil {
+		t.Errorf("TestForkDetection:CheckNotForked(): %v", err)
+	}
+	if int(helper_get_init_pid()) != os.Getpid() {
+		t.Errorf("TestForkDetection:helper_get_init_pid(): %d!=%d", helper_get_init_pid(), os.Getpid())
+	}
+
+	// Simulate being in a child process by pretending init happened elsewhere
+	originalPID := initPID
+	initPID = originalPID + 1
+	defer func() { initPID = originalPID }()
+
+	if !ForkedSinceInit() {
+		t.Errorf("TestForkDetection:ForkedSinceInit(): did not detect pid mismatch")
+	}
+	if err := CheckNotForked(); !errors.Is(err, ErrForkedProcess) {
+		t.Errorf("TestForkDetection:CheckNotForked(): expected ErrForkedProcess, got %v", err)
+	}
+	if helper_is_forked_process() != 1 {
+		t.Errorf("TestForkDetection:helper_is_forked_process(): %d!=1", helper_is_forked_process())
+	}
+
+	// Exports that can return an error return ErrForkedProcess instead of running
+	cError := helper_stop_trace()
+	defer FreeCString(cError)
+	if actual := CStringToString(cError); actual != ErrForkedProcess.Error() {
+		t.Errorf("TestForkDetection:helper_stop_trace(): %q, expected ErrForkedProcess", actual)
+	}
+	module, function := StringToCString("helper"), StringToCString("worker_pid")
+	defer FreeCString(module)
+	defer FreeCString(function)
+	cResponse := helper_host_call(module, function, nil)
+	defer FreeCString(cResponse)
+	var response WorkerResponse
+	if err := json.Unmarshal([]byte(CStringToString(cResponse)), &response); err != nil || response.Error != ErrForkedProcess.Error() {
+		t.Errorf("TestForkDetection:helper_host_call(): %v, %+v", err, response)
+	}
+}
+
+func TestExitIfForked(t *testing.T) {
+	// Run in a child process, since the export exits the process
+	if os.Getenv("HELPER_TEST_EXIT_IF_FORKED") == "1" {
+		initPID++
+		helper_return_string(nil)
+		os.Exit(0)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExitIfForked$")
+	cmd.Env = append(os.Environ(), "HELPER_TEST_EXIT_IF_FORKED=1")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != ForkedExitCode || !strings.Contains(stderr.String(), ErrForkedProcess.Error()) {
+		t.Errorf("TestExitIfForked:helper_return_string(): %v, %q", err, stderr.String())
+	}
+}
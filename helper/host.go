@@ -0,0 +1,58 @@
+package main
+
+import "C"
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/Descent098/cgo-python-helpers/registry"
+)
+
+// ======== Module Host ========
+
+// Calls a function registered in the registry, lets many packages share one library (and one Go runtime)
+//
+// Parameters:
+//   - module: Pointer to the C string with the module name (*C.char).
+//   - function: Pointer to the C string with the function name (*C.char).
+//   - arguments: Pointer to a C string with a JSON array of arguments (*C.char), NULL for no arguments.
+//
+// Returns:
+//   - Pointer to a C string with a JSON object containing "result" or "error" (*C.char).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_c_string.
+//
+//export helper_host_call
+func helper_host_call(module unsafe.Pointer, function unsafe.Pointer, arguments unsafe.Pointer) unsafe.Pointer {
+	request := WorkerRequest{Function: CStringToString(module) + "." + CStringToString(function)}
+
+	var response WorkerResponse
+	if err := CheckNotForked(); err != nil {
+		response.Error = err.Error()
+	} else if arguments != nil {
+		if err := json.Unmarshal([]byte(CStringToString(arguments)), &request.Args); err != nil {
+			response.Error = "malformed arguments: " + err.Error()
+		}
+	}
+	if response.Error == "" {
+		response = callWorkerFunction(request)
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		encoded, _ = json.Marshal(WorkerResponse{Error: err.Error()})
+	}
+	return StringToCString(string(encoded))
+}
+
+// Lists the modules registered in the host, and their functions
+//
+// Returns:
+//   - Pointer to a C string with a JSON object of module names to function names (*C.char).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_c_string.
+//
+//export helper_host_modules
+func helper_host_modules() unsafe.Pointer {
+	ExitIfForked()
+	encoded, _ := json.Marshal(registry.Modules())
+	return StringToCString(string(encoded))
+}
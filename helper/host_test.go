@@ -0,0 +1,75 @@
+package main
+
+// Tests for the module host, calls go through the same
+// C strings a python caller would use
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/Descent098/cgo-python-helpers/registry"
+)
+
+// Calls host_call with Go strings, and decodes the response
+func callHost(t *testing.T, module, function, arguments string) WorkerResponse {
+	cModule := StringToCString(module)
+	defer FreeCString(cModule)
+	cFunction := StringToCString(function)
+	defer FreeCString(cFunction)
+	var cArguments unsafe.Pointer
+	if arguments != "" {
+		cArguments = StringToCString(arguments)
+		defer FreeCString(cArguments)
+	}
+
+	result := helper_host_call(cModule, cFunction, cArguments)
+	defer FreeCString(result)
+
+	var response WorkerResponse
+	if err := json.Unmarshal([]byte(CStringToString(result)), &response); err != nil {
+		t.Fatalf("callHost(%s.%s): invalid response %v", module, function, err)
+	}
+	return response
+}
+
+func TestHost(t *testing.T) {
+	registry.Register("test_host", "greet", func(args []json.RawMessage) (any, error) {
+		var name string
+		if err := registry.DecodeArgs(args, &name); err != nil {
+			return nil, err
+		}
+		return "Hello " + name, nil
+	})
+
+	response := callHost(t, "test_host", "greet", `["World"]`)
+	if response.Error != "" || string(response.Result) != `"Hello World"` {
+		t.Errorf("TestHost:helper_host_call(test_host.greet): %s, %s", response.Result, response.Error)
+	}
+	response = callHost(t, "helper", "return_int_array", `[[1, -2, 3]]`)
+	if response.Error != "" || string(response.Result) != `[1,-2,3]` {
+		t.Errorf("TestHost:helper_host_call(helper.return_int_array): %s, %s", response.Result, response.Error)
+	}
+
+	for _, test_input := range [][3]string{
+		{"test_host", "greet", ""},
+		{"test_host", "greet", "not json"},
+		{"test_host", "does_not_exist", "[]"},
+		{"does_not_exist", "greet", `["World"]`},
+	} {
+		if response := callHost(t, test_input[0], test_input[1], test_input[2]); response.Error == "" {
+			t.Errorf("TestHost:helper_host_call(%s.%s, %q): expected an error", test_input[0], test_input[1], test_input[2])
+		}
+	}
+
+	cModules := helper_host_modules()
+	defer FreeCString(cModules)
+	var modules map[string][]string
+	if err := json.Unmarshal([]byte(CStringToString(cModules)), &modules); err != nil {
+		t.Fatalf("TestHost:helper_host_modules(): %v", err)
+	}
+	if !slices.Contains(modules["helper"], "return_string") || !slices.Equal(modules["test_host"], []string{"greet"}) {
+		t.Errorf("TestHost:helper_host_modules(): %v", modules)
+	}
+}
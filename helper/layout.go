@@ -0,0 +1,188 @@
+package main
+
+import "C"
+import (
+	"encoding/hex"
+	"encoding/json"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// ======== Memory Layout ========
+
+// Where a field sits in a C struct, as cgo laid it out
+type FieldLayout struct {
+	Name   string  `json:"name"`   // The C name of the field
+	Type   string  `json:"type"`   // The C type of the field (i.e. "char*", "size_t" shows as "unsigned long")
+	Offset uintptr `json:"offset"` // Bytes from the start of the struct
+	Size   uintptr `json:"size"`
+	Align  uintptr `json:"align"`
+}
+
+// The size, alignment and fields of a C struct, as cgo laid it out
+type StructLayout struct {
+	Size   uintptr       `json:"size"`
+	Align  uintptr       `json:"align"`
+	Fields []FieldLayout `json:"fields"` // In declaration order, empty for typedefs of scalars
+}
+
+var (
+	structLayoutsLock sync.Mutex
+	structLayouts     = map[string]StructLayout{}
+)
+
+// The spelling of cgo's C type names that differ from the C spelling
+var cTypeNames = map[string]string{
+	"schar":     "signed char",
+	"uchar":     "unsigned char",
+	"ushort":    "unsigned short",
+	"uint":      "unsigned int",
+	"ulong":     "unsigned long",
+	"longlong":  "long long",
+	"ulonglong": "unsigned long long",
+}
+
+// Registers a C struct so it's reported by StructLayouts() (and helper_struct_layouts()), call it from an init() in
+// the file whose preamble declares the struct, since C.* types are only visible in that file
+//
+// Parameters:
+//   - name: The C name of the struct, use the same name as the python Structure for compare_struct_layouts().
+//   - value: A zero value (or pointer to one) of the C type.
+//
+// Usage:
+//
+//	func init() {
+//		RegisterStructLayout("Suggestion", C.Suggestion{})
+//	}
+func RegisterStructLayout(name string, value any) {
+	layout := LayoutOf(value)
+	structLayoutsLock.Lock()
+	defer structLayoutsLock.Unlock()
+	structLayouts[name] = layout
+}
+
+// Works out the layout of a value's type
+//
+// Parameters:
+//   - value: A zero value (or pointer to one) of a C type.
+//
+// Returns:
+//   - The size, alignment and fields of the type, cgo's padding fields are left out.
+func LayoutOf(value any) StructLayout {
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	layout := StructLayout{Size: t.Size(), Align: uintptr(t.Align()), Fields: []FieldLayout{}}
+	if t.Kind() != reflect.Struct {
+		return layout
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Name == "_" {
+			continue
+		}
+		layout.Fields = append(layout.Fields, FieldLayout{
+			Name:   cFieldName(field.Name),
+			Type:   cTypeName(field.Type),
+			Offset: field.Offset,
+			Size:   field.Type.Size(),
+			Align:  uintptr(field.Type.Align()),
+		})
+	}
+	return layout
+}
+
+// Undoes cgo's renaming of fields that are Go keywords (a C field called type is _type in Go)
+func cFieldName(name string) string {
+	if strings.HasPrefix(name, "_") && token.IsKeyword(name[1:]) {
+		return name[1:]
+	}
+	return name
+}
+
+// Spells a cgo type the way C would, as close as reflect allows (typedefs show as the type they're for)
+func cTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.UnsafePointer:
+		return "void*"
+	case reflect.Pointer:
+		return cTypeName(t.Elem()) + "*"
+	case reflect.Array:
+		return cTypeName(t.Elem()) + "[" + strconv.Itoa(t.Len()) + "]"
+	}
+	name, ok := strings.CutPrefix(t.Name(), "_Ctype_")
+	if !ok {
+		return t.String()
+	}
+	if strings.HasPrefix(name, "struct_") {
+		return "struct" // Anonymous structs (i.e. typedef struct {...} StructHeader) have generated names
+	}
+	if spelling, ok := cTypeNames[name]; ok {
+		return spelling
+	}
+	return name
+}
+
+// Returns the layouts of every registered struct
+//
+// Returns:
+//   - A map of struct names to their layouts.
+func StructLayouts() map[string]StructLayout {
+	structLayoutsLock.Lock()
+	defer structLayoutsLock.Unlock()
+	layouts := make(map[string]StructLayout, len(structLayouts))
+	for name, layout := range structLayouts {
+		layouts[name] = layout
+	}
+	return layouts
+}
+
+// Formats memory like hexdump -C (offsets, hex bytes, then the printable characters)
+//
+// Parameters:
+//   - ptr: Pointer to the start of the memory, NULL returns an empty string.
+//   - length: The number of bytes to dump, every byte must be readable.
+//
+// Returns:
+//   - The dump, one line per 16 bytes.
+func HexDump(ptr unsafe.Pointer, length int) string {
+	if ptr == nil || length <= 0 {
+		return ""
+	}
+	return hex.Dump(unsafe.Slice((*byte)(ptr), length))
+}
+
+// Reports the layout of every registered C struct as cgo sees it, to compare against the bindings' structs
+//
+// Returns:
+//   - Pointer to a C string with a JSON object of struct names to {"size", "align", "fields": [{"name", "type",
+//     "offset", "size", "align"}, ...]} (*C.char).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_c_string.
+//
+//export helper_struct_layouts
+func helper_struct_layouts() unsafe.Pointer {
+	ExitIfForked()
+	encoded, _ := json.Marshal(StructLayouts())
+	return StringToCString(string(encoded))
+}
+
+// Dumps a range of memory for debugging, i.e. a struct filled by the other side of the ABI
+//
+// Parameters:
+//   - ptr: Pointer to the start of the memory, NULL returns an empty string.
+//   - length: The number of bytes to dump (C.size_t), reading past the end of an allocation can crash the process.
+//
+// Returns:
+//   - Pointer to a C string with the dump in the format of hexdump -C (*C.char).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_c_string.
+//
+//export helper_hexdump
+func helper_hexdump(ptr unsafe.Pointer, length C.size_t) unsafe.Pointer {
+	ExitIfForked()
+	return StringToCString(HexDump(ptr, capacityToInt(length)))
+}
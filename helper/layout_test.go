@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestLayoutOf(t *testing.T) {
+	type padded struct {
+		small byte
+		_     [3]byte
+		big   int64
+		text  *byte
+	}
+	for _, value := range []any{padded{}, &padded{}} {
+		layout := LayoutOf(value)
+		if layout.Size != 24 || layout.Align != 8 || len(layout.Fields) != 3 {
+			t.Fatalf("TestLayoutOf:LayoutOf(%T): %+v", value, layout)
+		}
+		if big := layout.Fields[1]; big.Name != "big" || big.Offset != 8 || big.Size != 8 || big.Align != 8 {
+			t.Errorf("TestLayoutOf:LayoutOf(%T): big is %+v", value, big)
+		}
+		if text := layout.Fields[2]; text.Type != "uint8*" || text.Offset != 16 {
+			t.Errorf("TestLayoutOf:LayoutOf(%T): text is %+v", value, text)
+		}
+	}
+	if layout := LayoutOf(int32(0)); layout.Size != 4 || len(layout.Fields) != 0 {
+		t.Errorf("TestLayoutOf:LayoutOf(int32): %+v", layout)
+	}
+
+	for name, expected := range map[string]string{"_type": "type", "_range": "range", "_private": "_private", "data": "data"} {
+		if actual := cFieldName(name); actual != expected {
+			t.Errorf("TestLayoutOf:cFieldName(%q): %q!=%q", name, actual, expected)
+		}
+	}
+}
+
+func TestStructLayouts(t *testing.T) {
+	pointerSize := unsafe.Sizeof(uintptr(0))
+	layouts := StructLayouts()
+	for _, name := range []string{"StringArrayResult", "IntArrayResult", "FloatArrayResult", "KeyValueResult", "NullableIntArrayResult",
+		"NullableFloatArrayResult", "DecodeResult", "BigIntResult", "DecimalResult", "Timestamp", "StructHeader", "LibraryInfo"} {
+		if _, ok := layouts[name]; !ok {
+			t.Errorf("TestStructLayouts: %s isn't registered", name)
+		}
+	}
+
+	ints := layouts["IntArrayResult"]
+	if ints.Size != 2*pointerSize || len(ints.Fields) != 2 {
+		t.Fatalf("TestStructLayouts:IntArrayResult: %+v", ints)
+	}
+	if data := ints.Fields[1]; data.Name != "data" || data.Type != "int*" || data.Offset != pointerSize {
+		t.Errorf("TestStructLayouts:IntArrayResult.data: %+v", data)
+	}
+	// features is a uint64_t after an int32_t, so there's 4 bytes of padding before it
+	info := layouts["LibraryInfo"]
+	if len(info.Fields) != 5 || info.Fields[2].Name != "features" || info.Fields[2].Offset != 16 || info.Size != 32 {
+		t.Errorf("TestStructLayouts:LibraryInfo: %+v", info)
+	}
+
+	// Registering the same name again replaces the layout
+	RegisterStructLayout("TestStructLayouts", int64(0))
+	RegisterStructLayout("TestStructLayouts", int32(0))
+	defer func() {
+		structLayoutsLock.Lock()
+		defer structLayoutsLock.Unlock()
+		delete(structLayouts, "TestStructLayouts")
+	}()
+	if layout := StructLayouts()["TestStructLayouts"]; layout.Size != 4 {
+		t.Errorf("TestStructLayouts:RegisterStructLayout() twice: %+v", layout)
+	}
+
+	encoded := helper_struct_layouts()
+	defer FreeCString(encoded)
+	var decoded map[string]StructLayout
+	if err := json.Unmarshal([]byte(CStringToString(encoded)), &decoded); err != nil || decoded["IntArrayResult"].Fields[1].Offset != pointerSize {
+		t.Errorf("TestStructLayouts:helper_struct_layouts(): %v, %s", err, CStringToString(encoded))
+	}
+}
+
+func TestHexDump(t *testing.T) {
+	text := StringToCString("Hello, World! This spans two lines")
+	defer FreeCString(text)
+
+	dump := HexDump(text, 20)
+	lines := strings.Split(strings.TrimSuffix(dump, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "00000000  48 65 6c 6c 6f 2c") || !strings.HasSuffix(lines[0], "|Hello, World! Th|") {
+		t.Errorf("TestHexDump:HexDump(): %q", dump)
+	}
+	if HexDump(nil, 10) != "" || HexDump(text, 0) != "" {
+		t.Errorf("TestHexDump:HexDump(): NULL and empty ranges should be empty")
+	}
+
+	exported := helper_hexdump(text, 5)
+	defer FreeCString(exported)
+	if actual := CStringToString(exported); !strings.Contains(actual, "|Hello|") {
+		t.Errorf("TestHexDump:helper_hexdump(): %q", actual)
+	}
+}
@@ -11,26 +11,203 @@
 //
 //	StringToCString(data string) *C.char{} // Convert a string to a c-compatible C-string (glorified alias for C.CString)
 //	StringSliceToCArray(data []string) *C.StringArrayResult{} // Return dynamically sized string array as a C-Compatible array
-//	IntSliceToCArray(data []int) *C.IntArrayResult{} // Return dynamically sized int array as a C-Compatible array
+//	IntSliceToCArray(data []int) (*C.IntArrayResult, error){} // Return dynamically sized int array as a C-Compatible array (errors if a value doesn't fit in a C int)
 //	FloatSliceToCArray(data []float32) *C.FloatArrayResult{} // Return dynamically float sized array as a C-Compatible array
 //
+// # Fill Caller-allocated Buffers (return the number of elements written, or the required capacity if it's larger)
+//
+//	FillIntBuffer(data []int, buffer unsafe.Pointer, capacity int) (int, error){} // Writes a slice of ints into a caller-allocated C int array (errors if a value doesn't fit in a C int)
+//	FillFloatBuffer(data []float32, buffer unsafe.Pointer, capacity int) int{} // Writes a slice of floats into a caller-allocated C float array
+//	FillByteBuffer(data []byte, buffer unsafe.Pointer, capacity int) int{} // Writes a slice of bytes into a caller-allocated C byte array
+//	FillStringBuffer(data string, buffer unsafe.Pointer, capacity int) int{} // Writes a null terminated string into a caller-allocated C char buffer
+//
+// # Overflow-checked Narrowing (array lengths are C.size_t, narrowing conversions return errors wrapping ErrIntegerOverflow)
+//
+//	IntToCInt(value int) (C.int, error){} // Converts a Go int to a C int, checking that it fits
+//	CheckIntSliceFitsCInt(data []int) error{} // Checks that every value in a slice fits in a C int
+//	CSizeToInt(size C.size_t) (int, error){} // Converts a C size_t to a Go int, checking that it fits
+//
+// # Text Encodings (policies are EncodingStrict, EncodingReplace and EncodingSurrogateEscape)
+//
+//	FindInvalidUTF8(data []byte) []int{} // Finds the byte offset of each invalid sequence in UTF-8 input
+//	DecodeUTF8(data []byte, policy EncodingPolicy) (string, []int, error){} // Decodes UTF-8 input, reporting where invalid sequences are
+//	DecodeUTF16(data []uint16, policy EncodingPolicy) (string, []int, error){} // Decodes UTF-16 input, reporting where unpaired surrogates are
+//	DecodeLatin1(data []byte) string{} // Decodes Latin-1 input
+//	helper_decode_utf8(data unsafe.Pointer, length C.size_t, policy C.int) *C.DecodeResult{} // C version of DecodeUTF8
+//	helper_decode_utf16(data unsafe.Pointer, length C.size_t, bigEndian C.int, policy C.int) *C.DecodeResult{} // C version of DecodeUTF16
+//	helper_decode_latin1(data unsafe.Pointer, length C.size_t) *C.DecodeResult{} // C version of DecodeLatin1
+//	helper_free_decode_result(ptr *C.DecodeResult){} // Free's a DecodeResult
+//
+// # Nullable Arrays (validity bitmaps use the Apache Arrow layout, NULL strings are missing values)
+//
+//	IsValid(validity []byte, i int) bool{} // Checks if element i is set in a validity bitmap
+//	NullableIntSliceToCArray(data []*int) (*C.NullableIntArrayResult, error){} // Return a slice of nullable ints as a C-Compatible array with a validity bitmap
+//	NullableFloatSliceToCArray(data []*float32) *C.NullableFloatArrayResult{} // Return a slice of nullable floats as a C-Compatible array with a validity bitmap
+//	NullableStringSliceToCArray(data []*string) *C.StringArrayResult{} // Return a slice of nullable strings as a C-Compatible array, nil entries are NULL
+//	CNullableIntArrayToSlice(cArray *C.int, validity *C.uchar, length int) []*int{} // Takes a C integer array and validity bitmap, and converts it to a slice of nullable ints
+//	CNullableFloatArrayToSlice(cArray *C.float, validity *C.uchar, length int) []*float32{} // Takes a C float array and validity bitmap, and converts it to a slice of nullable floats
+//	CStringArrayToNullableSlice(cArray **C.char, numberOfStrings int) []*string{} // Takes in an array of strings, NULL entries become nil
+//	helper_free_nullable_int_array_result(ptr *C.NullableIntArrayResult){} // Free's a NullableIntArrayResult (the array, bitmap and struct)
+//	helper_free_nullable_float_array_result(ptr *C.NullableFloatArrayResult){} // Free's a NullableFloatArrayResult (the array, bitmap and struct)
+//
+// # Time Conversions (a C.Timestamp is Unix nanoseconds plus a timezone offset, a C.Duration is nanoseconds)
+//
+//	TimeToCTimestamp(t time.Time) (C.Timestamp, error){} // Converts a time.Time to a Timestamp (errors if it's outside the range of Unix nanoseconds)
+//	CTimestampToTime(timestamp C.Timestamp) time.Time{} // Converts a Timestamp to a time.Time in a fixed timezone with its offset
+//	DurationToCDuration(d time.Duration) C.Duration{} // Converts a time.Duration to a Duration
+//	CDurationToDuration(d C.Duration) time.Duration{} // Converts a Duration to a time.Duration
+//	helper_now() C.Timestamp{} // Returns the current time according to Go
+//
+// # Arbitrary-precision Numbers (big.Int as two's-complement bytes or decimal strings, big.Float/big.Rat as decimal strings)
+//
+//	BigIntToTwosComplement(x *big.Int) []byte{} // Converts a big.Int to big-endian two's-complement bytes (python's int.to_bytes(..., signed=True))
+//	TwosComplementToBigInt(data []byte) *big.Int{} // Converts big-endian two's-complement bytes to a big.Int
+//	CBytesToBigInt(data *C.uchar, length int) *big.Int{} // Takes a C two's-complement byte buffer, and converts it to a big.Int
+//	BigIntToCBytes(x *big.Int) *C.BigIntResult{} // Converts a big.Int to a C-compatible two's-complement byte buffer
+//	ParseBigInt(text string) (*big.Int, error){} // Parses a decimal integer string (errors wrap ErrInvalidNumber)
+//	ParseBigFloat(text string, precision uint) (*big.Float, error){} // Parses a decimal string, i.e. from python's str(Decimal)
+//	ParseBigRat(text string) (*big.Rat, error){} // Parses a fraction ("22/7") or decimal string
+//	BigFloatToDecimalString(x *big.Float) string{} // Formats a big.Float as a string decimal.Decimal() can parse
+//	DecimalStringToCResult(text string, err error) *C.DecimalResult{} // Returns a number string (i.e. x.String(), x.RatString()) or an error to C
+//	helper_free_big_int_result(ptr *C.BigIntResult){} // Free's a BigIntResult (the bytes, error and struct)
+//	helper_free_decimal_result(ptr *C.DecimalResult){} // Free's a DecimalResult (the string, error and struct)
+//
+// # Versioned Structs (start with a C.StructHeader, fields are only ever appended)
+//
+//	ReadStructHeader(ptr unsafe.Pointer) (int, int, error){} // Reads the size and version from the header of a versioned struct
+//	FillVersionedStruct[T any](dst unsafe.Pointer, src *T, version int) (int, error){} // Copies a versioned struct into a caller-allocated one, writing only the fields that fit
+//	ReadVersionedStruct[T any](src unsafe.Pointer) (T, int, error){} // Reads a versioned struct sent by the caller, missing fields are zero values
+//	helper_library_info(info *C.LibraryInfo) *C.char{} // Fills a caller-allocated LibraryInfo (an example versioned struct)
+//
 // # Memory Freeing
 //
 //	FreeCString(data *C.char){} // Free's a C-string
-//	FreeStringArray(inputArray **C.char, count C.int){} // Free's an array of strings
+//	FreeStringArray(inputArray **C.char, count C.size_t){} // Free's an array of strings
 //	FreeIntArray(ptr *C.int){}  // Free's an array of integers
 //	FreeFloatArray(ptr *C.float){} // Free's an array of floats
+//	helper_free_c_string(data *C.char){} // C-callable wrapper for FreeCString
+//	helper_free_string_array(inputArray **C.char, count C.size_t){} // C-callable wrapper for FreeStringArray
+//	helper_free_int_array(ptr *C.int){} // C-callable wrapper for FreeIntArray
+//	helper_free_float_array(ptr *C.float){} // C-callable wrapper for FreeFloatArray
+//	helper_free_string_array_result(ptr *C.StringArrayResult){} // Free's a StringArrayResult (the strings, array and struct)
+//	helper_free_int_array_result(ptr *C.IntArrayResult){} // Free's an IntArrayResult (the array and struct)
+//	helper_free_float_array_result(ptr *C.FloatArrayResult){} // Free's a FloatArrayResult (the array and struct)
+//
+// # ABI Versioning (all C-callable helper functions are prefixed with helper_ to avoid collisions)
+//
+//	helper_abi_version() C.int{} // Returns ABIVersion, bump it whenever an exported signature or struct layout changes
+//	helper_abi_features() C.ulonglong{} // Returns the Feature* bitmap of optional features compiled into the library
+//
+// # Runtime Tuning
+//
+//	helper_get_gomaxprocs() C.int{} / helper_set_gomaxprocs(n C.int) C.int{} // Reads/sets GOMAXPROCS
+//	helper_get_gc_percent() C.int{} / helper_set_gc_percent(percent C.int) C.int{} // Reads/sets the GC percent (GOGC)
+//	helper_get_memory_limit() C.longlong{} / helper_set_memory_limit(limit C.longlong) C.longlong{} // Reads/sets the soft memory limit (GOMEMLIMIT)
+//	helper_clear_memory_limit() C.longlong{} // Removes the soft memory limit
+//	helper_gc(){} // Runs a garbage collection
+//	helper_free_os_memory(){} // Returns as much memory to the operating system as possible
+//
+// # Runtime Metrics
+//
+//	RuntimeMetrics() map[string]float64{} // Takes a snapshot of every scalar metric in runtime/metrics
+//	FloatMapToCKeyValueArray(data map[string]float64) *C.KeyValueResult{} // Return a map of floats as a C-Compatible key/value array
+//	helper_runtime_metrics() *C.KeyValueResult{} // Takes a snapshot of the runtime metrics
+//	helper_free_key_value_result(ptr *C.KeyValueResult){} // Free's a KeyValueResult
+//
+// # Worker Pool (exported functions submit their parallel work to SharedPool instead of starting their own goroutines)
+//
+//	pool.NewWorkerPool(size int) *pool.WorkerPool{} // Creates a bounded pool of goroutines (in the importable pool package)
+//	(*WorkerPool).Submit(ctx context.Context, priority Priority, run func(ctx context.Context) error) *Task{} // Queues a function, highest priority first
+//	(*WorkerPool).Map(ctx context.Context, priority Priority, n int, run func(ctx context.Context, i int) error) error{} // Runs a function for each index and waits for them, stops at the first error
+//	(*WorkerPool).Resize(size int) int{} / (*WorkerPool).Stats() PoolStats{} // Resizes/inspects a pool
+//	helper_pool_size() C.int{} / helper_pool_resize(size C.int) C.int{} // Reads/sets the size of SharedPool (pool.Shared, CGOHELPER_POOL_SIZE, defaults to GOMAXPROCS)
+//	helper_pool_stats() *C.KeyValueResult{} // Takes a snapshot of SharedPool's workers, queues and counters
+//
+// # C Buffer Pool (the int, float and string array conversions allocate from SharedBufferPool, and the free functions return memory to it)
+//
+// Pooled memory starts after a hidden 16 byte header, so the results of the array conversions (StringSliceToCArray(),
+// helper_return_int_array() etc.) and their arrays must be released with the helper_free_* functions (or
+// SharedBufferPool.Free() from Go). Calling free()/C.free() on them is an invalid free that corrupts (or aborts) the heap.
+// The cap is the most idle bytes kept in the free lists, it doesn't limit the memory that's handed out.
+//
+//	NewCBufferPool(capacity int64) *CBufferPool{} // Creates size-classed free lists of C memory, keeping at most capacity idle bytes
+//	(*CBufferPool).Alloc(size uintptr) unsafe.Pointer{} / (*CBufferPool).Free(ptr unsafe.Pointer){} // Allocates/returns a block (Free() also accepts memory from malloc(), but free() can't be used on pooled blocks)
+//	(*CBufferPool).SetCap(capacity int64) int64{} / (*CBufferPool).Trim() int64{} / (*CBufferPool).Stats() BufferPoolStats{} // Changes the idle byte cap/frees idle blocks/inspects a pool
+//	helper_buffer_pool_get_cap() C.longlong{} / helper_buffer_pool_set_cap(capacity C.longlong) C.longlong{} // Reads/sets the idle byte cap of SharedBufferPool (CGOHELPER_BUFFER_POOL_CAP, defaults to 64MiB)
+//	helper_buffer_pool_trim() C.longlong{} // Frees SharedBufferPool's idle blocks
+//	helper_buffer_pool_stats() *C.KeyValueResult{} // Takes a snapshot of SharedBufferPool's usage and hit/miss counters
+//
+// # Profiling (fallible exports return a C string error, or NULL on success)
+//
+//	StartCPUProfile(path string) error{} / StopCPUProfile() error{} // Starts/stops a pprof CPU profile
+//	WriteProfile(name string, path string, debug int) error{} // Writes a named runtime/pprof profile (heap, goroutine, mutex, block...) to a file
+//	StartTrace(path string) error{} / StopTrace() error{} // Starts/stops an execution trace
+//	helper_start_cpu_profile(path unsafe.Pointer) unsafe.Pointer{} / helper_stop_cpu_profile() unsafe.Pointer{}
+//	helper_write_profile(name unsafe.Pointer, path unsafe.Pointer, debug C.int) unsafe.Pointer{}
+//	helper_set_mutex_profile_fraction(rate C.int) C.int{} / helper_set_block_profile_rate(rate C.int){} // Enables mutex/block profiling
+//	helper_start_trace(path unsafe.Pointer) unsafe.Pointer{} / helper_stop_trace() unsafe.Pointer{}
+//
+// # Self-test (checks the conversions against memory from the real caller, i.e. python on the target machine)
+//
+//	RunSelfTest(buffers SelfTestBuffers) SelfTestReport{} // Runs the round-trips (empty arrays, NaN/Inf, int extremes, multi-byte UTF-8, long strings) through the caller's buffers
+//	helper_self_test(ints unsafe.Pointer, intCapacity C.size_t, floats unsafe.Pointer, floatCapacity C.size_t, text unsafe.Pointer, textCapacity C.size_t) unsafe.Pointer{} // Returns the report as JSON
+//
+// # Memory Layout (debugging struct mismatches between cgo and the bindings, each file registers the structs in it's preamble)
+//
+//	RegisterStructLayout(name string, value any){} // Registers a C struct (i.e. C.Suggestion{}) so it's layout is reported, call it from an init()
+//	LayoutOf(value any) StructLayout{} // Works out the size, alignment and field offsets of a C type as cgo laid it out
+//	StructLayouts() map[string]StructLayout{} // Returns the layouts of every registered struct
+//	HexDump(ptr unsafe.Pointer, length int) string{} // Formats memory like hexdump -C
+//	helper_struct_layouts() unsafe.Pointer{} // Returns the layouts of every registered struct as JSON
+//	helper_hexdump(ptr unsafe.Pointer, length C.size_t) unsafe.Pointer{} // Returns a hexdump of the memory as a C string
+//
+// # Fork Detection (every export except the ABI and fork checks fails fast when called after a fork())
+//
+//	ForkedSinceInit() bool{} // Checks if the current process is a fork of the process that initialized the Go runtime
+//	CheckNotForked() error{} // Used at the entrypoint of exported functions that can return an error to fail fast after a fork()
+//	ExitIfForked(){} // Used at the entrypoint of the other exported functions, exits with ForkedExitCode after a fork()
+//	helper_get_init_pid() C.longlong{} // Returns the pid of the process that initialized the Go runtime
+//	helper_is_forked_process() C.int{} // Checks if the current process was forked after the Go runtime started
+//
+// # Out-of-process Workers
+//
+//	RegisterWorkerFunction(name string, fn WorkerFunction){} // Registers a function so it can be called by name through a worker
+//	DecodeWorkerArgs(args []json.RawMessage, targets ...any) error{} // Decodes the arguments of a worker call into the provided pointers
+//	RunWorkerMain(args []string) error{} // Entrypoint for a library built as a standalone worker executable
+//	ServeWorker(r io.Reader, w io.Writer) error{} // Serves worker requests from r and writes responses to w
+//	ServeWorkerSocket(socketPath string) error{} // Serves worker requests on a Unix socket
+//	StartWorker(executable string, args ...string) (*WorkerClient, error){} // Starts a worker executable and connects over stdin/stdout
+//	DialWorker(socketPath string) (*WorkerClient, error){} // Connects to a worker listening on a Unix socket
+//
+// # Module Host (functions are registered with the registry package)
+//
+//	helper_host_call(module *C.char, function *C.char, arguments *C.char) *C.char{} // Calls a registered function with JSON arguments, returns a JSON result
+//	helper_host_modules() *C.char{} // Lists the registered modules as a JSON object
 //
 // # Debugging Functions
 //
-//	return_string(data *C.char) *C.char{} // Used to convert a C-compatible string to a C-compatible string, useful for debugging encoding issues
-//	return_string_array(cArray **C.char, numberOfStrings int) *C.StringArrayResult{} // Used to convert a C-compatible string array to wrapper type
-//	return_int_array(cArray *C.int, numberOfElements C.int) *C.IntArrayResult{} // Used to convert a C-compatible integer array to wrapper type
-//	return_float_array(cArray *C.float, numberOfElements C.int) *C.FloatArrayResult{} // Used to convert a C-compatible float array to wrapper type
-//	print_string(ptr *C.char){} // Prints the go representation of a C string, good for debugging encoding issues
-//	print_string_array(cArray **C.char, numberOfString int){} // Prints the go representation of an array, good for debugging encoding issues
-//	print_int_array(cArray *C.int, numberOfInts int){} // Prints the go representation of an array, good for debugging rounding/conversion issues
-//	print_float_array(cArray *C.float, numberOfFloats int){} // Prints the go representation of an array, good for debugging rounding/conversion issues
+//	helper_fill_int_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t{} // Copies a C int array into a caller-allocated buffer
+//	helper_fill_float_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t{} // Copies a C float array into a caller-allocated buffer
+//	helper_fill_byte_array(cArray unsafe.Pointer, numberOfElements C.size_t, buffer unsafe.Pointer, capacity C.size_t) C.size_t{} // Copies a C byte array into a caller-allocated buffer
+//	helper_fill_string(cString unsafe.Pointer, buffer unsafe.Pointer, capacity C.size_t) C.size_t{} // Copies a C string into a caller-allocated buffer
+//	helper_return_string(data *C.char) *C.char{} // Used to convert a C-compatible string to a C-compatible string, useful for debugging encoding issues
+//	helper_return_string_array(cArray **C.char, numberOfStrings C.size_t) *C.StringArrayResult{} // Used to convert a C-compatible string array to wrapper type
+//	helper_return_int_array(cArray *C.int, numberOfElements C.size_t) *C.IntArrayResult{} // Used to convert a C-compatible integer array to wrapper type
+//	helper_return_float_array(cArray *C.float, numberOfElements C.size_t) *C.FloatArrayResult{} // Used to convert a C-compatible float array to wrapper type
+//	helper_return_nullable_int_array(cArray *C.int, validity *C.uchar, numberOfElements C.size_t) *C.NullableIntArrayResult{} // Used to convert a C-compatible nullable integer array to wrapper type
+//	helper_return_nullable_float_array(cArray *C.float, validity *C.uchar, numberOfElements C.size_t) *C.NullableFloatArrayResult{} // Used to convert a C-compatible nullable float array to wrapper type
+//	helper_return_nullable_string_array(cArray **C.char, numberOfStrings C.size_t) *C.StringArrayResult{} // Used to convert a C-compatible string array with NULL entries to wrapper type
+//	helper_return_timestamp(timestamp C.Timestamp) C.Timestamp{} // Used to convert a C-compatible timestamp to a time.Time and back, good for debugging timezone issues
+//	helper_return_duration(duration C.Duration) C.Duration{} // Used to convert a C-compatible duration to a time.Duration and back
+//	helper_return_big_int(data *C.uchar, length C.size_t) *C.BigIntResult{} // Used to convert a C-compatible two's-complement integer to a big.Int and back
+//	helper_big_int_from_string(cString *C.char) *C.BigIntResult{} // Used to convert a decimal integer string to a big.Int, and return it as bytes
+//	helper_big_int_to_string(data *C.uchar, length C.size_t) *C.DecimalResult{} // Used to convert two's-complement bytes to a big.Int, and return it as a decimal string
+//	helper_return_big_float(cString *C.char, precision C.uint) *C.DecimalResult{} // Used to convert a decimal string to a big.Float and back, good for debugging precision issues
+//	helper_return_big_rat(cString *C.char) *C.DecimalResult{} // Used to convert a fraction or decimal string to a big.Rat and back
+//	helper_print_string(ptr *C.char){} // Prints the go representation of a C string, good for debugging encoding issues
+//	helper_print_string_array(cArray **C.char, numberOfString C.size_t){} // Prints the go representation of an array, good for debugging encoding issues
+//	helper_print_int_array(cArray *C.int, numberOfInts C.size_t){} // Prints the go representation of an array, good for debugging rounding/conversion issues
+//	helper_print_float_array(cArray *C.float, numberOfFloats C.size_t){} // Prints the go representation of an array, good for debugging rounding/conversion issues
 //
 // # Examples
 //
@@ -52,17 +229,17 @@ package main
 #include <stdlib.h>
 
 typedef struct{
-	int numberOfElements;
+	size_t numberOfElements;
 	char** data;
 } StringArrayResult;
 
 typedef struct {
-    int numberOfElements;
+    size_t numberOfElements;
     int* data;
 } IntArrayResult;
 
 typedef struct {
-    int numberOfElements;
+    size_t numberOfElements;
     float* data;
 } FloatArrayResult;
 
@@ -70,9 +247,17 @@ typedef struct {
 import "C"
 import (
 	"fmt"
+	"os"
 	"unsafe"
 )
 
+// So helper_struct_layouts() can report the array results
+func init() {
+	RegisterStructLayout("StringArrayResult", C.StringArrayResult{})
+	RegisterStructLayout("IntArrayResult", C.IntArrayResult{})
+	RegisterStructLayout("FloatArrayResult", C.FloatArrayResult{})
+}
+
 // ======== Convert Go types to C type ========
 
 // Convert a string to a c-compatible C-string (glorified alias for C.CString)
@@ -94,7 +279,8 @@ func StringToCString(input string) unsafe.Pointer {
 //
 // Returns:
 //   - Pointer to a C.StringArrayResult containing the converted C strings.
-//     Note: The caller is responsible for freeing the allocated memory using free_string_array_result.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_string_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
 func StringSliceToCArray(data []string) *C.StringArrayResult {
 	count := len(data)
 
@@ -102,23 +288,49 @@ func StringSliceToCArray(data []string) *C.StringArrayResult {
 	amountOfElements := C.size_t(count)
 	sizeOfSingleElement := C.size_t(unsafe.Sizeof(uintptr(0)))
 	amountOfMemory := amountOfElements * sizeOfSingleElement
-	stringArray := (**C.char)(C.malloc(amountOfMemory))
+	stringArray := (**C.char)(SharedBufferPool.Alloc(uintptr(amountOfMemory)))
 
 	// Create Array of data
+	array := unsafe.Slice(stringArray, count)
 	for i, currentString := range data {
-		// Calculate where to put the string
-		locationOfArray := uintptr(unsafe.Pointer(stringArray)) // Starting point of first byte of slice
-		offsetIntoArray := uintptr(i)                           // The offset for the current element
-		sizeOfSingleElement := unsafe.Sizeof(uintptr(0))        // Size of a single string
+		array[i] = C.CString(currentString) // Convert go string to C string and insert at location in array
+	}
+
+	// Allocate memory for the struct
+	result := (*C.StringArrayResult)(SharedBufferPool.Alloc(unsafe.Sizeof(C.StringArrayResult{})))
+	result.numberOfElements = C.size_t(count)
+	result.data = stringArray
+
+	return result
+}
+
+// Return a slice of nullable strings as a C-Compatible array, nil entries become NULL pointers
+//
+// Parameters:
+//   - data: Slice of Go strings to convert, nil entries are missing values (as opposed to "").
+//
+// Returns:
+//   - Pointer to a C.StringArrayResult containing the converted C strings, with NULL for the missing values.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_string_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+func NullableStringSliceToCArray(data []*string) *C.StringArrayResult {
+	count := len(data)
 
-		locationInMemory := (**C.char)(unsafe.Pointer(locationOfArray + offsetIntoArray*sizeOfSingleElement))
-		*locationInMemory = C.CString(currentString) // Convert go string to C string and insert at location in array
+	// Allocate memory for an array of C string pointers (char**)
+	stringArray := (**C.char)(SharedBufferPool.Alloc(uintptr(count) * unsafe.Sizeof(uintptr(0))))
 
+	// Create Array of data, free() ignores the NULL entries so the usual free functions work
+	array := unsafe.Slice(stringArray, count)
+	for i, currentString := range data {
+		array[i] = nil
+		if currentString != nil {
+			array[i] = C.CString(*currentString)
+		}
 	}
 
 	// Allocate memory for the struct
-	result := (*C.StringArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.StringArrayResult{}))))
-	result.numberOfElements = C.int(count)
+	result := (*C.StringArrayResult)(SharedBufferPool.Alloc(unsafe.Sizeof(C.StringArrayResult{})))
+	result.numberOfElements = C.size_t(count)
 	result.data = stringArray
 
 	return result
@@ -131,26 +343,33 @@ func StringSliceToCArray(data []string) *C.StringArrayResult {
 //
 // Returns:
 //   - Pointer to a C.IntArrayResult containing the converted C integers.
-//     Note: The caller is responsible for freeing the allocated memory using free_int_array_result.
-func IntSliceToCArray(data []int) *C.IntArrayResult {
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_int_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+//   - An error wrapping ErrIntegerOverflow if a value doesn't fit in a C int (nothing is allocated).
+func IntSliceToCArray(data []int) (*C.IntArrayResult, error) {
 	count := len(data)
 
+	// Check every value fits before allocating, so there's nothing to clean up on error
+	if err := CheckIntSliceFitsCInt(data); err != nil {
+		return nil, err
+	}
+
 	// Allocate memory in C for the int array
 	amountOfMemory := C.size_t(count) * C.size_t(unsafe.Sizeof(C.int(0)))
-	cArray := (*C.int)(C.malloc(amountOfMemory))
+	cArray := (*C.int)(SharedBufferPool.Alloc(uintptr(amountOfMemory)))
 
 	// Fill in the values
-	array := (*[1 << 30]C.int)(unsafe.Pointer(cArray))
+	array := unsafe.Slice(cArray, count)
 	for i, val := range data {
 		array[i] = C.int(val)
 	}
 
 	// Allocate the result struct
-	result := (*C.IntArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.IntArrayResult{}))))
-	result.numberOfElements = C.int(count)
+	result := (*C.IntArrayResult)(SharedBufferPool.Alloc(unsafe.Sizeof(C.IntArrayResult{})))
+	result.numberOfElements = C.size_t(count)
 	result.data = cArray
 
-	return result
+	return result, nil
 }
 
 // Return dynamically float sized array as a C-Compatible array
@@ -160,23 +379,24 @@ func IntSliceToCArray(data []int) *C.IntArrayResult {
 //
 // Returns:
 //   - Pointer to a C.FloatArrayResult containing the converted C floats.
-//     Note: The caller is responsible for freeing the allocated memory using free_float_array_result.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_float_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
 func FloatSliceToCArray(data []float32) *C.FloatArrayResult {
 	count := len(data)
 
 	// Allocate memory in C for the float array
 	amountOfMemory := C.size_t(count) * C.size_t(unsafe.Sizeof(C.float(0)))
-	cArray := (*C.float)(C.malloc(amountOfMemory))
+	cArray := (*C.float)(SharedBufferPool.Alloc(uintptr(amountOfMemory)))
 
 	// Fill in the values
-	array := (*[1 << 30]C.float)(unsafe.Pointer(cArray))
+	array := unsafe.Slice(cArray, count)
 	for i, val := range data {
 		array[i] = C.float(val)
 	}
 
 	// Allocate the result struct
-	result := (*C.FloatArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.FloatArrayResult{}))))
-	result.numberOfElements = C.int(count)
+	result := (*C.FloatArrayResult)(SharedBufferPool.Alloc(unsafe.Sizeof(C.FloatArrayResult{})))
+	result.numberOfElements = C.size_t(count)
 	result.data = cArray
 
 	return result
@@ -209,9 +429,8 @@ func CStringToString(input unsafe.Pointer) string {
 //	var cIntArray *C.int // Assuming it's set in some line after this
 //	goInts := CIntArrayToSlice(unsafe.Pointer(cIntArray), length)
 func CIntArrayToSlice(cArray unsafe.Pointer, length int) []int {
-	// Setup buffer for array contents
-	const bufferSize = 1 << 30 // Allocate a huge buffer
-	slice := (*[bufferSize]C.int)(cArray)[:length:length]
+	// View the array contents as a slice
+	slice := unsafe.Slice((*C.int)(cArray), length)
 
 	// Convert to []int
 	result := make([]int, length)
@@ -235,9 +454,8 @@ func CIntArrayToSlice(cArray unsafe.Pointer, length int) []int {
 //	var cFloatArray  *C.float // Assuming it's set in some line after this
 //	goFloats := CFloatArrayToSlice(unsafe.Pointer(cFloatArray), length)
 func CFloatArrayToSlice(cArray unsafe.Pointer, length int) []float32 {
-	// Setup buffer for array contents
-	const bufferSize = 1 << 30 // Allocate a huge buffer
-	slice := (*[bufferSize]C.float)(cArray)[:length:length]
+	// View the array contents as a slice
+	slice := unsafe.Slice((*C.float)(cArray), length)
 
 	// Convert to []float32
 	result := make([]float32, length)
@@ -261,9 +479,8 @@ func CFloatArrayToSlice(cArray unsafe.Pointer, length int) []float32 {
 //
 //   - This function DOES NOT clean memory of input array, that's up to others to clear
 func CStringArrayToSlice(cArray unsafe.Pointer, numberOfStrings int) []string {
-	// Setup buffer for array contents
-	const bufferSize = 1 << 30 // Allocate a huge buffer
-	stringPointers := (*[bufferSize]*C.char)(cArray)[:numberOfStrings:numberOfStrings]
+	// View the array contents as a slice
+	stringPointers := unsafe.Slice((**C.char)(cArray), numberOfStrings)
 
 	result := make([]string, 0, numberOfStrings)
 	for i := range numberOfStrings {
@@ -272,6 +489,25 @@ func CStringArrayToSlice(cArray unsafe.Pointer, numberOfStrings int) []string {
 	return result
 }
 
+// Takes in an array of strings that may contain NULL entries, and converts it to a slice of nullable strings
+//
+// Parameters:
+//   - cArray: Pointer to the C array of strings (**C.char), entries may be NULL.
+//   - numberOfStrings: Number of strings in the C array.
+//
+// Returns:
+//   - A Go slice containing the converted strings, with nil for the NULL entries (CStringArrayToSlice turns them into "").
+func CStringArrayToNullableSlice(cArray unsafe.Pointer, numberOfStrings int) []*string {
+	result := make([]*string, numberOfStrings)
+	for i, ptr := range unsafe.Slice((**C.char)(cArray), numberOfStrings) {
+		if ptr != nil {
+			value := C.GoString(ptr)
+			result[i] = &value
+		}
+	}
+	return result
+}
+
 // ========== Debugging Functions ==========
 
 // Used to convert a C-compatible string back to itself, good for debugging encoding issues
@@ -283,8 +519,9 @@ func CStringArrayToSlice(cArray unsafe.Pointer, numberOfStrings int) []string {
 //   - Pointer to a new C string with the same content (*C.char).
 //     Note: The caller is responsible for freeing the allocated memory using FreeCString.
 //
-//export return_string
-func return_string(cString unsafe.Pointer) unsafe.Pointer {
+//export helper_return_string
+func helper_return_string(cString unsafe.Pointer) unsafe.Pointer {
+	ExitIfForked()
 	internalRepresentation := C.GoString((*C.char)(cString))
 	result := StringToCString(internalRepresentation)
 	return result
@@ -294,52 +531,94 @@ func return_string(cString unsafe.Pointer) unsafe.Pointer {
 //
 // Parameters:
 //   - cArray: Pointer to the C array of strings (**C.char).
-//   - numberOfStrings: Number of strings in the C array.
+//   - numberOfStrings: Number of strings in the C array (C.size_t).
 //
 // Returns:
-//   - Pointer to a C.StringArrayResult containing the converted strings (*C.StringArrayResult).
-//     Note: The caller is responsible for freeing the allocated memory using free_string_array_result.
-//
-//export return_string_array
-func return_string_array(cArray unsafe.Pointer, numberOfStrings int) *C.StringArrayResult {
+//   - Pointer to a C.StringArrayResult containing the converted strings (*C.StringArrayResult), or NULL if numberOfStrings is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_string_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+//
+//export helper_return_string_array
+func helper_return_string_array(cArray unsafe.Pointer, numberOfStrings C.size_t) *C.StringArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfStrings)
+	if err != nil {
+		return nil
+	}
 
-	internalRepresentation := CStringArrayToSlice(cArray, numberOfStrings)
+	internalRepresentation := CStringArrayToSlice(cArray, length)
 
 	result := StringSliceToCArray(internalRepresentation)
 
 	return result
 }
 
+// Used to convert a C-compatible string array with NULL entries to wrapper type (keeping the NULLs)
+//
+// Parameters:
+//   - cArray: Pointer to the C array of strings (**C.char), entries may be NULL.
+//   - numberOfStrings: Number of strings in the C array (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.StringArrayResult containing the converted strings (*C.StringArrayResult), or NULL if numberOfStrings is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_string_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+//
+//export helper_return_nullable_string_array
+func helper_return_nullable_string_array(cArray unsafe.Pointer, numberOfStrings C.size_t) *C.StringArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfStrings)
+	if err != nil {
+		return nil
+	}
+	return NullableStringSliceToCArray(CStringArrayToNullableSlice(cArray, length))
+}
+
 // Used to convert a C-compatible integer array to wrapper type
 //
 // Parameters:
 //   - cArray: Pointer to the C array of integers (*C.int).
-//   - numberOfElements: Number of elements in the C array.
+//   - numberOfElements: Number of elements in the C array (C.size_t).
 //
 // Returns:
-//   - Pointer to a C.IntArrayResult containing the converted integers (*C.IntArrayResult).
-//     Note: The caller is responsible for freeing the allocated memory using free_int_array_result.
-//
-//export return_int_array
-func return_int_array(cArray unsafe.Pointer, numberOfElements C.int) *C.IntArrayResult {
-	internalRepresentation := CIntArrayToSlice(cArray, int(numberOfElements))
-	result := IntSliceToCArray(internalRepresentation)
-	return (*C.IntArrayResult)(result)
+//   - Pointer to a C.IntArrayResult containing the converted integers (*C.IntArrayResult), or NULL if numberOfElements is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_int_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+//
+//export helper_return_int_array
+func helper_return_int_array(cArray unsafe.Pointer, numberOfElements C.size_t) *C.IntArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return nil
+	}
+	internalRepresentation := CIntArrayToSlice(cArray, length)
+	result, err := IntSliceToCArray(internalRepresentation)
+	if err != nil {
+		return nil // Can't happen, every value started out as a C int
+	}
+	return result
 }
 
 // Used to convert a C-compatible float array to wrapper type
 //
 // Parameters:
 //   - cArray: Pointer to the C array of floats(*C.float).
-//   - numberOfElements: Number of elements in the C array.
+//   - numberOfElements: Number of elements in the C array (C.size_t).
 //
 // Returns:
-//   - Pointer to a C.FloatArrayResult containing the converted floats (*C.FloatArrayResult).
-//     Note: The caller is responsible for freeing the allocated memory using free_float_array_result.
-//
-//export return_float_array
-func return_float_array(cArray unsafe.Pointer, numberOfElements C.int) *C.FloatArrayResult {
-	internalRepresentation := CFloatArrayToSlice(cArray, int(numberOfElements))
+//   - Pointer to a C.FloatArrayResult containing the converted floats (*C.FloatArrayResult), or NULL if numberOfElements is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_float_array_result, not free()
+//     (the arrays and struct come from SharedBufferPool, so free() on them is an invalid free).
+//
+//export helper_return_float_array
+func helper_return_float_array(cArray unsafe.Pointer, numberOfElements C.size_t) *C.FloatArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return nil
+	}
+	internalRepresentation := CFloatArrayToSlice(cArray, length)
 	result := FloatSliceToCArray(internalRepresentation)
 	return (*C.FloatArrayResult)(result)
 }
@@ -349,8 +628,9 @@ func return_float_array(cArray unsafe.Pointer, numberOfElements C.int) *C.FloatA
 // Parameters:
 //   - ptr: Pointer to the C string (*C.char).
 //
-//export print_string
-func print_string(ptr unsafe.Pointer) {
+//export helper_print_string
+func helper_print_string(ptr unsafe.Pointer) {
+	ExitIfForked()
 	if ptr != nil {
 		fmt.Printf("print_string() Go representation: %s\n", C.GoString((*C.char)(ptr)))
 	} else {
@@ -362,11 +642,17 @@ func print_string(ptr unsafe.Pointer) {
 //
 // Parameters:
 //   - cArray: Pointer to the C array of strings (**C.char).
-//   - numberOfString: Number of strings in the C array.
-//
-//export print_string_array
-func print_string_array(cArray unsafe.Pointer, numberOfString int) {
-	res := CStringArrayToSlice(cArray, numberOfString)
+//   - numberOfString: Number of strings in the C array (C.size_t).
+//
+//export helper_print_string_array
+func helper_print_string_array(cArray unsafe.Pointer, numberOfString C.size_t) {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfString)
+	if err != nil {
+		fmt.Printf("print_string_array() %v\n", err)
+		return
+	}
+	res := CStringArrayToSlice(cArray, length)
 	fmt.Printf("print_string_array() Go representation: %v\n", res)
 }
 
@@ -374,12 +660,18 @@ func print_string_array(cArray unsafe.Pointer, numberOfString int) {
 //
 // Parameters:
 //   - cArray: Pointer to the C array of integers (*C.int).
-//   - numberOfInts: Number of integers in the C array.
-//
-//export print_int_array
-func print_int_array(cArray unsafe.Pointer, numberOfInts int) {
-	fmt.Printf("Got initial array with %d items, converting", numberOfInts)
-	res := CIntArrayToSlice(cArray, numberOfInts)
+//   - numberOfInts: Number of integers in the C array (C.size_t).
+//
+//export helper_print_int_array
+func helper_print_int_array(cArray unsafe.Pointer, numberOfInts C.size_t) {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfInts)
+	if err != nil {
+		fmt.Printf("print_int_array() %v\n", err)
+		return
+	}
+	fmt.Printf("Got initial array with %d items, converting", length)
+	res := CIntArrayToSlice(cArray, length)
 	fmt.Println("Converted array")
 
 	fmt.Printf("print_int_array() Go representation: %v\n", res)
@@ -389,11 +681,17 @@ func print_int_array(cArray unsafe.Pointer, numberOfInts int) {
 //
 // Parameters:
 //   - cArray: Pointer to the C array of floats (*C.float).
-//   - numberOfFloats: Number of floats in the C array.
-//
-//export print_float_array
-func print_float_array(cArray unsafe.Pointer, numberOfFloats int) {
-	res := CFloatArrayToSlice(cArray, numberOfFloats)
+//   - numberOfFloats: Number of floats in the C array (C.size_t).
+//
+//export helper_print_float_array
+func helper_print_float_array(cArray unsafe.Pointer, numberOfFloats C.size_t) {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfFloats)
+	if err != nil {
+		fmt.Printf("print_float_array() %v\n", err)
+		return
+	}
+	res := CFloatArrayToSlice(cArray, length)
 
 	fmt.Printf("print_float_array() Go representation: %v\n", res)
 }
@@ -404,8 +702,6 @@ func print_float_array(cArray unsafe.Pointer, numberOfFloats int) {
 //
 // Parameters:
 //   - ptr: Pointer to the C string to be freed (*C.char).
-//
-//export FreeCString
 func FreeCString(ptr unsafe.Pointer) {
 	if ptr != nil {
 		C.free(ptr)
@@ -416,39 +712,72 @@ func FreeCString(ptr unsafe.Pointer) {
 //
 // Parameters:
 //   - result: Pointer to the C.StringArrayResult to be freed (**C.char).
-//
-//export FreeStringArray
-func FreeStringArray(inputArray unsafe.Pointer, count C.int) {
-	for i := 0; i < int(count); i++ {
-		// Calculate where to find the string
-		locationOfArray := uintptr(inputArray)          // Starting point of first byte of slice
-		offsetIntoArray := uintptr(i)                   // The offset for the current element
-		memorySizeOfStruct := unsafe.Sizeof(uintptr(0)) // Size of a single struct
-
-		ptr := *(**C.char)(unsafe.Pointer(locationOfArray + offsetIntoArray*memorySizeOfStruct))
+func FreeStringArray(inputArray unsafe.Pointer, count C.size_t) {
+	for _, ptr := range unsafe.Slice((**C.char)(inputArray), count) {
 		C.free(unsafe.Pointer(ptr))
 	}
-	C.free(inputArray)
+	SharedBufferPool.Free(inputArray)
 }
 
-// Free an *C.int.
+// Free an *C.int, returning it to SharedBufferPool if it came from there.
 //
 // Parameters:
 //   - result: Pointer to the *C.int to be freed.
-//
-//export FreeIntArray
 func FreeIntArray(ptr unsafe.Pointer) {
-	C.free(ptr)
+	SharedBufferPool.Free(ptr)
 }
 
-// Free a *C.float.
+// Free a *C.float, returning it to SharedBufferPool if it came from there.
 //
 // Parameters:
 //   - result: Pointer to the C.FloatArrayResult to be freed (*C.float).
-//
-//export FreeFloatArray
 func FreeFloatArray(ptr unsafe.Pointer) {
-	C.free(ptr)
+	SharedBufferPool.Free(ptr)
+}
+
+// C-callable wrapper for FreeCString
+//
+// Parameters:
+//   - ptr: Pointer to the C string to be freed (*C.char).
+//
+//export helper_free_c_string
+func helper_free_c_string(ptr unsafe.Pointer) {
+	ExitIfForked()
+	FreeCString(ptr)
+}
+
+// C-callable wrapper for FreeStringArray
+//
+// Parameters:
+//   - inputArray: Pointer to the array of C strings to be freed (**C.char).
+//   - count: Number of strings in the array (C.size_t).
+//
+//export helper_free_string_array
+func helper_free_string_array(inputArray unsafe.Pointer, count C.size_t) {
+	ExitIfForked()
+	FreeStringArray(inputArray, count)
+}
+
+// C-callable wrapper for FreeIntArray
+//
+// Parameters:
+//   - ptr: Pointer to the *C.int to be freed.
+//
+//export helper_free_int_array
+func helper_free_int_array(ptr unsafe.Pointer) {
+	ExitIfForked()
+	FreeIntArray(ptr)
+}
+
+// C-callable wrapper for FreeFloatArray
+//
+// Parameters:
+//   - ptr: Pointer to the *C.float to be freed.
+//
+//export helper_free_float_array
+func helper_free_float_array(ptr unsafe.Pointer) {
+	ExitIfForked()
+	FreeFloatArray(ptr)
 }
 
 // Free a *C.StringArrayResult.
@@ -456,11 +785,12 @@ func FreeFloatArray(ptr unsafe.Pointer) {
 // Parameters:
 //   - result: Pointer to the C.StringArrayResult to be freed (*C.StringArrayResult).
 //
-//export free_string_array_result
-func free_string_array_result(StringArrayResultReference unsafe.Pointer) {
+//export helper_free_string_array_result
+func helper_free_string_array_result(StringArrayResultReference unsafe.Pointer) {
+	ExitIfForked()
 	temp := (*C.StringArrayResult)(StringArrayResultReference)
 	FreeStringArray(unsafe.Pointer(temp.data), temp.numberOfElements)
-	C.free(unsafe.Pointer(StringArrayResultReference))
+	SharedBufferPool.Free(StringArrayResultReference)
 }
 
 // Free a *C.IntArrayResult.
@@ -468,11 +798,12 @@ func free_string_array_result(StringArrayResultReference unsafe.Pointer) {
 // Parameters:
 //   - result: Pointer to the C.IntArrayResult to be freed (*C.IntArrayResult).
 //
-//export free_int_array_result
-func free_int_array_result(ptr unsafe.Pointer) {
+//export helper_free_int_array_result
+func helper_free_int_array_result(ptr unsafe.Pointer) {
+	ExitIfForked()
 	temp := (*C.IntArrayResult)(ptr)
 	FreeIntArray(unsafe.Pointer(temp.data))
-	C.free(unsafe.Pointer(ptr))
+	SharedBufferPool.Free(ptr)
 }
 
 // Free a *C.FloatArrayResult.
@@ -480,11 +811,18 @@ func free_int_array_result(ptr unsafe.Pointer) {
 // Parameters:
 //   - result: Pointer to the C.FloatArrayResult to be freed (*C.FloatArrayResult).
 //
-//export free_float_array_result
-func free_float_array_result(ptr unsafe.Pointer) {
+//export helper_free_float_array_result
+func helper_free_float_array_result(ptr unsafe.Pointer) {
+	ExitIfForked()
 	temp := (*C.FloatArrayResult)(ptr)
 	FreeFloatArray(unsafe.Pointer(temp.data))
-	C.free(unsafe.Pointer(ptr))
+	SharedBufferPool.Free(ptr)
 }
 
-func main() {}
+// Only runs when the library is built as an executable (not -buildmode=c-shared), see RunWorkerMain()
+func main() {
+	if err := RunWorkerMain(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "worker exited with error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,559 +1,3262 @@
 """A package to help with building Go-python libraries"""
 import os
+import sys
+import json
+import time
+import timeit
+import socket
+import struct
+import shutil
 import subprocess
-from platform import platform
-from ctypes import CDLL, Array, cdll, c_char_p, c_int, POINTER, c_float, Structure, string_at 
+import multiprocessing
+import importlib.util
+from contextlib import contextmanager
+from types import ModuleType
+from typing import Callable, Iterator, NamedTuple
+from datetime import datetime, timedelta, timezone
+from decimal import Decimal
+from fractions import Fraction
+from multiprocessing.pool import Pool
+from platform import platform, machine, libc_ver
+from ctypes import CDLL, CFUNCTYPE, Array, cdll, c_char_p, c_int, c_size_t, c_longlong, c_ulonglong, c_void_p, POINTER, c_float, c_double, c_char, c_ubyte, c_int32, c_int64, c_uint, c_uint32, c_uint64, Structure, byref, cast, sizeof, alignment, addressof, string_at, create_string_buffer 
+
+# ========== Fork Safety ============
+class ForkedProcessError(RuntimeError):
+    """Raised when a Go shared library loaded in a parent process is used after os.fork()"""
+
+# Maps the real path of each loaded library to the pid that loaded it
+_loaded_libraries: dict[str, int] = {}
+
+class GoLibrary:
+    """A fork-aware wrapper around a loaded Go shared library
 
-# ========== Helper Functions  ============
-def get_library(dll_path:str,source_path:str="", compile:bool=False) -> CDLL:
-    """Get's the DLL specified, will compile if not found and flag is specified
+    Notes
+    -----
+    - Attribute access is forwarded to the underlying CDLL, so it can be used exactly like one
+    - Every function lookup checks the current pid against the pid that loaded the library, 
+      forking after the Go runtime has started leaves the child with a broken runtime 
+      (dead goroutines, held locks), so this fails fast instead of hanging
+
+    Examples
+    --------
+    ```
+    lib = get_library("path/to/library.so")
+
+    lib.helper_return_string.argtypes = [c_char_p]
+    lib.helper_return_string.restype = c_char_p
+
+    if os.fork() == 0:
+        lib.helper_return_string(b"Hello") # Raises ForkedProcessError
+    ```
+    """
+    def __init__(self, library: CDLL, path: str):
+        self._library = library
+        self._path = path
+        self._pid = os.getpid()
+
+    @property
+    def forked(self) -> bool:
+        """If the current process is a fork of the process that loaded the library"""
+        return os.getpid() != self._pid
+
+    def check_fork(self):
+        """Raises a ForkedProcessError if the library is being used after a fork
+
+        Raises
+        ------
+        ForkedProcessError
+            If the current process is not the process that loaded the library
+        """
+        if self.forked:
+            raise ForkedProcessError(
+                f"{self._path} was loaded in process {self._pid} and cannot be used from forked process {os.getpid()}, "
+                "the Go runtime does not survive fork(). Use spawn_pool() or get_process_context() to create workers instead"
+            )
+
+    def __getattr__(self, name: str):
+        if name.startswith("_"):
+            raise AttributeError(name)
+        self.check_fork()
+        return getattr(self._library, name)
+
+    def __getitem__(self, name: str):
+        self.check_fork()
+        return self._library[name]
+
+def get_process_context() -> multiprocessing.context.BaseContext:
+    """Get's a multiprocessing context that is safe to use with Go shared libraries
+
+    Notes
+    -----
+    - Uses the "spawn" start method, each worker starts a fresh interpreter and loads its own Go runtime
+
+    Returns
+    -------
+    multiprocessing.context.BaseContext
+        The spawn-based context, use it in place of the multiprocessing module (i.e. ctx.Process(), ctx.Queue())
+    """
+    return multiprocessing.get_context("spawn")
+
+def spawn_pool(processes: int | None = None, initializer=None, initargs: tuple = ()) -> Pool:
+    """Creates a process pool whose workers are safe to call Go shared libraries from
+
+    Parameters
+    ----------
+    processes : int | None, optional
+        The number of worker processes, by default None (os.cpu_count())
+
+    initializer : Callable, optional
+        A function to run in each worker when it starts (i.e. to import your bindings), by default None
+
+    initargs : tuple, optional
+        The arguments to pass to the initializer, by default ()
+
+    Returns
+    -------
+    Pool
+        A spawn-based process pool
+
+    Examples
+    --------
+    ```
+    from scraping import Site
+
+    with spawn_pool(4) as pool:
+        sites = pool.map(Site.from_str, ["https://kieranwood.ca", "https://go.dev"])
+    ```
+    """
+    return get_process_context().Pool(processes, initializer, initargs)
+
+# ========== ABI Versioning ============
+
+# The version of the helper's C ABI these bindings were written for, must match ABIVersion in abi.go
+ABI_VERSION = 2
+
+# Feature bits reported by helper_abi_features(), must match the Feature* constants in abi.go
+FEATURE_FORK_DETECTION = 1 << 0
+FEATURE_WORKER = 1 << 1
+FEATURE_HOST = 1 << 2
+FEATURE_RUNTIME_TUNING = 1 << 3
+FEATURE_METRICS = 1 << 4
+FEATURE_PROFILING = 1 << 5
+FEATURE_FILL_BUFFERS = 1 << 6
+FEATURE_ENCODINGS = 1 << 7
+FEATURE_NULLABLE = 1 << 8
+FEATURE_TIME = 1 << 9
+FEATURE_BIG_NUMBERS = 1 << 10
+FEATURE_VERSIONED_STRUCTS = 1 << 11
+FEATURE_WORKER_POOL = 1 << 12
+FEATURE_BUFFER_POOL = 1 << 13
+FEATURE_SELF_TEST = 1 << 14
+FEATURE_LAYOUT = 1 << 15
+
+# The features these bindings need from the library
+REQUIRED_FEATURES = FEATURE_FORK_DETECTION | FEATURE_HOST | FEATURE_RUNTIME_TUNING | FEATURE_METRICS | FEATURE_PROFILING | FEATURE_FILL_BUFFERS | FEATURE_ENCODINGS | FEATURE_NULLABLE | FEATURE_TIME | FEATURE_BIG_NUMBERS | FEATURE_VERSIONED_STRUCTS | FEATURE_WORKER_POOL | FEATURE_BUFFER_POOL | FEATURE_SELF_TEST | FEATURE_LAYOUT
+
+class ABIMismatchError(RuntimeError):
+    """Raised when a shared library was built from a different version of the helper than the python bindings"""
+
+def check_abi(library: CDLL, dll_path: str, abi_version: int, required_features: int = 0):
+    """Checks that a loaded library matches the ABI version, and has the features the bindings expect
 
     Parameters
     ----------
+    library : CDLL
+        The loaded library
+
     dll_path : str
-        The path to the DLL file, if compile is specified this will be the output path
+        The path the library was loaded from, used in the error message
 
-    source_path:str, optional
-        The path to the source go file, only needed if compile is true, by default ""
+    abi_version : int
+        The ABI version the bindings were written for
 
-    compile : bool, optional
-        Specify if you should try to compile DLL if not in path, by default False
+    required_features : int, optional
+        A bitmap of FEATURE_* flags that must be present, by default 0
 
     Raises
     ------
-    ValueError:
-        If linked library is not available and/or compilable (if compile is specified)
+    ABIMismatchError
+        If the library is stale (wrong version, missing features, or built before versioning existed)
+    """
+    try:
+        library.helper_abi_version.restype = c_int
+        library.helper_abi_features.restype = c_ulonglong
+        version = library.helper_abi_version()
+        features = library.helper_abi_features()
+    except AttributeError:
+        version, features = 0, 0
+    fix = f"delete {dll_path} so it's rebuilt from the current source, then restart python"
+    if version != abi_version:
+        raise ABIMismatchError(f"{dll_path} has ABI version {version}, but the bindings expect version {abi_version}, {fix}")
+    missing = required_features & ~features
+    if missing:
+        raise ABIMismatchError(f"{dll_path} is missing features {missing:#b} (has {features:#b}), {fix}")
+
+# ========== Building ============
+BUILD_PROFILES = ("release", "debug", "race", "cgocheck")
+_HELPER_DIRECTORY = os.path.dirname(os.path.realpath(__file__))
+
+class BuildError(ValueError):
+    """Raised when the Go build driver (cmd/cgohelper) can't build a library, the message has the compiler output"""
+
+def build_library(output_path: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, build_mode: str = "c-shared", env: dict[str, str] | None = None, force: bool = False, modfile: str | None = None, files: list[str] | None = None) -> dict:
+    """Builds a Go package with the build driver (go run ./cmd/cgohelper build), which only rebuilds when the sources changed
+
+    Notes
+    -----
+    - The driver hashes the package's files (and local dependencies, go.mod/go.sum, the Go version and the build settings),
+      and records the hash next to the output in <output_path>.build.json
+    - Needs go installed, and the helper's cmd/cgohelper directory next to this file
+
+    Parameters
+    ----------
+    output_path : str
+        The file to write (i.e. lib.so)
+
+    source_path : str
+        The package directory (or .go file) to build
+
+    profile : str, optional
+        One of BUILD_PROFILES, by default "release" (stripped), "debug" has symbols and no optimizations, "race" adds the race detector
+        and "cgocheck" checks every pointer passed between Go and C (GOEXPERIMENT=cgocheck2)
+
+    tags : list[str] | None, optional
+        Build tags, by default None
+
+    cc : str | None, optional
+        The C compiler to use (i.e. "zig cc"), by default None (go's default, usually gcc)
+
+    build_mode : str, optional
+        The -buildmode, by default "c-shared" ("exe" for workers)
+
+    env : dict[str, str] | None, optional
+        Extra environment variables for the build (i.e. {"GOARCH": "arm64"}), by default None
+
+    force : bool, optional
+        Build even if nothing changed, by default False
+
+    modfile : str | None, optional
+        An alternate go.mod for the build (i.e. a host's host.mod, that requires the modules it includes), by default None
+
+    files : list[str] | None, optional
+        Extra .go files to build into the package (i.e. a host's file that imports it's modules), by default None
 
     Returns
     -------
-    CDLL
-        The linked library
+    dict
+        The driver's result, {"output": str, "hash": str, "rebuilt": bool, "command": list[str]}
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed, the profile is unknown, or the build failed
     """
-    if not os.path.exists(dll_path):
-        if not compile:
-            raise ValueError(f"Linked Library is not available: {dll_path}")
-        if platform().lower().startswith("windows"):
-            additional_flags = "set GOTRACEBACK=system &&"
-        else:
-            additional_flags = "env GOTRACEBACK=system"
-        command = f"{additional_flags} go build -ldflags \"-s -w\" -buildmode=c-shared -o \"{dll_path}\" \"{source_path}\""
-        if compile:
-            print("\nRequired shared library is not available, building...")
-            try:
-                subprocess.run(command, shell=True, check=True)
-            except Exception as e:
-                if isinstance(e, FileNotFoundError):
-                    print("Unable to find Go install, please install it and try again\n")
-                else:
-                    print(f"Ran into error while trying to build shared library, make sure go, and a compatible compiler are installed, then try building manually using:\n\t{command}\nExiting with error:\n\t{e}")
-                raise ValueError(f"Linked Library is not available or compileable: {dll_path}")
-    return cdll.LoadLibrary(dll_path)
+    if profile not in BUILD_PROFILES:
+        raise BuildError(f"Unknown build profile {profile!r}, expected one of {BUILD_PROFILES}")
+    command = ["go", "run", "./cmd/cgohelper", "build", "-json", "-o", os.path.abspath(output_path), "-profile", profile, "-buildmode", build_mode]
+    if tags:
+        command += ["-tags", ",".join(tags)]
+    if cc:
+        command += ["-cc", cc]
+    for key, value in (env or {}).items():
+        command += ["-env", f"{key}={value}"]
+    if force:
+        command.append("-force")
+    if modfile:
+        command += ["-modfile", os.path.abspath(modfile)]
+    for file in files or []:
+        command += ["-file", os.path.abspath(file)]
+    command.append(os.path.abspath(source_path))
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, please install it and try again")
+    if completed.returncode != 0:
+        raise BuildError(completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    return json.loads(completed.stdout)
+
+# The targets build_targets() cross-compiles when none are specified, must match build.DefaultTargets
+BUILD_TARGETS = ("linux/amd64/gnu", "linux/arm64/gnu", "linux/amd64/musl", "linux/arm64/musl")
+
+# platform.machine() names for each GOARCH
+_GO_ARCHES = {
+    "x86_64": "amd64", "amd64": "amd64", "x64": "amd64",
+    "aarch64": "arm64", "arm64": "arm64", "armv8l": "arm64",
+    "i386": "386", "i486": "386", "i586": "386", "i686": "386", "x86": "386",
+    "armv6l": "arm", "armv7l": "arm", "riscv64": "riscv64", "ppc64le": "ppc64le", "s390x": "s390x",
+}
+
+def platform_tag() -> str:
+    """The name of the directory cross-compiled libraries for this platform are in (i.e. "linux-amd64-gnu" or "linux-arm64-musl")
 
-# ========== C Structs ==========
-class _CStringArrayResult(Structure):
-    _fields_ = [
-        ("numberOfElements", c_int),
-        ("data", POINTER(c_char_p)),
-    ]
-    
-class _CIntArrayResult(Structure):
-    _fields_ = [
-        ("numberOfElements", c_int),
-        ("data", POINTER(c_int)),
-    ]
+    Notes
+    -----
+    - Uses Go's names (GOOS-GOARCH), with the libc on linux ("gnu" for glibc, "musl" otherwise)
+    - A 32-bit python on a 64-bit OS gets the 32-bit architecture, since that's what it can load
 
-class _CFloatArrayResult(Structure):
-    _fields_ = [
-        ("numberOfElements", c_int),
-        ("data", POINTER(c_float)),
-    ]
+    Returns
+    -------
+    str
+        The platform tag
+    """
+    goos = {"win32": "windows", "cygwin": "windows"}.get(sys.platform, sys.platform.rstrip("0123456789"))
+    goarch = _GO_ARCHES.get(machine().lower(), machine().lower())
+    if struct.calcsize("P") == 4:
+        goarch = {"amd64": "386", "arm64": "arm"}.get(goarch, goarch)
+    if goos != "linux":
+        return f"{goos}-{goarch}"
+    libc = "gnu" if libc_ver()[0] == "glibc" else "musl"
+    return f"{goos}-{goarch}-{libc}"
+
+def find_platform_library(dll_path: str) -> str | None:
+    """Finds the library cross-compiled for this platform next to dll_path (<dir>/<platform_tag()>/<name>)
 
-# ========== Setup CGo functions ==========
+    Parameters
+    ----------
+    dll_path : str
+        The path of the library (i.e. "go/lib.so"), the same path passed to build_targets()
 
-# import library
-dll_source_file = os.path.join(os.path.dirname(os.path.realpath(__file__)), "lib.go")
-if platform().lower().startswith("windows"):
-    dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.dll")
-    lib = get_library(dll_file, dll_source_file, True)
-else:
-    dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.so")
-    lib = get_library(dll_file, dll_source_file, True)
+    Returns
+    -------
+    str | None
+        The path to the library for this platform, or None if there isn't one
+    """
+    path = os.path.join(os.path.dirname(os.path.abspath(dll_path)), platform_tag(), os.path.basename(dll_path))
+    return path if os.path.exists(path) else None
 
-lib.print_string_array.argtypes =  [POINTER(c_char_p), c_int]
-lib.FreeStringArray.argtypes = [POINTER(c_char_p), c_int]
+def is_bundled_library(dll_path: str) -> bool:
+    """Checks if a library was bundled in a wheel by the build backend (backend.py), which marks it with <dll_path>.bundled.json
 
-lib.print_int_array.argtypes =  [POINTER(c_int), c_int]
-lib.FreeIntArray.argtypes = [POINTER(c_int)]
+    Notes
+    -----
+    - get_library() never builds a bundled library (even with compile=True), and prefers it over a cross-compiled one
 
-lib.print_float_array.argtypes =  [POINTER(c_float), c_int]
-lib.FreeFloatArray.argtypes =  [POINTER(c_float)]
+    Parameters
+    ----------
+    dll_path : str
+        The path of the library
 
-lib.return_string.argtypes = [c_char_p]
-lib.return_string.restype = c_char_p
+    Returns
+    -------
+    bool
+        True if the library exists and was bundled
+    """
+    return os.path.exists(dll_path) and os.path.exists(dll_path + ".bundled.json")
 
-lib.FreeCString.argtypes = [c_char_p]
+def build_targets(output_path: str, source_path: str, targets: list[str] | None = None, profile: str = "release", tags: list[str] | None = None, force: bool = False) -> list[dict]:
+    """Cross-compiles a library for several platforms with zig cc (go run ./cmd/cgohelper build -targets), into a directory per platform
 
-lib.print_string.argtypes = [c_char_p]
+    Notes
+    -----
+    - Needs go and zig (https://ziglang.org) installed, zig is the C compiler for every target
+    - Each target is written to <dir of output_path>/<platform>/<name of output_path> (i.e. go/linux-arm64-musl/lib.so),
+      which get_library(output_path) picks from at runtime (see platform_tag())
+    - Every target is attempted, even if some fail
 
-## ========== Array-based functions ==========
+    Parameters
+    ----------
+    output_path : str
+        The library path to build around (i.e. go/lib.so)
 
-lib.FreeStringArray.argtypes = [POINTER(c_char_p), c_int]
-lib.free_string_array_result.argtypes = [POINTER(_CStringArrayResult)]
-lib.return_string_array.argtypes = [POINTER(c_char_p), c_int] 
-lib.return_string_array.restype = POINTER(_CStringArrayResult)
+    source_path : str
+        The package directory (or .go file) to build
 
-lib.return_int_array.argtypes = [POINTER(c_int), c_int]
-lib.return_int_array.restype = POINTER(_CIntArrayResult)
-lib.free_int_array_result.argtypes = [POINTER(_CIntArrayResult)]
+    targets : list[str] | None, optional
+        Targets as GOOS/GOARCH/libc (i.e. "linux/arm64/musl", "linux/amd64/gnu.2.17" to support glibc 2.17 and newer),
+        by default None (BUILD_TARGETS)
 
-lib.return_float_array.argtypes = [POINTER(c_float), c_int]
-lib.return_float_array.restype = POINTER(_CFloatArrayResult)
-lib.free_float_array_result.argtypes = [POINTER(_CFloatArrayResult)]
+    profile : str, optional
+        One of BUILD_PROFILES, by default "release"
 
-# ========== Nice Typehints/Type Aliases ==========
-CIntArray = Array[c_int]
-CFloatArray = Array[c_float]
-CStringArray = Array[c_char_p]
+    tags : list[str] | None, optional
+        Build tags, by default None
+
+    force : bool, optional
+        Build even if nothing changed, by default False
+
+    Returns
+    -------
+    list[dict]
+        The result of each target, {"target": {"goos", "goarch", "libc"}, "output": str, "hash": str, "rebuilt": bool, "command": list[str]}
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed, a target or the profile is invalid, or any target failed to build (the message has each target's error)
+    """
+    if profile not in BUILD_PROFILES:
+        raise BuildError(f"Unknown build profile {profile!r}, expected one of {BUILD_PROFILES}")
+    command = ["go", "run", "./cmd/cgohelper", "build", "-json", "-o", os.path.abspath(output_path), "-profile", profile, "-targets", ",".join(targets or BUILD_TARGETS)]
+    if tags:
+        command += ["-tags", ",".join(tags)]
+    if force:
+        command.append("-force")
+    command.append(os.path.abspath(source_path))
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, please install it and try again")
+    if completed.returncode != 0:
+        results = json.loads(completed.stdout) if completed.stdout.strip() else []
+        failures = "\n".join(f"{r['target']['goos']}/{r['target']['goarch']}/{r['target']['libc']}: {r['error']}" for r in results if r.get("error"))
+        raise BuildError(failures or completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    return json.loads(completed.stdout)
+
+def new_project(name: str, directory: str, structs: list[str] | None = None, force: bool = False) -> str:
+    """Creates a new Go library with python bindings from the project templates (go run ./cmd/cgohelper new)
 
-# ========== Python types to C ============
-def prepare_string(data: str | bytes) -> c_char_p:
-    """Takes in a string and returns a C-compatible string
-    
     Notes
     -----
-    - Does not prune null terminators (\\0 characters)
+    - Creates <directory>/<name>/ (the python package, with the Go package in go/ and a copy of this helper in helpers/)
+      and <directory>/test_<name>.py, a round-trip test of each struct (go/lib_test.go does the same in Go)
+    - Importing the package builds go/lib.so with get_library()
 
     Parameters
     ----------
-    data : str | bytes
-        The string to prepare
+    name : str
+        The python package name (i.e. "geometry")
+
+    directory : str
+        The directory to create the project in
+
+    structs : list[str] | None, optional
+        Structs as "Name:field=type,..." (types are int, int64, float, string and bool), by default None (an example Item struct)
+
+    force : bool, optional
+        Overwrite the files of an existing project, by default False
 
     Returns
     -------
-    c_char_p
-        The resulting pointer to the string
+    str
+        The path to the new package
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed, a name or struct is invalid, or the project already exists
     """
-    if not data:
-        return c_char_p(bytes())
-    if type(data) == str:
-        return c_char_p(data.encode())
-    return c_char_p(bytes(data))
+    command = ["go", "run", "./cmd/cgohelper", "new", "-o", os.path.abspath(directory)]
+    for struct in structs or []:
+        command += ["-struct", struct]
+    if force:
+        command.append("-force")
+    command.append(name)
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, please install it and try again")
+    if completed.returncode != 0:
+        raise BuildError(completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    return os.path.join(os.path.abspath(directory), name)
 
-def prepare_string_array(data:list[str|bytes]) -> tuple[CStringArray, int]:
-    """Takes in a string list, and converts it to a C-compatible array
+def doctor(python: str | None = None, cc: str | None = None, source_path: str = "") -> dict:
+    """Checks the toolchain needed to build libraries (go run ./cmd/cgohelper doctor), and how to fix what's broken
+
+    Notes
+    -----
+    - Checks go (and it's version against go.mod), that cgo is on, that the C compiler (or zig cc) works, that python's
+      architecture/pointer size matches go's target, and that a -buildmode=c-shared library builds and loads in python
+    - Each check is {"name": str, "status": "ok" | "warning" | "error" | "skipped", "detail": str, "fix": str (warnings and errors)}
 
     Parameters
     ----------
-    data : list[str | bytes]
-        The list to convert
+    python : str | None, optional
+        The python that will load the library, by default None (this one, sys.executable)
+
+    cc : str | None, optional
+        The C compiler to check (i.e. "zig cc"), by default None (go's default)
+
+    source_path : str, optional
+        The directory of the go.mod to check the Go version against, by default "" (the helper's)
 
     Returns
     -------
-    Array[c_char_p], int
-        The resulting array, and the number of items
-        
+    dict
+        {"ok": bool (no errors), "checks": list[dict]}
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed (so the doctor can't run), the message says how to install it
+    """
+    command = ["go", "run", "./cmd/cgohelper", "doctor", "-json", "-python", python or sys.executable]
+    if cc:
+        command += ["-cc", cc]
+    command.append(os.path.abspath(source_path) if source_path else _HELPER_DIRECTORY)
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, install it from https://go.dev/dl/ and make sure it's on the PATH")
+    # Exits with 1 when a check failed, the report is still printed
+    if not completed.stdout.strip():
+        raise BuildError(completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    return json.loads(completed.stdout)
+
+def _print_doctor_report(cc: str | None = None, source_path: str = ""):
+    """Prints the failed checks of doctor(), and how to fix them"""
+    try:
+        report = doctor(cc=cc, source_path=os.path.dirname(source_path) if source_path.endswith(".go") else source_path)
+    except BuildError as e:
+        print(f"\t{e}")
+        return
+    for check in report["checks"]:
+        if check["status"] in ("warning", "error"):
+            print(f"\t{check['status']}: {check['name']}: {check['detail']}\n\t\tfix: {check.get('fix', '')}")
+
+def build_extension(module: str, output_directory: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, python: str | None = None, force: bool = False) -> dict:
+    """Builds a Go package into a CPython extension module (go run ./cmd/cgohelper extension), instead of a library for ctypes
+
     Notes
     -----
-    - Because the data is allocated in python, python will free the memory afterwords
-    - Does not prune null terminators (\\0 characters)
+    - The package is built with -buildmode=c-archive, and C glue generated from it's header is linked with it, so each
+      exported function converts it's arguments and results straight to python objects (str, int, float, list, dict)
+    - Pointer arrays take one argument (the length is passed for you), structs are dicts, and results are freed by the module
+    - Functions with types that can't be converted (i.e. void*) are skipped, the reason is in the result
+    - Needs Python.h (the python3-dev package on linux) and a C compiler, the glue is only recompiled when something changed
 
-    Examples
-    --------
-    ```
-    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
+    Parameters
+    ----------
+    module : str
+        The name to import the module as, a C identifier (i.e. "scraping_ext")
 
-    # Function that takes in string array, and number of items, then prints them in C
-    lib.print_string_array.argtypes =  [POINTER(c_char_p), c_int]
+    output_directory : str
+        The directory to write the module (and the archive, header and glue) to
 
-    # Prep data using function
-    data = ["Hello", "World", "!"]
-    c_array, number_of_items = prepare_string_array(data)
+    source_path : str
+        The Go package directory to build
 
-    # Use data in C
-    lib.print_string_array(c_array, number_of_items)
-    ```
+    profile : str, optional
+        One of BUILD_PROFILES, by default "release"
+
+    tags : list[str] | None, optional
+        Build tags, by default None
+
+    cc : str | None, optional
+        The C compiler (i.e. "zig cc"), by default None (go's default)
+
+    python : str | None, optional
+        The python the module is for, by default None (this one, sys.executable)
+
+    force : bool, optional
+        Build even if nothing changed, by default False
+
+    Returns
+    -------
+    dict
+        {"output": str (the module), "archive": str, "glue": str (the C source), "wrapped": list[str], "skipped": [{"name", "reason"}], "rebuilt": bool}
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed, the module name or profile is invalid, or the package or glue doesn't compile
     """
-    # Encode items to bytes
-    data = [
-            c_char_p(item.encode())
-        if type(item) == str
-        else
-            c_char_p(bytes(item))
-        for item in data
-    ] 
-    number_of_items = len(data)
-    array_type = c_char_p * number_of_items # Create a C array of char* (aka **char)
-    c_array = array_type(*data)
-    return c_array, number_of_items
+    if profile not in BUILD_PROFILES:
+        raise BuildError(f"Unknown build profile {profile!r}, expected one of {BUILD_PROFILES}")
+    command = ["go", "run", "./cmd/cgohelper", "extension", "-json", "-module", module, "-o", os.path.abspath(output_directory), "-profile", profile, "-python", python or sys.executable]
+    if tags:
+        command += ["-tags", ",".join(tags)]
+    if cc:
+        command += ["-cc", cc]
+    if force:
+        command.append("-force")
+    command.append(os.path.abspath(source_path))
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, please install it and try again")
+    if completed.returncode != 0:
+        raise BuildError(completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    return json.loads(completed.stdout)
 
-def prepare_int_array(data:list[int]) -> tuple[CIntArray, int]:
-    """Takes in an int list, and converts it to a C-compatible array
+def load_extension(path: str) -> ModuleType:
+    """Imports an extension module built by build_extension() from it's path
 
     Parameters
     ----------
-    data : list[int]
-        The list of integers to convert to an array
+    path : str
+        The module's file (the "output" of build_extension())
 
     Returns
     -------
-    Array[c_int], int
-        The resulting array, and the number of items
-    
-    Notes
-    -----
-    - Because the data is allocated in python, python will free the memory afterwords
-        
-    Examples
-    --------
-    ```
-    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
+    ModuleType
+        The module, also added to sys.modules
 
-    # Function that takes in int array, and number of items, then prints them in C
-    lib.print_int_array.argtypes =  [POINTER(c_int), c_int]
+    Raises
+    ------
+    ImportError
+        If the module can't be loaded (i.e. it was built for another python)
+    """
+    name = os.path.basename(path).split(".")[0]
+    spec = importlib.util.spec_from_file_location(name, path)
+    if spec is None or spec.loader is None:
+        raise ImportError(f"{path} isn't an extension module", path=path)
+    module = importlib.util.module_from_spec(spec)
+    spec.loader.exec_module(module)
+    sys.modules[name] = module
+    return module
+
+def compare_latency(functions: dict[str, Callable[[], object]], number: int = 10000, repeat: int = 5) -> dict[str, float]:
+    """Times calls of each function (i.e. the same Go function through ctypes and an extension module)
 
-    # Prep data using function
-    data = [1,2,3,4]
-    c_array, number_of_items = prepare_int_array(data)
+    Parameters
+    ----------
+    functions : dict[str, Callable[[], object]]
+        The name and a no argument call of each function to time (i.e. {"ctypes": lambda: lib.sum(...), "extension": lambda: ext.sum(...)})
 
-    # Use data in C
-    lib.print_int_array(c_array, number_of_items)
-    ```
+    number : int, optional
+        The number of calls per timing, by default 10000
+
+    repeat : int, optional
+        The number of timings, the best is kept, by default 5
+
+    Returns
+    -------
+    dict[str, float]
+        The seconds per call of each function
     """
-    data = [c_int(item) for item in data] # Force an error if wrong type
-    number_of_items = len(data)
-    array_type = c_int * number_of_items # Create a C array of int*
-    c_array = array_type(*data)
-    return c_array, number_of_items
+    return {name: min(timeit.repeat(function, number=number, repeat=repeat)) / number for name, function in functions.items()}
 
-def prepare_float_array(data:list[float]) -> tuple[CFloatArray, int]:
-    """Takes in an float list, and converts it to a C-compatible array
+def generate_cffi(module: str, output_directory: str, source_path: str, profile: str = "release", tags: list[str] | None = None, cc: str | None = None, force: bool = False, compile: bool = False, python: str | None = None) -> dict:
+    """Builds a Go package into an archive and generates a cffi (API mode) binding of it (go run ./cmd/cgohelper cffi)
+
+    Notes
+    -----
+    - Writes <module>.cdef (the declarations for ffi.cdef()), <module>_build.py (compiles the _<module> extension with
+      the archive linked in) and <module>.py (a function per exported function, which converts to python objects)
+    - Results are freed the same way as this module does, with the exported free_<struct>()/helper_free_*() functions
+    - Compiling needs cffi (and setuptools on python 3.12+) installed in the python that runs the build script
 
     Parameters
     ----------
-    data : list[float]
-        The list of integers to convert to an array
+    module : str
+        The name to import the binding as, a C identifier (i.e. "scraping_cffi")
+
+    output_directory : str
+        The directory to write the binding (and the archive and header) to
+
+    source_path : str
+        The Go package directory to build
+
+    profile : str, optional
+        One of BUILD_PROFILES, by default "release"
+
+    tags : list[str] | None, optional
+        Build tags, by default None
+
+    cc : str | None, optional
+        The C compiler (i.e. "zig cc"), by default None (go's default)
+
+    force : bool, optional
+        Build even if nothing changed, by default False
+
+    compile : bool, optional
+        Run the build script when the binding changed (or hasn't been compiled), by default False
+
+    python : str | None, optional
+        The python to compile with, by default None (this one, sys.executable)
 
     Returns
     -------
-    Array[c_float], int
-        The resulting array, and the number of items
-    
+    dict
+        {"wrapper": str, "build_script": str, "cdef": str, "archive": str, "wrapped": list[str], "skipped": [{"name", "reason"}],
+        "rebuilt": bool, "compiled": bool}
+
+    Raises
+    ------
+    BuildError
+        If go isn't installed, the module name or profile is invalid, the package doesn't build, or compiling failed
+    """
+    if profile not in BUILD_PROFILES:
+        raise BuildError(f"Unknown build profile {profile!r}, expected one of {BUILD_PROFILES}")
+    command = ["go", "run", "./cmd/cgohelper", "cffi", "-json", "-module", module, "-o", os.path.abspath(output_directory), "-profile", profile]
+    if tags:
+        command += ["-tags", ",".join(tags)]
+    if cc:
+        command += ["-cc", cc]
+    if force:
+        command.append("-force")
+    command.append(os.path.abspath(source_path))
+    try:
+        completed = subprocess.run(command, cwd=_HELPER_DIRECTORY, capture_output=True, text=True)
+    except FileNotFoundError:
+        raise BuildError("Unable to find Go install, please install it and try again")
+    if completed.returncode != 0:
+        raise BuildError(completed.stderr.strip() or f"{' '.join(command)} exited with {completed.returncode}")
+    result = json.loads(completed.stdout)
+    result["compiled"] = False
+
+    directory = os.path.dirname(result["build_script"])
+    compiled = any(name.startswith(f"_{module}.") and name.endswith((".so", ".pyd")) for name in os.listdir(directory))
+    if compile and (result["rebuilt"] or force or not compiled):
+        completed = subprocess.run([python or sys.executable, result["build_script"]], cwd=directory, capture_output=True, text=True)
+        if completed.returncode != 0:
+            raise BuildError(f"Compiling {result['build_script']} failed (is cffi installed?)\n{completed.stdout.strip()}\n{completed.stderr.strip()}")
+        result["compiled"] = True
+    return result
+
+# ========== Helper Functions  ============
+def get_library(dll_path:str,source_path:str="", compile:bool=False, abi_version:int|None=None, required_features:int=0, profile:str="release", tags:list[str]|None=None, cc:str|None=None, modfile:str|None=None, files:list[str]|None=None) -> GoLibrary:
+    """Get's the DLL specified, if compile is specified it's built (or rebuilt when the sources changed) with the build driver first
+
+    Parameters
+    ----------
+    dll_path : str
+        The path to the DLL file, if compile is specified this will be the output path. If a library cross-compiled for this
+        platform exists (<dir>/<platform_tag()>/<name>, see build_targets()) it's used instead, unless compile is specified
+        (then it's only used if the library is missing and can't be built). Libraries bundled in a wheel (see is_bundled_library())
+        are always used as they are
+
+    source_path:str, optional
+        The path to the source go file (or package directory), only needed if compile is true, by default ""
+
+    compile : bool, optional
+        Specify if you should build the DLL when it's missing or it's sources changed (see build_library()), by default False.
+        If the DLL exists and Go isn't installed it's used without checking the sources
+
+    abi_version : int | None, optional
+        If specified the library must report this version from helper_abi_version(), by default None (not checked)
+
+    required_features : int, optional
+        A bitmap of FEATURE_* flags the library must report from helper_abi_features(), by default 0
+
+    profile : str, optional
+        The build profile if compile is specified, one of BUILD_PROFILES, by default "release"
+
+    tags : list[str] | None, optional
+        Build tags if compile is specified, by default None
+
+    cc : str | None, optional
+        The C compiler if compile is specified (i.e. "zig cc"), by default None (go's default)
+
+    modfile : str | None, optional
+        An alternate go.mod if compile is specified (see build_library()), by default None
+
+    files : list[str] | None, optional
+        Extra .go files to build into the package if compile is specified (see build_library()), by default None
+
+    Raises
+    ------
+    ValueError:
+        If linked library is not available and/or compilable (if compile is specified)
+
+    ABIMismatchError:
+        If abi_version is specified and the library doesn't match (i.e. a stale lib.so)
+
+    ForkedProcessError:
+        If the library was already loaded by a parent process before this process was forked
+
+    Returns
+    -------
+    GoLibrary
+        The linked library, wrapped to detect use after fork
+    """
+    # A library bundled in a wheel (backend.py) is used as is, the machine it's installed on may not have Go
+    if is_bundled_library(dll_path):
+        compile = False
+    # A library cross-compiled for this platform (build_targets()), used when this one can't be built
+    platform_path = None if is_bundled_library(dll_path) else find_platform_library(dll_path)
+    if platform_path is not None and os.path.realpath(platform_path) in _loaded_libraries:
+        dll_path = platform_path # Already loaded instead of dll_path earlier in this process
+    loaded_by = _loaded_libraries.get(os.path.realpath(dll_path))
+    if loaded_by is not None and loaded_by != os.getpid():
+        raise ForkedProcessError(f"{dll_path} was loaded in process {loaded_by} before fork(), use spawn_pool() or get_process_context() to create workers instead")
+
+    # A library that's already loaded in this process can't be replaced, so only build before the first load. Without Go an
+    # existing library can't be checked, so it's used as is (i.e. sources shipped next to a library, on a machine without Go)
+    if compile and loaded_by is None and not (os.path.exists(dll_path) and shutil.which("go") is None):
+        exists = os.path.exists(dll_path)
+        if not exists:
+            print("\nRequired shared library is not available, building...")
+        try:
+            result = build_library(dll_path, source_path, profile, tags, cc, modfile=modfile, files=files)
+            if result["rebuilt"] and exists:
+                print(f"\nSources of {dll_path} changed, rebuilt it ({profile})")
+        except BuildError as e:
+            if not exists and platform_path is not None:
+                print(f"Unable to build {dll_path}, using {platform_path} instead:\n\t{e}")
+                dll_path = platform_path
+            elif not exists:
+                print(f"Ran into error while trying to build shared library, make sure go, and a compatible compiler are installed\nExiting with error:\n\t{e}")
+                print("Checking the toolchain (go run ./cmd/cgohelper doctor):")
+                _print_doctor_report(cc, source_path)
+                raise ValueError(f"Linked Library is not available or compileable: {dll_path}") from e
+            else:
+                print(f"Unable to check if {dll_path} is up to date, using the existing library:\n\t{e}")
+    elif not compile and platform_path is not None:
+        dll_path = platform_path
+    if not os.path.exists(dll_path):
+        raise ValueError(f"Linked Library is not available: {dll_path}")
+    library = cdll.LoadLibrary(dll_path)
+
+    # Libraries that include the helper record the pid the Go runtime started in
+    try:
+        library.helper_get_init_pid.restype = c_longlong
+        init_pid = library.helper_get_init_pid()
+    except AttributeError:
+        init_pid = os.getpid()
+    if init_pid != os.getpid():
+        raise ForkedProcessError(f"{dll_path} started its Go runtime in process {init_pid}, and cannot be used from forked process {os.getpid()}")
+
+    if abi_version is not None:
+        check_abi(library, dll_path, abi_version, required_features)
+
+    _loaded_libraries[os.path.realpath(dll_path)] = os.getpid()
+    return GoLibrary(library, dll_path)
+
+# ========== C Structs ==========
+class _CStringArrayResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("data", POINTER(c_char_p)),
+    ]
+    
+class _CIntArrayResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("data", POINTER(c_int)),
+    ]
+
+class _CFloatArrayResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("data", POINTER(c_float)),
+    ]
+
+class _CKeyValueResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("keys", POINTER(c_char_p)),
+        ("values", POINTER(c_double)),
+    ]
+
+class _CNullableIntArrayResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("data", POINTER(c_int)),
+        ("validity", POINTER(c_ubyte)),
+    ]
+
+class _CNullableFloatArrayResult(Structure):
+    _fields_ = [
+        ("numberOfElements", c_size_t),
+        ("data", POINTER(c_float)),
+        ("validity", POINTER(c_ubyte)),
+    ]
+
+class _CDecodeResult(Structure):
+    _fields_ = [
+        ("data", c_void_p), # Not c_char_p, the text can contain null bytes
+        ("length", c_size_t),
+        ("numberOfInvalid", c_size_t),
+        ("invalidOffsets", POINTER(c_size_t)),
+        ("error", c_char_p),
+    ]
+
+class _CTimestamp(Structure):
+    _fields_ = [
+        ("unixNanos", c_int64),
+        ("offsetSeconds", c_int32),
+    ]
+
+class _CBigIntResult(Structure):
+    _fields_ = [
+        ("length", c_size_t),
+        ("data", c_void_p), # Not c_char_p, the bytes can contain nulls
+        ("error", c_char_p),
+    ]
+
+class _CDecimalResult(Structure):
+    _fields_ = [
+        ("data", c_char_p),
+        ("error", c_char_p),
+    ]
+
+class StructHeader(Structure):
+    _fields_ = [
+        ("size", c_uint32),    # sizeof() the struct on the way in, the number of bytes Go wrote on the way out
+        ("version", c_uint32), # The version of the struct Go was built with
+    ]
+
+class StructVersionError(RuntimeError):
+    """Raised when the header of a versioned struct doesn't make sense for the bindings (i.e. it's smaller than the header)"""
+
+class VersionedStructure(Structure):
+    """Base class for C structs that start with a StructHeader, so a library and its bindings can be upgraded independently
+
+    Notes
+    -----
+    - Subclasses put ("header", StructHeader) first in _fields_, only ever append fields, and set _version_
+    - Use new() to allocate one with header.size set, Go only writes the fields that fit in it
+    - After Go fills it, fields past header.size weren't written (the library is older than the bindings), use has_field()/get()
+
+    Examples
+    --------
+    ```
+    class _CLibraryInfo(VersionedStructure):
+        _version_ = 2
+        _fields_ = [("header", StructHeader), ("abiVersion", c_int32), ("features", c_uint64), ("gomaxprocs", c_int32), ("numCPU", c_int32)]
+
+    info = _CLibraryInfo.new()
+    lib.helper_library_info(byref(info))
+    info.check()
+    print(info.get("gomaxprocs")) # None if the library only has version 1 of the struct
+    ```
+    """
+    _version_ = 1
+
+    @classmethod
+    def new(cls):
+        """Allocates a zeroed struct with header.size and header.version set to this version of the bindings"""
+        result = cls()
+        result.header.size = sizeof(cls)
+        result.header.version = cls._version_
+        return result
+
+    def check(self):
+        """Checks the header after Go filled the struct
+
+        Raises
+        ------
+        StructVersionError
+            If header.size is smaller than the header, or bigger than the struct (Go wrote past the end)
+        """
+        if not sizeof(StructHeader) <= self.header.size <= sizeof(self):
+            raise StructVersionError(f"{type(self).__name__} header.size is {self.header.size}, expected between {sizeof(StructHeader)} and {sizeof(self)}")
+        return self
+
+    def has_field(self, name: str) -> bool:
+        """Checks if Go wrote a field (it fits in header.size)"""
+        field = getattr(type(self), name)
+        return field.offset + field.size <= self.header.size
+
+    def get(self, name: str, default=None):
+        """Gets a field, or default if Go didn't write it"""
+        return getattr(self, name) if self.has_field(name) else default
+
+class _CLibraryInfo(VersionedStructure):
+    _version_ = 2
+    _fields_ = [
+        ("header", StructHeader),
+        # Version 1
+        ("abiVersion", c_int32),
+        ("features", c_uint64),
+        # Version 2
+        ("gomaxprocs", c_int32),
+        ("numCPU", c_int32),
+    ]
+
+# ========== Setup CGo functions ==========
+
+# import library
+dll_source_file = os.path.dirname(os.path.realpath(__file__))
+if platform().lower().startswith("windows"):
+    dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.dll")
+    lib = get_library(dll_file, dll_source_file, True, ABI_VERSION, REQUIRED_FEATURES)
+else:
+    dll_file = os.path.join(os.path.dirname(os.path.realpath(__file__)),"lib.so")
+    lib = get_library(dll_file, dll_source_file, True, ABI_VERSION, REQUIRED_FEATURES)
+
+lib.helper_print_string_array.argtypes =  [POINTER(c_char_p), c_size_t]
+lib.helper_free_string_array.argtypes = [POINTER(c_char_p), c_size_t]
+
+lib.helper_print_int_array.argtypes =  [POINTER(c_int), c_size_t]
+lib.helper_free_int_array.argtypes = [POINTER(c_int)]
+
+lib.helper_print_float_array.argtypes =  [POINTER(c_float), c_size_t]
+lib.helper_free_float_array.argtypes =  [POINTER(c_float)]
+
+lib.helper_return_string.argtypes = [c_char_p]
+lib.helper_return_string.restype = c_char_p
+
+lib.helper_free_c_string.argtypes = [c_char_p]
+
+lib.helper_print_string.argtypes = [c_char_p]
+
+def _free_c_string_function(library: GoLibrary | CDLL):
+    """Creates a separate function object for a library's helper_free_c_string() that takes a c_void_p, for pointers from
+    functions whose restype is c_void_p. Setting library.helper_free_c_string.argtypes instead would change it for every
+    other caller of the library (i.e. lib.helper_free_c_string(), which takes c_char_p)"""
+    if isinstance(library, GoLibrary):
+        library.check_fork()
+        library = library._library
+    return CFUNCTYPE(None, c_void_p)(("helper_free_c_string", library))
+
+lib.helper_get_init_pid.restype = c_longlong
+lib.helper_is_forked_process.restype = c_int
+
+## ========== Array-based functions ==========
+
+lib.helper_free_string_array.argtypes = [POINTER(c_char_p), c_size_t]
+lib.helper_free_string_array_result.argtypes = [POINTER(_CStringArrayResult)]
+lib.helper_return_string_array.argtypes = [POINTER(c_char_p), c_size_t] 
+lib.helper_return_string_array.restype = POINTER(_CStringArrayResult)
+
+lib.helper_return_int_array.argtypes = [POINTER(c_int), c_size_t]
+lib.helper_return_int_array.restype = POINTER(_CIntArrayResult)
+lib.helper_free_int_array_result.argtypes = [POINTER(_CIntArrayResult)]
+
+lib.helper_return_float_array.argtypes = [POINTER(c_float), c_size_t]
+lib.helper_return_float_array.restype = POINTER(_CFloatArrayResult)
+lib.helper_free_float_array_result.argtypes = [POINTER(_CFloatArrayResult)]
+
+## ========== Caller-allocated Buffer functions ==========
+
+lib.helper_fill_int_array.argtypes = [POINTER(c_int), c_size_t, POINTER(c_int), c_size_t]
+lib.helper_fill_int_array.restype = c_size_t
+
+lib.helper_fill_float_array.argtypes = [POINTER(c_float), c_size_t, POINTER(c_float), c_size_t]
+lib.helper_fill_float_array.restype = c_size_t
+
+lib.helper_fill_byte_array.argtypes = [c_char_p, c_size_t, POINTER(c_char), c_size_t]
+lib.helper_fill_byte_array.restype = c_size_t
+
+lib.helper_fill_string.argtypes = [c_char_p, POINTER(c_char), c_size_t]
+lib.helper_fill_string.restype = c_size_t
+
+## ========== Nullable Array functions ==========
+
+lib.helper_return_nullable_int_array.argtypes = [POINTER(c_int), POINTER(c_ubyte), c_size_t]
+lib.helper_return_nullable_int_array.restype = POINTER(_CNullableIntArrayResult)
+lib.helper_free_nullable_int_array_result.argtypes = [POINTER(_CNullableIntArrayResult)]
+
+lib.helper_return_nullable_float_array.argtypes = [POINTER(c_float), POINTER(c_ubyte), c_size_t]
+lib.helper_return_nullable_float_array.restype = POINTER(_CNullableFloatArrayResult)
+lib.helper_free_nullable_float_array_result.argtypes = [POINTER(_CNullableFloatArrayResult)]
+
+lib.helper_return_nullable_string_array.argtypes = [POINTER(c_char_p), c_size_t]
+lib.helper_return_nullable_string_array.restype = POINTER(_CStringArrayResult)
+
+## ========== Text Encoding functions ==========
+
+lib.helper_decode_utf8.argtypes = [c_char_p, c_size_t, c_int]
+lib.helper_decode_utf8.restype = POINTER(_CDecodeResult)
+
+lib.helper_decode_utf16.argtypes = [c_char_p, c_size_t, c_int, c_int]
+lib.helper_decode_utf16.restype = POINTER(_CDecodeResult)
+
+lib.helper_decode_latin1.argtypes = [c_char_p, c_size_t]
+lib.helper_decode_latin1.restype = POINTER(_CDecodeResult)
+
+lib.helper_free_decode_result.argtypes = [POINTER(_CDecodeResult)]
+
+## ========== Time Conversion functions ==========
+
+lib.helper_return_timestamp.argtypes = [_CTimestamp]
+lib.helper_return_timestamp.restype = _CTimestamp
+
+lib.helper_return_duration.argtypes = [c_int64]
+lib.helper_return_duration.restype = c_int64
+
+lib.helper_now.argtypes = []
+lib.helper_now.restype = _CTimestamp
+
+## ========== Arbitrary-precision Number functions ==========
+
+lib.helper_return_big_int.argtypes = [c_char_p, c_size_t]
+lib.helper_return_big_int.restype = POINTER(_CBigIntResult)
+
+lib.helper_big_int_from_string.argtypes = [c_char_p]
+lib.helper_big_int_from_string.restype = POINTER(_CBigIntResult)
+
+lib.helper_big_int_to_string.argtypes = [c_char_p, c_size_t]
+lib.helper_big_int_to_string.restype = POINTER(_CDecimalResult)
+
+lib.helper_return_big_float.argtypes = [c_char_p, c_uint]
+lib.helper_return_big_float.restype = POINTER(_CDecimalResult)
+
+lib.helper_return_big_rat.argtypes = [c_char_p]
+lib.helper_return_big_rat.restype = POINTER(_CDecimalResult)
+
+lib.helper_free_big_int_result.argtypes = [POINTER(_CBigIntResult)]
+lib.helper_free_decimal_result.argtypes = [POINTER(_CDecimalResult)]
+
+## ========== Versioned Struct functions ==========
+
+lib.helper_library_info.argtypes = [c_void_p]
+lib.helper_library_info.restype = c_void_p
+
+# ========== Nice Typehints/Type Aliases ==========
+CIntArray = Array[c_int]
+CFloatArray = Array[c_float]
+CStringArray = Array[c_char_p]
+CByteBuffer = Array[c_char]
+CValidityBitmap = Array[c_ubyte]
+
+# ========== Python types to C ============
+C_INT_MAX = 2 ** (8 * sizeof(c_int) - 1) - 1
+C_INT_MIN = -C_INT_MAX - 1
+
+def prepare_string(data: str | bytes) -> c_char_p:
+    """Takes in a string and returns a C-compatible string
+    
+    Notes
+    -----
+    - Does not prune null terminators (\\0 characters)
+
+    Parameters
+    ----------
+    data : str | bytes
+        The string to prepare
+
+    Returns
+    -------
+    c_char_p
+        The resulting pointer to the string
+    """
+    if not data:
+        return c_char_p(bytes())
+    if type(data) == str:
+        return c_char_p(data.encode())
+    return c_char_p(bytes(data))
+
+def prepare_string_array(data:list[str|bytes]) -> tuple[CStringArray, int]:
+    """Takes in a string list, and converts it to a C-compatible array
+
+    Parameters
+    ----------
+    data : list[str | bytes]
+        The list to convert
+
+    Returns
+    -------
+    Array[c_char_p], int
+        The resulting array, and the number of items
+        
+    Notes
+    -----
+    - Because the data is allocated in python, python will free the memory afterwords
+    - Does not prune null terminators (\\0 characters)
+    - None entries become NULL pointers, so Go can tell a missing value from "" (see CStringArrayToNullableSlice())
+
+    Examples
+    --------
+    ```
+    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
+
+    # Function that takes in string array, and number of items, then prints them in C
+    lib.helper_print_string_array.argtypes =  [POINTER(c_char_p), c_size_t]
+
+    # Prep data using function
+    data = ["Hello", "World", "!"]
+    c_array, number_of_items = prepare_string_array(data)
+
+    # Use data in C
+    lib.helper_print_string_array(c_array, number_of_items)
+    ```
+    """
+    # Encode items to bytes (None becomes a NULL pointer)
+    data = [
+            c_char_p(item.encode())
+        if type(item) == str
+        else
+            c_char_p(None)
+        if item is None
+        else
+            c_char_p(bytes(item))
+        for item in data
+    ] 
+    number_of_items = len(data)
+    array_type = c_char_p * number_of_items # Create a C array of char* (aka **char)
+    c_array = array_type(*data)
+    return c_array, number_of_items
+
+def check_c_int(value: int) -> int:
+    """Checks that a python int fits in a C int (ctypes silently wraps values that don't, i.e. c_int(2**32) is 0)
+
+    Parameters
+    ----------
+    value : int
+        The value to check
+
+    Returns
+    -------
+    int
+        The value, unchanged
+
+    Raises
+    ------
+    OverflowError
+        If the value is outside of the range of a C int
+    """
+    if not C_INT_MIN <= value <= C_INT_MAX:
+        raise OverflowError(f"{value} doesn't fit in a C int ({C_INT_MIN} to {C_INT_MAX})")
+    return value
+
+def prepare_int_array(data:list[int]) -> tuple[CIntArray, int]:
+    """Takes in an int list, and converts it to a C-compatible array
+
+    Parameters
+    ----------
+    data : list[int]
+        The list of integers to convert to an array
+
+    Returns
+    -------
+    Array[c_int], int
+        The resulting array, and the number of items
+    
+    Notes
+    -----
+    - Because the data is allocated in python, python will free the memory afterwords
+        
+    Examples
+    --------
+    ```
+    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
+
+    # Function that takes in int array, and number of items, then prints them in C
+    lib.helper_print_int_array.argtypes =  [POINTER(c_int), c_size_t]
+
+    # Prep data using function
+    data = [1,2,3,4]
+    c_array, number_of_items = prepare_int_array(data)
+
+    # Use data in C
+    lib.helper_print_int_array(c_array, number_of_items)
+    ```
+    """
+    data = [c_int(check_c_int(item)) for item in data] # Force an error if wrong type, or too big to fit
+    number_of_items = len(data)
+    array_type = c_int * number_of_items # Create a C array of int*
+    c_array = array_type(*data)
+    return c_array, number_of_items
+
+def prepare_float_array(data:list[float]) -> tuple[CFloatArray, int]:
+    """Takes in an float list, and converts it to a C-compatible array
+
+    Parameters
+    ----------
+    data : list[float]
+        The list of integers to convert to an array
+
+    Returns
+    -------
+    Array[c_float], int
+        The resulting array, and the number of items
+    
+    Notes
+    -----
+    - Because the data is allocated in python, python will free the memory afterwords
+    - The data is only accurate up to ~4 decimals (i.e. if value is -790.5207366698761 you might get -790.520751953125)
+        
+    Examples
+    --------
+    ```
+    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
+
+    # Function that takes in float array, and number of items, then prints them in C
+    lib.helper_print_float_array.argtypes =  [POINTER(c_float), c_size_t]
+
+    # Prep data using function
+    data = [1.0,2.604,3.14159,4.964]
+    c_array, number_of_items = prepare_float_array(data)
+
+    # Use data in C
+    lib.helper_print_float_array(c_array, number_of_items)
+    ```
+    """
+    data = [c_float(item) for item in data]  # Force an error if wrong type
+    number_of_items = len(data)
+    array_type = c_float * number_of_items # Create a C array of float*
+    c_array = array_type(*data)
+    return c_array, number_of_items
+
+def prepare_validity_bitmap(data: list) -> CValidityBitmap:
+    """Builds a validity bitmap (Apache Arrow layout) with a bit set for each item that isn't None
+
+    Parameters
+    ----------
+    data : list
+        The items, None is a missing value
+
+    Returns
+    -------
+    Array[c_ubyte]
+        The bitmap, item i has a value if bit (i % 8) of bitmap[i // 8] is set
+    """
+    bitmap = (c_ubyte * ((len(data) + 7) // 8))()
+    for i, item in enumerate(data):
+        if item is not None:
+            bitmap[i // 8] |= 1 << (i % 8)
+    return bitmap
+
+def is_valid(validity, i: int) -> bool:
+    """Checks if item i has a value in a validity bitmap (a NULL/None bitmap means every item has a value)"""
+    return not validity or bool(validity[i // 8] & (1 << (i % 8)))
+
+def prepare_nullable_int_array(data: list[int | None]) -> tuple[CIntArray, CValidityBitmap, int]:
+    """Takes in a list of ints and Nones, and converts it to a C-compatible array with a validity bitmap
+
+    Parameters
+    ----------
+    data : list[int | None]
+        The list to convert, None is a missing value (as opposed to 0)
+
+    Returns
+    -------
+    Array[c_int], Array[c_ubyte], int
+        The resulting array (missing values are 0), the validity bitmap, and the number of items
+
+    Examples
+    --------
+    ```
+    c_array, validity, number_of_items = prepare_nullable_int_array([1, None, 0])
+    print(return_nullable_int_array(c_array, validity, number_of_items)) # [1, None, 0]
+    ```
+    """
+    c_array, number_of_items = prepare_int_array([0 if item is None else item for item in data])
+    return c_array, prepare_validity_bitmap(data), number_of_items
+
+def prepare_nullable_float_array(data: list[float | None]) -> tuple[CFloatArray, CValidityBitmap, int]:
+    """Takes in a list of floats and Nones, and converts it to a C-compatible array with a validity bitmap, see prepare_nullable_int_array()"""
+    c_array, number_of_items = prepare_float_array([0.0 if item is None else item for item in data])
+    return c_array, prepare_validity_bitmap(data), number_of_items
+
+# ========== Caller-allocated Buffers ============
+class BufferTooSmallError(ValueError):
+    """Raised when a buffer passed to a fill function can't hold the result, required is the capacity needed"""
+    def __init__(self, required: int, capacity: int):
+        super().__init__(f"buffer holds {capacity} elements, but {required} are needed")
+        self.required = required
+        self.capacity = capacity
+
+def prepare_int_buffer(capacity: int) -> tuple[CIntArray, int]:
+    """Allocates a zeroed C int array for Go to fill in place, reuse it across calls to avoid allocations
+
+    Parameters
+    ----------
+    capacity : int
+        The number of elements the buffer can hold
+
+    Returns
+    -------
+    Array[c_int], int
+        The buffer, and its capacity
+
+    Notes
+    -----
+    - Because the data is allocated in python, python will free the memory afterwords
+    """
+    return (c_int * capacity)(), capacity
+
+def prepare_float_buffer(capacity: int) -> tuple[CFloatArray, int]:
+    """Allocates a zeroed C float array for Go to fill in place, see prepare_int_buffer()"""
+    return (c_float * capacity)(), capacity
+
+def prepare_byte_buffer(capacity: int) -> tuple[CByteBuffer, int]:
+    """Allocates a zeroed C char buffer for Go to fill in place with bytes or a null terminated string, see prepare_int_buffer()"""
+    return create_string_buffer(capacity), capacity
+
+def check_fill(result: int, capacity: int) -> int:
+    """Checks the return value of a fill function (the number of elements written, or the required capacity)
+
+    Parameters
+    ----------
+    result : int
+        The value the fill function returned
+
+    capacity : int
+        The capacity of the buffer that was passed to it
+
+    Returns
+    -------
+    int
+        The number of elements written
+
+    Raises
+    ------
+    BufferTooSmallError
+        If the buffer was too small, nothing was written and error.required is the capacity needed
+
+    Examples
+    --------
+    ```
+    buffer, capacity = prepare_int_buffer(1024)
+    for batch in batches:
+        c_array, number_of_items = prepare_int_array(batch)
+        written = check_fill(lib.helper_fill_int_array(c_array, number_of_items, buffer, capacity), capacity)
+        print(buffer[:written])
+    ```
+    """
+    if result > capacity:
+        raise BufferTooSmallError(result, capacity)
+    return result
+
+# ========== Convert C types to python ============
+def string_to_str(pointer: c_char_p) -> str:
+    """Takes in a pointer to a C string and returns a Python string
+
+    Parameters
+    ----------
+    pointer : c_char_p
+        A C-style string pointer returned from Go
+
+    Notes
+    -----
+    - Assumes the pointer is a valid null-terminated UTF-8 encoded string
+    - Does NOT free the pointer automatically, you must call `lib.helper_free_c_string(pointer)` if needed
+
+    Returns
+    -------
+    str
+        The decoded Python string representation of the C string
+        
+    Examples
+    --------
+    ```
+    c_str = prepare_string(b"Hello from Python!")
+    result: str = string_to_str(c_str)
+    lib.helper_free_c_string(c_str)
+    ```
+    """
+    if pointer:
+        return pointer.value.decode("utf-8", errors="replace")
+    return ""
+
+def string_array_result_to_list(pointer:_CStringArrayResult) -> list[str]:
+    """Takes in a pointer to a string result and returns a list of strings
+
+    Parameters
+    ----------
+    pointer : _CStringArrayResult
+        A pointer to a CString Result
+
+    Notes
+    -----
+    - free's the original pointer
+
+    Returns
+    -------
+    list[str]
+        The list of strings the pointer pointed to (NULL entries become None)
+        
+    Examples
+    --------
+    ```
+    data = [
+        random.choice(["Lorem", "ipsum", "dolor", "sit", "amet"]) 
+        for _ in range(100)
+    ]
+    
+    c_array, number_of_elements = prepare_string_array(data)
+    
+    pointer = return_string_array(c_array, number_of_elements)
+    
+    result:list[str] = string_array_result_to_list(pointer)
+    ```
+    """
+    try:
+        result_data = pointer.contents
+        results = []
+        for i in range(result_data.numberOfElements):
+            item = result_data.data[i]
+            results.append(None if item is None else item.decode(errors='replace')) # NULL entries are missing values
+        return results
+    finally:
+        lib.helper_free_string_array_result(pointer)
+
+def int_array_result_to_list(pointer: _CIntArrayResult) -> list[int]:
+    """Converts C int result struct to a Python list, and frees memory."""
+    try:
+        result_data = pointer.contents
+        return [result_data.data[i] for i in range(result_data.numberOfElements)]
+    finally:
+        lib.helper_free_int_array_result(pointer)
+
+def float_array_result_to_list(pointer: _CFloatArrayResult) -> list[float]:
+    """Converts C float result struct to a Python list, and frees memory."""
+    try:
+        result_data = pointer.contents
+        return [result_data.data[i] for i in range(result_data.numberOfElements)]
+    finally:
+        lib.helper_free_float_array_result(pointer)
+
+def nullable_int_array_result_to_list(pointer: _CNullableIntArrayResult) -> list[int | None]:
+    """Converts C nullable int result struct to a Python list (missing values are None), and frees memory."""
+    try:
+        result_data = pointer.contents
+        return [
+            result_data.data[i] if is_valid(result_data.validity, i) else None
+            for i in range(result_data.numberOfElements)
+        ]
+    finally:
+        lib.helper_free_nullable_int_array_result(pointer)
+
+def nullable_float_array_result_to_list(pointer: _CNullableFloatArrayResult) -> list[float | None]:
+    """Converts C nullable float result struct to a Python list (missing values are None), and frees memory."""
+    try:
+        result_data = pointer.contents
+        return [
+            result_data.data[i] if is_valid(result_data.validity, i) else None
+            for i in range(result_data.numberOfElements)
+        ]
+    finally:
+        lib.helper_free_nullable_float_array_result(pointer)
+
+# ========== Text Encodings ============
+
+# Encoding policies, must match the Encoding* constants in encoding.go
+ENCODING_STRICT = 0
+ENCODING_REPLACE = 1
+ENCODING_SURROGATEESCAPE = 2
+
+# The python errors= name of each policy
+_ENCODING_POLICIES = {"strict": ENCODING_STRICT, "replace": ENCODING_REPLACE, "surrogateescape": ENCODING_SURROGATEESCAPE}
+
+class InvalidEncodingError(ValueError):
+    """Raised by the strict policy when the input isn't valid, invalid_offsets has where the invalid sequences are"""
+    def __init__(self, message: str, invalid_offsets: list[int]):
+        super().__init__(message)
+        self.invalid_offsets = invalid_offsets
+
+class DecodedText(NamedTuple):
+    """Text decoded by Go, and where the invalid sequences were in the input (empty if there were none)"""
+    text: str
+    invalid_offsets: list[int]
+
+def _encoding_policy(errors: str) -> int:
+    """The ENCODING_* policy for an errors= argument, raising a ValueError for an unknown one (like bytes.decode())"""
+    try:
+        return _ENCODING_POLICIES[errors]
+    except KeyError:
+        raise ValueError(f"Unknown errors policy {errors!r}, expected one of {tuple(_ENCODING_POLICIES)}") from None
+
+def _decode_result_to_text(pointer: _CDecodeResult, python_errors: str) -> DecodedText:
+    """Converts a DecodeResult to a DecodedText (raising InvalidEncodingError if it has an error), and frees memory."""
+    try:
+        result_data = pointer.contents
+        invalid_offsets = [result_data.invalidOffsets[i] for i in range(result_data.numberOfInvalid)]
+        if result_data.error:
+            raise InvalidEncodingError(result_data.error.decode(errors="replace"), invalid_offsets)
+        text = string_at(result_data.data, result_data.length).decode("utf-8", errors=python_errors)
+        return DecodedText(text, invalid_offsets)
+    finally:
+        lib.helper_free_decode_result(pointer)
+
+def decode_utf8(data: bytes, errors: str = "strict") -> DecodedText:
+    """Decodes UTF-8 bytes (i.e. a scraped page) in Go, and reports where any invalid sequences are
+
+    Parameters
+    ----------
+    data : bytes
+        The bytes to decode
+
+    errors : str, optional
+        The encoding policy, by default "strict"
+        - "strict": Raise an InvalidEncodingError
+        - "replace": Replace each invalid byte with U+FFFD
+        - "surrogateescape": Keep the invalid bytes, as lone surrogates (the same as bytes.decode(errors="surrogateescape"),
+          so text.encode(errors="surrogateescape") gives back the original bytes)
+
+    Returns
+    -------
+    DecodedText
+        The text, and the byte offset of each invalid sequence
+
+    Raises
+    ------
+    ValueError
+        If errors isn't one of the policies above
+
+    InvalidEncodingError
+        If errors is "strict" and the data isn't valid UTF-8
+
+    Examples
+    --------
+    ```
+    decoded = decode_utf8(b"caf\xe9", "replace")
+    print(decoded.text) # caf�
+    print(decoded.invalid_offsets) # [3]
+    ```
+    """
+    pointer = lib.helper_decode_utf8(data, len(data), _encoding_policy(errors))
+    return _decode_result_to_text(pointer, "surrogateescape" if errors == "surrogateescape" else "strict")
+
+def decode_utf16(data: bytes, errors: str = "strict", big_endian: bool = False) -> DecodedText:
+    """Decodes UTF-16 bytes (without a byte order mark) in Go, and reports where any unpaired surrogates are
+
+    Parameters
+    ----------
+    data : bytes
+        The bytes to decode, must be an even length
+
+    errors : str, optional
+        The encoding policy, by default "strict" (see decode_utf8()), with "surrogateescape" unpaired surrogates are kept as lone surrogates
+
+    big_endian : bool, optional
+        If the code units are big endian, by default False (little endian, i.e. Windows wchar_t)
+
+    Returns
+    -------
+    DecodedText
+        The text, and the code unit index (byte offset // 2) of each unpaired surrogate
+
+    Raises
+    ------
+    ValueError
+        If data is an odd number of bytes, or errors isn't a known policy
+
+    InvalidEncodingError
+        If errors is "strict" and the data has an unpaired surrogate
+    """
+    if len(data) % 2:
+        raise ValueError(f"UTF-16 data must be an even number of bytes, got {len(data)}")
+    pointer = lib.helper_decode_utf16(data, len(data) // 2, int(big_endian), _encoding_policy(errors))
+    # Go passes unpaired surrogates through as 3 byte sequences, which is what surrogatepass expects
+    return _decode_result_to_text(pointer, "surrogatepass" if errors == "surrogateescape" else "strict")
+
+def decode_latin1(data: bytes) -> str:
+    """Decodes Latin-1 (ISO-8859-1) bytes in Go, every byte is a valid character so this can't fail"""
+    return _decode_result_to_text(lib.helper_decode_latin1(data, len(data)), "strict").text
+
+# ========== Time Conversions ============
+
+_EPOCH = datetime(1970, 1, 1, tzinfo=timezone.utc)
+_INT64_MIN, _INT64_MAX = -(2 ** 63), 2 ** 63 - 1
+
+def _timedelta_to_nanoseconds(value: timedelta) -> int:
+    return (value.days * 86400 + value.seconds) * 10**9 + value.microseconds * 1000
+
+def prepare_timestamp(value: datetime) -> _CTimestamp:
+    """Converts a datetime to a C-compatible Timestamp (Unix nanoseconds plus the offset of its timezone)
+
+    Parameters
+    ----------
+    value : datetime
+        The datetime to convert, naive datetimes are treated as local time (like datetime.timestamp())
+
+    Returns
+    -------
+    _CTimestamp
+        The timestamp, pass it to a Go function that takes a Timestamp
+
+    Raises
+    ------
+    OverflowError
+        If the datetime is outside the range of Unix nanoseconds (roughly the years 1678 to 2262)
+    """
+    if value.tzinfo is None or value.utcoffset() is None:
+        value = value.astimezone()
+    nanoseconds = _timedelta_to_nanoseconds(value - _EPOCH)
+    if not _INT64_MIN <= nanoseconds <= _INT64_MAX:
+        raise OverflowError(f"{value.isoformat()} doesn't fit in a Timestamp (Unix nanoseconds in an int64)")
+    return _CTimestamp(nanoseconds, int(value.utcoffset().total_seconds()))
+
+def timestamp_to_datetime(timestamp: _CTimestamp) -> datetime:
+    """Converts a C Timestamp returned from Go to a timezone-aware datetime
+
+    Notes
+    -----
+    - datetime only has microsecond precision, so the nanoseconds are rounded down
+    - The timezone is a fixed offset (timezone.utc if the offset is 0), Go doesn't send timezone names
+
+    Parameters
+    ----------
+    timestamp : _CTimestamp
+        The timestamp Go returned
+
+    Returns
+    -------
+    datetime
+        The timezone-aware datetime, in the timezone the Go time was in
+    """
+    offset = timestamp.offsetSeconds
+    tz = timezone.utc if offset == 0 else timezone(timedelta(seconds=offset))
+    return (_EPOCH + timedelta(microseconds=timestamp.unixNanos // 1000)).astimezone(tz)
+
+def prepare_duration(value: timedelta) -> int:
+    """Converts a timedelta to a C-compatible Duration (nanoseconds, the same as Go's time.Duration)
+
+    Raises
+    ------
+    OverflowError
+        If the timedelta is longer than a time.Duration can hold (roughly 292 years)
+    """
+    nanoseconds = _timedelta_to_nanoseconds(value)
+    if not _INT64_MIN <= nanoseconds <= _INT64_MAX:
+        raise OverflowError(f"{value} doesn't fit in a Duration (nanoseconds in an int64)")
+    return nanoseconds
+
+def duration_to_timedelta(nanoseconds: int) -> timedelta:
+    """Converts a C Duration (nanoseconds) returned from Go to a timedelta, rounded down to microseconds"""
+    return timedelta(microseconds=nanoseconds // 1000)
+
+# ========== Arbitrary-precision Numbers ============
+
+def prepare_big_int(value: int) -> tuple[bytes, int]:
+    """Converts a python int of any size to big-endian two's-complement bytes, for Go's TwosComplementToBigInt()
+
+    Parameters
+    ----------
+    value : int
+        The integer to convert
+
+    Returns
+    -------
+    bytes, int
+        The shortest two's-complement bytes, and the number of bytes
+
+    Examples
+    --------
+    ```
+    data, length = prepare_big_int(2 ** 100)
+    print(return_big_int(2 ** 100)) # 1267650600228229401496703205376
+    ```
+    """
+    # Negative numbers need one less bit, i.e. -128 fits in one byte but 128 doesn't
+    length = (value + (value < 0)).bit_length() // 8 + 1
+    return value.to_bytes(length, "big", signed=True), length
+
+def _check_number_result(error: bytes | None):
+    if error is not None:
+        raise ValueError(error.decode(errors="replace"))
+
+def big_int_result_to_int(pointer: _CBigIntResult) -> int:
+    """Converts a C BigIntResult to a python int, and frees memory
+
+    Raises
+    ------
+    ValueError
+        If Go couldn't produce the integer (i.e. a string wasn't a decimal integer)
+    """
+    try:
+        result = pointer.contents
+        _check_number_result(result.error)
+        return int.from_bytes(string_at(result.data, result.length), "big", signed=True)
+    finally:
+        lib.helper_free_big_int_result(pointer)
+
+def _decimal_result_to_str(pointer: _CDecimalResult) -> str:
+    try:
+        result = pointer.contents
+        _check_number_result(result.error)
+        return result.data.decode()
+    finally:
+        lib.helper_free_decimal_result(pointer)
+
+def decimal_result_to_int(pointer: _CDecimalResult) -> int:
+    """Converts a C DecimalResult holding a decimal integer (i.e. from big.Int.String()) to a python int, and frees memory"""
+    return int(_decimal_result_to_str(pointer))
+
+def decimal_result_to_decimal(pointer: _CDecimalResult) -> Decimal:
+    """Converts a C DecimalResult (i.e. from BigFloatToDecimalString()) to a decimal.Decimal, and frees memory
+
+    Raises
+    ------
+    ValueError
+        If Go couldn't produce the number
+    """
+    return Decimal(_decimal_result_to_str(pointer))
+
+def decimal_result_to_fraction(pointer: _CDecimalResult) -> Fraction:
+    """Converts a C DecimalResult holding a fraction (i.e. from big.Rat.RatString()) to a fractions.Fraction, and frees memory"""
+    return Fraction(_decimal_result_to_str(pointer))
+
+# ========== Versioned Structs ============
+
+def fill_versioned_struct(function, struct: VersionedStructure) -> VersionedStructure:
+    """Calls a Go function that fills a caller-allocated versioned struct, and checks the header
+
+    Parameters
+    ----------
+    function : Callable
+        The Go function, takes a pointer to the struct and returns NULL or a C string error (restype must be c_void_p, i.e. lib.helper_library_info)
+
+    struct : VersionedStructure
+        The struct to fill, from new()
+
+    Returns
+    -------
+    VersionedStructure
+        The struct, fields Go didn't write are missing (see has_field()/get())
+
+    Raises
+    ------
+    StructVersionError
+        If Go rejected the struct, or the header doesn't make sense after Go filled it
+    """
+    error_pointer = function(byref(struct))
+    if error_pointer:
+        try:
+            message = string_at(error_pointer).decode(errors="replace")
+        finally:
+            lib.helper_free_c_string(cast(error_pointer, c_char_p))
+        raise StructVersionError(message)
+    return struct.check()
+
+class LibraryInfo(NamedTuple):
+    """Information about the loaded library, fields the library is too old to have are None"""
+    struct_version: int
+    abi_version: int
+    features: int
+    gomaxprocs: int | None
+    num_cpu: int | None
+
+def library_info() -> LibraryInfo:
+    """Gets information about the loaded library through a versioned struct"""
+    info = fill_versioned_struct(lib.helper_library_info, _CLibraryInfo.new())
+    return LibraryInfo(info.header.version, info.abiVersion, info.features, info.get("gomaxprocs"), info.get("numCPU"))
+
+# ========== Debugging Functions ==========
+
+def return_string(text: str | bytes) -> str:
+    """Debugging function that shows you the Go representation of a C string and returns the python string version
+
+    Parameters
+    ----------
+    text : str | bytes
+        The text to get the representation of
+
+    Returns
+    -------
+    str
+        The returned string
+    """
+    c_input = prepare_string(text)
+    result = lib.helper_return_string(c_input)
+
+    if not result:
+        return ""
+
+    copied_bytes = string_at(result)
+    decoded = copied_bytes.decode(errors="replace")
+
+    return decoded
+
+def return_string_array(c_array:CStringArray, number_of_elements:int) ->list[str]:
+    """Debugging function that shows you the Go representation of a C array and returns the python list version
+
+    Parameters
+    ----------
+    c_array : Array[c_char_p]
+        The array to print and convert
+    number_of_elements : int
+        The number of elements in the array
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY
+    - This function returns the PYTHON list version, do not reassign input variable or it'll never free (i.e. c_array = return_string_array(c_array, number_of_elements))
+
+    Returns
+    -------
+    list[str]
+        The python string representation of the array
+        
+    Examples
+    --------
+    ```
+    data = [
+        random.choice(["Lorem", "ipsum", "dolor", "sit", "amet"]) 
+        for _ in range(100)
+    ]
+    
+    c_array, number_of_elements = prepare_string_array(data)
+    
+    result:list[str] = return_string_array(c_array, number_of_elements)
+    ```
+    """
+    return string_array_result_to_list(lib.helper_return_string_array(c_array, number_of_elements))
+
+def return_int_array(c_array: CIntArray, number_of_elements: int) -> list[int]:
+    """Debugging function that shows you the Go representation of a C int array and returns a Python list
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY
+    - Frees input array ONLY on exception
+    - Returns the PYTHON list version, do not reassign input variable
+
+    Returns
+    -------
+    list[int]
+    """
+    pointer = lib.helper_return_int_array(c_array, number_of_elements)
+    try:
+        result_data = pointer.contents
+        return [result_data.data[i] for i in range(result_data.numberOfElements)]
+    except Exception as e:
+        print(f"return_int_array(): Ran into error, freeing memory. Error: {e}")
+        lib.helper_free_int_array_result(c_array)  # In case you define a similar freeing function for input
+        raise e
+    finally:
+        lib.helper_free_int_array_result(pointer)
+
+def return_float_array(c_array: CFloatArray, number_of_elements: int) -> list[float]:
+    """Debugging function that shows you the Go representation of a C float array and returns a Python list
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY ON SUCCESS
+    - Frees input array ONLY on exception
+    - Returns the PYTHON list version, do not reassign input variable
+    - The data is only accurate up to ~4 decimals (i.e. if value is -790.5207366698761 you might get -790.520751953125)
+
+    Returns
+    -------
+    list[float]
+    """
+    pointer = lib.helper_return_float_array(c_array, number_of_elements)
+    try:
+        result_data = pointer.contents
+        return [result_data.data[i] for i in range(result_data.numberOfElements)]
+    except Exception as e:
+        print(f"return_float_array(): Ran into error, freeing memory. Error: {e}")
+        lib.helper_free_float_array_result(c_array)  # In case you define a similar freeing function for input
+        raise e
+    finally:
+        lib.helper_free_float_array_result(pointer)
+
+def print_string(text: str | bytes):
+    """Prints a string's go representation, useful to look for encoding issues
+
+    Parameters
+    ----------
+    text : str | bytes
+        The data you want to see the go representation of
+    """
+    c_input = prepare_string(text)
+    lib.helper_print_string(c_input)
+
+def print_string_array(data:list[str|bytes]):
+    """Prints a string array's go representation, useful to look for encoding issues
+
+    Notes
+    -----
+    - Does not free because everything is allocated in python, so GC will take care of it
+
+    Parameters
+    ----------
+    data : list[str | bytes]
+        The data you want to see the go representation of
+    """
+    c_array, number_of_items = prepare_string_array(data)
+
+    lib.helper_print_string_array(c_array, number_of_items)
+
+def print_int_array(data:list[int]):
+    """Prints a int array's go representation, useful to look for rounding/conversion issues
+
+    Notes
+    -----
+    - Does not free because everything is allocated in python, so GC will take care of it
+
+    Parameters
+    ----------
+    data : list[int]
+        The data you want to see the go representation of
+    """
+    c_array, number_of_items = prepare_int_array(data)
+
+    lib.helper_print_int_array(c_array, number_of_items)
+
+def print_float_array(data:list[float]):
+    """Prints a float array's go representation, useful to look for rounding/conversion issues
+
+    Notes
+    -----
+    - Does not free because everything is allocated in python, so GC will take care of it
+
+    Parameters
+    ----------
+    data : list[float]
+        The data you want to see the go representation of
+    """
+    c_array, number_of_items = prepare_float_array(data)
+    lib.helper_print_float_array(c_array, number_of_items)
+
+def return_nullable_int_array(c_array: CIntArray, validity: CValidityBitmap, number_of_elements: int) -> list[int | None]:
+    """Debugging function that round trips a nullable C int array through Go and returns a Python list (missing values are None)
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY
+
+    Returns
+    -------
+    list[int | None]
+    """
+    return nullable_int_array_result_to_list(lib.helper_return_nullable_int_array(c_array, validity, number_of_elements))
+
+def return_nullable_float_array(c_array: CFloatArray, validity: CValidityBitmap, number_of_elements: int) -> list[float | None]:
+    """Debugging function that round trips a nullable C float array through Go and returns a Python list (missing values are None)
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY
+
+    Returns
+    -------
+    list[float | None]
+    """
+    return nullable_float_array_result_to_list(lib.helper_return_nullable_float_array(c_array, validity, number_of_elements))
+
+def return_nullable_string_array(c_array: CStringArray, number_of_elements: int) -> list[str | None]:
+    """Debugging function that round trips a C string array with NULL entries through Go, and returns a Python list (NULLs are None)
+
+    Notes
+    -----
+    - DOES NOT FREE INPUT ARRAY
+    - return_string_array() would turn the NULL entries into "", because Go's CStringArrayToSlice() does
+
+    Returns
+    -------
+    list[str | None]
+    """
+    return string_array_result_to_list(lib.helper_return_nullable_string_array(c_array, number_of_elements))
+
+def return_timestamp(value: datetime) -> datetime:
+    """Debugging function that round trips a datetime through Go's time.Time, good for debugging timezone issues
+
+    Returns
+    -------
+    datetime
+        The timezone-aware datetime Go returned (naive inputs come back in the local timezone)
+    """
+    return timestamp_to_datetime(lib.helper_return_timestamp(prepare_timestamp(value)))
+
+def return_duration(value: timedelta) -> timedelta:
+    """Debugging function that round trips a timedelta through Go's time.Duration"""
+    return duration_to_timedelta(lib.helper_return_duration(prepare_duration(value)))
+
+def go_now() -> datetime:
+    """Returns the current time according to Go, in the local timezone of the process"""
+    return timestamp_to_datetime(lib.helper_now())
+
+def return_big_int(value: int) -> int:
+    """Debugging function that round trips a python int through Go's big.Int as two's-complement bytes"""
+    data, length = prepare_big_int(value)
+    return big_int_result_to_int(lib.helper_return_big_int(data, length))
+
+def return_big_int_string(value: int | str) -> int:
+    """Debugging function that has Go parse a decimal integer string to a big.Int, and return it as bytes
+
+    Raises
+    ------
+    ValueError
+        If the string isn't a decimal integer
+    """
+    return big_int_result_to_int(lib.helper_big_int_from_string(str(value).encode()))
+
+def format_big_int(value: int) -> str:
+    """Debugging function that has Go format a python int (sent as two's-complement bytes) as a decimal string"""
+    data, length = prepare_big_int(value)
+    return _decimal_result_to_str(lib.helper_big_int_to_string(data, length))
+
+def return_big_float(value: Decimal | str, precision: int = 0) -> Decimal:
+    """Debugging function that round trips a Decimal through Go's big.Float, good for debugging precision issues
+
+    Parameters
+    ----------
+    value : Decimal | str
+        The number to convert ("NaN" isn't supported)
+
+    precision : int, optional
+        The precision of the big.Float in bits, by default 0 (enough to keep every digit)
+
+    Returns
+    -------
+    Decimal
+        The number as the big.Float saw it
+
+    Raises
+    ------
+    ValueError
+        If value isn't a number
+    """
+    return decimal_result_to_decimal(lib.helper_return_big_float(str(value).encode(), precision))
+
+def return_big_rat(value: Fraction | Decimal | str) -> Fraction:
+    """Debugging function that round trips a Fraction (or a finite Decimal) through Go's big.Rat"""
+    return decimal_result_to_fraction(lib.helper_return_big_rat(str(value).encode()))
+
+def fill_int_array(c_array: CIntArray, number_of_elements: int, buffer: CIntArray, capacity: int) -> list[int]:
+    """Debugging function that has Go copy a C int array into a caller-allocated buffer, and returns the filled part as a Python list
+
+    Raises
+    ------
+    BufferTooSmallError
+        If the buffer can't hold number_of_elements, error.required is the capacity needed
+
+    Returns
+    -------
+    list[int]
+    """
+    written = check_fill(lib.helper_fill_int_array(c_array, number_of_elements, buffer, capacity), capacity)
+    return buffer[:written]
+
+def fill_float_array(c_array: CFloatArray, number_of_elements: int, buffer: CFloatArray, capacity: int) -> list[float]:
+    """Debugging function that has Go copy a C float array into a caller-allocated buffer, and returns the filled part as a Python list
+
+    Raises
+    ------
+    BufferTooSmallError
+        If the buffer can't hold number_of_elements, error.required is the capacity needed
+
+    Returns
+    -------
+    list[float]
+    """
+    written = check_fill(lib.helper_fill_float_array(c_array, number_of_elements, buffer, capacity), capacity)
+    return buffer[:written]
+
+def fill_bytes(data: bytes, buffer: CByteBuffer, capacity: int) -> bytes:
+    """Debugging function that has Go copy bytes (null bytes included) into a caller-allocated buffer, and returns the filled part
+
+    Raises
+    ------
+    BufferTooSmallError
+        If the buffer can't hold the data, error.required is the capacity needed
+
+    Returns
+    -------
+    bytes
+    """
+    written = check_fill(lib.helper_fill_byte_array(data, len(data), buffer, capacity), capacity)
+    return buffer.raw[:written]
+
+def fill_string(text: str | bytes, buffer: CByteBuffer, capacity: int) -> str:
+    """Debugging function that has Go copy a string into a caller-allocated buffer, and returns it as a Python string
+
+    Notes
+    -----
+    - The null terminator counts towards the capacity, so "Hello" needs a buffer of 6
+
+    Raises
+    ------
+    BufferTooSmallError
+        If the buffer can't hold the string, error.required is the capacity needed
+
+    Returns
+    -------
+    str
+    """
+    check_fill(lib.helper_fill_string(prepare_string(text), buffer, capacity), capacity)
+    return buffer.value.decode(errors="replace")
+
+# ========== Free Functions ==========
+def free_c_string(ptr: c_char_p):
+    """Frees a single C string returned from Go (allocated via C.CString)."""
+    lib.helper_free_c_string(ptr)
+
+def free_string_array(ptr: CStringArray, count: int):
+    """Frees an array of C strings returned from Go."""
+    lib.helper_free_string_array(ptr, count)
+
+def free_int_array(ptr: CIntArray):
+    """Frees a C int array returned from Go."""
+    lib.helper_free_int_array(ptr)
+    
+def free_float_array(ptr: CFloatArray):
+    """Frees a C float array returned from Go."""
+    lib.helper_free_float_array(ptr)
+
+def free_string_array_result(ptr: _CStringArrayResult):
+    """Frees a StringArrayResult (including the array of strings and struct itself)."""
+    lib.helper_free_string_array_result(ptr)
+
+def free_int_array_result(ptr: _CIntArrayResult):
+    """Frees an IntArrayResult (including the array and the struct itself)."""
+    lib.helper_free_int_array_result(ptr)
+
+def free_float_array_result(ptr: _CFloatArrayResult):
+    """Frees a FloatArrayResult (including the array and the struct itself)."""
+    lib.helper_free_float_array_result(ptr)
+
+# ========== Out-of-process Workers ==========
+class WorkerCrashedError(RuntimeError):
+    """Raised when a worker process dies while handling a call"""
+
+class WorkerError(RuntimeError):
+    """Raised when a function called in a worker returns an error"""
+
+class GoWorker:
+    """A proxy that calls functions in a Go library running as a separate worker process
+
+    Notes
+    -----
+    - The worker is the same Go library built as an executable (see get_worker()), a crash (i.e. a bad pointer) only kills the worker
+    - Calls are sent using a length-prefixed JSON protocol over stdin/stdout, or a Unix socket if socket_path is specified
+    - Arguments and results must be JSON compatible (str, int, float, list, dict, None)
+    - If the worker dies a WorkerCrashedError is raised, and the worker is restarted on the next call (if restart is True)
+
+    Examples
+    --------
+    ```
+    with get_worker("path/to/worker", "path/to/source", compile=True) as worker:
+        print(worker.return_string("Hello World!"))          # Same as the in-process function
+        print(worker.call("return_int_array", [1, 2, 3]))    # Or call by name
+    ```
+    """
+    def __init__(self, executable_path: str, socket_path: str = "", restart: bool = True):
+        self.executable_path = executable_path
+        self.socket_path = socket_path
+        self.restart = restart
+        self._process: subprocess.Popen | None = None
+        self._socket: socket.socket | None = None
+        self._next_id = 0
+        self.start()
+
+    @property
+    def running(self) -> bool:
+        """If the worker process is currently alive"""
+        return self._process is not None and self._process.poll() is None
+
+    def start(self):
+        """Starts the worker process (does nothing if it's already running)
+
+        Raises
+        ------
+        WorkerCrashedError
+            If the worker exits, or the socket doesn't appear, right after starting
+        """
+        if self.running:
+            return
+        self.close()
+        if self.socket_path:
+            if os.path.exists(self.socket_path):
+                os.remove(self.socket_path)
+            self._process = subprocess.Popen([self.executable_path, "-socket", self.socket_path])
+            for _ in range(200): # Wait up to ~2 seconds for the worker to start listening
+                if os.path.exists(self.socket_path):
+                    break
+                if self._process.poll() is not None:
+                    raise WorkerCrashedError(f"Worker {self.executable_path} exited with code {self._process.returncode} while starting")
+                time.sleep(0.01)
+            else:
+                raise WorkerCrashedError(f"Worker {self.executable_path} did not create socket {self.socket_path}")
+            self._socket = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+            self._socket.connect(self.socket_path)
+        else:
+            self._process = subprocess.Popen([self.executable_path], stdin=subprocess.PIPE, stdout=subprocess.PIPE)
+
+    def _write(self, data: bytes):
+        if self._socket:
+            self._socket.sendall(data)
+        else:
+            self._process.stdin.write(data)
+            self._process.stdin.flush()
+
+    def _read(self, size: int) -> bytes:
+        data = b""
+        while len(data) < size:
+            if self._socket:
+                chunk = self._socket.recv(size - len(data))
+            else:
+                chunk = self._process.stdout.read(size - len(data))
+            if not chunk:
+                raise EOFError("Worker closed the connection")
+            data += chunk
+        return data
+
+    def call(self, function: str, *args):
+        """Calls a function in the worker by the name it was registered with
+
+        Parameters
+        ----------
+        function : str
+            The name of the function (i.e. "return_string")
+
+        *args
+            The JSON compatible arguments to pass to the function
+
+        Raises
+        ------
+        WorkerCrashedError
+            If the worker died before or during the call
+
+        WorkerError
+            If the function returned an error
+
+        Returns
+        -------
+        Any
+            The JSON decoded result of the function
+        """
+        if not self.running:
+            if not self.restart and self._process is not None:
+                raise WorkerCrashedError(f"Worker {self.executable_path} is not running")
+            self.start()
+        self._next_id += 1
+        payload = json.dumps({"id": self._next_id, "function": function, "args": list(args)}).encode()
+        try:
+            self._write(struct.pack(">I", len(payload)) + payload)
+            size = struct.unpack(">I", self._read(4))[0]
+            response = json.loads(self._read(size))
+        except (OSError, EOFError, ValueError) as e:
+            # A garbled response can come from a worker that's still running, so stop it before reading the exit code
+            process = self._process
+            self.close()
+            return_code = process.returncode if process else None
+            raise WorkerCrashedError(f"Worker {self.executable_path} crashed while calling {function}() (exit code {return_code}): {e}")
+        if response.get("error"):
+            raise WorkerError(f"{function}(): {response['error']}")
+        return response.get("result")
+
+    def __getattr__(self, name: str):
+        if name.startswith("_"):
+            raise AttributeError(name)
+        def remote_function(*args):
+            return self.call(name, *args)
+        remote_function.__name__ = name
+        return remote_function
+
+    def close(self):
+        """Stops the worker process and closes any open connections"""
+        if self._socket:
+            self._socket.close()
+            self._socket = None
+        if self._process:
+            if self._process.stdin:
+                self._process.stdin.close()
+            if self._process.poll() is None:
+                self._process.terminate()
+            self._process.wait()
+            if self._process.stdout:
+                self._process.stdout.close()
+            self._process = None
+        if self.socket_path and os.path.exists(self.socket_path):
+            os.remove(self.socket_path)
+
+    def __enter__(self) -> 'GoWorker':
+        return self
+
+    def __exit__(self, *_):
+        self.close()
+
+def get_worker(executable_path:str, source_path:str="", compile:bool=False, socket_path:str="") -> GoWorker:
+    """Get's a worker process for the Go library, will compile the worker executable if not found and flag is specified
+
+    Parameters
+    ----------
+    executable_path : str
+        The path to the worker executable, if compile is specified this will be the output path
+
+    source_path:str, optional
+        The path to the source go package directory, only needed if compile is true, by default ""
+
+    compile : bool, optional
+        Specify if you should build the executable when it's missing or it's sources changed, by default False
+
+    socket_path : str, optional
+        Serve over a Unix socket at this path instead of stdin/stdout, by default ""
+
+    Raises
+    ------
+    ValueError:
+        If the executable is not available and/or compilable (if compile is specified)
+
+    Returns
+    -------
+    GoWorker
+        A proxy to the running worker
+    """
+    if compile:
+        exists = os.path.exists(executable_path)
+        if not exists:
+            print("\nRequired worker executable is not available, building...")
+        try:
+            build_library(executable_path, source_path, build_mode="exe")
+        except BuildError as e:
+            if not exists:
+                print(f"Ran into error while trying to build worker\nExiting with error:\n\t{e}")
+                raise ValueError(f"Worker executable is not available or compileable: {executable_path}") from e
+            print(f"Unable to check if {executable_path} is up to date, using the existing executable:\n\t{e}")
+    if not os.path.exists(executable_path):
+        raise ValueError(f"Worker executable is not available: {executable_path}")
+    return GoWorker(executable_path, socket_path)
+
+# ========== Module Host ==========
+class HostError(RuntimeError):
+    """Raised when a function called through a host library returns an error"""
+
+class GoModule:
+    """A namespaced view of the functions one Go package registered in a host library, use GoHost to create one"""
+    def __init__(self, host: 'GoHost', name: str, functions: list[str]):
+        self._host = host
+        self._name = name
+        self._functions = functions
+
+    def __getattr__(self, name: str):
+        if name.startswith("_"):
+            raise AttributeError(name)
+        if name not in self._functions:
+            raise AttributeError(f"Module {self._name} has no function {name}, available functions are: {', '.join(self._functions)}")
+        def module_function(*args):
+            return self._host.call(self._name, name, *args)
+        module_function.__name__ = name
+        return module_function
+
+    def __dir__(self) -> list[str]:
+        return list(self._functions)
+
+class GoHost:
+    """A host library that many Go packages registered their functions in, so they share a single Go runtime
+
     Notes
     -----
-    - Because the data is allocated in python, python will free the memory afterwords
-    - The data is only accurate up to ~4 decimals (i.e. if value is -790.5207366698761 you might get -790.520751953125)
-        
+    - Each Go package registers it's functions with the registry package under a module name, 
+      the host is the helper's package main built with a file that imports each of them (see get_host() and examples/host)
+    - Arguments and results must be JSON compatible (str, int, float, list, dict, None)
+    - The helper's own round-trip functions are available as the "helper" module
+
     Examples
     --------
     ```
-    lib = cdll.LoadLibrary("path/to/library.dll") # Load Library
-
-    # Function that takes in float array, and number of items, then prints them in C
-    lib.print_float_array.argtypes =  [POINTER(c_float), c_int]
-
-    # Prep data using function
-    data = [1.0,2.604,3.14159,4.964]
-    c_array, number_of_items = prepare_float_array(data)
+    host = get_host("path/to/host.so", "examples/host", compile=True)
 
-    # Use data in C
-    lib.print_float_array(c_array, number_of_items)
+    print(host.modules())                               # i.e. {"helper": [...], "scraping": ["parse_urls", ...], "similarity": [...]}
+    sites = host.scraping.parse_urls(["https://kieranwood.ca"])
+    print(host.helper.return_string("Hello World!"))
     ```
     """
-    data = [c_float(item) for item in data]  # Force an error if wrong type
-    number_of_items = len(data)
-    array_type = c_float * number_of_items # Create a C array of float*
-    c_array = array_type(*data)
-    return c_array, number_of_items
+    def __init__(self, library: GoLibrary):
+        self._library = library
+        self._library.helper_host_call.argtypes = [c_char_p, c_char_p, c_char_p]
+        self._library.helper_host_call.restype = c_void_p
+        self._library.helper_host_modules.restype = c_void_p
+        self._free_c_string = _free_c_string_function(library)
+
+    def _take_string(self, pointer: int) -> str:
+        """Copies a C string returned by the host into python, and frees it"""
+        try:
+            return string_at(pointer).decode(errors="replace")
+        finally:
+            self._free_c_string(pointer)
+
+    def modules(self) -> dict[str, list[str]]:
+        """Lists the modules registered in the host, and their functions
+
+        Returns
+        -------
+        dict[str, list[str]]
+            The module names, mapped to the names of their functions
+        """
+        return json.loads(self._take_string(self._library.helper_host_modules()))
+
+    def call(self, module: str, function: str, *args):
+        """Calls a function registered in the host by name
+
+        Parameters
+        ----------
+        module : str
+            The name of the module (i.e. "scraping")
+
+        function : str
+            The name of the function (i.e. "parse_urls")
+
+        *args
+            The JSON compatible arguments to pass to the function
+
+        Raises
+        ------
+        HostError
+            If the function isn't registered, or returns an error
+
+        Returns
+        -------
+        Any
+            The JSON decoded result of the function
+        """
+        arguments = json.dumps(list(args)).encode()
+        response = json.loads(self._take_string(self._library.helper_host_call(module.encode(), function.encode(), arguments)))
+        if response.get("error"):
+            raise HostError(f"{module}.{function}(): {response['error']}")
+        return response.get("result")
+
+    def __getattr__(self, name: str) -> GoModule:
+        if name.startswith("_"):
+            raise AttributeError(name)
+        modules = self.modules()
+        if name not in modules:
+            raise AttributeError(f"Host has no module {name}, available modules are: {', '.join(modules)}")
+        return GoModule(self, name, modules[name])
+
+    def __dir__(self) -> list[str]:
+        return list(self.modules())
+
+def get_host(dll_path:str, source_path:str="", compile:bool=False) -> GoHost:
+    """Get's a host library, will compile if not found and flag is specified
 
-# ========== Convert C types to python ============
-def string_to_str(pointer: c_char_p) -> str:
-    """Takes in a pointer to a C string and returns a Python string
+    Notes
+    -----
+    - A host directory has a host.mod, the helper's go.mod with the modules it includes required (and replaced with their
+      directories, relative to the helper's directory), and .go files that import the packages that register them.
+      It's built into the helper's own package main, see examples/host
 
     Parameters
     ----------
-    pointer : c_char_p
-        A C-style string pointer returned from Go
+    dll_path : str
+        The path to the host DLL file, if compile is specified this will be the output path
 
-    Notes
-    -----
-    - Assumes the pointer is a valid null-terminated UTF-8 encoded string
-    - Does NOT free the pointer automatically, you must call `lib.FreeCString(pointer)` if needed
+    source_path:str, optional
+        The path to the host directory (or a package main that includes the helper), only needed if compile is true, by default ""
+
+    compile : bool, optional
+        Specify if you should try to compile DLL if not in path, by default False
 
     Returns
     -------
-    str
-        The decoded Python string representation of the C string
-        
+    GoHost
+        The host, with a namespaced view per module (i.e. host.scraping.parse_urls)
+    """
+    host_modfile = os.path.join(source_path, "host.mod")
+    if compile and os.path.isfile(host_modfile):
+        files = sorted(os.path.join(source_path, name) for name in os.listdir(source_path) if name.endswith(".go"))
+        return GoHost(get_library(dll_path, _HELPER_DIRECTORY, compile, modfile=host_modfile, files=files))
+    return GoHost(get_library(dll_path, source_path, compile))
+
+# ========== Runtime Tuning ==========
+class ProfilingError(RuntimeError):
+    """Raised when a profile or trace of the Go runtime can't be started, stopped or written"""
+
+class GoRuntime:
+    """Reads and sets the tuning knobs of the Go runtime embedded in a library, after it's been loaded
+
+    Notes
+    -----
+    - Works with any library that includes the helper (it needs the helper_set_gomaxprocs() etc. functions)
+    - A module level instance for the helper's own library is available as go_runtime
+    - Each library has it's own runtime (unless you use a host, see GoHost), so settings only apply to the library passed in
+
     Examples
     --------
     ```
-    c_str = prepare_string(b"Hello from Python!")
-    result: str = string_to_str(c_str)
-    lib.FreeCString(c_str)
+    runtime = GoRuntime(get_library("path/to/lib.so"))
+
+    runtime.gomaxprocs = 2                  # Same as GOMAXPROCS=2
+    runtime.gc_percent = 50                 # Same as GOGC=50
+    runtime.memory_limit = 512 * 1024**2    # Same as GOMEMLIMIT=512MiB, cap the Go heap inside your own memory budget
+    runtime.free_os_memory()                # Return as much memory as possible to the OS
     ```
     """
-    if pointer:
-        return pointer.value.decode("utf-8", errors="replace")
-    return ""
-
-def string_array_result_to_list(pointer:_CStringArrayResult) -> list[str]:
-    """Takes in a pointer to a string result and returns a list of strings
+    def __init__(self, library: GoLibrary | CDLL):
+        self._library = library
+        for name in ("helper_get_gomaxprocs", "helper_get_gc_percent"):
+            getattr(library, name).restype = c_int
+        for name in ("helper_set_gomaxprocs", "helper_set_gc_percent"):
+            getattr(library, name).argtypes = [c_int]
+            getattr(library, name).restype = c_int
+        library.helper_get_memory_limit.restype = c_longlong
+        library.helper_set_memory_limit.argtypes = [c_longlong]
+        library.helper_set_memory_limit.restype = c_longlong
+        library.helper_clear_memory_limit.restype = c_longlong
+        library.helper_runtime_metrics.restype = POINTER(_CKeyValueResult)
+        library.helper_free_key_value_result.argtypes = [POINTER(_CKeyValueResult)]
+        for name in ("helper_start_cpu_profile", "helper_start_trace"):
+            getattr(library, name).argtypes = [c_char_p]
+            getattr(library, name).restype = c_void_p
+        for name in ("helper_stop_cpu_profile", "helper_stop_trace"):
+            getattr(library, name).restype = c_void_p
+        library.helper_write_profile.argtypes = [c_char_p, c_char_p, c_int]
+        library.helper_write_profile.restype = c_void_p
+        library.helper_set_mutex_profile_fraction.argtypes = [c_int]
+        library.helper_set_mutex_profile_fraction.restype = c_int
+        library.helper_set_block_profile_rate.argtypes = [c_int]
+        self._free_c_string = _free_c_string_function(library)
+
+    @property
+    def gomaxprocs(self) -> int:
+        """The number of OS threads that can run Go code at once (GOMAXPROCS)"""
+        return self._library.helper_get_gomaxprocs()
+
+    @gomaxprocs.setter
+    def gomaxprocs(self, value: int):
+        if value < 1:
+            raise ValueError(f"gomaxprocs must be at least 1, got {value}")
+        self._library.helper_set_gomaxprocs(value)
+
+    @property
+    def gc_percent(self) -> int:
+        """How much the heap can grow (in %) since the last collection before the next one (GOGC), negative means the GC is off"""
+        return self._library.helper_get_gc_percent()
+
+    @gc_percent.setter
+    def gc_percent(self, value: int):
+        self._library.helper_set_gc_percent(value)
+
+    @property
+    def memory_limit(self) -> int | None:
+        """The soft memory limit of the Go runtime in bytes (GOMEMLIMIT), None if there is no limit"""
+        limit = self._library.helper_get_memory_limit()
+        if limit == 2**63 - 1:
+            return None
+        return limit
+
+    @memory_limit.setter
+    def memory_limit(self, value: int | None):
+        if value is None:
+            self._library.helper_clear_memory_limit()
+            return
+        if value < 0:
+            raise ValueError(f"memory_limit must be positive, got {value}")
+        self._library.helper_set_memory_limit(value)
+
+    def gc(self):
+        """Runs a garbage collection, blocking until it's complete"""
+        self._library.helper_gc()
+
+    def free_os_memory(self):
+        """Runs a garbage collection, and returns as much memory to the operating system as possible"""
+        self._library.helper_free_os_memory()
+
+    def metrics(self) -> dict[str, float]:
+        """Takes a snapshot of the runtime/metrics of the Go runtime (heap, goroutines, GC cycles, cgo calls etc.)
+
+        Notes
+        -----
+        - Keys are the runtime/metrics names (i.e. "/sched/goroutines:goroutines"), see https://pkg.go.dev/runtime/metrics
+        - Histograms (i.e. "/gc/pauses:seconds") are left out, a single value can't stand in for their buckets
+
+        Returns
+        -------
+        dict[str, float]
+            The metric names mapped to their current values
+        """
+        pointer = self._library.helper_runtime_metrics()
+        try:
+            result_data = pointer.contents
+            return {
+                result_data.keys[i].decode(errors="replace"): result_data.values[i] 
+                for i in range(result_data.numberOfElements)
+            }
+        finally:
+            self._library.helper_free_key_value_result(pointer)
+
+    def prometheus(self, prefix: str = "go", labels: dict[str, str] | None = None) -> str:
+        """Takes a snapshot of the runtime metrics, and formats it as Prometheus text, see metrics_to_prometheus()"""
+        return metrics_to_prometheus(self.metrics(), prefix, labels)
+
+    def _check(self, error_pointer: int | None):
+        """Raises a ProfilingError if an exported profiling function returned an error string (and frees it)"""
+        if not error_pointer:
+            return
+        try:
+            message = string_at(error_pointer).decode(errors="replace")
+        finally:
+            self._free_c_string(error_pointer)
+        raise ProfilingError(message)
+
+    def start_cpu_profile(self, path: str):
+        """Starts a CPU profile of the Go runtime, written to path (in the pprof format) when stop_cpu_profile() is called
+
+        Raises
+        ------
+        ProfilingError
+            If a profile is already running, or the file can't be created
+        """
+        self._check(self._library.helper_start_cpu_profile(os.fspath(path).encode()))
+
+    def stop_cpu_profile(self):
+        """Stops the running CPU profile
+
+        Raises
+        ------
+        ProfilingError
+            If no profile is running
+        """
+        self._check(self._library.helper_stop_cpu_profile())
+
+    def start_trace(self, path: str):
+        """Starts an execution trace of the Go runtime, written to path when stop_trace() is called
+
+        Raises
+        ------
+        ProfilingError
+            If a trace is already running, or the file can't be created
+        """
+        self._check(self._library.helper_start_trace(os.fspath(path).encode()))
+
+    def stop_trace(self):
+        """Stops the running execution trace
+
+        Raises
+        ------
+        ProfilingError
+            If no trace is running
+        """
+        self._check(self._library.helper_stop_trace())
+
+    def write_profile(self, name: str, path: str, debug: int = 0):
+        """Writes a named runtime profile to a file
+
+        Parameters
+        ----------
+        name : str
+            The profile to write, one of "heap", "allocs", "goroutine", "mutex", "block" or "threadcreate"
+
+        path : str
+            The file to write the profile to
+
+        debug : int, optional
+            0 for the pprof format, 1 or 2 for human readable text, by default 0
+
+        Raises
+        ------
+        ProfilingError
+            If the profile doesn't exist, or the file can't be written
+        """
+        self._check(self._library.helper_write_profile(name.encode(), os.fspath(path).encode(), debug))
+
+    @contextmanager
+    def cpu_profile(self, path: str) -> Iterator[str]:
+        """Profiles the Go side of the code run inside the with block
+
+        Examples
+        --------
+        ```
+        with go_runtime.cpu_profile("cpu.pprof"):
+            check_dictionary_similarity_levenstein("almni")
+        # Then run: go tool pprof cpu.pprof
+        ```
+        """
+        self.start_cpu_profile(path)
+        try:
+            yield path
+        finally:
+            self.stop_cpu_profile()
+
+    @contextmanager
+    def trace(self, path: str) -> Iterator[str]:
+        """Captures an execution trace of the Go side of the code run inside the with block (open it with go tool trace)"""
+        self.start_trace(path)
+        try:
+            yield path
+        finally:
+            self.stop_trace()
+
+    @contextmanager
+    def mutex_profile(self, path: str, fraction: int = 5) -> Iterator[str]:
+        """Records mutex contention inside the with block, and writes the mutex profile to path afterwards
+
+        Parameters
+        ----------
+        path : str
+            The file to write the profile to
+
+        fraction : int, optional
+            On average 1/fraction contention events are recorded, by default 5
+        """
+        previous = self._library.helper_set_mutex_profile_fraction(fraction)
+        try:
+            yield path
+        finally:
+            self._library.helper_set_mutex_profile_fraction(previous)
+            self.write_profile("mutex", path)
+
+    @contextmanager
+    def block_profile(self, path: str, rate: int = 1) -> Iterator[str]:
+        """Records goroutine blocking inside the with block, and writes the block profile to path afterwards
+
+        Parameters
+        ----------
+        path : str
+            The file to write the profile to
+
+        rate : int, optional
+            One event is sampled per rate nanoseconds spent blocked, by default 1 (every event)
+        """
+        self._library.helper_set_block_profile_rate(rate)
+        try:
+            yield path
+        finally:
+            self._library.helper_set_block_profile_rate(0)
+            self.write_profile("block", path)
+
+def _prometheus_value(value: float) -> str:
+    """Formats a sample value without losing precision (i.e. 123456789 instead of 1.23457e+08)"""
+    value = float(value)
+    if value != value:
+        return "NaN"
+    if value in (float("inf"), float("-inf")):
+        return "+Inf" if value > 0 else "-Inf"
+    if value.is_integer():
+        return str(int(value))
+    return repr(value)
+
+def metrics_to_prometheus(metrics: dict[str, float], prefix: str = "go", labels: dict[str, str] | None = None) -> str:
+    """Formats a runtime metrics snapshot (see GoRuntime.metrics()) in the Prometheus text exposition format
 
     Parameters
     ----------
-    pointer : _CStringArrayResult
-        A pointer to a CString Result
+    metrics : dict[str, float]
+        The snapshot to format
+
+    prefix : str, optional
+        Prepended to each metric name, by default "go"
+
+    labels : dict[str, str] | None, optional
+        Labels to add to every metric (i.e. {"library": "scraping"}), by default None
 
     Notes
     -----
-    - free's the original pointer
+    - There are no histograms, GoRuntime.metrics() leaves them out of the snapshot (only scalar metrics are exported)
+    - Whole numbers are written as integers and other values with repr(), so large counters (i.e. heap bytes) aren't rounded
 
     Returns
     -------
-    list[str]
-        The list of strings the pointer pointed to
-        
+    str
+        The metrics in Prometheus text format (i.e. "go_sched_goroutines_goroutines 4")
+
     Examples
     --------
     ```
-    data = [
-        random.choice(["Lorem", "ipsum", "dolor", "sit", "amet"]) 
-        for _ in range(100)
-    ]
-    
-    c_array, number_of_elements = prepare_string_array(data)
-    
-    pointer = return_string_array(c_array, number_of_elements)
-    
-    result:list[str] = string_array_result_to_list(pointer)
+    print(metrics_to_prometheus(go_runtime.metrics(), labels={"library": "helper"}))
+    # TYPE go_gc_cycles_total_gc_cycles counter
+    # go_gc_cycles_total_gc_cycles{library="helper"} 3
     ```
     """
-    try:
-        result_data = pointer.contents
-        results = []
-        for i in range(result_data.numberOfElements):
-            results.append(result_data.data[i].decode(errors='replace'))
-        return results
-    finally:
-        lib.free_string_array_result(pointer)
-
-def int_array_result_to_list(pointer: _CIntArrayResult) -> list[int]:
-    """Converts C int result struct to a Python list, and frees memory."""
-    try:
-        result_data = pointer.contents
-        return [result_data.data[i] for i in range(result_data.numberOfElements)]
-    finally:
-        lib.free_int_array_result(pointer)
-
-def float_array_result_to_list(pointer: _CFloatArrayResult) -> list[float]:
-    """Converts C float result struct to a Python list, and frees memory."""
-    try:
-        result_data = pointer.contents
-        return [result_data.data[i] for i in range(result_data.numberOfElements)]
-    finally:
-        lib.free_float_array_result(pointer)
+    label_text = ""
+    if labels:
+        escaped = []
+        for key, value in labels.items():
+            value = str(value).replace("\\", "\\\\").replace('"', '\\"').replace("\n", "\\n")
+            escaped.append(f'{key}="{value}"')
+        label_text = "{" + ",".join(escaped) + "}"
+
+    lines = []
+    for name, value in sorted(metrics.items()):
+        # i.e. /gc/cycles/total:gc-cycles -> go_gc_cycles_total_gc_cycles
+        metric_name = "".join(c if c.isalnum() else "_" for c in name.strip("/"))
+        metric_name = f"{prefix}_{metric_name}" if prefix else metric_name
+
+        # Cumulative metrics are either totals or call counts
+        if "/total:" in name or name.endswith(":calls"):
+            metric_type = "counter"
+        else:
+            metric_type = "gauge"
+        lines.append(f"# TYPE {metric_name} {metric_type}")
+        lines.append(f"{metric_name}{label_text} {_prometheus_value(value)}")
+    return "\n".join(lines) + "\n"
 
-# ========== Debugging Functions ==========
+class GoPool:
+    """Resizes and inspects the worker pool that a library's exported functions submit their parallel work to (SharedPool)
 
-def return_string(text: str | bytes) -> str:
-    """Debugging function that shows you the Go representation of a C string and returns the python string version
+    Notes
+    -----
+    - Works with any library that includes the helper (it needs the helper_pool_size() etc. functions)
+    - A module level instance for the helper's own library is available as go_pool
+    - The pool starts with CGOHELPER_POOL_SIZE workers (GOMAXPROCS if it isn't set), resizing it only limits how many
+      tasks run at once, GOMAXPROCS still limits how many OS threads run Go code (see GoRuntime)
 
-    Parameters
-    ----------
-    text : str | bytes
-        The text to get the representation of
+    Examples
+    --------
+    ```
+    pool = GoPool(get_library("path/to/lib.so"))
 
-    Returns
-    -------
-    str
-        The returned string
+    pool.size = 8           # At most 8 tasks run at once, across every exported function
+    parse_urls(urls)
+    print(pool.stats())     # i.e. {"active": 0, "completed": 412, "queued": 0, "size": 8, ...}
+    ```
     """
-    c_input = prepare_string(text)
-    result = lib.return_string(c_input)
-
-    if not result:
-        return ""
+    def __init__(self, library: GoLibrary | CDLL):
+        self._library = library
+        library.helper_pool_size.restype = c_int
+        library.helper_pool_resize.argtypes = [c_int]
+        library.helper_pool_resize.restype = c_int
+        library.helper_pool_stats.restype = POINTER(_CKeyValueResult)
+        library.helper_free_key_value_result.argtypes = [POINTER(_CKeyValueResult)]
+
+    @property
+    def size(self) -> int:
+        """The number of tasks the pool runs at once, workers beyond a smaller size exit once they finish their current task"""
+        return self._library.helper_pool_size()
+
+    @size.setter
+    def size(self, value: int):
+        if value < 1:
+            raise ValueError(f"size must be at least 1, got {value}")
+        self._library.helper_pool_resize(value)
+
+    def stats(self) -> dict[str, int]:
+        """Takes a snapshot of the pool
+
+        Returns
+        -------
+        dict[str, int]
+            The "size", running "workers", "active" workers, "queued" tasks (and "queued_low"/"queued_normal"/"queued_high" by
+            priority), and the number of tasks "submitted", "completed", "failed" (returned an error or panicked) and
+            "cancelled" (their context was done before they started) since the library was loaded
+        """
+        pointer = self._library.helper_pool_stats()
+        try:
+            result_data = pointer.contents
+            return {
+                result_data.keys[i].decode(errors="replace"): int(result_data.values[i])
+                for i in range(result_data.numberOfElements)
+            }
+        finally:
+            self._library.helper_free_key_value_result(pointer)
+
+class GoBufferPool:
+    """Configures and inspects the pool of C memory a library's array conversions allocate from (SharedBufferPool)
 
-    copied_bytes = string_at(result)
-    decoded = copied_bytes.decode(errors="replace")
+    Notes
+    -----
+    - Works with any library that includes the helper (it needs the helper_buffer_pool_stats() etc. functions)
+    - A module level instance for the helper's own library is available as go_buffer_pool
+    - Results from return_int_array(), return_float_array() and return_string_array() etc. are drawn from the pool, and
+      go back to it when they're freed (which the conversion functions do for you), so calling them in a loop reuses the
+      same memory instead of calling malloc()/free() each time
+    - The pool keeps at most cap bytes of idle memory in its free lists (CGOHELPER_BUFFER_POOL_CAP, 64MiB if it isn't set),
+      memory that's in use isn't counted, so cap doesn't limit how much the pool hands out
+    - Pooled memory starts after a hidden header, so results must be freed with the helper's free functions
+      (helper_free_int_array_result() etc.), never libc's free()
 
-    return decoded
+    Examples
+    --------
+    ```
+    pool = GoBufferPool(get_library("path/to/lib.so"))
 
-def return_string_array(c_array:CStringArray, number_of_elements:int) ->list[str]:
-    """Debugging function that shows you the Go representation of a C array and returns the python list version
+    for batch in batches:
+        return_int_array(*prepare_int_array(batch))
+    print(pool.stats())     # i.e. {"hits": 999, "misses": 2, "idle_bytes": 4112, ...}
+    pool.trim()             # Give the idle memory back
+    pool.cap = 0            # Or turn pooling off
+    ```
+    """
+    def __init__(self, library: GoLibrary | CDLL):
+        self._library = library
+        library.helper_buffer_pool_get_cap.restype = c_longlong
+        library.helper_buffer_pool_set_cap.argtypes = [c_longlong]
+        library.helper_buffer_pool_set_cap.restype = c_longlong
+        library.helper_buffer_pool_trim.restype = c_longlong
+        library.helper_buffer_pool_stats.restype = POINTER(_CKeyValueResult)
+        library.helper_free_key_value_result.argtypes = [POINTER(_CKeyValueResult)]
+
+    @property
+    def cap(self) -> int:
+        """The most bytes of idle memory the pool keeps (not a limit on memory in use), lowering it frees the idle memory over the new cap, 0 turns pooling off"""
+        return self._library.helper_buffer_pool_get_cap()
+
+    @cap.setter
+    def cap(self, value: int):
+        if value < 0:
+            raise ValueError(f"cap must be positive, got {value}")
+        self._library.helper_buffer_pool_set_cap(value)
+
+    def trim(self) -> int:
+        """Frees the idle memory in the pool, returns the number of bytes that were freed"""
+        return self._library.helper_buffer_pool_trim()
+
+    def stats(self) -> dict[str, int]:
+        """Takes a snapshot of the pool
+
+        Returns
+        -------
+        dict[str, int]
+            The "cap", "idle_bytes"/"idle_blocks" in the free lists, "in_use_bytes"/"in_use_blocks" that haven't been freed,
+            and the number of allocations that reused a block ("hits"), had to malloc() one ("misses") or were too big to
+            pool ("oversized"), and frees that kept the block ("recycled"), free()'d it ("released") or found it's header
+            overwritten ("corrupted")
+        """
+        pointer = self._library.helper_buffer_pool_stats()
+        try:
+            result_data = pointer.contents
+            return {
+                result_data.keys[i].decode(errors="replace"): int(result_data.values[i])
+                for i in range(result_data.numberOfElements)
+            }
+        finally:
+            self._library.helper_free_key_value_result(pointer)
+
+# ========== Self-test ==========
+# The values written into the buffers passed to helper_self_test(), keep them in sync with SelfTestInts etc. in selftest.go
+SELF_TEST_INTS = [-2**31, -1, 0, 1, 2**31 - 1]
+SELF_TEST_FLOATS = [float("nan"), float("inf"), float("-inf"), -0.0, 3.4028234663852886e38, 1.401298464324817e-45, -1.5]
+SELF_TEST_TEXT = "h\u00e9llo w\u00f6rld \u2764 \u65e5\u672c\u8a9e \U0001F40D"
+
+class SelfTestError(RuntimeError):
+    """Raised by self_test(check=True) when a round-trip fails, the message lists the failed cases"""
+
+def self_test(library: GoLibrary | CDLL | None = None, check: bool = False) -> dict:
+    """Runs the helper's conversion round-trips (empty arrays, NaN/Inf, int extremes, multi-byte UTF-8, very long strings)
+    against buffers allocated here, so the ABI is checked with this python and this machine instead of only in go test
 
     Parameters
     ----------
-    c_array : Array[c_char_p]
-        The array to print and convert
-    number_of_elements : int
-        The number of elements in the array
+    library : GoLibrary | CDLL | None, optional
+        Any library that includes the helper, by default the helper's own library
+
+    check : bool, optional
+        Raise a SelfTestError if any case failed, by default False
 
     Notes
     -----
-    - DOES NOT FREE INPUT ARRAY
-    - This function returns the PYTHON list version, do not reassign input variable or it'll never free (i.e. c_array = return_string_array(c_array, number_of_elements))
+    - The buffers start with SELF_TEST_INTS, SELF_TEST_FLOATS and SELF_TEST_TEXT, which Go checks ("caller/*" cases),
+      Go writes them back at the end, and they're checked again from python ("python/*" cases)
+    - The buffers are grown and the test is run again if Go reports they're too small for every case
 
     Returns
     -------
-    list[str]
-        The python string representation of the array
-        
+    dict
+        {"passed": int, "failed": int, "results": [{"name": str, "passed": bool, "error": str}, ...], "required": dict[str, int]}
+
+    Raises
+    ------
+    SelfTestError
+        If check is True and a case failed
+
     Examples
     --------
     ```
-    data = [
-        random.choice(["Lorem", "ipsum", "dolor", "sit", "amet"]) 
-        for _ in range(100)
-    ]
-    
-    c_array, number_of_elements = prepare_string_array(data)
-    
-    result:list[str] = return_string_array(c_array, number_of_elements)
-    
-    lib.free_string_array_result(c_array, number_of_elements)
+    report = self_test(get_library("path/to/lib.so"))
+    print(report["passed"], report["failed"])   # i.e. 31 0
+
+    self_test(check=True) # Raises if anything failed, good for a smoke test after installing on a new machine
     ```
     """
-    pointer = lib.return_string_array(c_array, number_of_elements)
-
-    result_data = pointer.contents
-    results = []
-    for i in range(result_data.numberOfElements):
-        results.append(result_data.data[i].decode(errors='replace'))
-    return results
-
-def return_int_array(c_array: CIntArray, number_of_elements: int) -> list[int]:
-    """Debugging function that shows you the Go representation of a C int array and returns a Python list
+    library = library if library is not None else lib
+    library.helper_self_test.argtypes = [c_void_p, c_size_t, c_void_p, c_size_t, c_void_p, c_size_t]
+    library.helper_self_test.restype = c_void_p
+    free_c_string = _free_c_string_function(library)
+
+    text = SELF_TEST_TEXT.encode()
+    capacities = {"ints": len(SELF_TEST_INTS), "floats": len(SELF_TEST_FLOATS), "text": len(text) + 1}
+    while True:
+        ints = (c_int * capacities["ints"])(*SELF_TEST_INTS)
+        floats = (c_float * capacities["floats"])(*SELF_TEST_FLOATS)
+        text_buffer = create_string_buffer(text, capacities["text"])
+        pointer = library.helper_self_test(ints, len(ints), floats, len(floats), text_buffer, len(text_buffer))
+        try:
+            report = json.loads(string_at(pointer).decode())
+        finally:
+            free_c_string(pointer)
+        if all(report["required"][name] <= capacity for name, capacity in capacities.items()):
+            break
+        capacities = {name: max(capacity, report["required"][name]) for name, capacity in capacities.items()}
+
+    # Check what Go wrote back, with python's view of the memory
+    expected_floats = [c_float(value).value for value in SELF_TEST_FLOATS]
+    checks = {
+        "python/ints": list(ints[:len(SELF_TEST_INTS)]) == SELF_TEST_INTS,
+        "python/floats": all(
+            (actual != actual and value != value) or (actual == value and str(actual) == str(value))
+            for actual, value in zip(floats[:len(expected_floats)], expected_floats)
+        ),
+        "python/text": text_buffer.value.decode(errors="replace") == SELF_TEST_TEXT,
+    }
+    for name, passed in checks.items():
+        result = {"name": name, "passed": passed}
+        if not passed:
+            result["error"] = "Go didn't write back the values python expects"
+        report["results"].append(result)
+        report["passed" if passed else "failed"] += 1
+
+    if check and report["failed"]:
+        failures = "\n".join(f"{r['name']}: {r.get('error', '')}" for r in report["results"] if not r["passed"])
+        raise SelfTestError(f"{report['failed']} self-test case(s) failed:\n{failures}")
+    return report
+
+# ========== Memory Layout ==========
+# The helper's structs, by the name Go registers them under (see RegisterStructLayout() in layout.go)
+HELPER_STRUCTURES: dict[str, type[Structure]] = {
+    "StringArrayResult": _CStringArrayResult,
+    "IntArrayResult": _CIntArrayResult,
+    "FloatArrayResult": _CFloatArrayResult,
+    "KeyValueResult": _CKeyValueResult,
+    "NullableIntArrayResult": _CNullableIntArrayResult,
+    "NullableFloatArrayResult": _CNullableFloatArrayResult,
+    "DecodeResult": _CDecodeResult,
+    "BigIntResult": _CBigIntResult,
+    "DecimalResult": _CDecimalResult,
+    "Timestamp": _CTimestamp,
+    "StructHeader": StructHeader,
+    "LibraryInfo": _CLibraryInfo,
+}
+
+class LayoutMismatch(NamedTuple):
+    """A difference between how Go and ctypes lay out a struct"""
+    struct: str
+    field: str | None   # None when it's the struct itself that differs
+    attribute: str      # "size", "align", "offset", or "exists" when only one side has the struct or field
+    go: int | bool
+    python: int | bool
+
+    def __str__(self) -> str:
+        name = self.struct if self.field is None else f"{self.struct}.{self.field}"
+        if self.attribute == "exists":
+            return f"{name} is missing in {'python' if self.go else 'Go'}"
+        return f"{name} {self.attribute} is {self.go} in Go but {self.python} in python"
+
+class StructLayoutError(RuntimeError):
+    """Raised by compare_struct_layouts(check=True) when Go and ctypes disagree on a layout"""
+
+def struct_layouts(library: GoLibrary | CDLL | None = None) -> dict[str, dict]:
+    """Gets the layout of every struct registered in a library, as cgo laid it out
 
-    Notes
-    -----
-    - DOES NOT FREE INPUT ARRAY
-    - Frees input array ONLY on exception
-    - Returns the PYTHON list version, do not reassign input variable
+    Parameters
+    ----------
+    library : GoLibrary | CDLL | None, optional
+        Any library that includes the helper, by default the helper's own library
 
     Returns
     -------
-    list[int]
+    dict[str, dict]
+        Struct names to {"size": int, "align": int, "fields": [{"name": str, "type": str, "offset": int, "size": int, "align": int}, ...]}
     """
-    pointer = lib.return_int_array(c_array, number_of_elements)
+    library = library if library is not None else lib
+    library.helper_struct_layouts.restype = c_void_p
+    free_c_string = _free_c_string_function(library)
+    pointer = library.helper_struct_layouts()
     try:
-        result_data = pointer.contents
-        return [result_data.data[i] for i in range(result_data.numberOfElements)]
-    except Exception as e:
-        print(f"return_int_array(): Ran into error, freeing memory. Error: {e}")
-        lib.free_int_array_result(c_array)  # In case you define a similar freeing function for input
-        raise e
+        return json.loads(string_at(pointer).decode())
     finally:
-        lib.free_int_array_result(pointer)
+        free_c_string(pointer)
 
-def return_float_array(c_array: CFloatArray, number_of_elements: int) -> list[float]:
-    """Debugging function that shows you the Go representation of a C float array and returns a Python list
+def structure_layout(structure: type[Structure]) -> dict:
+    """Gets the layout of a ctypes Structure, in the same form as struct_layouts()
 
-    Notes
-    -----
-    - DOES NOT FREE INPUT ARRAY ON SUCCESS
-    - Frees input array ONLY on exception
-    - Returns the PYTHON list version, do not reassign input variable
-    - The data is only accurate up to ~4 decimals (i.e. if value is -790.5207366698761 you might get -790.520751953125)
+    Parameters
+    ----------
+    structure : type[Structure]
+        The Structure subclass, fields from Structure base classes come first (like ctypes lays them out)
 
     Returns
     -------
-    list[float]
+    dict
+        {"size": int, "align": int, "fields": [{"name": str, "type": str, "offset": int, "size": int, "align": int}, ...]}
     """
-    pointer = lib.return_float_array(c_array, number_of_elements)
-    try:
-        result_data = pointer.contents
-        return [result_data.data[i] for i in range(result_data.numberOfElements)]
-    except Exception as e:
-        print(f"return_float_array(): Ran into error, freeing memory. Error: {e}")
-        lib.free_float_array_result(c_array)  # In case you define a similar freeing function for input
-        raise e
-    finally:
-        lib.free_float_array_result(pointer)
-
-def print_string(text: str | bytes):
-    """Prints a string's go representation, useful to look for encoding issues
+    fields = []
+    for cls in reversed(structure.__mro__):
+        for name, field_type, *_ in cls.__dict__.get("_fields_", []):
+            descriptor = getattr(structure, name)
+            fields.append({
+                "name": name,
+                "type": field_type.__name__,
+                "offset": descriptor.offset,
+                "size": descriptor.size,
+                "align": alignment(field_type),
+            })
+    return {"size": sizeof(structure), "align": alignment(structure), "fields": fields}
+
+def compare_struct_layouts(structures: dict[str, type[Structure]] | None = None, library: GoLibrary | CDLL | None = None, check: bool = False) -> list[LayoutMismatch]:
+    """Compares the size, alignment and field offsets of ctypes Structures against the C structs cgo compiled, to find
+    padding mismatches (i.e. a Structure that packs a float right after a pointer on a platform where C doesn't)
 
     Parameters
     ----------
-    text : str | bytes
-        The data you want to see the go representation of
-    """
-    c_input = prepare_string(text)
-    lib.print_string(c_input)
+    structures : dict[str, type[Structure]] | None, optional
+        The Structures to check by the name their C struct is registered under in Go, by default HELPER_STRUCTURES
 
-def print_string_array(data:list[str|bytes]):
-    """Prints a string array's go representation, useful to look for encoding issues
+    library : GoLibrary | CDLL | None, optional
+        Any library that includes the helper, by default the helper's own library
+
+    check : bool, optional
+        Raise a StructLayoutError if anything differs, by default False
 
     Notes
     -----
-    - Does not free because everything is allocated in python, so GC will take care of it
+    - Go only knows about structs registered with RegisterStructLayout(), call it in an init() next to the struct's
+      preamble (the helper registers it's own structs)
+    - Fields are matched by name, so a field renamed on one side shows up as missing on both
+    - Use hexdump() to see what's actually in the memory once you know which field is off
 
-    Parameters
-    ----------
-    data : list[str | bytes]
-        The data you want to see the go representation of
-    """
-    c_array, number_of_items = prepare_string_array(data)
+    Returns
+    -------
+    list[LayoutMismatch]
+        Every difference found, empty if the layouts match
 
-    lib.print_string_array(c_array, number_of_items)
+    Raises
+    ------
+    StructLayoutError
+        If check is True and a layout differs
 
-def print_int_array(data:list[int]):
-    """Prints a int array's go representation, useful to look for rounding/conversion issues
+    Examples
+    --------
+    ```
+    class Suggestion(Structure):
+        _fields_ = [("word", c_char_p), ("likelihood", c_float)]
 
-    Notes
-    -----
-    - Does not free because everything is allocated in python, so GC will take care of it
+    for mismatch in compare_struct_layouts({"Suggestion": Suggestion}, get_library("path/to/lib.so")):
+        print(mismatch) # i.e. Suggestion.likelihood offset is 8 in Go but 4 in python
+
+    compare_struct_layouts(check=True) # Raises if the helper's own Structures are wrong for this platform
+    ```
+    """
+    structures = structures if structures is not None else HELPER_STRUCTURES
+    go_layouts = struct_layouts(library)
+    mismatches = []
+    for struct_name, structure in structures.items():
+        if struct_name not in go_layouts:
+            mismatches.append(LayoutMismatch(struct_name, None, "exists", False, True))
+            continue
+        go_layout, python_layout = go_layouts[struct_name], structure_layout(structure)
+        for attribute in ("size", "align"):
+            if go_layout[attribute] != python_layout[attribute]:
+                mismatches.append(LayoutMismatch(struct_name, None, attribute, go_layout[attribute], python_layout[attribute]))
+
+        go_fields = {field["name"]: field for field in go_layout["fields"]}
+        python_fields = {field["name"]: field for field in python_layout["fields"]}
+        for field_name in list(go_fields) + [name for name in python_fields if name not in go_fields]:
+            if field_name not in go_fields or field_name not in python_fields:
+                mismatches.append(LayoutMismatch(struct_name, field_name, "exists", field_name in go_fields, field_name in python_fields))
+                continue
+            for attribute in ("offset", "size", "align"):
+                if go_fields[field_name][attribute] != python_fields[field_name][attribute]:
+                    mismatches.append(LayoutMismatch(struct_name, field_name, attribute, go_fields[field_name][attribute], python_fields[field_name][attribute]))
+
+    if check and mismatches:
+        raise StructLayoutError(f"{len(mismatches)} struct layout difference(s):\n" + "\n".join(str(mismatch) for mismatch in mismatches))
+    return mismatches
+
+def hexdump(data: int | Structure | Array, length: int | None = None, library: GoLibrary | CDLL | None = None) -> str:
+    """Dumps memory the way Go sees it (in the format of hexdump -C), to check what each side actually wrote
 
     Parameters
     ----------
-    data : list[int]
-        The data you want to see the go representation of
-    """
-    c_array, number_of_items = prepare_int_array(data)
+    data : int | Structure | Array
+        An address, or a ctypes object (i.e. a Structure, array, or pointer.contents) to dump in place
 
-    lib.print_int_array(c_array, number_of_items)
+    length : int | None, optional
+        The number of bytes to dump, by default sizeof() the ctypes object (required for addresses)
 
-def print_float_array(data:list[float]):
-    """Prints a float array's go representation, useful to look for rounding/conversion issues
+    library : GoLibrary | CDLL | None, optional
+        Any library that includes the helper, by default the helper's own library
 
     Notes
     -----
-    - Does not free because everything is allocated in python, so GC will take care of it
-
-    Parameters
-    ----------
-    data : list[float]
-        The data you want to see the go representation of
-    """
-    c_array, number_of_items = prepare_float_array(data)
-    lib.print_float_array(c_array, number_of_items)
-
-# ========== Free Functions ==========
-def free_c_string(ptr: c_char_p):
-    """Frees a single C string returned from Go (allocated via C.CString)."""
-    lib.FreeCString(ptr)
+    - Every byte in the range has to be readable, dumping past the end of an allocation can crash the process
 
-def free_string_array(ptr: CStringArray, count: int):
-    """Frees an array of C strings returned from Go."""
-    lib.FreeStringArray(ptr, count)
+    Returns
+    -------
+    str
+        The dump, one line per 16 bytes (empty for a NULL address or a length of 0)
 
-def free_int_array(ptr: CIntArray):
-    """Frees a C int array returned from Go."""
-    lib.FreeIntArray(ptr)
-    
-def free_float_array(ptr: CFloatArray):
-    """Frees a C float array returned from Go."""
-    lib.FreeFloatArray(ptr)
+    Examples
+    --------
+    ```
+    info = _CLibraryInfo.new()
+    print(hexdump(info))
+    # 00000000  20 00 00 00 02 00 00 00  00 00 00 00 00 00 00 00  | ...............|
+    # 00000010  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|
+    ```
+    """
+    if isinstance(data, int):
+        if length is None:
+            raise ValueError("length is required when dumping an address")
+        address = data
+    else:
+        address = addressof(data)
+        length = sizeof(data) if length is None else length
+    if length < 0:
+        raise ValueError(f"length must be positive, got {length}")
+
+    library = library if library is not None else lib
+    library.helper_hexdump.argtypes = [c_void_p, c_size_t]
+    library.helper_hexdump.restype = c_void_p
+    free_c_string = _free_c_string_function(library)
+    pointer = library.helper_hexdump(address, length)
+    try:
+        return string_at(pointer).decode()
+    finally:
+        free_c_string(pointer)
 
-def free_string_array_result(ptr: _CStringArrayResult):
-    """Frees a StringArrayResult (including the array of strings and struct itself)."""
-    lib.free_string_array_result(ptr)
+# The runtime of the helper's own library
+go_runtime = GoRuntime(lib)
 
-def free_int_array_result(ptr: _CIntArrayResult):
-    """Frees an IntArrayResult (including the array and the struct itself)."""
-    lib.free_int_array_result(ptr)
+# The worker pool of the helper's own library
+go_pool = GoPool(lib)
 
-def free_float_array_result(ptr: _CFloatArrayResult):
-    """Frees a FloatArrayResult (including the array and the struct itself)."""
-    lib.free_float_array_result(ptr)
+# The buffer pool of the helper's own library
+go_buffer_pool = GoBufferPool(lib)
@@ -31,7 +31,7 @@ func TestStringConversions(t *testing.T) {
 		{},
 	} {
 		r := StringSliceToCArray(test_input)
-		defer free_string_array_result(unsafe.Pointer(r))
+		defer helper_free_string_array_result(unsafe.Pointer(r))
 
 		temp := CStringArrayToSlice(unsafe.Pointer(r.data), int(r.numberOfElements))
 
@@ -50,15 +50,18 @@ func TestNumberConversions(t *testing.T) {
 		test_input := make([]int, 100)
 		for i := range 100 {
 			n := rand.IntN(10_000)
-			modifier := rand.IntN(1)
+			modifier := rand.IntN(2) // 0 or 1, so both signs are tested
 			if modifier == 0 {
 				modifier = -1
 			}
 
 			test_input[i] = n * modifier
 		}
-		r := IntSliceToCArray(test_input)
-		defer free_int_array_result(unsafe.Pointer(r))
+		r, err := IntSliceToCArray(test_input)
+		if err != nil {
+			t.Fatalf("TestNumberConversions:IntSliceToCArray(%v): %v", test_input, err)
+		}
+		defer helper_free_int_array_result(unsafe.Pointer(r))
 		temp := CIntArrayToSlice(unsafe.Pointer(r.data), int(r.numberOfElements))
 
 		for i := range len(test_input) {
@@ -74,7 +77,7 @@ func TestNumberConversions(t *testing.T) {
 		test_input := make([]float32, 100)
 		for i := range 100 {
 			n := rand.Float32() * float32(rand.IntN(10_000))
-			modifier := rand.IntN(1)
+			modifier := rand.IntN(2) // 0 or 1, so both signs are tested
 			if modifier == 0 {
 				modifier = -1
 			}
@@ -82,7 +85,7 @@ func TestNumberConversions(t *testing.T) {
 			test_input[i] = n * float32(modifier)
 		}
 		r := FloatSliceToCArray(test_input)
-		defer free_float_array_result(unsafe.Pointer(r))
+		defer helper_free_float_array_result(unsafe.Pointer(r))
 		temp := CFloatArrayToSlice(unsafe.Pointer(r.data), int(r.numberOfElements))
 
 		for i := range len(test_input) {
@@ -0,0 +1,139 @@
+package main
+
+/*
+#include <stdlib.h>
+
+typedef struct {
+    size_t numberOfElements;
+    char** keys;
+    double* values;
+} KeyValueResult;
+*/
+import "C"
+import (
+	"runtime/metrics"
+	"slices"
+	"unsafe"
+)
+
+func init() {
+	RegisterStructLayout("KeyValueResult", C.KeyValueResult{})
+}
+
+// ======== Runtime Metrics ========
+
+// Takes a snapshot of every scalar metric in runtime/metrics (heap, goroutines, GC cycles, cgo calls etc.)
+//
+// Returns:
+//   - A map of runtime/metrics names (i.e. "/sched/goroutines:goroutines") to their current values.
+//
+// Notes:
+//   - Histograms (i.e. "/gc/pauses:seconds") are left out, a single value can't stand in for their buckets
+func RuntimeMetrics() map[string]float64 {
+	descriptions := metrics.All()
+	samples := make([]metrics.Sample, len(descriptions))
+	for i, description := range descriptions {
+		samples[i].Name = description.Name
+	}
+	metrics.Read(samples)
+
+	result := make(map[string]float64, len(samples))
+	for _, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			result[sample.Name] = float64(sample.Value.Uint64())
+		case metrics.KindFloat64:
+			result[sample.Name] = sample.Value.Float64()
+		}
+	}
+	return result
+}
+
+// Return a map of floats as a C-Compatible key/value array, sorted by key
+//
+// Parameters:
+//   - data: The map to convert.
+//
+// Returns:
+//   - Pointer to a C.KeyValueResult containing the keys and values.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_key_value_result.
+func FloatMapToCKeyValueArray(data map[string]float64) *C.KeyValueResult {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	count := len(keys)
+	cKeys := (**C.char)(C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(uintptr(0)))))
+	cValues := (*C.double)(C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(C.double(0)))))
+
+	keyArray := unsafe.Slice(cKeys, count)
+	valueArray := unsafe.Slice(cValues, count)
+	for i, key := range keys {
+		keyArray[i] = C.CString(key)
+		valueArray[i] = C.double(data[key])
+	}
+
+	result := (*C.KeyValueResult)(C.malloc(C.size_t(unsafe.Sizeof(C.KeyValueResult{}))))
+	result.numberOfElements = C.size_t(count)
+	result.keys = cKeys
+	result.values = cValues
+	return result
+}
+
+// Takes a snapshot of the runtime metrics, see RuntimeMetrics()
+//
+// Returns:
+//   - Pointer to a C.KeyValueResult of metric names and values (*C.KeyValueResult).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_key_value_result.
+//
+//export helper_runtime_metrics
+func helper_runtime_metrics() *C.KeyValueResult {
+	ExitIfForked()
+	return FloatMapToCKeyValueArray(RuntimeMetrics())
+}
+
+// Takes a snapshot of the shared worker pool, see pool.WorkerPool.Stats()
+//
+// Returns:
+//   - Pointer to a C.KeyValueResult of the keys in pool.PoolStats.Map() and their values (*C.KeyValueResult).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_key_value_result.
+//
+//export helper_pool_stats
+func helper_pool_stats() *C.KeyValueResult {
+	ExitIfForked()
+	return FloatMapToCKeyValueArray(SharedPool.Stats().Map())
+}
+
+// Takes a snapshot of the shared buffer pool, see CBufferPool.Stats()
+//
+// Returns:
+//   - Pointer to a C.KeyValueResult of the keys in BufferPoolStats.Map() and their values (*C.KeyValueResult).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_key_value_result.
+//
+//export helper_buffer_pool_stats
+func helper_buffer_pool_stats() *C.KeyValueResult {
+	ExitIfForked()
+	return FloatMapToCKeyValueArray(SharedBufferPool.Stats().Map())
+}
+
+// Free a *C.KeyValueResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.KeyValueResult to be freed (*C.KeyValueResult).
+//
+//export helper_free_key_value_result
+func helper_free_key_value_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	if ptr == nil {
+		return
+	}
+	temp := (*C.KeyValueResult)(ptr)
+	for _, key := range unsafe.Slice(temp.keys, int(temp.numberOfElements)) {
+		C.free(unsafe.Pointer(key))
+	}
+	C.free(unsafe.Pointer(temp.keys))
+	C.free(unsafe.Pointer(temp.values))
+	C.free(ptr)
+}
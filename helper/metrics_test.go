@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestRuntimeMetrics(t *testing.T) {
+	result := RuntimeMetrics()
+	for _, name := range []string{
+		"/sched/goroutines:goroutines",
+		"/memory/classes/heap/objects:bytes",
+		"/gc/cycles/total:gc-cycles",
+		"/cgo/go-to-c-calls:calls",
+	} {
+		if _, ok := result[name]; !ok {
+			t.Errorf("TestRuntimeMetrics:RuntimeMetrics(): missing %s", name)
+		}
+	}
+	if result["/sched/goroutines:goroutines"] < 1 {
+		t.Errorf("TestRuntimeMetrics:RuntimeMetrics(): %v goroutines", result["/sched/goroutines:goroutines"])
+	}
+
+	// Histograms are left out, instead of estimated
+	for name := range result {
+		if strings.HasPrefix(name, "/gc/pauses:seconds") || strings.HasSuffix(name, "_count") || strings.HasSuffix(name, "_sum") {
+			t.Errorf("TestRuntimeMetrics:RuntimeMetrics(): histogram entry %s", name)
+		}
+	}
+}
+
+func TestKeyValueConversions(t *testing.T) {
+	for _, test_input := range []map[string]float64{
+		{"b": 2, "a": -1.5, "c": math.MaxFloat64},
+		{},
+	} {
+		r := FloatMapToCKeyValueArray(test_input)
+		defer helper_free_key_value_result(unsafe.Pointer(r))
+
+		keys := CStringArrayToSlice(unsafe.Pointer(r.keys), int(r.numberOfElements))
+		if !slices.IsSorted(keys) || len(keys) != len(test_input) {
+			t.Errorf("TestKeyValueConversions:FloatMapToCKeyValueArray(%v): keys %v", test_input, keys)
+		}
+		values := unsafe.Slice(r.values, int(r.numberOfElements))
+		for i, key := range keys {
+			if float64(values[i]) != test_input[key] {
+				t.Errorf("TestKeyValueConversions:FloatMapToCKeyValueArray(%v): %s %v!=%v", test_input, key, values[i], test_input[key])
+			}
+		}
+	}
+}
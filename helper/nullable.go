@@ -0,0 +1,244 @@
+package main
+
+/*
+#include <stdlib.h>
+
+// The validity bitmaps follow the Apache Arrow layout, element i has a value if bit (i % 8) of validity[i / 8] is set
+typedef struct {
+    size_t numberOfElements;
+    int* data;
+    unsigned char* validity;
+} NullableIntArrayResult;
+
+typedef struct {
+    size_t numberOfElements;
+    float* data;
+    unsigned char* validity;
+} NullableFloatArrayResult;
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+func init() {
+	RegisterStructLayout("NullableIntArrayResult", C.NullableIntArrayResult{})
+	RegisterStructLayout("NullableFloatArrayResult", C.NullableFloatArrayResult{})
+}
+
+// ======== Nullable Arrays ========
+
+// Checks if element i is set in a validity bitmap
+//
+// Parameters:
+//   - validity: The bitmap, one bit per element (least significant bit first).
+//   - i: The index of the element.
+//
+// Returns:
+//   - true if element i has a value, false if it's missing.
+func IsValid(validity []byte, i int) bool {
+	return validity[i/8]&(1<<(i%8)) != 0
+}
+
+// Returns the number of bytes in the validity bitmap for length elements
+func ValidityBitmapSize(length int) int {
+	return (length + 7) / 8
+}
+
+// Allocates a C validity bitmap, with the bits set for the elements that have a value
+//
+// Parameters:
+//   - length: The number of elements.
+//   - valid: Reports if element i has a value.
+//
+// Returns:
+//   - Pointer to the C bitmap (*C.uchar), or NULL if length is 0.
+//     Note: The caller is responsible for freeing the allocated memory using C.free.
+func newCValidityBitmap(length int, valid func(i int) bool) *C.uchar {
+	if length == 0 {
+		return nil
+	}
+	size := ValidityBitmapSize(length)
+	bitmap := (*C.uchar)(C.calloc(C.size_t(size), 1))
+	bits := unsafe.Slice((*byte)(unsafe.Pointer(bitmap)), size)
+	for i := range length {
+		if valid(i) {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	return bitmap
+}
+
+// Return a slice of nullable ints as a C-Compatible array with a validity bitmap
+//
+// Parameters:
+//   - data: Slice of Go integers to convert, nil entries are missing values.
+//
+// Returns:
+//   - Pointer to a C.NullableIntArrayResult, missing values are 0 in data and unset in the bitmap.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_nullable_int_array_result.
+//   - An error wrapping ErrIntegerOverflow if a value doesn't fit in a C int (nothing is allocated).
+func NullableIntSliceToCArray(data []*int) (*C.NullableIntArrayResult, error) {
+	count := len(data)
+	values := make([]int, count)
+	for i, val := range data {
+		if val != nil {
+			values[i] = *val
+		}
+	}
+	if err := CheckIntSliceFitsCInt(values); err != nil {
+		return nil, err
+	}
+
+	cArray := (*C.int)(C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(C.int(0)))))
+	array := unsafe.Slice(cArray, count)
+	for i, val := range values {
+		array[i] = C.int(val)
+	}
+
+	result := (*C.NullableIntArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.NullableIntArrayResult{}))))
+	result.numberOfElements = C.size_t(count)
+	result.data = cArray
+	result.validity = newCValidityBitmap(count, func(i int) bool { return data[i] != nil })
+	return result, nil
+}
+
+// Return a slice of nullable floats as a C-Compatible array with a validity bitmap
+//
+// Parameters:
+//   - data: Slice of Go float32 values to convert, nil entries are missing values.
+//
+// Returns:
+//   - Pointer to a C.NullableFloatArrayResult, missing values are 0 in data and unset in the bitmap.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_nullable_float_array_result.
+func NullableFloatSliceToCArray(data []*float32) *C.NullableFloatArrayResult {
+	count := len(data)
+	cArray := (*C.float)(C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(C.float(0)))))
+	array := unsafe.Slice(cArray, count)
+	for i, val := range data {
+		array[i] = 0
+		if val != nil {
+			array[i] = C.float(*val)
+		}
+	}
+
+	result := (*C.NullableFloatArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.NullableFloatArrayResult{}))))
+	result.numberOfElements = C.size_t(count)
+	result.data = cArray
+	result.validity = newCValidityBitmap(count, func(i int) bool { return data[i] != nil })
+	return result
+}
+
+// Takes a C integer array and validity bitmap, and converts it to a slice of nullable ints
+//
+// Parameters:
+//   - cArray: Pointer to the C array of integers (*C.int).
+//   - validity: Pointer to the validity bitmap (*C.uchar), NULL means every element has a value.
+//   - length: Number of elements in the C array.
+//
+// Returns:
+//   - A Go slice with nil for the missing values.
+func CNullableIntArrayToSlice(cArray unsafe.Pointer, validity unsafe.Pointer, length int) []*int {
+	values := CIntArrayToSlice(cArray, length)
+	result := make([]*int, length)
+	for i := range values {
+		if validity == nil || IsValid(unsafe.Slice((*byte)(validity), ValidityBitmapSize(length)), i) {
+			result[i] = &values[i]
+		}
+	}
+	return result
+}
+
+// Takes a C float array and validity bitmap, and converts it to a slice of nullable floats
+//
+// Parameters:
+//   - cArray: Pointer to the C array of floats (*C.float).
+//   - validity: Pointer to the validity bitmap (*C.uchar), NULL means every element has a value.
+//   - length: Number of elements in the C array.
+//
+// Returns:
+//   - A Go slice with nil for the missing values.
+func CNullableFloatArrayToSlice(cArray unsafe.Pointer, validity unsafe.Pointer, length int) []*float32 {
+	values := CFloatArrayToSlice(cArray, length)
+	result := make([]*float32, length)
+	for i := range values {
+		if validity == nil || IsValid(unsafe.Slice((*byte)(validity), ValidityBitmapSize(length)), i) {
+			result[i] = &values[i]
+		}
+	}
+	return result
+}
+
+// Used to convert a C-compatible nullable integer array to wrapper type
+//
+// Parameters:
+//   - cArray: Pointer to the C array of integers (*C.int).
+//   - validity: Pointer to the validity bitmap (*C.uchar), NULL means every element has a value.
+//   - numberOfElements: Number of elements in the C array (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.NullableIntArrayResult (*C.NullableIntArrayResult), or NULL if numberOfElements is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_nullable_int_array_result.
+//
+//export helper_return_nullable_int_array
+func helper_return_nullable_int_array(cArray unsafe.Pointer, validity unsafe.Pointer, numberOfElements C.size_t) *C.NullableIntArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return nil
+	}
+	result, err := NullableIntSliceToCArray(CNullableIntArrayToSlice(cArray, validity, length))
+	if err != nil {
+		return nil // Can't happen, every value started out as a C int
+	}
+	return result
+}
+
+// Used to convert a C-compatible nullable float array to wrapper type
+//
+// Parameters:
+//   - cArray: Pointer to the C array of floats (*C.float).
+//   - validity: Pointer to the validity bitmap (*C.uchar), NULL means every element has a value.
+//   - numberOfElements: Number of elements in the C array (C.size_t).
+//
+// Returns:
+//   - Pointer to a C.NullableFloatArrayResult (*C.NullableFloatArrayResult), or NULL if numberOfElements is too large.
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_nullable_float_array_result.
+//
+//export helper_return_nullable_float_array
+func helper_return_nullable_float_array(cArray unsafe.Pointer, validity unsafe.Pointer, numberOfElements C.size_t) *C.NullableFloatArrayResult {
+	ExitIfForked()
+	length, err := CSizeToInt(numberOfElements)
+	if err != nil {
+		return nil
+	}
+	return NullableFloatSliceToCArray(CNullableFloatArrayToSlice(cArray, validity, length))
+}
+
+// Free a *C.NullableIntArrayResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.NullableIntArrayResult to be freed (*C.NullableIntArrayResult).
+//
+//export helper_free_nullable_int_array_result
+func helper_free_nullable_int_array_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	temp := (*C.NullableIntArrayResult)(ptr)
+	C.free(unsafe.Pointer(temp.data))
+	C.free(unsafe.Pointer(temp.validity))
+	C.free(ptr)
+}
+
+// Free a *C.NullableFloatArrayResult.
+//
+// Parameters:
+//   - ptr: Pointer to the C.NullableFloatArrayResult to be freed (*C.NullableFloatArrayResult).
+//
+//export helper_free_nullable_float_array_result
+func helper_free_nullable_float_array_result(ptr unsafe.Pointer) {
+	ExitIfForked()
+	temp := (*C.NullableFloatArrayResult)(ptr)
+	C.free(unsafe.Pointer(temp.data))
+	C.free(unsafe.Pointer(temp.validity))
+	C.free(ptr)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+)
+
+func TestValidityBitmap(t *testing.T) {
+	validity := []byte{0b0000_0101, 0b1000_0000}
+	for i, expected := range []bool{true, false, true, false, false, false, false, false, false, false, false, false, false, false, false, true} {
+		if IsValid(validity, i) != expected {
+			t.Errorf("TestValidityBitmap:IsValid(%08b, %d): %v!=%v", validity, i, !expected, expected)
+		}
+	}
+	for length, expected := range map[int]int{0: 0, 1: 1, 8: 1, 9: 2, 17: 3} {
+		if temp := ValidityBitmapSize(length); temp != expected {
+			t.Errorf("TestValidityBitmap:ValidityBitmapSize(%d): %d!=%d", length, temp, expected)
+		}
+	}
+}
+
+func TestNullableConversions(t *testing.T) {
+	zero, one, big := 0, 1, 1<<40
+	test_input := []*int{&one, nil, &zero, nil, nil, nil, nil, nil, &one}
+	r, err := NullableIntSliceToCArray(test_input)
+	if err != nil {
+		t.Fatalf("TestNullableConversions:NullableIntSliceToCArray(): %v", err)
+	}
+	defer helper_free_nullable_int_array_result(unsafe.Pointer(r))
+	temp := CNullableIntArrayToSlice(unsafe.Pointer(r.data), unsafe.Pointer(r.validity), int(r.numberOfElements))
+	for i := range test_input {
+		// 0 and missing have to stay different
+		if (temp[i] == nil) != (test_input[i] == nil) || (temp[i] != nil && *temp[i] != *test_input[i]) {
+			t.Errorf("TestNullableConversions:NullableIntSliceToCArray(): element %d %v!=%v", i, temp[i], test_input[i])
+		}
+	}
+	if _, err := NullableIntSliceToCArray([]*int{nil, &big}); !errors.Is(err, ErrIntegerOverflow) {
+		t.Errorf("TestNullableConversions:NullableIntSliceToCArray(1<<40): expected ErrIntegerOverflow, got %v", err)
+	}
+
+	half := float32(0.5)
+	floatInput := []*float32{nil, &half}
+	f := NullableFloatSliceToCArray(floatInput)
+	defer helper_free_nullable_float_array_result(unsafe.Pointer(f))
+	floatResult := CNullableFloatArrayToSlice(unsafe.Pointer(f.data), unsafe.Pointer(f.validity), int(f.numberOfElements))
+	if floatResult[0] != nil || floatResult[1] == nil || *floatResult[1] != half {
+		t.Errorf("TestNullableConversions:NullableFloatSliceToCArray(): %v!=%v", floatResult, floatInput)
+	}
+
+	// A NULL bitmap means every element has a value
+	if temp := CNullableFloatArrayToSlice(unsafe.Pointer(f.data), nil, 2); temp[0] == nil || temp[1] == nil {
+		t.Errorf("TestNullableConversions:CNullableFloatArrayToSlice(no bitmap): %v", temp)
+	}
+
+	empty, hello := "", "Hello"
+	stringInput := []*string{&empty, nil, &hello}
+	s := NullableStringSliceToCArray(stringInput)
+	defer helper_free_string_array_result(unsafe.Pointer(s))
+	stringResult := CStringArrayToNullableSlice(unsafe.Pointer(s.data), int(s.numberOfElements))
+	if stringResult[0] == nil || *stringResult[0] != "" || stringResult[1] != nil || *stringResult[2] != "Hello" {
+		t.Errorf("TestNullableConversions:NullableStringSliceToCArray(): %v!=%v", stringResult, stringInput)
+	}
+	// The non-nullable conversion still works on arrays with NULLs
+	if temp := CStringArrayToSlice(unsafe.Pointer(s.data), int(s.numberOfElements)); temp[1] != "" {
+		t.Errorf("TestNullableConversions:CStringArrayToSlice(): %q!=\"\"", temp[1])
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Returned (wrapped) when a value doesn't fit in the type it's being narrowed to, instead of silently wrapping around
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+// ======== Overflow-checked Narrowing ========
+
+// Converts a Go int to a C int, checking that it fits
+//
+// Parameters:
+//   - value: The Go integer to convert.
+//
+// Returns:
+//   - The value as a C.int.
+//   - An error wrapping ErrIntegerOverflow if the value is outside of the range of a C int (32-bit).
+func IntToCInt(value int) (C.int, error) {
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: %d doesn't fit in a C int", ErrIntegerOverflow, value)
+	}
+	return C.int(value), nil
+}
+
+// Checks that every value in a slice fits in a C int, used before writing the slice into C memory
+//
+// Parameters:
+//   - data: Slice of Go integers to check.
+//
+// Returns:
+//   - An error wrapping ErrIntegerOverflow with the index of the first value that doesn't fit, otherwise nil.
+func CheckIntSliceFitsCInt(data []int) error {
+	for i, val := range data {
+		if _, err := IntToCInt(val); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Converts a C size_t (i.e. an array length passed in from C) to a Go int, checking that it fits
+//
+// Parameters:
+//   - size: The C size to convert.
+//
+// Returns:
+//   - The size as a Go int.
+//   - An error wrapping ErrIntegerOverflow if the size is larger than the largest Go int (i.e. a negative python int passed as a size_t).
+func CSizeToInt(size C.size_t) (int, error) {
+	if uint64(size) > math.MaxInt {
+		return 0, fmt.Errorf("%w: length %d doesn't fit in a Go int", ErrIntegerOverflow, uint64(size))
+	}
+	return int(size), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestIntToCInt(t *testing.T) {
+	for _, test_input := range []int{0, -1, 1, math.MaxInt32, math.MinInt32} {
+		temp, err := IntToCInt(test_input)
+		if err != nil || int(temp) != test_input {
+			t.Errorf("TestIntToCInt:IntToCInt(%d): %d!=%d (%v)", test_input, temp, test_input, err)
+		}
+	}
+	for _, test_input := range []int{math.MaxInt32 + 1, math.MinInt32 - 1, math.MaxInt64, math.MinInt64} {
+		if _, err := IntToCInt(test_input); !errors.Is(err, ErrIntegerOverflow) {
+			t.Errorf("TestIntToCInt:IntToCInt(%d): expected ErrIntegerOverflow, got %v", test_input, err)
+		}
+	}
+}
+
+func TestIntSliceOverflow(t *testing.T) {
+	// Previously 1<<32 was silently truncated to 0
+	for _, test_input := range [][]int{{1 << 32}, {0, 1, -(1 << 31) - 1}} {
+		r, err := IntSliceToCArray(test_input)
+		if !errors.Is(err, ErrIntegerOverflow) || r != nil {
+			t.Errorf("TestIntSliceOverflow:IntSliceToCArray(%v): expected ErrIntegerOverflow, got %v", test_input, err)
+		}
+	}
+
+	// Lengths are size_t, so a result can report more than 2^31 elements
+	r, err := IntSliceToCArray([]int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("TestIntSliceOverflow:IntSliceToCArray([1 2 3]): %v", err)
+	}
+	defer helper_free_int_array_result(unsafe.Pointer(r))
+	if unsafe.Sizeof(r.numberOfElements) != 8 && unsafe.Sizeof(uintptr(0)) == 8 {
+		t.Errorf("TestIntSliceOverflow:IntArrayResult.numberOfElements is %d bytes, expected a 64-bit size_t", unsafe.Sizeof(r.numberOfElements))
+	}
+}
+
+func TestCSizeToInt(t *testing.T) {
+	if temp, err := CSizeToInt(42); temp != 42 || err != nil {
+		t.Errorf("TestCSizeToInt:CSizeToInt(42): %d!=42 (%v)", temp, err)
+	}
+	// A negative length from python (i.e. -1) arrives as a huge size_t
+	if _, err := CSizeToInt(math.MaxUint64); !errors.Is(err, ErrIntegerOverflow) {
+		t.Errorf("TestCSizeToInt:CSizeToInt(MaxUint64): expected ErrIntegerOverflow, got %v", err)
+	}
+}
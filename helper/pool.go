@@ -0,0 +1,35 @@
+package main
+
+import "C"
+import "github.com/Descent098/cgo-python-helpers/pool"
+
+// ======== Worker Pool ========
+
+// The pool shared by every exported function in the library, it's the pool package's Shared pool so packages
+// built into the library (i.e. the modules of a host) run on the same workers
+var SharedPool = pool.Shared
+
+// Returns the number of workers in the shared pool
+//
+// Returns:
+//   - The size of SharedPool (C.int).
+//
+//export helper_pool_size
+func helper_pool_size() C.int {
+	ExitIfForked()
+	return C.int(SharedPool.Size())
+}
+
+// Resizes the shared pool, see WorkerPool.Resize()
+//
+// Parameters:
+//   - size: The new number of workers, values < 1 leave the size unchanged.
+//
+// Returns:
+//   - The previous size (C.int).
+//
+//export helper_pool_resize
+func helper_pool_resize(size C.int) C.int {
+	ExitIfForked()
+	return C.int(SharedPool.Resize(int(size)))
+}
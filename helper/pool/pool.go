@@ -0,0 +1,369 @@
+// A bounded pool of goroutines with priorities, that a library's exported functions submit their parallel work to
+// instead of each starting their own goroutines behind a hard-coded semaphore
+//
+// # Functions
+//
+//	NewWorkerPool(size int) *WorkerPool{} // Creates a pool and starts it's workers
+//	(*WorkerPool).Submit(ctx context.Context, priority Priority, run func(ctx context.Context) error) *Task{} // Queues a function, highest priority first
+//	(*WorkerPool).Map(ctx context.Context, priority Priority, n int, run func(ctx context.Context, i int) error) error{} // Runs a function for each index and waits for them, stops at the first error
+//	(*WorkerPool).Resize(size int) int{} // Changes the number of workers
+//	(*WorkerPool).Stats() PoolStats{} // Takes a snapshot of the workers, queues and counters
+//	(*WorkerPool).Close(){} // Stops the pool once the queued tasks finish
+//
+// # Examples
+//
+// Hash a list of files on the shared pool, which has a worker per core for CPU bound work like this
+//
+//	sums := make([][sha256.Size]byte, len(paths))
+//	err := pool.Shared.Map(context.Background(), pool.PriorityNormal, len(paths), func(ctx context.Context, i int) error {
+//		data, err := os.ReadFile(paths[i])
+//		if err != nil {
+//			return err // Stops the remaining calls, and Map returns it
+//		}
+//		sums[i] = sha256.Sum256(data) // Each index is written by one call, so no lock is needed
+//		return nil
+//	})
+//
+// Work that waits on the network gets a pool of its own instead, sized for how many requests should run at once
+// (i.e. scraper.ParseURLs() in examples/scraping/with-helper)
+//
+//	scrapePool := pool.NewWorkerPool(50)
+//	result := make([]*scraper.Site, len(urls))
+//	scrapePool.Map(context.Background(), pool.PriorityNormal, len(urls), func(ctx context.Context, i int) error {
+//		site, err := scraper.ScrapeSite(urls[i])
+//		if err != nil {
+//			site = &scraper.Site{URL: urls[i], Port: 80}
+//		}
+//		result[i] = site
+//		return nil
+//	})
+//
+// The helper's package main resizes and inspects Shared for python (helper_pool_resize(), helper_pool_stats()), so
+// packages built into the same library share it's workers
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// The priority of a task in a WorkerPool, queued tasks with a higher priority are started first
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	numberOfPriorities = 3
+)
+
+// The environment variable that sets the size of Shared when the library loads (defaults to GOMAXPROCS)
+const PoolSizeEnvironmentVariable = "CGOHELPER_POOL_SIZE"
+
+// Returned (wrapped) by Task.Wait() when a task panicked, the panic is recovered so it can't take down the process
+var ErrTaskPanicked = errors.New("task panicked")
+
+// Returned by Task.Wait() when a task was submitted to a closed pool
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+// A function submitted to a WorkerPool, and it's outcome
+type Task struct {
+	ctx  context.Context
+	run  func(ctx context.Context) error
+	done chan struct{}
+	err  error
+}
+
+// Waits for the task to finish
+//
+// Returns:
+//   - The error the task returned, an error wrapping ErrTaskPanicked if it panicked, or the context's error
+//     if it was cancelled before it started.
+func (t *Task) Wait() error {
+	<-t.done
+	return t.err
+}
+
+// Returns a channel that's closed when the task finishes
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+// A snapshot of a WorkerPool
+type PoolStats struct {
+	Size      int                     // The number of workers the pool runs
+	Workers   int                     // The worker goroutines that are running (more than Size until they finish their task after shrinking)
+	Active    int                     // The workers that are running a task
+	Queued    [numberOfPriorities]int // The tasks waiting for a worker, by priority
+	Submitted uint64                  // The tasks submitted since the pool was created
+	Completed uint64                  // The tasks that returned nil
+	Failed    uint64                  // The tasks that returned an error or panicked
+	Cancelled uint64                  // The tasks whose context was done before they started
+}
+
+// A bounded pool of goroutines that run submitted tasks, highest priority first (then in the order they were submitted)
+//
+// Notes:
+//   - Exported functions should submit their parallel work to Shared, so how much runs at once is set in one place
+//     (the python bindings can resize and inspect it) instead of by a semaphore in each function
+type WorkerPool struct {
+	lock      sync.Mutex
+	ready     *sync.Cond // Signalled when a task is queued, the pool shrinks, or it's closed
+	queues    [numberOfPriorities][]*Task
+	size      int
+	workers   int
+	active    int
+	closed    bool
+	submitted uint64
+	completed uint64
+	failed    uint64
+	cancelled uint64
+}
+
+// The pool shared by every exported function in a library, so how much runs at once is set in one place
+var Shared = NewWorkerPool(defaultPoolSize())
+
+// The size of Shared from PoolSizeEnvironmentVariable, or GOMAXPROCS if it isn't set to a positive integer
+func defaultPoolSize() int {
+	if size, err := strconv.Atoi(os.Getenv(PoolSizeEnvironmentVariable)); err == nil && size > 0 {
+		return size
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Creates a worker pool and starts it's workers
+//
+// Parameters:
+//   - size: The number of workers, values < 1 are 1.
+//
+// Returns:
+//   - A pointer to the new pool.
+func NewWorkerPool(size int) *WorkerPool {
+	pool := &WorkerPool{}
+	pool.ready = sync.NewCond(&pool.lock)
+	pool.Resize(size)
+	return pool
+}
+
+// Changes the number of workers, workers beyond the new size exit once they finish their current task
+//
+// Parameters:
+//   - size: The new number of workers, values < 1 leave the size unchanged.
+//
+// Returns:
+//   - The previous size.
+func (p *WorkerPool) Resize(size int) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	previous := p.size
+	if size < 1 || p.closed {
+		return previous
+	}
+	p.size = size
+	for p.workers < p.size {
+		p.workers++
+		go p.work()
+	}
+	p.ready.Broadcast()
+	return previous
+}
+
+// Returns the number of workers the pool runs
+func (p *WorkerPool) Size() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.size
+}
+
+// Takes a snapshot of the pool's size, queues and counters
+func (p *WorkerPool) Stats() PoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	stats := PoolStats{Size: p.size, Workers: p.workers, Active: p.active, Submitted: p.submitted, Completed: p.completed, Failed: p.failed, Cancelled: p.cancelled}
+	for priority, queue := range p.queues {
+		stats.Queued[priority] = len(queue)
+	}
+	return stats
+}
+
+// Flattens the stats for helper_pool_stats()
+//
+// Returns:
+//   - A map of "size", "workers", "active", "queued", "queued_low", "queued_normal", "queued_high", "submitted",
+//     "completed", "failed" and "cancelled" to their values.
+func (s PoolStats) Map() map[string]float64 {
+	return map[string]float64{
+		"size":          float64(s.Size),
+		"workers":       float64(s.Workers),
+		"active":        float64(s.Active),
+		"queued":        float64(s.Queued[PriorityLow] + s.Queued[PriorityNormal] + s.Queued[PriorityHigh]),
+		"queued_low":    float64(s.Queued[PriorityLow]),
+		"queued_normal": float64(s.Queued[PriorityNormal]),
+		"queued_high":   float64(s.Queued[PriorityHigh]),
+		"submitted":     float64(s.Submitted),
+		"completed":     float64(s.Completed),
+		"failed":        float64(s.Failed),
+		"cancelled":     float64(s.Cancelled),
+	}
+}
+
+// Queues a function to run on a worker
+//
+// Parameters:
+//   - ctx: Passed to the function, if it's done before a worker starts the task the function isn't run.
+//   - priority: Where the task is queued, priorities outside PriorityLow-PriorityHigh are clamped.
+//   - run: The function to run, it should return when ctx is done.
+//
+// Returns:
+//   - The task, Wait() for it's result.
+func (p *WorkerPool) Submit(ctx context.Context, priority Priority, run func(ctx context.Context) error) *Task {
+	task := &Task{ctx: ctx, run: run, done: make(chan struct{})}
+	priority = min(max(priority, PriorityLow), PriorityHigh)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		task.err = ErrPoolClosed
+		close(task.done)
+		return task
+	}
+	p.submitted++
+	p.queues[priority] = append(p.queues[priority], task)
+	p.ready.Signal()
+	return task
+}
+
+// Runs a function for each index in [0, n) on the pool, and waits for them to finish
+//
+// Parameters:
+//   - ctx: The parent of the context passed to each call, which is cancelled after the first error.
+//   - priority: The priority of the tasks.
+//   - n: The number of calls.
+//   - run: The function to call with each index.
+//
+// Returns:
+//   - The first error a call returned (or ctx's error), calls that haven't started when it happens are skipped.
+//
+// Notes:
+//   - The calling goroutine runs calls too, so Map can be called from inside a task (i.e. an exported function
+//     that's itself running on the pool) without deadlocking when every worker is busy
+func (p *WorkerPool) Map(ctx context.Context, priority Priority, n int, run func(ctx context.Context, i int) error) error {
+	if n <= 0 {
+		return ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64 // The next index to claim
+		finished atomic.Int64 // The number of claimed indexes that have finished
+		errOnce  sync.Once
+		firstErr error
+		done     = make(chan struct{})
+	)
+	runAll := func(ctx context.Context) error {
+		for {
+			i := int(next.Add(1) - 1)
+			if i >= n {
+				return nil
+			}
+			if err := ctx.Err(); err == nil {
+				err = callRecovered(func() error { return run(ctx, i) })
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					cancel()
+				}
+			}
+			if finished.Add(1) == int64(n) {
+				close(done)
+			}
+		}
+	}
+
+	helpers := min(n-1, p.Size())
+	for range helpers {
+		p.Submit(ctx, priority, runAll)
+	}
+	runAll(ctx)
+	<-done
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// Stops the pool once the queued tasks finish, tasks submitted after it's closed fail with ErrPoolClosed
+func (p *WorkerPool) Close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	p.ready.Broadcast()
+}
+
+// Calls a function, turning a panic into an error wrapping ErrTaskPanicked
+func callRecovered(run func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrTaskPanicked, r)
+		}
+	}()
+	return run()
+}
+
+// Removes the next task to run from the queues, the lock must be held
+func (p *WorkerPool) next() *Task {
+	for priority := PriorityHigh; priority >= PriorityLow; priority-- {
+		if queue := p.queues[priority]; len(queue) > 0 {
+			task := queue[0]
+			queue[0] = nil
+			p.queues[priority] = queue[1:]
+			return task
+		}
+	}
+	return nil
+}
+
+// Runs tasks until the pool shrinks below this worker, or it's closed with nothing queued
+func (p *WorkerPool) work() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for {
+		task := p.next()
+		for task == nil && p.workers <= p.size && !p.closed {
+			p.ready.Wait()
+			task = p.next()
+		}
+		if task == nil {
+			p.workers--
+			return
+		}
+
+		if err := task.ctx.Err(); err != nil {
+			p.cancelled++
+			task.err = err
+			close(task.done)
+		} else {
+			p.active++
+			p.lock.Unlock()
+			err := callRecovered(func() error { return task.run(task.ctx) })
+			p.lock.Lock()
+			p.active--
+			if err != nil {
+				p.failed++
+			} else {
+				p.completed++
+			}
+			task.err = err
+			close(task.done)
+		}
+		if p.workers > p.size {
+			p.workers--
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Submits a task that blocks the pool's only worker until the returned function is called
+func blockPool(pool *WorkerPool) (release func()) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	pool.Submit(context.Background(), PriorityHigh, func(ctx context.Context) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	<-started
+	return func() { close(unblock) }
+}
+
+func TestWorkerPoolPriorities(t *testing.T) {
+	pool := NewWorkerPool(1)
+	defer pool.Close()
+	release := blockPool(pool)
+
+	var lock sync.Mutex
+	var order []string
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+	tasks := []*Task{
+		pool.Submit(context.Background(), PriorityLow, record("low")),
+		pool.Submit(context.Background(), PriorityNormal, record("normal 1")),
+		pool.Submit(context.Background(), PriorityHigh+5, record("high")), // Clamped to PriorityHigh
+		pool.Submit(context.Background(), PriorityNormal, record("normal 2")),
+	}
+	if stats := pool.Stats(); stats.Active != 1 || stats.Queued != [numberOfPriorities]int{1, 2, 1} {
+		t.Errorf("TestWorkerPoolPriorities:Stats() while blocked: %+v", stats)
+	}
+	release()
+	for _, task := range tasks {
+		if err := task.Wait(); err != nil {
+			t.Errorf("TestWorkerPoolPriorities:Wait(): %v", err)
+		}
+	}
+	if expected := []string{"high", "normal 1", "normal 2", "low"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("TestWorkerPoolPriorities: ran %v, expected %v", order, expected)
+	}
+	if stats := pool.Stats(); stats.Submitted != 5 || stats.Completed != 5 {
+		t.Errorf("TestWorkerPoolPriorities:Stats(): %+v", stats)
+	}
+}
+
+func TestWorkerPoolErrors(t *testing.T) {
+	pool := NewWorkerPool(1)
+	defer pool.Close()
+
+	failure := errors.New("failed")
+	if err := pool.Submit(context.Background(), PriorityNormal, func(ctx context.Context) error { return failure }).Wait(); err != failure {
+		t.Errorf("TestWorkerPoolErrors:Submit(error): %v", err)
+	}
+	if err := pool.Submit(context.Background(), PriorityNormal, func(ctx context.Context) error { panic("boom") }).Wait(); !errors.Is(err, ErrTaskPanicked) {
+		t.Errorf("TestWorkerPoolErrors:Submit(panic): %v, expected ErrTaskPanicked", err)
+	}
+
+	// Tasks whose context is done before they start aren't run
+	release := blockPool(pool)
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+	task := pool.Submit(ctx, PriorityNormal, func(ctx context.Context) error { ran = true; return nil })
+	cancel()
+	release()
+	if err := task.Wait(); !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("TestWorkerPoolErrors:Submit(cancelled): %v, ran %v", err, ran)
+	}
+	if stats := pool.Stats(); stats.Failed != 2 || stats.Cancelled != 1 {
+		t.Errorf("TestWorkerPoolErrors:Stats(): %+v", stats)
+	}
+
+	pool.Close()
+	if err := pool.Submit(context.Background(), PriorityNormal, func(ctx context.Context) error { return nil }).Wait(); err != ErrPoolClosed {
+		t.Errorf("TestWorkerPoolErrors:Submit() after Close(): %v, expected ErrPoolClosed", err)
+	}
+}
+
+func TestWorkerPoolResize(t *testing.T) {
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	// Only size tasks run at once
+	var running, most atomic.Int32
+	err := pool.Map(context.Background(), PriorityNormal, 50, func(ctx context.Context, i int) error {
+		now := running.Add(1)
+		for previous := most.Load(); now > previous && !most.CompareAndSwap(previous, now); previous = most.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return nil
+	})
+	// Map's caller runs calls too, so one more than the pool's size
+	if err != nil || most.Load() > 3 {
+		t.Errorf("TestWorkerPoolResize:Map(): %v, %d ran at once", err, most.Load())
+	}
+
+	if previous := pool.Resize(4); previous != 2 || pool.Size() != 4 || pool.Stats().Workers != 4 {
+		t.Errorf("TestWorkerPoolResize:Resize(4): previous %d, %+v", previous, pool.Stats())
+	}
+	if previous := pool.Resize(0); previous != 4 || pool.Size() != 4 {
+		t.Errorf("TestWorkerPoolResize:Resize(0): should not change the size")
+	}
+	pool.Resize(1)
+	for deadline := time.Now().Add(5 * time.Second); pool.Stats().Workers != 1 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := pool.Stats(); stats.Workers != 1 {
+		t.Errorf("TestWorkerPoolResize:Resize(1): %d workers are still running", stats.Workers)
+	}
+}
+
+func TestWorkerPoolMap(t *testing.T) {
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	results := make([]int, 100)
+	if err := pool.Map(context.Background(), PriorityNormal, len(results), func(ctx context.Context, i int) error {
+		results[i] = i * i
+		return nil
+	}); err != nil {
+		t.Errorf("TestWorkerPoolMap:Map(): %v", err)
+	}
+	for i, result := range results {
+		if result != i*i {
+			t.Errorf("TestWorkerPoolMap:Map(): results[%d] %d!=%d", i, result, i*i)
+		}
+	}
+
+	// Calls stop after the first error
+	failure := errors.New("failed")
+	var calls atomic.Int32
+	err := pool.Map(context.Background(), PriorityNormal, 1000, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		if i == 0 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure || calls.Load() == 1000 {
+		t.Errorf("TestWorkerPoolMap:Map(error): %v after %d calls", err, calls.Load())
+	}
+
+	// Nested calls don't deadlock when every worker is busy
+	var total atomic.Int32
+	err = pool.Map(context.Background(), PriorityNormal, 4, func(ctx context.Context, i int) error {
+		return pool.Map(ctx, PriorityHigh, 4, func(ctx context.Context, j int) error {
+			total.Add(1)
+			return nil
+		})
+	})
+	if err != nil || total.Load() != 16 {
+		t.Errorf("TestWorkerPoolMap:Map(nested): %v, %d calls", err, total.Load())
+	}
+
+	if err := pool.Map(context.Background(), PriorityNormal, 0, nil); err != nil {
+		t.Errorf("TestWorkerPoolMap:Map(0): %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestPoolExports(t *testing.T) {
+	original := helper_pool_resize(3)
+	defer helper_pool_resize(original)
+	if helper_pool_size() != 3 || SharedPool.Size() != 3 {
+		t.Errorf("TestPoolExports:helper_pool_resize(3): %d!=3", helper_pool_size())
+	}
+	if previous := helper_pool_resize(-1); previous != 3 || helper_pool_size() != 3 {
+		t.Errorf("TestPoolExports:helper_pool_resize(-1): should not change the size")
+	}
+
+	stats := SharedPool.Stats().Map()
+	for _, key := range []string{"size", "workers", "active", "queued", "queued_low", "queued_normal", "queued_high", "submitted", "completed", "failed", "cancelled"} {
+		if _, ok := stats[key]; !ok {
+			t.Errorf("TestPoolExports:PoolStats.Map(): missing %s", key)
+		}
+	}
+	if stats["size"] != 3 {
+		t.Errorf("TestPoolExports:PoolStats.Map(): size %v!=3", stats["size"])
+	}
+}
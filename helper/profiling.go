@@ -0,0 +1,254 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+	"unsafe"
+)
+
+// Returned when starting a CPU profile or trace while one is already running
+var ErrProfileRunning = errors.New("a capture is already running")
+
+// Returned when stopping a CPU profile or trace that isn't running
+var ErrProfileNotRunning = errors.New("no capture is running")
+
+var (
+	profilingLock  sync.Mutex
+	cpuProfileFile *os.File // Set while a CPU profile is running
+	traceFile      *os.File // Set while an execution trace is running
+)
+
+// ======== Profiling ========
+
+// Starts a CPU profile, written to path in the pprof format when StopCPUProfile() is called
+//
+// Parameters:
+//   - path: The file to write the profile to (i.e. "cpu.pprof"), open it with `go tool pprof <path>`.
+//
+// Returns:
+//   - An error if a profile is already running, or the file can't be created.
+func StartCPUProfile(path string) error {
+	profilingLock.Lock()
+	defer profilingLock.Unlock()
+	if cpuProfileFile != nil {
+		return ErrProfileRunning
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	cpuProfileFile = file
+	return nil
+}
+
+// Stops the CPU profile started by StartCPUProfile(), and closes it's file
+//
+// Returns:
+//   - An error if no profile is running, or the file can't be closed.
+func StopCPUProfile() error {
+	profilingLock.Lock()
+	defer profilingLock.Unlock()
+	if cpuProfileFile == nil {
+		return ErrProfileNotRunning
+	}
+	pprof.StopCPUProfile()
+	err := cpuProfileFile.Close()
+	cpuProfileFile = nil
+	return err
+}
+
+// Writes a named runtime profile to a file
+//
+// Parameters:
+//   - name: The profile to write, one of "heap", "allocs", "goroutine", "mutex", "block" or "threadcreate".
+//   - path: The file to write the profile to.
+//   - debug: 0 for the pprof format, 1 or 2 for human readable text.
+//
+// Returns:
+//   - An error if the profile doesn't exist, or the file can't be written.
+//
+// Notes:
+//   - The mutex and block profiles are empty unless SetMutexProfileFraction()/SetBlockProfileRate() were set first
+func WriteProfile(name string, path string, debug int) error {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if name == "heap" || name == "allocs" {
+		runtime.GC() // Get up to date statistics
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := profile.WriteTo(file, debug); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Starts an execution trace, written to path when StopTrace() is called
+//
+// Parameters:
+//   - path: The file to write the trace to (i.e. "trace.out"), open it with `go tool trace <path>`.
+//
+// Returns:
+//   - An error if a trace is already running, or the file can't be created.
+func StartTrace(path string) error {
+	profilingLock.Lock()
+	defer profilingLock.Unlock()
+	if traceFile != nil {
+		return ErrProfileRunning
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := trace.Start(file); err != nil {
+		file.Close()
+		return err
+	}
+	traceFile = file
+	return nil
+}
+
+// Stops the execution trace started by StartTrace(), and closes it's file
+//
+// Returns:
+//   - An error if no trace is running, or the file can't be closed.
+func StopTrace() error {
+	profilingLock.Lock()
+	defer profilingLock.Unlock()
+	if traceFile == nil {
+		return ErrProfileNotRunning
+	}
+	trace.Stop()
+	err := traceFile.Close()
+	traceFile = nil
+	return err
+}
+
+// Converts an error to a C string for exported functions, nil errors become NULL
+func errorToCString(err error) unsafe.Pointer {
+	if err == nil {
+		return nil
+	}
+	return StringToCString(err.Error())
+}
+
+// Starts a CPU profile, see StartCPUProfile()
+//
+// Parameters:
+//   - path: Pointer to the C string with the file to write the profile to (*C.char).
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (*C.char).
+//     Note: The caller is responsible for freeing the error using helper_free_c_string.
+//
+//export helper_start_cpu_profile
+func helper_start_cpu_profile(path unsafe.Pointer) unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	return errorToCString(StartCPUProfile(CStringToString(path)))
+}
+
+// Stops the running CPU profile, see StopCPUProfile()
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (*C.char).
+//     Note: The caller is responsible for freeing the error using helper_free_c_string.
+//
+//export helper_stop_cpu_profile
+func helper_stop_cpu_profile() unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	return errorToCString(StopCPUProfile())
+}
+
+// Writes a named runtime profile (heap, goroutine, mutex etc.) to a file, see WriteProfile()
+//
+// Parameters:
+//   - name: Pointer to the C string with the profile name (*C.char).
+//   - path: Pointer to the C string with the file to write the profile to (*C.char).
+//   - debug: 0 for the pprof format, 1 or 2 for human readable text.
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (*C.char).
+//     Note: The caller is responsible for freeing the error using helper_free_c_string.
+//
+//export helper_write_profile
+func helper_write_profile(name unsafe.Pointer, path unsafe.Pointer, debug C.int) unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	return errorToCString(WriteProfile(CStringToString(name), CStringToString(path), int(debug)))
+}
+
+// Sets the fraction of mutex contention events that are reported in the mutex profile
+//
+// Parameters:
+//   - rate: On average 1/rate events are reported, 0 turns off reporting, negative values only read the current rate.
+//
+// Returns:
+//   - The previous rate (C.int).
+//
+//export helper_set_mutex_profile_fraction
+func helper_set_mutex_profile_fraction(rate C.int) C.int {
+	ExitIfForked()
+	return C.int(runtime.SetMutexProfileFraction(int(rate)))
+}
+
+// Sets the rate of goroutine blocking events that are reported in the block profile
+//
+// Parameters:
+//   - rate: One event is sampled per rate nanoseconds spent blocked, 0 turns off reporting.
+//
+//export helper_set_block_profile_rate
+func helper_set_block_profile_rate(rate C.int) {
+	ExitIfForked()
+	runtime.SetBlockProfileRate(int(rate))
+}
+
+// Starts an execution trace, see StartTrace()
+//
+// Parameters:
+//   - path: Pointer to the C string with the file to write the trace to (*C.char).
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (*C.char).
+//     Note: The caller is responsible for freeing the error using helper_free_c_string.
+//
+//export helper_start_trace
+func helper_start_trace(path unsafe.Pointer) unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	return errorToCString(StartTrace(CStringToString(path)))
+}
+
+// Stops the running execution trace, see StopTrace()
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (*C.char).
+//     Note: The caller is responsible for freeing the error using helper_free_c_string.
+//
+//export helper_stop_trace
+func helper_stop_trace() unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	return errorToCString(StopTrace())
+}
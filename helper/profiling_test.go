@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Checks that a capture wrote something to it's file
+func checkProfileFile(t *testing.T, path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		t.Errorf("checkProfileFile(%s): file is missing or empty (%v)", path, err)
+	}
+}
+
+func TestCPUProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpu.pprof")
+	if err := StartCPUProfile(path); err != nil {
+		t.Fatalf("TestCPUProfile:StartCPUProfile(%s): %v", path, err)
+	}
+	if err := StartCPUProfile(path); !errors.Is(err, ErrProfileRunning) {
+		t.Errorf("TestCPUProfile:StartCPUProfile(%s): expected ErrProfileRunning, got %v", path, err)
+	}
+	if err := StopCPUProfile(); err != nil {
+		t.Fatalf("TestCPUProfile:StopCPUProfile(): %v", err)
+	}
+	if err := StopCPUProfile(); !errors.Is(err, ErrProfileNotRunning) {
+		t.Errorf("TestCPUProfile:StopCPUProfile(): expected ErrProfileNotRunning, got %v", err)
+	}
+	checkProfileFile(t, path)
+
+	// Errors are returned to C as strings
+	badPath := StringToCString(filepath.Join(t.TempDir(), "missing", "cpu.pprof"))
+	defer FreeCString(badPath)
+	result := helper_start_cpu_profile(badPath)
+	if result == nil {
+		t.Errorf("TestCPUProfile:helper_start_cpu_profile(missing directory): expected an error")
+	}
+	FreeCString(result)
+}
+
+func TestWriteProfile(t *testing.T) {
+	previous := helper_set_mutex_profile_fraction(5)
+	defer helper_set_mutex_profile_fraction(previous)
+
+	for _, name := range []string{"heap", "allocs", "goroutine", "mutex", "block", "threadcreate"} {
+		path := filepath.Join(t.TempDir(), name+".pprof")
+		if err := WriteProfile(name, path, 0); err != nil {
+			t.Errorf("TestWriteProfile:WriteProfile(%s): %v", name, err)
+			continue
+		}
+		checkProfileFile(t, path)
+	}
+	if err := WriteProfile("does_not_exist", filepath.Join(t.TempDir(), "x.pprof"), 0); err == nil {
+		t.Errorf("TestWriteProfile:WriteProfile(does_not_exist): expected an error")
+	}
+}
+
+func TestTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.out")
+	if err := StartTrace(path); err != nil {
+		t.Fatalf("TestTrace:StartTrace(%s): %v", path, err)
+	}
+	if err := StartTrace(path); !errors.Is(err, ErrProfileRunning) {
+		t.Errorf("TestTrace:StartTrace(%s): expected ErrProfileRunning, got %v", path, err)
+	}
+	if err := StopTrace(); err != nil {
+		t.Fatalf("TestTrace:StopTrace(): %v", err)
+	}
+	if err := StopTrace(); !errors.Is(err, ErrProfileNotRunning) {
+		t.Errorf("TestTrace:StopTrace(): expected ErrProfileNotRunning, got %v", err)
+	}
+	checkProfileFile(t, path)
+}
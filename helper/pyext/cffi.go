@@ -0,0 +1,522 @@
+package pyext
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The files of a cffi (API mode) binding
+type Binding struct {
+	CDef    []byte    `json:"-"`       // The declarations for ffi.cdef(), <module>.cdef
+	Build   []byte    `json:"-"`       // The build script that compiles the _<module> extension with cffi, <module>_build.py
+	Wrapper []byte    `json:"-"`       // The python module that converts to and from python objects, <module>.py
+	Wrapped []string  `json:"wrapped"` // The functions the wrapper has, in header order
+	Skipped []Skipped `json:"skipped"` // The exported functions it doesn't have
+}
+
+// The outcome of a BuildCFFI
+type CFFIResult struct {
+	Wrapper     string    `json:"wrapper"`      // The python module (i.e. scraping_cffi.py)
+	BuildScript string    `json:"build_script"` // The script that compiles the extension it imports (i.e. scraping_cffi_build.py)
+	CDef        string    `json:"cdef"`         // The declarations the build script passes to ffi.cdef()
+	Archive     string    `json:"archive"`      // The Go archive the extension links
+	Wrapped     []string  `json:"wrapped"`      // The functions the python module has
+	Skipped     []Skipped `json:"skipped"`      // The exported functions it doesn't have, and why
+	Rebuilt     bool      `json:"rebuilt"`      // Whether the archive or a file changed, so the extension needs compiling again
+}
+
+// The typedefs of the cgo prologue, which are declared when something uses them
+var goTypedefs = map[string]string{
+	"GoInt8": "signed char", "GoUint8": "unsigned char", "GoInt16": "short", "GoUint16": "unsigned short",
+	"GoInt32": "int", "GoUint32": "unsigned int", "GoInt64": "long long", "GoUint64": "unsigned long long",
+	"GoInt": "GoInt64", "GoUint": "GoUint64", "GoUintptr": "size_t", "GoFloat32": "float", "GoFloat64": "double",
+}
+
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true,
+	"finally": true, "for": true, "from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true, "return": true, "try": true,
+	"while": true, "with": true, "yield": true,
+}
+
+// A python name for a C name, which doesn't shadow a keyword or the module's ffi and lib
+func pyName(name string) string {
+	if pythonKeywords[name] || name == "ffi" || name == "lib" {
+		return name + "_"
+	}
+	return name
+}
+
+// A python expression reading a field of a cdata struct
+func pyField(value, field string) string {
+	if pythonKeywords[field] {
+		return fmt.Sprintf("getattr(%s, %s)", value, strconv.Quote(field))
+	}
+	return value + "." + field
+}
+
+// A python docstring
+func pyDocstring(s string) string {
+	if strings.ContainsAny(s, "\"\\") {
+		return strconv.Quote(s)
+	}
+	return `"""` + s + `"""`
+}
+
+// A python expression converting value (of type t, a cdata or a number cffi already converted) to a python object
+func (g *generator) pyToPython(t cType, value string) string {
+	switch t.kind {
+	case kindSigned, kindUnsigned, kindFloat:
+		return value
+	case kindBool:
+		return fmt.Sprintf("bool(%s)", value)
+	case kindString:
+		return fmt.Sprintf("_from_string(%s)", value)
+	case kindStruct:
+		g.used[t.elem] = true
+		return fmt.Sprintf("_%s_to_python(%s)", snake(t.elem), value)
+	}
+	panic("pyext: no conversion from " + t.name + " to python")
+}
+
+// A python expression converting the python object src to something cffi accepts for a t, keep holds what
+// has to stay alive until the call returns
+func (g *generator) pyFromPython(t cType, src, keep string) string {
+	switch t.kind {
+	case kindSigned, kindUnsigned, kindFloat:
+		return src // cffi raises OverflowError and TypeError itself
+	case kindBool:
+		return fmt.Sprintf("bool(%s)", src)
+	case kindString:
+		return fmt.Sprintf("_to_string(%s, %s)", src, keep)
+	case kindStruct:
+		g.parsing[t.elem] = true
+		return fmt.Sprintf("_%s_from_python(%s, %s)[0]", snake(t.elem), src, keep)
+	}
+	panic("pyext: no conversion from python to " + t.name)
+}
+
+// Python statements freeing what value (of type t) points to, but not value itself
+func (g *generator) pyFreeFields(t cType, value string) string {
+	switch t.kind {
+	case kindString:
+		return fmt.Sprintf("lib.free(%s)", value)
+	case kindStruct:
+		g.used[t.elem] = true
+		return fmt.Sprintf("_free_%s_fields(%s)", snake(t.elem), value)
+	}
+	return ""
+}
+
+// Writes the python converters of a struct
+func (g *generator) writePythonStruct(out *strings.Builder, definition Struct) {
+	name, snakeName := definition.Name, snake(definition.Name)
+	fields := map[string]cType{}
+	for _, field := range definition.Fields {
+		fields[field.Name] = g.resolve(field.Type)
+	}
+	var frees []string
+	switch g.shape(name) {
+	case shapeArray:
+		element := g.resolve(fields["data"].elem)
+		item := g.pyToPython(element, "value.data[i]")
+		fmt.Fprintf(out, "def _%s_to_python(value):\n", snakeName)
+		if _, ok := fields["validity"]; ok {
+			item = fmt.Sprintf("None if value.validity != ffi.NULL and not value.validity[i // 8] & (1 << (i %% 8)) else %s", item)
+		}
+		fmt.Fprintf(out, "    return [%s for i in range(value.numberOfElements)]\n\n", item)
+		if element.kind == kindString {
+			frees = append(frees, "if value.data != ffi.NULL:", "    for i in range(value.numberOfElements):", "        lib.free(value.data[i])")
+		}
+		frees = append(frees, "lib.free(value.data)")
+		if _, ok := fields["validity"]; ok {
+			frees = append(frees, "lib.free(value.validity)")
+		}
+	case shapeKeyValue:
+		element := g.resolve(fields["values"].elem)
+		fmt.Fprintf(out, "def _%s_to_python(value):\n", snakeName)
+		fmt.Fprintf(out, "    return {_from_string(value.keys[i]): %s for i in range(value.numberOfElements)}\n\n", g.pyToPython(element, "value.values[i]"))
+		frees = append(frees, "if value.keys != ffi.NULL:", "    for i in range(value.numberOfElements):", "        lib.free(value.keys[i])")
+		if element.kind == kindString {
+			frees = append(frees, "if value.values != ffi.NULL:", "    for i in range(value.numberOfElements):", "        lib.free(value.values[i])")
+		}
+		frees = append(frees, "lib.free(value.keys)", "lib.free(value.values)")
+	default:
+		fmt.Fprintf(out, "def _%s_to_python(value):\n    return {\n", snakeName)
+		for _, field := range definition.Fields {
+			fmt.Fprintf(out, "        %s: %s,\n", strconv.Quote(field.Name), g.pyToPython(fields[field.Name], pyField("value", field.Name)))
+		}
+		fmt.Fprintf(out, "    }\n\n")
+
+		if g.parsing[name] {
+			fmt.Fprintf(out, "def _%s_from_python(value, keep):\n", snakeName)
+			fmt.Fprintf(out, "    if not isinstance(value, dict):\n")
+			fmt.Fprintf(out, "        raise TypeError(f\"expected a dict for a %s, got {type(value).__name__}\")\n", name)
+			fmt.Fprintf(out, "    result = ffi.new(%s)\n", strconv.Quote(name+"*"))
+			fmt.Fprintf(out, "    keep.append(result)\n")
+			for _, field := range definition.Fields {
+				converted := g.pyFromPython(fields[field.Name], fmt.Sprintf("value[%s]", strconv.Quote(field.Name)), "keep")
+				if pythonKeywords[field.Name] {
+					fmt.Fprintf(out, "    setattr(result, %s, %s)\n", strconv.Quote(field.Name), converted)
+				} else {
+					fmt.Fprintf(out, "    result.%s = %s\n", field.Name, converted)
+				}
+			}
+			fmt.Fprintf(out, "    return result\n\n")
+		}
+
+		for _, field := range definition.Fields {
+			if free := g.pyFreeFields(fields[field.Name], pyField("value", field.Name)); free != "" {
+				frees = append(frees, free)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "def _free_%s_fields(value):\n", snakeName)
+	if len(frees) == 0 {
+		frees = append(frees, "pass")
+	}
+	for _, free := range frees {
+		fmt.Fprintf(out, "    %s\n", free)
+	}
+	fmt.Fprintf(out, "\n")
+
+	fmt.Fprintf(out, "def _free_%s(pointer):\n", snakeName)
+	if free, ok := g.frees[name]; ok {
+		g.declared[free] = true
+		fmt.Fprintf(out, "    lib.%s(pointer)\n\n", free)
+	} else {
+		fmt.Fprintf(out, "    _free_%s_fields(pointer)\n    lib.free(pointer)\n\n", snakeName)
+	}
+}
+
+// Writes the python wrapper of a function, or returns why it can't have one
+func (g *generator) writePythonFunction(out *strings.Builder, function Function) (string, error) {
+	sig, err := g.signature(function)
+	if err != nil {
+		return "", err
+	}
+	params := make([]string, len(sig.names))
+	for i, name := range sig.names {
+		params[i] = pyName(name)
+	}
+	if len(params) == 0 {
+		fmt.Fprintf(out, "def %s():\n", pyName(function.Name))
+	} else {
+		fmt.Fprintf(out, "def %s(%s):\n", pyName(function.Name), strings.Join(append(params, "/"), ", "))
+	}
+	if function.Comment != "" {
+		fmt.Fprintf(out, "    %s\n", pyDocstring(function.Comment))
+	}
+	// Structs and arrays of strings (and the strings in them) have to outlive the call, cffi doesn't keep
+	// what they point to alive
+	for _, a := range sig.arguments {
+		if a.t.kind == kindStruct || a.t.kind == kindStructPointer || a.t.kind == kindStringArray {
+			fmt.Fprintf(out, "    _keep = []\n")
+			break
+		}
+	}
+	call := make([]string, len(sig.arguments))
+	for i, a := range sig.arguments {
+		call[i] = fmt.Sprintf("_a%d", i)
+		if a.python < 0 {
+			continue
+		}
+		src := params[a.python]
+		switch a.t.kind {
+		case kindStringArray, kindScalarArray:
+			items := fmt.Sprintf("list(%s)", src)
+			if element := g.resolve(a.t.elem); element.kind == kindString || element.kind == kindBool {
+				items = fmt.Sprintf("[%s for item in %s]", g.pyFromPython(element, "item", "_keep"), src)
+			}
+			fmt.Fprintf(out, "    _a%d = ffi.new(%s, %s)\n", i, strconv.Quote(a.t.elem+"[]"), items)
+			fmt.Fprintf(out, "    _a%d = len(_a%d)\n", i+1, i)
+		case kindStructPointer:
+			g.parsing[a.t.elem] = true
+			fmt.Fprintf(out, "    _a%d = ffi.NULL if %s is None else _%s_from_python(%s, _keep)\n", i, src, snake(a.t.elem), src)
+		case kindString:
+			fmt.Fprintf(out, "    _a%d = %s\n", i, g.pyFromPython(a.t, src, "None"))
+		default:
+			fmt.Fprintf(out, "    _a%d = %s\n", i, g.pyFromPython(a.t, src, "_keep"))
+		}
+	}
+	called := fmt.Sprintf("lib.%s(%s)", function.Name, strings.Join(call, ", "))
+
+	result := sig.result
+	switch {
+	case result.kind == kindVoid:
+		fmt.Fprintf(out, "    %s\n\n", called)
+		return function.Name, nil
+	case result.kind == kindString:
+		free := "lib.free(_r)"
+		if g.stringFree != "" {
+			g.declared[g.stringFree] = true
+			free = fmt.Sprintf("if _r != ffi.NULL:\n            lib.%s(_r)", g.stringFree)
+		}
+		fmt.Fprintf(out, "    _r = %s\n    try:\n        return _from_string(_r)\n    finally:\n        %s\n\n", called, free)
+	case result.kind == kindStruct:
+		fmt.Fprintf(out, "    _r = %s\n    try:\n        return %s\n    finally:\n        %s\n\n", called, g.pyToPython(result, "_r"), g.pyFreeFields(result, "_r"))
+	case sig.arrayFree != "":
+		g.used[result.elem] = true
+		g.declared[sig.arrayFree] = true
+		fmt.Fprintf(out, "    _r = %s\n    if _r == ffi.NULL:\n        return None\n", called)
+		fmt.Fprintf(out, "    try:\n        return [_%s_to_python(_r[i]) for i in range(_a%d)]\n", snake(result.elem), sig.count)
+		fmt.Fprintf(out, "    finally:\n        lib.%s(_r, _a%d)\n\n", sig.arrayFree, sig.count)
+	case result.kind == kindStructPointer:
+		g.used[result.elem] = true
+		fmt.Fprintf(out, "    _r = %s\n    if _r == ffi.NULL:\n        return None\n", called)
+		fmt.Fprintf(out, "    try:\n        return _%s_to_python(_r)\n    finally:\n        _free_%s(_r)\n\n", snake(result.elem), snake(result.elem))
+	default:
+		fmt.Fprintf(out, "    return %s\n\n", g.pyToPython(result, called))
+	}
+	return function.Name, nil
+}
+
+// The names of the types a C type is built from (i.e. "Site" for "Site*")
+func baseType(name string) string {
+	return strings.TrimRight(name, "*")
+}
+
+// Writes the declarations of the structs, typedefs and functions the wrapper uses, for ffi.cdef()
+func (g *generator) writeCDef(out *strings.Builder, structs []Struct, functions []Function) {
+	types := map[string]bool{}
+	for _, definition := range structs {
+		for _, field := range definition.Fields {
+			types[baseType(field.Type)] = true
+		}
+	}
+	for _, function := range functions {
+		types[baseType(function.Return)] = true
+		for _, param := range function.Params {
+			types[baseType(param.Type)] = true
+		}
+	}
+	var typedefs []string
+	for name := range g.header.Typedefs {
+		typedefs = append(typedefs, name)
+	}
+	sort.Strings(typedefs)
+	// The cgo prologue's typedefs refer to each other (GoInt is a GoInt64), so they're declared in dependency order
+	goNames := []string{"GoInt8", "GoUint8", "GoInt16", "GoUint16", "GoInt32", "GoUint32", "GoInt64", "GoUint64", "GoInt", "GoUint", "GoUintptr", "GoFloat32", "GoFloat64"}
+	for _, name := range goNames {
+		if types[name] {
+			types[goTypedefs[name]] = true
+		}
+	}
+	for _, name := range goNames {
+		if types[name] {
+			fmt.Fprintf(out, "typedef %s %s;\n", goTypedefs[name], name)
+		}
+	}
+	for _, name := range typedefs {
+		if _, ok := scalarKinds[g.header.Typedefs[name]]; ok || g.header.Typedefs[name] == "void" {
+			fmt.Fprintf(out, "typedef %s %s;\n", g.header.Typedefs[name], name)
+		}
+	}
+	fmt.Fprintf(out, "\n")
+	for _, definition := range structs {
+		fmt.Fprintf(out, "typedef struct {\n")
+		for _, field := range definition.Fields {
+			fmt.Fprintf(out, "    %s %s;\n", field.Type, field.Name)
+		}
+		fmt.Fprintf(out, "} %s;\n\n", definition.Name)
+	}
+	fmt.Fprintf(out, "void free(void* pointer);\n")
+	for _, function := range functions {
+		params := make([]string, len(function.Params))
+		for i, param := range function.Params {
+			params[i] = param.Type + " " + param.Name
+		}
+		if len(params) == 0 {
+			params = []string{"void"}
+		}
+		fmt.Fprintf(out, "%s %s(%s);\n", function.Return, function.Name, strings.Join(params, ", "))
+	}
+}
+
+const cffiPrelude = `# Code generated by cgohelper cffi from %[2]s. DO NOT EDIT.
+"""Functions exported from Go, called through cffi (generated from %[2]s)
+
+Compile the _%[1]s extension this imports first, with python %[1]s_build.py
+"""
+try:
+    from ._%[1]s import ffi, lib
+except ImportError:
+    from _%[1]s import ffi, lib
+
+def _to_string(value, keep):
+    """A char* of a str or bytes (NULL for None), keep holds on to it until the call returns (None if something else does)"""
+    if value is None:
+        return ffi.NULL
+    if isinstance(value, str):
+        value = value.encode("utf-8")
+    elif not isinstance(value, bytes):
+        raise TypeError(f"expected str, bytes or None, got {type(value).__name__}")
+    if b"\0" in value:
+        raise ValueError("embedded null character")
+    result = ffi.new("char[]", value)
+    if keep is not None:
+        keep.append(result)
+    return result
+
+def _from_string(pointer):
+    """Decodes a C string (invalid UTF-8 is replaced), NULL is None"""
+    if pointer == ffi.NULL:
+        return None
+    return ffi.string(pointer).decode("utf-8", errors="replace")
+
+`
+
+const cffiBuildScript = `# Code generated by cgohelper cffi from %[2]s. DO NOT EDIT.
+"""Compiles the _%[1]s extension that %[1]s.py imports (cffi API mode), with the Go archive %[1]s.a linked into it
+
+    python %[1]s_build.py
+
+Or from setuptools, setup(cffi_modules=["%[1]s_build.py:ffibuilder"])
+"""
+import os
+import sys
+
+from cffi import FFI
+
+_DIRECTORY = os.path.dirname(os.path.abspath(__file__))
+
+ffibuilder = FFI()
+with open(os.path.join(_DIRECTORY, "%[1]s.cdef")) as file:
+    ffibuilder.cdef(file.read())
+
+# What the Go runtime in the archive links against
+if sys.platform == "darwin":
+    libraries, extra_link_args = [], ["-framework", "CoreFoundation", "-framework", "Security"]
+elif sys.platform == "win32":
+    libraries, extra_link_args = ["ws2_32", "winmm", "ntdll"], []
+else:
+    libraries, extra_link_args = ["pthread"], []
+
+ffibuilder.set_source(
+    "_%[1]s",
+    '#include <stdlib.h>\n#include %[3]s',
+    include_dirs=[_DIRECTORY],
+    extra_objects=[os.path.join(_DIRECTORY, "%[1]s.a")],
+    libraries=libraries,
+    extra_link_args=extra_link_args,
+)
+
+if __name__ == "__main__":
+    ffibuilder.compile(tmpdir=_DIRECTORY, verbose=True)
+`
+
+// Generates a cffi API mode binding of a header: the declarations for ffi.cdef(), a build script that compiles
+// them into an extension with the Go archive, and a python module with a function for each exported function
+// that has arguments and a result that can be converted (the same conversions as Generate(), results are freed)
+//
+// Parameters:
+//   - module: The name of the python module, a C identifier (the extension is _<module>).
+//   - headerFile: The file name of the header, to #include.
+//   - header: The parsed header (see ParseHeader()).
+//
+// Returns:
+//   - The binding, with the functions that were wrapped and why the others weren't.
+//   - An error wrapping ErrInvalidModule if module isn't a C identifier.
+func GenerateCFFI(module, headerFile string, header Header) (Binding, error) {
+	if !identifier.MatchString(module) {
+		return Binding{}, fmt.Errorf("%w: %q isn't a C identifier", ErrInvalidModule, module)
+	}
+	g := newGenerator(header)
+
+	binding := Binding{}
+	var wrappers strings.Builder
+	for _, function := range header.Functions {
+		var body strings.Builder
+		name, err := g.writePythonFunction(&body, function)
+		if err != nil {
+			binding.Skipped = append(binding.Skipped, Skipped{function.Name, err.Error()})
+			continue
+		}
+		wrappers.WriteString(body.String())
+		binding.Wrapped = append(binding.Wrapped, name)
+		g.declared[name] = true
+	}
+
+	// Converting a struct marks the structs in it as used, until there are no new ones
+	for marked := -1; marked != len(g.used)+len(g.parsing); {
+		marked = len(g.used) + len(g.parsing)
+		for _, definition := range header.Structs {
+			if g.used[definition.Name] || g.parsing[definition.Name] {
+				var discard strings.Builder
+				g.writePythonStruct(&discard, definition)
+			}
+		}
+	}
+	var structs []Struct
+	var converters strings.Builder
+	for _, definition := range header.Structs {
+		if g.used[definition.Name] || g.parsing[definition.Name] {
+			structs = append(structs, definition)
+			g.writePythonStruct(&converters, definition)
+		}
+	}
+
+	var wrapper strings.Builder
+	fmt.Fprintf(&wrapper, cffiPrelude, module, headerFile)
+	wrapper.WriteString(converters.String())
+	wrapper.WriteString(wrappers.String())
+	binding.Wrapper = []byte(strings.TrimSuffix(wrapper.String(), "\n"))
+
+	var functions []Function
+	for _, function := range header.Functions {
+		if g.declared[function.Name] {
+			functions = append(functions, function)
+		}
+	}
+	var cdef strings.Builder
+	fmt.Fprintf(&cdef, "// Generated by cgohelper cffi from %s, the declarations of the functions %s.py calls\n\n", headerFile, module)
+	g.writeCDef(&cdef, structs, functions)
+	binding.CDef = []byte(cdef.String())
+	binding.Build = []byte(fmt.Sprintf(cffiBuildScript, module, headerFile, strconv.Quote(headerFile)))
+	return binding, nil
+}
+
+// Builds a Go package into an archive, and generates a cffi binding of it next to it (the extension is
+// compiled by running the build script with a python that has cffi)
+//
+// Parameters:
+//   - ctx: Cancels the build.
+//   - c: The build configuration (Python is unused).
+//
+// Returns:
+//   - The result, with the functions that were and weren't wrapped.
+//   - An error wrapping ErrInvalidModule or build.ErrBuildFailed.
+func BuildCFFI(ctx context.Context, c Config) (CFFIResult, error) {
+	result := CFFIResult{}
+	if !identifier.MatchString(c.Module) {
+		return result, fmt.Errorf("%w: %q isn't a C identifier", ErrInvalidModule, c.Module)
+	}
+	result.Archive = filepath.Join(c.Output, c.Module+".a")
+	header, archiveRebuilt, err := buildArchive(ctx, c)
+	if err != nil {
+		return result, err
+	}
+	binding, err := GenerateCFFI(c.Module, c.Module+".h", header)
+	if err != nil {
+		return result, err
+	}
+	result.Wrapped, result.Skipped, result.Rebuilt = binding.Wrapped, binding.Skipped, archiveRebuilt
+
+	result.Wrapper = filepath.Join(c.Output, c.Module+".py")
+	result.BuildScript = filepath.Join(c.Output, c.Module+"_build.py")
+	result.CDef = filepath.Join(c.Output, c.Module+".cdef")
+	for path, data := range map[string][]byte{result.Wrapper: binding.Wrapper, result.BuildScript: binding.Build, result.CDef: binding.CDef} {
+		changed, err := writeIfChanged(path, data)
+		if err != nil {
+			return result, err
+		}
+		result.Rebuilt = result.Rebuilt || changed
+	}
+	return result, nil
+}
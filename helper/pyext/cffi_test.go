@@ -0,0 +1,147 @@
+package pyext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Checks that generated python parses
+func checkPython(t *testing.T, name string, source []byte) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(python, "-c", "import ast, sys; ast.parse(sys.stdin.read())")
+	cmd.Stdin = bytes.NewReader(source)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("%s isn't valid python: %v\n%s\n%s", name, err, out, source)
+	}
+}
+
+func TestGenerateCFFI(t *testing.T) {
+	header := ParseHeader([]byte(scrapingHeader))
+	header.Structs = append(header.Structs, Struct{"StringArrayResult", []Param{{"numberOfElements", "size_t"}, {"data", "char**"}}})
+	header.Functions = append(header.Functions,
+		Function{Name: "words", Return: "StringArrayResult*", Params: []Param{{"from", "char**"}, {"count", "GoInt"}}},
+		Function{Name: "helper_free_string_array_result", Return: "void", Params: []Param{{"ptr", "void*"}}},
+	)
+	binding, err := GenerateCFFI("scraping_cffi", "scraping_cffi.h", header)
+	if err != nil {
+		t.Fatalf("TestGenerateCFFI:GenerateCFFI(): %v", err)
+	}
+	if expected := []string{"parse_urls", "scrape_single_url", "count_sites", "words"}; !reflect.DeepEqual(binding.Wrapped, expected) {
+		t.Errorf("TestGenerateCFFI:GenerateCFFI() wrapped %v!=%v", binding.Wrapped, expected)
+	}
+	if len(binding.Skipped) != 3 {
+		t.Errorf("TestGenerateCFFI:GenerateCFFI() skipped %+v", binding.Skipped)
+	}
+
+	for file, expected := range map[string][]string{
+		"cdef": {
+			"typedef GoInt64 GoInt;",                      // The cgo prologue's typedefs that are used
+			"typedef long long Counter;",                  // And the preamble's
+			"Site* parse_urls(char** cUrls, int cCount);", // Wrapped functions
+			"void free_sites(Site* sites, int count);",    // And the functions that free their results
+			"void helper_free_string_array_result(void* ptr);",
+			"void free(void* pointer);",
+		},
+		"wrapper": {
+			"from ._scraping_cffi import ffi, lib",
+			"def parse_urls(cUrls, /):\n    \"\"\"C-callable wrapper that parses multiple URLs and returns C structs\"\"\"",
+			"lib.free_sites(_r, _a1)",                      // parse_urls returns a list, freed with free_sites
+			"_free_site(_r)",                               // scrape_single_url returns a dict
+			"lib.free_site(pointer)",                       // freed with free_site
+			"def words(from_, /):",                         // Keywords are renamed
+			"lib.helper_free_string_array_result(pointer)", // The helper's results are freed the same way as lib.py
+			"\"url\": _from_string(value.url),",
+		},
+		"build": {
+			`ffibuilder.set_source(`,
+			`'#include <stdlib.h>\n#include "scraping_cffi.h"'`,
+			`"scraping_cffi.a"`,
+		},
+	} {
+		source := map[string][]byte{"cdef": binding.CDef, "wrapper": binding.Wrapper, "build": binding.Build}[file]
+		for _, s := range expected {
+			if !bytes.Contains(source, []byte(s)) {
+				t.Errorf("TestGenerateCFFI:GenerateCFFI() %s doesn't have %q", file, s)
+			}
+		}
+	}
+	if bytes.Contains(binding.CDef, []byte("callback")) {
+		t.Errorf("TestGenerateCFFI:GenerateCFFI() declared a function that isn't wrapped")
+	}
+	checkPython(t, "the wrapper", binding.Wrapper)
+	checkPython(t, "the build script", binding.Build)
+
+	if _, err := GenerateCFFI("scraping-cffi", "scraping_cffi.h", Header{}); !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("TestGenerateCFFI:GenerateCFFI(scraping-cffi): %v, expected ErrInvalidModule", err)
+	}
+}
+
+const itemsCFFIScript = `
+import sys
+sys.path.insert(0, sys.argv[1])
+import items_cffi as m
+assert m.greet("go") == "hello go"
+assert m.sum([1, 2, 3]) == 6 and m.sum([]) == 0
+assert m.doubled([1.5, -2]) == [3.0, -4.0]
+assert m.make_items(["a", "b"]) == [{"name": "a", "count": 0, "price": 1.5}, {"name": "b", "count": 1, "price": 1.5}]
+assert m.restock({"name": "x", "count": 2, "price": 3.0}, 5) == {"name": "x", "count": 7, "price": 3.0}
+assert m.restock(None, 5) is None
+for call, error in [(lambda: m.sum([2**40]), OverflowError), (lambda: m.greet(1), TypeError), (lambda: m.greet("a\0b"), ValueError),
+                    (lambda: m.restock({"name": "x"}, 1), KeyError), (lambda: m.sum(), TypeError)]:
+    try:
+        call()
+    except error:
+        pass
+    else:
+        raise AssertionError(f"{call} didn't raise {error}")
+print("ok")
+`
+
+func TestBuildCFFI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds an archive")
+	}
+	source := t.TempDir()
+	os.WriteFile(filepath.Join(source, "go.mod"), []byte("module items\n\ngo 1.22\n"), 0o644)
+	os.WriteFile(filepath.Join(source, "items.go"), []byte(itemsSource), 0o644)
+	output := filepath.Join(t.TempDir(), "out")
+	config := Config{Module: "items_cffi", Source: source, Output: output}
+
+	result, err := BuildCFFI(context.Background(), config)
+	if err != nil {
+		t.Fatalf("TestBuildCFFI:BuildCFFI(): %v", err)
+	}
+	if !result.Rebuilt || len(result.Wrapped) != 5 {
+		t.Errorf("TestBuildCFFI:BuildCFFI(): %+v", result)
+	}
+	for _, path := range []string{result.Wrapper, result.BuildScript, result.CDef, result.Archive} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("TestBuildCFFI:BuildCFFI() didn't write %s", path)
+		}
+	}
+	if result, err = BuildCFFI(context.Background(), config); err != nil || result.Rebuilt {
+		t.Errorf("TestBuildCFFI:BuildCFFI() again: rebuilt %v, %v", result.Rebuilt, err)
+	}
+
+	// Compiling the extension needs cffi (and setuptools on python 3.12+)
+	python, err := exec.LookPath("python3")
+	if err != nil || exec.Command(python, "-c", "import cffi").Run() != nil {
+		t.Skip("python3 with cffi isn't installed")
+	}
+	if out, err := exec.Command(python, result.BuildScript).CombinedOutput(); err != nil {
+		t.Fatalf("TestBuildCFFI: %s failed: %v\n%s", result.BuildScript, err, out)
+	}
+	if out, err := exec.Command(python, "-c", itemsCFFIScript, output).CombinedOutput(); err != nil || strings.TrimSpace(string(out)) != "ok" {
+		t.Errorf("TestBuildCFFI: the binding doesn't work: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,677 @@
+package pyext
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Returned (wrapped) for a module name that isn't a C identifier
+var ErrInvalidModule = errors.New("invalid module name")
+
+// A function that has no wrapper in the extension, and why
+type Skipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// The C source of an extension module
+type Glue struct {
+	Source  []byte    `json:"-"`
+	Wrapped []string  `json:"wrapped"` // The functions the module has, in header order
+	Skipped []Skipped `json:"skipped"` // The exported functions it doesn't have
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// How a C type is converted to and from python
+type kind int
+
+const (
+	kindUnsupported   kind = iota
+	kindVoid               // None
+	kindSigned             // int
+	kindUnsigned           // int (negative numbers raise OverflowError)
+	kindFloat              // float
+	kindBool               // bool
+	kindString             // str (or bytes/None as an argument)
+	kindStruct             // dict, or a list/dict for array/key value results
+	kindStructPointer      // the same as kindStruct, or None for NULL
+	kindStringArray        // list[str], an argument followed by it's length
+	kindScalarArray        // list[int]/list[float], an argument followed by it's length
+)
+
+var scalarKinds = map[string]kind{
+	"int": kindSigned, "short": kindSigned, "long": kindSigned, "long int": kindSigned, "long long": kindSigned,
+	"long long int": kindSigned, "signed char": kindSigned, "int8_t": kindSigned, "int16_t": kindSigned,
+	"int32_t": kindSigned, "int64_t": kindSigned, "ssize_t": kindSigned, "ptrdiff_t": kindSigned,
+	"GoInt": kindSigned, "GoInt8": kindSigned, "GoInt16": kindSigned, "GoInt32": kindSigned, "GoInt64": kindSigned,
+	"unsigned": kindUnsigned, "unsigned int": kindUnsigned, "unsigned short": kindUnsigned, "unsigned long": kindUnsigned,
+	"long unsigned int": kindUnsigned, "unsigned long long": kindUnsigned, "long long unsigned int": kindUnsigned,
+	"unsigned char": kindUnsigned, "uint8_t": kindUnsigned, "uint16_t": kindUnsigned, "uint32_t": kindUnsigned,
+	"uint64_t": kindUnsigned, "size_t": kindUnsigned, "uintptr_t": kindUnsigned, "GoUint": kindUnsigned,
+	"GoUint8": kindUnsigned, "GoUint16": kindUnsigned, "GoUint32": kindUnsigned, "GoUint64": kindUnsigned,
+	"GoUintptr": kindUnsigned,
+	"float":     kindFloat, "double": kindFloat, "GoFloat32": kindFloat, "GoFloat64": kindFloat,
+	"bool": kindBool, "_Bool": kindBool,
+}
+
+// A C type and how it's converted
+type cType struct {
+	kind kind
+	name string // The C type, as written in the header
+	elem string // The element type of an array, or the struct of a struct pointer
+}
+
+// The shape of a struct, which decides what it converts to
+type shape int
+
+const (
+	shapeDict        shape = iota // A dict of it's fields
+	shapeArray                    // A list, from numberOfElements and data (with a validity bitmap for nullable arrays)
+	shapeKeyValue                 // A dict, from numberOfElements, keys and values
+	shapeUnsupported              // A field can't be converted
+)
+
+type generator struct {
+	header     Header
+	structs    map[string]Struct
+	frees      map[string]string // The exported function that frees one of a struct (i.e. "Site": "free_site")
+	arrayFrees map[string]string // The exported function that frees an array of a struct (i.e. "Site": "free_sites")
+	stringFree string            // The exported function that frees a C string, "" uses free()
+	used       map[string]bool   // The structs converters are needed for
+	parsing    map[string]bool   // The structs converters from python are needed for
+	declared   map[string]bool   // The exported functions a cffi binding calls
+}
+
+// Splits a CamelCase name into snake_case (i.e. "StringArrayResult" is "string_array_result")
+func snake(name string) string {
+	var result strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			result.WriteByte('_')
+		}
+		result.WriteRune(unicode.ToLower(r))
+	}
+	return result.String()
+}
+
+func (g *generator) resolve(name string) cType {
+	resolved := name
+	for i := 0; i < 10; i++ {
+		if next, ok := g.header.Typedefs[resolved]; ok {
+			resolved = next
+		}
+	}
+	if resolved == "void" {
+		return cType{kind: kindVoid, name: name}
+	}
+	if k, ok := scalarKinds[resolved]; ok {
+		return cType{kind: k, name: name}
+	}
+	if resolved == "char*" {
+		return cType{kind: kindString, name: name}
+	}
+	if resolved == "char**" {
+		return cType{kind: kindStringArray, name: name, elem: "char*"}
+	}
+	if _, ok := g.structs[resolved]; ok {
+		return cType{kind: kindStruct, name: name, elem: resolved}
+	}
+	if base, ok := strings.CutSuffix(resolved, "*"); ok {
+		if _, ok := g.structs[base]; ok {
+			return cType{kind: kindStructPointer, name: name, elem: base}
+		}
+		if element := g.resolve(base); element.kind >= kindSigned && element.kind <= kindBool {
+			return cType{kind: kindScalarArray, name: name, elem: base}
+		}
+	}
+	return cType{kind: kindUnsupported, name: name}
+}
+
+func (g *generator) shape(name string) shape {
+	return g.shapeOf(name, map[string]bool{})
+}
+
+func (g *generator) shapeOf(name string, seen map[string]bool) shape {
+	if seen[name] {
+		return shapeUnsupported
+	}
+	seen[name] = true
+	defer delete(seen, name)
+	fields := map[string]cType{}
+	for _, field := range g.structs[name].Fields {
+		fields[field.Name] = g.resolve(field.Type)
+	}
+	count, hasCount := fields["numberOfElements"]
+	if hasCount && (count.kind == kindSigned || count.kind == kindUnsigned) {
+		data, hasData := fields["data"]
+		validity, hasValidity := fields["validity"]
+		if hasData && (data.kind == kindStringArray || data.kind == kindScalarArray) && len(fields) == 2+boolInt(hasValidity) &&
+			(!hasValidity || (validity.kind == kindScalarArray && g.resolve(validity.elem).kind == kindUnsigned)) {
+			return shapeArray
+		}
+		keys, values := fields["keys"], fields["values"]
+		if keys.kind == kindStringArray && (values.kind == kindStringArray || values.kind == kindScalarArray) && len(fields) == 3 {
+			return shapeKeyValue
+		}
+	}
+	for _, field := range g.structs[name].Fields {
+		t := fields[field.Name]
+		if t.kind == kindStruct && g.shapeOf(t.elem, seen) == shapeDict {
+			continue
+		}
+		if t.kind < kindSigned || t.kind > kindString {
+			return shapeUnsupported
+		}
+	}
+	return shapeDict
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Escapes a string for a C string literal
+func cString(s string) string {
+	var result strings.Builder
+	result.WriteByte('"')
+	for _, b := range []byte(s) {
+		switch {
+		case b == '\\' || b == '"':
+			result.WriteByte('\\')
+			result.WriteByte(b)
+		case b == '\n':
+			result.WriteString(`\n`)
+		case b < 0x20 || b >= 0x7f:
+			fmt.Fprintf(&result, `\%03o`, b)
+		default:
+			result.WriteByte(b)
+		}
+	}
+	result.WriteByte('"')
+	return result.String()
+}
+
+// C statements converting the python object src to dst (an lvalue of type t), jumping to fail on errors
+func (g *generator) fromPython(t cType, src, dst, keep string) string {
+	switch t.kind {
+	case kindSigned:
+		return fmt.Sprintf("{ long long v = PyLong_AsLongLong(%s); if (v == -1 && PyErr_Occurred()) goto fail; "+
+			"if ((long long)(%s)v != v) { PyErr_Format(PyExc_OverflowError, \"%%lld doesn't fit in a %s\", v); goto fail; } %s = (%s)v; }",
+			src, t.name, t.name, dst, t.name)
+	case kindUnsigned:
+		return fmt.Sprintf("{ unsigned long long v = PyLong_AsUnsignedLongLong(%s); if (v == (unsigned long long)-1 && PyErr_Occurred()) goto fail; "+
+			"if ((unsigned long long)(%s)v != v) { PyErr_Format(PyExc_OverflowError, \"%%llu doesn't fit in a %s\", v); goto fail; } %s = (%s)v; }",
+			src, t.name, t.name, dst, t.name)
+	case kindFloat:
+		return fmt.Sprintf("{ double v = PyFloat_AsDouble(%s); if (v == -1.0 && PyErr_Occurred()) goto fail; %s = (%s)v; }", src, dst, t.name)
+	case kindBool:
+		return fmt.Sprintf("{ int v = PyObject_IsTrue(%s); if (v < 0) goto fail; %s = v; }", src, dst)
+	case kindString:
+		return fmt.Sprintf("{ const char* v; if (cgohelper_as_string(%s, %s, &v) < 0) goto fail; %s = (%s)v; }", src, keep, dst, t.name)
+	case kindStruct:
+		g.parsing[t.elem] = true
+		return fmt.Sprintf("if (cgohelper_from_python_%s(%s, %s, &%s) < 0) goto fail;", t.elem, src, keep, dst)
+	}
+	panic("pyext: no conversion from python to " + t.name)
+}
+
+// A C expression converting value (of type t) to a new reference, NULL on errors
+func (g *generator) toPython(t cType, value string) string {
+	switch t.kind {
+	case kindSigned:
+		return fmt.Sprintf("PyLong_FromLongLong((long long)(%s))", value)
+	case kindUnsigned:
+		return fmt.Sprintf("PyLong_FromUnsignedLongLong((unsigned long long)(%s))", value)
+	case kindFloat:
+		return fmt.Sprintf("PyFloat_FromDouble((double)(%s))", value)
+	case kindBool:
+		return fmt.Sprintf("PyBool_FromLong((%s) != 0)", value)
+	case kindString:
+		return fmt.Sprintf("cgohelper_from_string(%s)", value)
+	case kindStruct:
+		g.used[t.elem] = true
+		return fmt.Sprintf("cgohelper_to_python_%s(&(%s))", t.elem, value)
+	}
+	panic("pyext: no conversion from " + t.name + " to python")
+}
+
+// C statements freeing what value (of type t) points to, but not value itself
+func (g *generator) freeFields(t cType, value string) string {
+	switch t.kind {
+	case kindString:
+		return fmt.Sprintf("free(%s);", value)
+	case kindStruct:
+		g.used[t.elem] = true
+		return fmt.Sprintf("cgohelper_free_fields_%s(&(%s));", t.elem, value)
+	}
+	return ""
+}
+
+// Writes the converters of a struct
+func (g *generator) writeStruct(out *strings.Builder, definition Struct) {
+	name := definition.Name
+	fields := map[string]cType{}
+	for _, field := range definition.Fields {
+		fields[field.Name] = g.resolve(field.Type)
+	}
+	switch g.shape(name) {
+	case shapeArray:
+		data := fields["data"]
+		element := g.resolve(data.elem)
+		fmt.Fprintf(out, "static PyObject* cgohelper_to_python_%s(const %s* value) {\n", name, name)
+		fmt.Fprintf(out, "    PyObject* result = PyList_New((Py_ssize_t)value->numberOfElements);\n")
+		fmt.Fprintf(out, "    if (result == NULL) return NULL;\n")
+		fmt.Fprintf(out, "    for (size_t i = 0; i < (size_t)value->numberOfElements; i++) {\n")
+		fmt.Fprintf(out, "        PyObject* item;\n")
+		if _, ok := fields["validity"]; ok {
+			fmt.Fprintf(out, "        if (value->validity != NULL && !(value->validity[i / 8] & (1 << (i %% 8)))) {\n")
+			fmt.Fprintf(out, "            Py_INCREF(Py_None);\n            item = Py_None;\n        } else\n    ")
+		}
+		fmt.Fprintf(out, "        item = %s;\n", g.toPython(element, "value->data[i]"))
+		fmt.Fprintf(out, "        if (item == NULL) {\n            Py_DECREF(result);\n            return NULL;\n        }\n")
+		fmt.Fprintf(out, "        PyList_SET_ITEM(result, (Py_ssize_t)i, item);\n    }\n    return result;\n}\n\n")
+
+		fmt.Fprintf(out, "static void cgohelper_free_fields_%s(%s* value) {\n", name, name)
+		if element.kind == kindString {
+			fmt.Fprintf(out, "    for (size_t i = 0; value->data != NULL && i < (size_t)value->numberOfElements; i++) free(value->data[i]);\n")
+		}
+		fmt.Fprintf(out, "    free(value->data);\n")
+		if _, ok := fields["validity"]; ok {
+			fmt.Fprintf(out, "    free(value->validity);\n")
+		}
+		fmt.Fprintf(out, "}\n\n")
+	case shapeKeyValue:
+		values := fields["values"]
+		element := g.resolve(values.elem)
+		fmt.Fprintf(out, "static PyObject* cgohelper_to_python_%s(const %s* value) {\n", name, name)
+		fmt.Fprintf(out, "    PyObject* result = PyDict_New();\n")
+		fmt.Fprintf(out, "    if (result == NULL) return NULL;\n")
+		fmt.Fprintf(out, "    for (size_t i = 0; i < (size_t)value->numberOfElements; i++) {\n")
+		fmt.Fprintf(out, "        PyObject* key = cgohelper_from_string(value->keys[i]);\n")
+		fmt.Fprintf(out, "        PyObject* item = %s;\n", g.toPython(element, "value->values[i]"))
+		fmt.Fprintf(out, "        if (key == NULL || item == NULL || PyDict_SetItem(result, key, item) < 0) {\n")
+		fmt.Fprintf(out, "            Py_XDECREF(key);\n            Py_XDECREF(item);\n            Py_DECREF(result);\n            return NULL;\n        }\n")
+		fmt.Fprintf(out, "        Py_DECREF(key);\n        Py_DECREF(item);\n    }\n    return result;\n}\n\n")
+
+		fmt.Fprintf(out, "static void cgohelper_free_fields_%s(%s* value) {\n", name, name)
+		fmt.Fprintf(out, "    for (size_t i = 0; value->keys != NULL && i < (size_t)value->numberOfElements; i++) free(value->keys[i]);\n")
+		if element.kind == kindString {
+			fmt.Fprintf(out, "    for (size_t i = 0; value->values != NULL && i < (size_t)value->numberOfElements; i++) free(value->values[i]);\n")
+		}
+		fmt.Fprintf(out, "    free(value->keys);\n    free(value->values);\n}\n\n")
+	default:
+		fmt.Fprintf(out, "static PyObject* cgohelper_to_python_%s(const %s* value) {\n", name, name)
+		fmt.Fprintf(out, "    PyObject* item = NULL;\n    PyObject* result = PyDict_New();\n")
+		fmt.Fprintf(out, "    if (result == NULL) return NULL;\n")
+		for _, field := range definition.Fields {
+			fmt.Fprintf(out, "    item = %s;\n", g.toPython(fields[field.Name], "value->"+field.Name))
+			fmt.Fprintf(out, "    if (item == NULL || PyDict_SetItemString(result, %s, item) < 0) goto fail;\n", cString(field.Name))
+			fmt.Fprintf(out, "    Py_DECREF(item);\n")
+		}
+		fmt.Fprintf(out, "    return result;\nfail:\n    Py_XDECREF(item);\n    Py_DECREF(result);\n    return NULL;\n}\n\n")
+
+		if g.parsing[name] {
+			fmt.Fprintf(out, "static int cgohelper_from_python_%s(PyObject* object, PyObject* keep, %s* out) {\n", name, name)
+			fmt.Fprintf(out, "    PyObject* item;\n")
+			fmt.Fprintf(out, "    if (!PyDict_Check(object)) {\n")
+			fmt.Fprintf(out, "        PyErr_Format(PyExc_TypeError, \"expected a dict for a %s, got %%s\", Py_TYPE(object)->tp_name);\n        return -1;\n    }\n", name)
+			fmt.Fprintf(out, "    memset(out, 0, sizeof(*out));\n")
+			for _, field := range definition.Fields {
+				fmt.Fprintf(out, "    item = PyDict_GetItemString(object, %s);\n", cString(field.Name))
+				fmt.Fprintf(out, "    if (item == NULL) {\n        PyErr_SetString(PyExc_KeyError, %s);\n        return -1;\n    }\n", cString(field.Name))
+				fmt.Fprintf(out, "    %s\n", g.fromPython(fields[field.Name], "item", "out->"+field.Name, "keep"))
+			}
+			fmt.Fprintf(out, "    return 0;\nfail:\n    return -1;\n}\n\n")
+		}
+
+		fmt.Fprintf(out, "static void cgohelper_free_fields_%s(%s* value) {\n", name, name)
+		for _, field := range definition.Fields {
+			if free := g.freeFields(fields[field.Name], "value->"+field.Name); free != "" {
+				fmt.Fprintf(out, "    %s\n", free)
+			}
+		}
+		fmt.Fprintf(out, "    (void)value;\n}\n\n")
+	}
+
+	fmt.Fprintf(out, "static void cgohelper_free_%s(%s* value) {\n", name, name)
+	fmt.Fprintf(out, "    if (value == NULL) return;\n")
+	if free, ok := g.frees[name]; ok {
+		fmt.Fprintf(out, "    %s(value);\n}\n\n", free)
+	} else {
+		fmt.Fprintf(out, "    cgohelper_free_fields_%s(value);\n    free(value);\n}\n\n", name)
+	}
+}
+
+// A C argument of a wrapper, and the python argument it comes from
+type argument struct {
+	param  Param
+	t      cType
+	python int // The index of the python argument, -1 for the length of an array
+}
+
+// What a wrapper converts, worked out from a function's signature
+type signature struct {
+	arguments []argument
+	names     []string // The python arguments
+	result    cType
+	count     int    // The index of the length of the first array argument, -1 if there isn't one
+	arrayFree string // The exported function that frees the array of structs the function returns, "" if it doesn't return one
+}
+
+// Works out what a wrapper of a function converts, or why it can't have one
+func (g *generator) signature(function Function) (signature, error) {
+	sig := signature{count: -1}
+	if (strings.HasPrefix(function.Name, "free_") || strings.HasPrefix(function.Name, "helper_free_")) && len(function.Params) > 0 && strings.HasSuffix(function.Params[0].Type, "*") {
+		return sig, errors.New("frees memory, which the wrapper does itself")
+	}
+	sig.result = g.resolve(function.Return)
+	switch result := sig.result; {
+	case result.kind == kindUnsupported, result.kind == kindStringArray, result.kind == kindScalarArray:
+		return sig, fmt.Errorf("returns %s, which can't be converted", function.Return)
+	case (result.kind == kindStruct || result.kind == kindStructPointer) && g.shape(result.elem) == shapeUnsupported:
+		return sig, fmt.Errorf("returns a %s, which has fields that can't be converted", result.elem)
+	}
+
+	for i := 0; i < len(function.Params); i++ {
+		param := function.Params[i]
+		t := g.resolve(param.Type)
+		switch t.kind {
+		case kindUnsupported, kindVoid:
+			return sig, fmt.Errorf("%s is a %s, which can't be converted", param.Name, param.Type)
+		case kindStruct, kindStructPointer:
+			if g.shape(t.elem) != shapeDict {
+				return sig, fmt.Errorf("%s is a %s, which can't be converted from a dict", param.Name, t.elem)
+			}
+		case kindStringArray, kindScalarArray:
+			if i+1 == len(function.Params) {
+				return sig, fmt.Errorf("%s is a %s without a length after it", param.Name, param.Type)
+			}
+			length := g.resolve(function.Params[i+1].Type)
+			if length.kind != kindSigned && length.kind != kindUnsigned {
+				return sig, fmt.Errorf("%s is a %s without a length after it", param.Name, param.Type)
+			}
+			sig.arguments = append(sig.arguments, argument{param, t, len(sig.names)}, argument{function.Params[i+1], length, -1})
+			if sig.count < 0 {
+				sig.count = i + 1
+			}
+			sig.names = append(sig.names, param.Name)
+			i++
+			continue
+		}
+		sig.arguments = append(sig.arguments, argument{param, t, len(sig.names)})
+		sig.names = append(sig.names, param.Name)
+	}
+
+	if sig.result.kind == kindStructPointer && sig.count >= 0 && g.shape(sig.result.elem) == shapeDict {
+		sig.arrayFree = g.arrayFrees[sig.result.elem]
+	}
+	return sig, nil
+}
+
+// Writes the wrapper of a function, or returns why it can't have one
+func (g *generator) writeFunction(out *strings.Builder, function Function) (string, error) {
+	sig, err := g.signature(function)
+	if err != nil {
+		return "", err
+	}
+	arguments, names, result := sig.arguments, sig.names, sig.result
+	count := fmt.Sprintf("(size_t)a%d", sig.count)
+
+	doc := fmt.Sprintf("%s($module, %s)\n--\n\n%s", function.Name, strings.Join(append(names, "/"), ", "), function.Comment)
+	if len(names) == 0 {
+		doc = fmt.Sprintf("%s($module, /)\n--\n\n%s", function.Name, function.Comment)
+	}
+	fmt.Fprintf(out, "PyDoc_STRVAR(cgohelper_doc_%s, %s);\n\n", function.Name, cString(doc))
+	fmt.Fprintf(out, "static PyObject* cgohelper_wrap_%s(PyObject* module, PyObject* const* args, Py_ssize_t nargs) {\n", function.Name)
+	fmt.Fprintf(out, "    PyObject* keep = NULL;\n    PyObject* result = NULL;\n")
+	for i, a := range arguments {
+		switch a.t.kind {
+		case kindStringArray, kindScalarArray:
+			fmt.Fprintf(out, "    %s a%d = NULL;\n", a.t.name, i)
+		case kindStructPointer:
+			fmt.Fprintf(out, "    %s a%d_value;\n    %s* a%d = NULL;\n", a.t.elem, i, a.t.elem, i)
+		default:
+			fmt.Fprintf(out, "    %s a%d;\n", a.t.name, i)
+		}
+	}
+	if result.kind != kindVoid {
+		fmt.Fprintf(out, "    %s r;\n", result.name)
+	}
+	fmt.Fprintf(out, "    (void)module;\n")
+	fmt.Fprintf(out, "    if (nargs != %d) {\n", len(names))
+	fmt.Fprintf(out, "        PyErr_Format(PyExc_TypeError, \"%s() takes %d arguments (%%zd given)\", nargs);\n        return NULL;\n    }\n", function.Name, len(names))
+	// Arguments are kept alive by the caller, but lists and dicts can change while the GIL is released,
+	// so the objects borrowed from them are kept until the call returns
+	needsKeep := false
+	for _, a := range arguments {
+		needsKeep = needsKeep || (a.t.kind >= kindStruct && a.t.kind <= kindScalarArray)
+	}
+	if needsKeep {
+		fmt.Fprintf(out, "    keep = PyList_New(0);\n    if (keep == NULL) return NULL;\n")
+	}
+	for i, a := range arguments {
+		if a.python < 0 {
+			continue
+		}
+		src := fmt.Sprintf("args[%d]", a.python)
+		switch a.t.kind {
+		case kindStringArray, kindScalarArray:
+			length := arguments[i+1].t
+			fmt.Fprintf(out, "    {\n")
+			fmt.Fprintf(out, "        PyObject* sequence = PySequence_Tuple(%s);\n", src)
+			fmt.Fprintf(out, "        Py_ssize_t n;\n")
+			fmt.Fprintf(out, "        if (sequence == NULL) goto fail;\n")
+			fmt.Fprintf(out, "        if (PyList_Append(keep, sequence) < 0) {\n            Py_DECREF(sequence);\n            goto fail;\n        }\n")
+			fmt.Fprintf(out, "        Py_DECREF(sequence);\n")
+			fmt.Fprintf(out, "        n = PyTuple_GET_SIZE(sequence);\n")
+			fmt.Fprintf(out, "        if ((long long)(%s)n != (long long)n) {\n            PyErr_SetString(PyExc_OverflowError, %s);\n            goto fail;\n        }\n",
+				length.name, cString(a.param.Name+" has too many items"))
+			fmt.Fprintf(out, "        a%d = PyMem_Malloc(sizeof(*a%d) * (n > 0 ? (size_t)n : 1));\n", i, i)
+			fmt.Fprintf(out, "        if (a%d == NULL) {\n            PyErr_NoMemory();\n            goto fail;\n        }\n", i)
+			fmt.Fprintf(out, "        for (Py_ssize_t j = 0; j < n; j++) {\n")
+			// The tuple keeps the items alive, so strings don't need to be kept
+			fmt.Fprintf(out, "            %s\n", g.fromPython(g.resolve(a.t.elem), "PyTuple_GET_ITEM(sequence, j)", fmt.Sprintf("a%d[j]", i), "NULL"))
+			fmt.Fprintf(out, "        }\n")
+			fmt.Fprintf(out, "        a%d = (%s)n;\n", i+1, length.name)
+			fmt.Fprintf(out, "    }\n")
+		case kindStructPointer:
+			fmt.Fprintf(out, "    if (%s != Py_None) {\n", src)
+			fmt.Fprintf(out, "        %s\n", g.fromPython(cType{kind: kindStruct, name: a.t.elem, elem: a.t.elem}, src, fmt.Sprintf("a%d_value", i), "keep"))
+			fmt.Fprintf(out, "        a%d = &a%d_value;\n    }\n", i, i)
+		default:
+			fmt.Fprintf(out, "    %s\n", g.fromPython(a.t, src, fmt.Sprintf("a%d", i), "NULL"))
+		}
+	}
+
+	call := make([]string, len(arguments))
+	for i := range arguments {
+		call[i] = fmt.Sprintf("a%d", i)
+	}
+	fmt.Fprintf(out, "    Py_BEGIN_ALLOW_THREADS\n")
+	if result.kind == kindVoid {
+		fmt.Fprintf(out, "    %s(%s);\n", function.Name, strings.Join(call, ", "))
+	} else {
+		fmt.Fprintf(out, "    r = %s(%s);\n", function.Name, strings.Join(call, ", "))
+	}
+	fmt.Fprintf(out, "    Py_END_ALLOW_THREADS\n")
+
+	switch {
+	case result.kind == kindVoid:
+		fmt.Fprintf(out, "    Py_INCREF(Py_None);\n    result = Py_None;\n")
+	case result.kind == kindString:
+		fmt.Fprintf(out, "    result = cgohelper_from_string(r);\n")
+		if g.stringFree != "" {
+			fmt.Fprintf(out, "    if (r != NULL) %s(r);\n", g.stringFree)
+		} else {
+			fmt.Fprintf(out, "    free(r);\n")
+		}
+	case result.kind == kindStruct:
+		fmt.Fprintf(out, "    result = %s;\n", g.toPython(result, "r"))
+		fmt.Fprintf(out, "    %s\n", g.freeFields(result, "r"))
+	case sig.arrayFree != "":
+		g.used[result.elem] = true
+		fmt.Fprintf(out, "    if (r == NULL) {\n        Py_INCREF(Py_None);\n        result = Py_None;\n    } else {\n")
+		fmt.Fprintf(out, "        result = PyList_New((Py_ssize_t)%s);\n", count)
+		fmt.Fprintf(out, "        for (size_t j = 0; result != NULL && j < %s; j++) {\n", count)
+		fmt.Fprintf(out, "            PyObject* item = cgohelper_to_python_%s(&r[j]);\n", result.elem)
+		fmt.Fprintf(out, "            if (item == NULL) {\n                Py_CLEAR(result);\n                break;\n            }\n")
+		fmt.Fprintf(out, "            PyList_SET_ITEM(result, (Py_ssize_t)j, item);\n        }\n")
+		fmt.Fprintf(out, "        %s(r, a%d);\n    }\n", sig.arrayFree, sig.count)
+	case result.kind == kindStructPointer:
+		g.used[result.elem] = true
+		fmt.Fprintf(out, "    if (r == NULL) {\n        Py_INCREF(Py_None);\n        result = Py_None;\n    } else {\n")
+		fmt.Fprintf(out, "        result = cgohelper_to_python_%s(r);\n", result.elem)
+		fmt.Fprintf(out, "        cgohelper_free_%s(r);\n    }\n", result.elem)
+	default:
+		fmt.Fprintf(out, "    result = %s;\n", g.toPython(result, "r"))
+	}
+
+	fmt.Fprintf(out, "fail:\n")
+	for i, a := range arguments {
+		if a.t.kind == kindStringArray || a.t.kind == kindScalarArray {
+			fmt.Fprintf(out, "    PyMem_Free(a%d);\n", i)
+		}
+	}
+	fmt.Fprintf(out, "    Py_XDECREF(keep);\n    return result;\n}\n\n")
+	return function.Name, nil
+}
+
+const prelude = `#define PY_SSIZE_T_CLEAN
+#include <Python.h>
+#include <stdlib.h>
+#include <string.h>
+#include %s
+
+// Borrows the UTF-8 of a str (or bytes) for the length of a call, keep holds a reference to it (NULL if
+// something else already does)
+static int cgohelper_as_string(PyObject* object, PyObject* keep, const char** out) {
+    Py_ssize_t length;
+    if (object == Py_None) {
+        *out = NULL;
+        return 0;
+    }
+    if (PyUnicode_Check(object)) {
+        *out = PyUnicode_AsUTF8AndSize(object, &length);
+    } else if (PyBytes_Check(object)) {
+        *out = PyBytes_AS_STRING(object);
+        length = PyBytes_GET_SIZE(object);
+    } else {
+        PyErr_Format(PyExc_TypeError, "expected str, bytes or None, got %%s", Py_TYPE(object)->tp_name);
+        return -1;
+    }
+    if (*out == NULL) return -1;
+    if ((Py_ssize_t)strlen(*out) != length) {
+        PyErr_SetString(PyExc_ValueError, "embedded null character");
+        return -1;
+    }
+    return keep == NULL ? 0 : PyList_Append(keep, object);
+}
+
+// Decodes a C string (invalid UTF-8 is replaced), NULL is None
+static PyObject* cgohelper_from_string(const char* value) {
+    if (value == NULL) {
+        Py_INCREF(Py_None);
+        return Py_None;
+    }
+    return PyUnicode_DecodeUTF8(value, (Py_ssize_t)strlen(value), "replace");
+}
+
+`
+
+// A generator for a header, with the exported functions that free it's structs and strings found
+func newGenerator(header Header) *generator {
+	g := &generator{header: header, structs: map[string]Struct{}, frees: map[string]string{}, arrayFrees: map[string]string{}, used: map[string]bool{}, parsing: map[string]bool{}, declared: map[string]bool{}}
+	for _, definition := range header.Structs {
+		g.structs[definition.Name] = definition
+	}
+	for _, function := range header.Functions {
+		if !strings.Contains(function.Name, "free") || function.Return != "void" {
+			continue
+		}
+		params := function.Params
+		if function.Name == "helper_free_c_string" || function.Name == "free_c_string" {
+			g.stringFree = function.Name
+			continue
+		}
+		for name := range g.structs {
+			pointer := len(params) > 0 && (params[0].Type == name+"*" || (params[0].Type == "void*" && strings.HasSuffix(function.Name, "free_"+snake(name))))
+			switch {
+			case pointer && len(params) == 1:
+				g.frees[name] = function.Name
+			case pointer && len(params) == 2 && params[0].Type == name+"*" && g.resolve(params[1].Type).kind >= kindSigned && g.resolve(params[1].Type).kind <= kindUnsigned:
+				g.arrayFrees[name] = function.Name
+			}
+		}
+	}
+	return g
+}
+
+// Generates the C source of a CPython extension module, with a function for each exported function
+// of a header that has arguments and a result that can be converted
+//
+// Parameters:
+//   - module: The name of the module (what python imports), a C identifier.
+//   - headerFile: The file name of the header, to #include.
+//   - header: The parsed header (see ParseHeader()).
+//
+// Returns:
+//   - The module, with the functions that were wrapped and why the others weren't.
+//   - An error wrapping ErrInvalidModule if module isn't a C identifier.
+func Generate(module, headerFile string, header Header) (Glue, error) {
+	if !identifier.MatchString(module) {
+		return Glue{}, fmt.Errorf("%w: %q isn't a C identifier", ErrInvalidModule, module)
+	}
+	g := newGenerator(header)
+	glue := Glue{}
+	var wrappers strings.Builder
+	for _, function := range header.Functions {
+		var body strings.Builder
+		name, err := g.writeFunction(&body, function)
+		if err != nil {
+			glue.Skipped = append(glue.Skipped, Skipped{function.Name, err.Error()})
+			continue
+		}
+		wrappers.WriteString(body.String())
+		glue.Wrapped = append(glue.Wrapped, name)
+	}
+
+	// Structs are converted in header order, which C needs to be the order they depend on each other
+	var source strings.Builder
+	fmt.Fprintf(&source, "// Code generated by cgohelper extension from %s. DO NOT EDIT.\n\n", headerFile)
+	fmt.Fprintf(&source, prelude, cString(headerFile))
+	var converters strings.Builder
+	for i := len(header.Structs) - 1; i >= 0; i-- {
+		// Marks the structs nested in the ones that are used as used too
+		definition := header.Structs[i]
+		if g.used[definition.Name] || g.parsing[definition.Name] {
+			var discard strings.Builder
+			g.writeStruct(&discard, definition)
+		}
+	}
+	for _, definition := range header.Structs {
+		if g.used[definition.Name] || g.parsing[definition.Name] {
+			g.writeStruct(&converters, definition)
+		}
+	}
+	source.WriteString(converters.String())
+	source.WriteString(wrappers.String())
+
+	fmt.Fprintf(&source, "static PyMethodDef cgohelper_methods[] = {\n")
+	for _, name := range glue.Wrapped {
+		fmt.Fprintf(&source, "    {%s, (PyCFunction)(void (*)(void))cgohelper_wrap_%s, METH_FASTCALL, cgohelper_doc_%s},\n", cString(name), name, name)
+	}
+	fmt.Fprintf(&source, "    {NULL, NULL, 0, NULL},\n};\n\n")
+	fmt.Fprintf(&source, "static struct PyModuleDef cgohelper_module = {\n    PyModuleDef_HEAD_INIT, %s, %s, -1, cgohelper_methods,\n};\n\n",
+		cString(module), cString("Functions exported from Go, converted to python objects in C (generated from "+headerFile+")"))
+	fmt.Fprintf(&source, "PyMODINIT_FUNC PyInit_%s(void) {\n    return PyModule_Create(&cgohelper_module);\n}\n", module)
+	glue.Source = []byte(source.String())
+	return glue, nil
+}
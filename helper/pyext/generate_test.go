@@ -0,0 +1,90 @@
+package pyext
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnake(t *testing.T) {
+	for test_input, expected := range map[string]string{"Site": "site", "StringArrayResult": "string_array_result", "HTTPResponse": "http_response", "Item2D": "item2_d"} {
+		if temp := snake(test_input); temp != expected {
+			t.Errorf("TestSnake:snake(%q): %q!=%q", test_input, temp, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	glue, err := Generate("scraping_ext", "scraping_ext.h", ParseHeader([]byte(scrapingHeader)))
+	if err != nil {
+		t.Fatalf("TestGenerate:Generate(): %v", err)
+	}
+	if expected := []string{"parse_urls", "scrape_single_url", "count_sites"}; !reflect.DeepEqual(glue.Wrapped, expected) {
+		t.Errorf("TestGenerate:Generate() wrapped %v!=%v", glue.Wrapped, expected)
+	}
+	skipped := map[string]bool{}
+	for _, function := range glue.Skipped {
+		skipped[function.Name] = true
+	}
+	if !skipped["free_site"] || !skipped["free_sites"] || len(glue.Skipped) != 2 {
+		t.Errorf("TestGenerate:Generate() skipped %+v", glue.Skipped)
+	}
+
+	source := string(glue.Source)
+	for _, expected := range []string{
+		`#include "scraping_ext.h"`,
+		"PyMODINIT_FUNC PyInit_scraping_ext(void)",
+		"free_sites(r, a1);",                    // parse_urls returns a list, freed with free_sites
+		"free_site(value);",                     // scrape_single_url returns a dict, freed with free_site
+		"Py_BEGIN_ALLOW_THREADS",                // The GIL is released while Go runs
+		`PyDict_SetItemString(result, "domain"`, // Structs are dicts
+		"PyLong_FromLongLong((long long)(r))",   // typedefs are resolved
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("TestGenerate:Generate() doesn't have %q", expected)
+		}
+	}
+
+	if _, err := Generate("scraping-ext", "scraping_ext.h", Header{}); !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("TestGenerate:Generate(scraping-ext): %v, expected ErrInvalidModule", err)
+	}
+}
+
+func TestGenerateShapes(t *testing.T) {
+	header := Header{
+		Structs: []Struct{
+			{"IntArrayResult", []Param{{"numberOfElements", "size_t"}, {"data", "int*"}}},
+			{"NullableIntArrayResult", []Param{{"numberOfElements", "size_t"}, {"data", "int*"}, {"validity", "unsigned char*"}}},
+			{"KeyValueResult", []Param{{"numberOfElements", "size_t"}, {"keys", "char**"}, {"values", "double*"}}},
+			{"BigIntResult", []Param{{"length", "size_t"}, {"data", "unsigned char*"}}},
+		},
+		Functions: []Function{
+			{Name: "ints", Return: "IntArrayResult*", Params: []Param{{"values", "int*"}, {"count", "size_t"}}},
+			{Name: "nullable", Return: "NullableIntArrayResult*"},
+			{Name: "metrics", Return: "KeyValueResult*"},
+			{Name: "big", Return: "BigIntResult*"},
+			{Name: "no_length", Params: []Param{{"values", "int*"}}, Return: "void"},
+			{Name: "helper_free_int_array_result", Params: []Param{{"ptr", "void*"}}, Return: "void"},
+			{Name: "helper_free_os_memory", Return: "void"},
+		},
+	}
+	glue, err := Generate("shapes", "shapes.h", header)
+	if err != nil {
+		t.Fatalf("TestGenerateShapes:Generate(): %v", err)
+	}
+	if expected := []string{"ints", "nullable", "metrics", "helper_free_os_memory"}; !reflect.DeepEqual(glue.Wrapped, expected) {
+		t.Errorf("TestGenerateShapes:Generate() wrapped %v!=%v, skipped %+v", glue.Wrapped, expected, glue.Skipped)
+	}
+	source := string(glue.Source)
+	for _, expected := range []string{
+		"helper_free_int_array_result(value);",    // The exported free function is used when there is one
+		"value->validity[i / 8] & (1 << (i % 8))", // Nullable arrays have None
+		"PyDict_SetItem(result, key, item)",       // Key value results are dicts
+		"free(value->validity);",                  // And structs without one are freed field by field
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("TestGenerateShapes:Generate() doesn't have %q", expected)
+		}
+	}
+}
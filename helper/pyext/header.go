@@ -0,0 +1,142 @@
+package pyext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A function parameter or struct field
+type Param struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // The C type without const, and with no spaces before *'s (i.e. "char*", "long long int")
+}
+
+// An exported function declared in a cgo header
+type Function struct {
+	Name    string  `json:"name"`
+	Return  string  `json:"return"` // The C return type ("void" for none)
+	Params  []Param `json:"params"`
+	Comment string  `json:"comment,omitempty"` // The first line of the Go doc comment
+}
+
+// A struct typedef from the preamble of a cgo header (i.e. typedef struct { ... } Site;)
+type Struct struct {
+	Name   string  `json:"name"`
+	Fields []Param `json:"fields"`
+}
+
+// The declarations of a cgo header that the glue is generated from
+type Header struct {
+	Functions []Function        `json:"functions"`
+	Structs   []Struct          `json:"structs"`
+	Typedefs  map[string]string `json:"typedefs"` // Other typedefs from the preamble (i.e. "Duration": "int64_t")
+}
+
+var (
+	blockComment    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	lineComment     = regexp.MustCompile(`//[^\n]*`)
+	lineDirective   = regexp.MustCompile(`(?m)^#.*$`)
+	structTypedef   = regexp.MustCompile(`typedef\s+struct\s*\w*\s*\{([^{}]*)\}\s*(\w+)\s*;`)
+	scalarTypedef   = regexp.MustCompile(`typedef\s+([\w\s\*]+?)\s*\b(\w+)\s*;`)
+	externFunction  = regexp.MustCompile(`^extern\s+(?:__declspec\(dllexport\)\s+)?(.+?)\s*\b(\w+)\s*\((.*)\)\s*;$`)
+	declaration     = regexp.MustCompile(`^(.*?)\s*\b(\w+)$`)
+	spaceBeforeStar = regexp.MustCompile(`\s*\*`)
+)
+
+// Normalizes a C type, so the same type is always spelled the same way (i.e. "const char *" is "char*")
+func normalizeType(t string) string {
+	t = strings.Join(strings.Fields(strings.ReplaceAll(" "+t+" ", " const ", " ")), " ")
+	return spaceBeforeStar.ReplaceAllString(t, "*")
+}
+
+// Splits a declaration (i.e. "char* url" or "char *url") into a Param
+func parseDeclaration(text string) (Param, bool) {
+	text = strings.TrimSpace(text)
+	match := declaration.FindStringSubmatch(text)
+	if match == nil || strings.TrimSpace(match[1]) == "" {
+		return Param{}, false
+	}
+	return Param{Name: match[2], Type: normalizeType(match[1])}, true
+}
+
+// Parses the exported functions, and the typedefs of the preamble, of a header written by go build
+// -buildmode=c-archive (or c-shared)
+//
+// Parameters:
+//   - data: The header.
+//
+// Returns:
+//   - The declarations, functions with a declaration that can't be parsed (i.e. function pointer
+//     parameters) are left out.
+func ParseHeader(data []byte) Header {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	header := Header{Typedefs: map[string]string{}}
+
+	preamble := text
+	if start := strings.Index(text, "Start of preamble"); start >= 0 {
+		preamble = text[start:]
+		if end := strings.Index(preamble, "End of preamble"); end >= 0 {
+			preamble = preamble[:end]
+		}
+		preamble = preamble[strings.Index(preamble, "\n")+1:]
+	}
+	preamble = lineDirective.ReplaceAllString(lineComment.ReplaceAllString(blockComment.ReplaceAllString(preamble, ""), ""), "")
+	for _, match := range structTypedef.FindAllStringSubmatch(preamble, -1) {
+		definition := Struct{Name: match[2]}
+		for _, field := range strings.Split(match[1], ";") {
+			if strings.TrimSpace(field) == "" {
+				continue
+			}
+			if param, ok := parseDeclaration(field); ok {
+				definition.Fields = append(definition.Fields, param)
+			} else {
+				// Keeps the struct, but with a field type nothing converts (i.e. an array field)
+				definition.Fields = append(definition.Fields, Param{Name: "?", Type: normalizeType(field)})
+			}
+		}
+		header.Structs = append(header.Structs, definition)
+	}
+	for _, match := range scalarTypedef.FindAllStringSubmatch(structTypedef.ReplaceAllString(preamble, ""), -1) {
+		if first := strings.Fields(match[1])[0]; first != "struct" && first != "enum" && first != "union" {
+			header.Typedefs[match[2]] = normalizeType(match[1])
+		}
+	}
+
+	var comment []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") {
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+			continue
+		}
+		match := externFunction.FindStringSubmatch(line)
+		if match == nil || strings.HasPrefix(match[2], "_") {
+			comment = nil
+			continue
+		}
+		function := Function{Name: match[2], Return: normalizeType(match[1])}
+		if len(comment) > 0 {
+			function.Comment = comment[0]
+		}
+		comment = nil
+		parsed := true
+		if params := strings.TrimSpace(match[3]); params != "" && params != "void" {
+			for i, text := range strings.Split(params, ",") {
+				param, ok := parseDeclaration(text)
+				if !ok {
+					// An unnamed parameter (i.e. "int")
+					param = Param{Name: "p" + strconv.Itoa(i), Type: normalizeType(text)}
+				}
+				if strings.ContainsAny(param.Type, "()[]") {
+					parsed = false
+				}
+				function.Params = append(function.Params, param)
+			}
+		}
+		if parsed {
+			header.Functions = append(header.Functions, function)
+		}
+	}
+	return header
+}
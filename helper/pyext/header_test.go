@@ -0,0 +1,80 @@
+package pyext
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The parts of the scraping example's header that matter (go build on windows writes __declspec)
+const scrapingHeader = `/* Code generated by cmd/cgo; DO NOT EDIT. */
+
+/* Start of preamble from import "C" comments.  */
+#line 3 "lib.go"
+
+#include <stdlib.h>
+
+typedef struct{
+	char* url;
+	char *domain; // The domain
+	const char* server;
+	int port;
+} Site;
+
+typedef long long Counter;
+
+#line 1 "cgo-generated-wrapper"
+
+/* End of preamble from import "C" comments.  */
+
+/* Start of boilerplate cgo prologue.  */
+typedef struct { void *data; GoInt len; GoInt cap; } GoSlice;
+extern size_t _GoStringLen(_GoString_ s);
+/* End of boilerplate cgo prologue.  */
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// C-callable wrapper that parses multiple URLs and returns C structs
+//
+// # Parameters
+extern __declspec(dllexport) Site* parse_urls(char** cUrls, int cCount);
+extern Site* scrape_single_url(char* cUrl);
+extern void free_site(Site* site);
+extern void free_sites(Site* sites, int count);
+extern Counter count_sites(void);
+extern void callback(void (*f)(int));
+
+#ifdef __cplusplus
+}
+#endif
+`
+
+func TestParseHeader(t *testing.T) {
+	header := ParseHeader([]byte(scrapingHeader))
+	expectedStructs := []Struct{{"Site", []Param{{"url", "char*"}, {"domain", "char*"}, {"server", "char*"}, {"port", "int"}}}}
+	if !reflect.DeepEqual(header.Structs, expectedStructs) {
+		t.Errorf("TestParseHeader:ParseHeader() structs: %+v!=%+v", header.Structs, expectedStructs)
+	}
+	if !reflect.DeepEqual(header.Typedefs, map[string]string{"Counter": "long long"}) {
+		t.Errorf("TestParseHeader:ParseHeader() typedefs: %+v", header.Typedefs)
+	}
+	expectedFunctions := []Function{
+		{"parse_urls", "Site*", []Param{{"cUrls", "char**"}, {"cCount", "int"}}, "C-callable wrapper that parses multiple URLs and returns C structs"},
+		{"scrape_single_url", "Site*", []Param{{"cUrl", "char*"}}, ""},
+		{"free_site", "void", []Param{{"site", "Site*"}}, ""},
+		{"free_sites", "void", []Param{{"sites", "Site*"}, {"count", "int"}}, ""},
+		{"count_sites", "Counter", nil, ""},
+	}
+	if !reflect.DeepEqual(header.Functions, expectedFunctions) {
+		t.Errorf("TestParseHeader:ParseHeader() functions: %+v!=%+v", header.Functions, expectedFunctions)
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	for test_input, expected := range map[string]string{"const char *": "char*", "char**": "char**", "long  long unsigned int": "long long unsigned int", "Site *": "Site*", "constant": "constant"} {
+		if temp := normalizeType(test_input); temp != expected {
+			t.Errorf("TestNormalizeType:normalizeType(%q): %q!=%q", test_input, temp, expected)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Builds Go packages into CPython extension modules: the package is built with -buildmode=c-archive,
+// and C glue generated from it's header converts arguments and results straight to python objects
+// (str, int, float, list and dict), which skips the per-call overhead of ctypes
+//
+// It can also generate cffi (API mode) bindings from the same header instead: the declarations for
+// ffi.cdef(), a build script that compiles them with the archive, and a python module with the same
+// conversions (results are freed with the exported free functions, like the helper's lib.py does)
+//
+// # Functions
+//
+//	ParseHeader(data []byte) Header{} // Parses the exported functions and struct typedefs of a cgo header
+//	Generate(module, headerFile string, header Header) (Glue, error){} // Generates the C source of an extension module from a header
+//	Build(ctx context.Context, c Config) (Result, error){} // Builds the archive, generates the glue and compiles the extension module
+//	GenerateCFFI(module, headerFile string, header Header) (Binding, error){} // Generates the cdef, build script and python module of a cffi binding
+//	BuildCFFI(ctx context.Context, c Config) (CFFIResult, error){} // Builds the archive and writes a cffi binding of it next to it
+//
+// # Conversions
+//
+//	int, size_t, int64_t etc.     <-> int (OverflowError if it doesn't fit)
+//	float, double                 <-> float
+//	bool                          <-> bool
+//	char*                         <-> str (bytes and None are accepted as arguments, results are freed)
+//	char**, int*, float* + length  -> list (one argument, the length is passed for you)
+//	struct / struct*              <-> dict of it's fields (None for NULL results, which are freed with the exported free_<struct>())
+//	struct* returned for an array  -> list[dict] (when there's an exported free_<struct>s(struct*, length) to free it)
+//	StringArrayResult* etc.        -> list (structs with numberOfElements and data, or keys and values for a dict)
+//
+// # Examples
+//
+// Build the scraping example into scraping_ext.cpython-311-x86_64-linux-gnu.so
+//
+//	result, err := pyext.Build(context.Background(), pyext.Config{
+//		Module: "scraping_ext",
+//		Source: "../examples/scraping/with-helper/scraping/go",
+//		Output: "../examples/scraping/with-helper/scraping",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.Output, result.Skipped)
+//
+// Generate a cffi binding of it instead (then python scraping_cffi_build.py compiles _scraping_cffi)
+//
+//	binding, err := pyext.BuildCFFI(context.Background(), pyext.Config{
+//		Module: "scraping_cffi",
+//		Source: "../examples/scraping/with-helper/scraping/go",
+//		Output: "../examples/scraping/with-helper/scraping",
+//	})
+package pyext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Descent098/cgo-python-helpers/build"
+)
+
+// Returned (wrapped) when the C compiler fails to compile or link the extension, the error includes it's output
+var ErrCompileFailed = errors.New("compiling the extension failed")
+
+// The configuration of an extension build
+type Config struct {
+	Module  string        // The name python imports the module as, a C identifier (i.e. "scraping_ext")
+	Source  string        // The Go package directory to build
+	Output  string        // The directory to write the module (and the archive, header and glue) to
+	Python  string        // The python the module is for, "" is python3 (python on windows)
+	CC      string        // The C compiler (i.e. "zig cc"), "" uses go's
+	Profile build.Profile // The Go compiler/linker flags, "" is build.ProfileRelease
+	Tags    []string      // Build tags (-tags)
+	Force   bool          // Build even if nothing changed
+}
+
+// The outcome of a Build
+type Result struct {
+	Output  string    `json:"output"`  // The extension module (i.e. scraping_ext.cpython-311-x86_64-linux-gnu.so)
+	Archive string    `json:"archive"` // The Go archive it links
+	Glue    string    `json:"glue"`    // The generated C source
+	Wrapped []string  `json:"wrapped"` // The functions the module has
+	Skipped []Skipped `json:"skipped"` // The exported functions it doesn't have, and why
+	Rebuilt bool      `json:"rebuilt"` // Whether the module was compiled (false if it was up to date)
+}
+
+// What's needed from python to compile an extension for it
+type pythonConfig struct {
+	Include  string `json:"include"`  // The directory Python.h is in
+	Suffix   string `json:"suffix"`   // The file extension of modules (i.e. ".cpython-311-x86_64-linux-gnu.so")
+	Platform string `json:"platform"` // sys.platform
+	LibDir   string `json:"lib_dir"`  // Where python3XY.lib is on windows
+	Library  string `json:"library"`  // The library to link on windows (i.e. "python311")
+}
+
+const pythonScript = `import json, os, sys, sysconfig
+print(json.dumps({
+    "include": sysconfig.get_paths()["include"],
+    "suffix": sysconfig.get_config_var("EXT_SUFFIX") or ".so",
+    "platform": sys.platform,
+    "lib_dir": os.path.join(sys.base_prefix, "libs"),
+    "library": "python%d%d" % sys.version_info[:2],
+}))`
+
+func queryPython(ctx context.Context, python string) (pythonConfig, error) {
+	var config pythonConfig
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, python, "-c", pythonScript)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return config, fmt.Errorf("%s: %w %s", python, err, strings.TrimSpace(stderr.String()))
+	}
+	return config, json.Unmarshal(output, &config)
+}
+
+// The compiler and linker arguments for an extension module on a platform
+func linkArgs(platform string) []string {
+	switch {
+	case platform == "darwin":
+		// Python's symbols are resolved when the module is loaded, and the Go runtime needs these frameworks
+		return []string{"-bundle", "-undefined", "dynamic_lookup", "-framework", "CoreFoundation", "-framework", "Security"}
+	case strings.HasPrefix(platform, "win"):
+		return []string{"-shared", "-lws2_32", "-lwinmm", "-lntdll"}
+	default:
+		return []string{"-shared", "-fPIC", "-lpthread"}
+	}
+}
+
+// Builds the package into <Output>/<Module>.a with -buildmode=c-archive, and parses the header cgo writes next to it
+func buildArchive(ctx context.Context, c Config) (Header, bool, error) {
+	if err := os.MkdirAll(c.Output, 0o755); err != nil {
+		return Header{}, false, err
+	}
+	archive, err := build.Build(ctx, build.Config{
+		Source:    c.Source,
+		Output:    filepath.Join(c.Output, c.Module+".a"),
+		BuildMode: "c-archive",
+		Profile:   c.Profile,
+		Tags:      c.Tags,
+		CC:        c.CC,
+		Force:     c.Force,
+	})
+	if err != nil {
+		return Header{}, false, err
+	}
+	data, err := os.ReadFile(filepath.Join(c.Output, c.Module+".h"))
+	if err != nil {
+		return Header{}, false, err
+	}
+	return ParseHeader(data), archive.Rebuilt, nil
+}
+
+// Writes a generated file, unless it already has the data (so it's modification time only changes with it)
+func writeIfChanged(path string, data []byte) (bool, error) {
+	if previous, err := os.ReadFile(path); err == nil && bytes.Equal(previous, data) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, data, 0o644)
+}
+
+// Builds a Go package into a CPython extension module, only compiling what changed since the last build
+//
+// Parameters:
+//   - ctx: Cancels the build.
+//   - c: The build configuration.
+//
+// Returns:
+//   - The result, with the functions that were and weren't wrapped.
+//   - An error wrapping ErrInvalidModule, build.ErrBuildFailed or ErrCompileFailed, or an error if python
+//     couldn't be run.
+func Build(ctx context.Context, c Config) (Result, error) {
+	result := Result{}
+	if !identifier.MatchString(c.Module) {
+		return result, fmt.Errorf("%w: %q isn't a C identifier", ErrInvalidModule, c.Module)
+	}
+	python := c.Python
+	if python == "" {
+		python = "python3"
+		if runtime.GOOS == "windows" {
+			python = "python"
+		}
+	}
+	info, err := queryPython(ctx, python)
+	if err != nil {
+		return result, err
+	}
+
+	result.Archive = filepath.Join(c.Output, c.Module+".a")
+	header, archiveRebuilt, err := buildArchive(ctx, c)
+	if err != nil {
+		return result, err
+	}
+	headerFile := c.Module + ".h"
+	glue, err := Generate(c.Module, headerFile, header)
+	if err != nil {
+		return result, err
+	}
+	result.Wrapped, result.Skipped = glue.Wrapped, glue.Skipped
+
+	result.Glue = filepath.Join(c.Output, c.Module+"module.c")
+	changed, err := writeIfChanged(result.Glue, glue.Source)
+	if err != nil {
+		return result, err
+	}
+	result.Output = filepath.Join(c.Output, c.Module+info.Suffix)
+	if _, err := os.Stat(result.Output); err == nil && !changed && !archiveRebuilt && !c.Force {
+		return result, nil
+	}
+
+	cc := strings.Fields(c.CC)
+	if len(cc) == 0 {
+		output, err := exec.CommandContext(ctx, "go", "env", "CC").Output()
+		if err != nil {
+			return result, fmt.Errorf("go env CC: %w", err)
+		}
+		cc = strings.Fields(string(output))
+	}
+	args := append(cc[1:], "-O2", "-I", info.Include, "-I", c.Output, "-o", result.Output, result.Glue, result.Archive)
+	if strings.HasPrefix(info.Platform, "win") {
+		args = append(args, "-L", info.LibDir, "-l"+info.Library)
+	}
+	args = append(args, linkArgs(info.Platform)...)
+	output, err := exec.CommandContext(ctx, cc[0], args...).CombinedOutput()
+	if err != nil {
+		return result, fmt.Errorf("%w: %v\n%s", ErrCompileFailed, err, strings.TrimSpace(string(output)))
+	}
+	result.Rebuilt = true
+	return result, nil
+}
@@ -0,0 +1,143 @@
+package pyext
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const itemsSource = `package main
+
+/*
+#include <stdlib.h>
+
+typedef struct {
+	char* name;
+	int count;
+	double price;
+} Item;
+
+typedef struct {
+	size_t numberOfElements;
+	double* data;
+} DoubleArrayResult;
+*/
+import "C"
+import "unsafe"
+
+//export greet
+func greet(name *C.char) *C.char {
+	return C.CString("hello " + C.GoString(name))
+}
+
+//export sum
+func sum(values *C.int, count C.int) C.longlong {
+	total := C.longlong(0)
+	for _, value := range unsafe.Slice(values, int(count)) {
+		total += C.longlong(value)
+	}
+	return total
+}
+
+//export doubled
+func doubled(values *C.double, count C.size_t) *C.DoubleArrayResult {
+	result := (*C.DoubleArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.DoubleArrayResult{}))))
+	result.numberOfElements = count
+	result.data = (*C.double)(C.malloc(C.size_t(count) * 8))
+	data := unsafe.Slice(result.data, int(count))
+	for i, value := range unsafe.Slice(values, int(count)) {
+		data[i] = value * 2
+	}
+	return result
+}
+
+//export make_items
+func make_items(names **C.char, count C.int) *C.Item {
+	items := unsafe.Slice((*C.Item)(C.malloc(C.size_t(count)*C.size_t(unsafe.Sizeof(C.Item{})))), int(count))
+	for i, name := range unsafe.Slice(names, int(count)) {
+		items[i] = C.Item{name: C.CString(C.GoString(name)), count: C.int(i), price: 1.5}
+	}
+	return &items[0]
+}
+
+//export free_items
+func free_items(items *C.Item, count C.int) {
+	for _, item := range unsafe.Slice(items, int(count)) {
+		C.free(unsafe.Pointer(item.name))
+	}
+	C.free(unsafe.Pointer(items))
+}
+
+//export restock
+func restock(item *C.Item, count C.int) *C.Item {
+	if item == nil {
+		return nil
+	}
+	result := (*C.Item)(C.malloc(C.size_t(unsafe.Sizeof(C.Item{}))))
+	*result = C.Item{name: C.CString(C.GoString(item.name)), count: item.count + count, price: item.price}
+	return result
+}
+
+func main() {}
+`
+
+const itemsScript = `
+import sys, inspect
+sys.path.insert(0, sys.argv[1])
+import items_ext as m
+assert m.greet("go") == "hello go", m.greet("go")
+assert m.greet(b"bytes") == "hello bytes"
+assert m.sum([1, 2, 3]) == 6 and m.sum(()) == 0
+assert m.doubled([1.5, -2]) == [3.0, -4.0]
+assert m.make_items(["a", "b"]) == [{"name": "a", "count": 0, "price": 1.5}, {"name": "b", "count": 1, "price": 1.5}]
+assert m.restock({"name": "x", "count": 2, "price": 3.0}, 5) == {"name": "x", "count": 7, "price": 3.0}
+assert m.restock(None, 5) is None
+for call, error in [(lambda: m.sum([2**40]), OverflowError), (lambda: m.greet(1), TypeError), (lambda: m.greet("a\0b"), ValueError),
+                    (lambda: m.restock({"name": "x"}, 1), KeyError), (lambda: m.sum(), TypeError)]:
+    try:
+        call()
+    except error:
+        pass
+    else:
+        raise AssertionError(f"{call} didn't raise {error}")
+assert str(inspect.signature(m.sum)) == "(values, /)"
+print("ok")
+`
+
+func TestBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds an extension module")
+	}
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 isn't installed")
+	}
+	source := t.TempDir()
+	os.WriteFile(filepath.Join(source, "go.mod"), []byte("module items\n\ngo 1.22\n"), 0o644)
+	os.WriteFile(filepath.Join(source, "items.go"), []byte(itemsSource), 0o644)
+	output := filepath.Join(t.TempDir(), "out")
+	config := Config{Module: "items_ext", Source: source, Output: output, Python: python}
+
+	result, err := Build(context.Background(), config)
+	if err != nil {
+		t.Fatalf("TestBuild:Build(): %v", err)
+	}
+	if !result.Rebuilt || len(result.Wrapped) != 5 || len(result.Skipped) != 1 {
+		t.Errorf("TestBuild:Build(): %+v", result)
+	}
+	if out, err := exec.Command(python, "-c", itemsScript, output).CombinedOutput(); err != nil || strings.TrimSpace(string(out)) != "ok" {
+		t.Errorf("TestBuild:Build() module doesn't work: %v\n%s", err, out)
+	}
+
+	if result, err = Build(context.Background(), config); err != nil || result.Rebuilt {
+		t.Errorf("TestBuild:Build() again: rebuilt %v, %v", result.Rebuilt, err)
+	}
+	config.Module = "items-ext"
+	if _, err := Build(context.Background(), config); !errors.Is(err, ErrInvalidModule) {
+		t.Errorf("TestBuild:Build(items-ext): %v, expected ErrInvalidModule", err)
+	}
+}
@@ -0,0 +1,139 @@
+// A registry that lets independent Go packages expose functions by name, so they can share a
+// single c-shared host library (and a single Go runtime) instead of each building their own
+//
+// # Functions
+//
+//	Register(module, name string, fn Function){} // Registers a function under a module namespace
+//	Lookup(module, name string) (Function, bool){} // Finds a registered function
+//	Call(module, name string, args []json.RawMessage) (any, error){} // Calls a registered function by name
+//	Modules() map[string][]string{} // Lists every registered module and it's functions
+//	DecodeArgs(args []json.RawMessage, targets ...any) error{} // Decodes the arguments of a call into the provided pointers
+//
+// # Examples
+//
+// Register functions from a package (i.e. scraping/scraping.go)
+//
+//	func init() {
+//		registry.Register("scraping", "parse_urls", func(args []json.RawMessage) (any, error) {
+//			var urls []string
+//			if err := registry.DecodeArgs(args, &urls); err != nil {
+//				return nil, err
+//			}
+//			return ParseURLs(urls), nil
+//		})
+//	}
+//
+// Then import each package into the host (a package main built with -buildmode=c-shared)
+//
+//	import (
+//		_ "example.com/scraping"
+//		_ "example.com/similarity"
+//	)
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// A function that can be called by name, arguments and results are JSON encoded
+//
+// Parameters:
+//   - args: The JSON encoded arguments sent by the caller, decode them with DecodeArgs.
+//
+// Returns:
+//   - Any JSON encodable value, and an error that is sent back to the caller as a string.
+type Function func(args []json.RawMessage) (any, error)
+
+var (
+	modulesLock sync.RWMutex
+	modules     = map[string]map[string]Function{}
+)
+
+// Registers a function under a module namespace, registering the same name twice replaces the function
+//
+// Parameters:
+//   - module: The namespace for the function (i.e. "scraping"), should be unique per package.
+//   - name: The name of the function within the module (i.e. "parse_urls").
+//   - fn: The function to run.
+func Register(module, name string, fn Function) {
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+	if modules[module] == nil {
+		modules[module] = map[string]Function{}
+	}
+	modules[module][name] = fn
+}
+
+// Finds a registered function
+//
+// Parameters:
+//   - module: The namespace the function was registered under.
+//   - name: The name of the function.
+//
+// Returns:
+//   - The function, and whether it was found.
+func Lookup(module, name string) (Function, bool) {
+	modulesLock.RLock()
+	defer modulesLock.RUnlock()
+	fn, ok := modules[module][name]
+	return fn, ok
+}
+
+// Calls a registered function by name
+//
+// Parameters:
+//   - module: The namespace the function was registered under.
+//   - name: The name of the function.
+//   - args: The JSON encoded arguments.
+//
+// Returns:
+//   - The result of the function, or an error if it isn't registered or fails.
+func Call(module, name string, args []json.RawMessage) (any, error) {
+	fn, ok := Lookup(module, name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s.%s", module, name)
+	}
+	return fn(args)
+}
+
+// Lists every registered module and it's functions
+//
+// Returns:
+//   - A map of module names to the sorted names of their functions.
+func Modules() map[string][]string {
+	modulesLock.RLock()
+	defer modulesLock.RUnlock()
+	result := make(map[string][]string, len(modules))
+	for module, functions := range modules {
+		names := make([]string, 0, len(functions))
+		for name := range functions {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		result[module] = names
+	}
+	return result
+}
+
+// Decodes the arguments of a call into the provided pointers, in order
+//
+// Parameters:
+//   - args: The JSON encoded arguments from the caller.
+//   - targets: Pointers to decode each argument into.
+//
+// Returns:
+//   - An error if the number of arguments doesn't match, or an argument can't be decoded.
+func DecodeArgs(args []json.RawMessage, targets ...any) error {
+	if len(args) != len(targets) {
+		return fmt.Errorf("expected %d arguments, got %d", len(targets), len(args))
+	}
+	for i, target := range targets {
+		if err := json.Unmarshal(args[i], target); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+	}
+	return nil
+}
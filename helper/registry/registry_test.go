@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	Register("test_math", "add", func(args []json.RawMessage) (any, error) {
+		var a, b int
+		if err := DecodeArgs(args, &a, &b); err != nil {
+			return nil, err
+		}
+		return a + b, nil
+	})
+	Register("test_math", "negate", func(args []json.RawMessage) (any, error) {
+		var a int
+		if err := DecodeArgs(args, &a); err != nil {
+			return nil, err
+		}
+		return -a, nil
+	})
+
+	result, err := Call("test_math", "add", []json.RawMessage{json.RawMessage("2"), json.RawMessage("3")})
+	if err != nil || result != 5 {
+		t.Errorf("TestRegistry:Call(test_math.add): %v, %v", result, err)
+	}
+	if _, err := Call("test_math", "add", []json.RawMessage{json.RawMessage(`"2"`), json.RawMessage("3")}); err == nil {
+		t.Errorf("TestRegistry:Call(test_math.add): expected a decoding error")
+	}
+	if _, err := Call("test_math", "add", nil); err == nil {
+		t.Errorf("TestRegistry:Call(test_math.add): expected an argument count error")
+	}
+	if _, err := Call("test_math", "does_not_exist", nil); err == nil {
+		t.Errorf("TestRegistry:Call(test_math.does_not_exist): expected an error")
+	}
+	if _, ok := Lookup("does_not_exist", "add"); ok {
+		t.Errorf("TestRegistry:Lookup(does_not_exist.add): found a function in an unregistered module")
+	}
+
+	if functions := Modules()["test_math"]; !slices.Equal(functions, []string{"add", "negate"}) {
+		t.Errorf("TestRegistry:Modules(): %v!=[add negate]", functions)
+	}
+}
@@ -0,0 +1,446 @@
+// Generates the skeleton of a new Go library with python bindings: the Go package (with the helper's
+// conversions), C struct typedefs, the python package that builds and loads it with get_library(),
+// and a round-trip test for each struct in both languages
+//
+// The helper is a package main (so it can be built as a library on it's own), which go can't import,
+// so its Go files are copied into the new package as helpers_*.go and its python package is copied
+// next to the bindings (the same layout as the examples)
+//
+// # Layout
+//
+//	<directory>/
+//		test_<name>.py          // Round-trip tests for pytest
+//		<name>/
+//			__init__.py         // Re-exports the bindings
+//			lib.py              // Builds/loads go/lib.so with get_library(), and the ctypes structs and bindings
+//			helpers/            // A copy of the helper (get_library(), prepare_string() etc.)
+//			go/
+//				go.mod          // module lib
+//				lib.go          // The C typedefs, Go structs, conversions and exported functions
+//				lib_test.go     // Round-trip tests for go test
+//				helpers_*.go    // The helper's Go files (conversions, and main())
+//
+// # Functions
+//
+//	ParseStruct(spec string) (Struct, error){} // Parses a struct definition (i.e. "Point:x=int,y=float,label=string")
+//	FindHelper(ctx context.Context) (string, error){} // Finds the directory of the helper module go would use
+//	Generate(c Config) ([]string, error){} // Writes a new project, returns the files it wrote
+//
+// # Examples
+//
+// Create ./geometry with a Point struct
+//
+//	point, err := scaffold.ParseStruct("Point:x=int,y=float,label=string")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	files, err := scaffold.Generate(scaffold.Config{Name: "geometry", Directory: ".", HelperDir: "../helper", Structs: []scaffold.Struct{point}})
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// The import path of the helper module
+const HelperModule = "github.com/Descent098/cgo-python-helpers"
+
+// The struct used when none are specified, so a new project has something to round-trip
+const DefaultStruct = "Item:name=string,count=int,price=float,available=bool"
+
+// Returned (wrapped) for project, struct or field names that can't be used in Go, C and python
+var ErrInvalidName = errors.New("invalid name")
+
+// Returned (wrapped) for a struct definition that can't be parsed
+var ErrInvalidSpec = errors.New("invalid struct definition")
+
+// Returned (wrapped) when the project directory already exists and Config.Force isn't set
+var ErrExists = errors.New("project already exists")
+
+//go:embed templates
+var templates embed.FS
+
+// A field type, and how it's spelled in each language
+type FieldType struct {
+	Name   string // The name used in struct definitions (i.e. "float")
+	C      string // The C type (i.e. "double")
+	Go     string // The Go type (i.e. "float64")
+	Ctypes string // The ctypes type (i.e. "c_double")
+	Python string // The python type hint (i.e. "float")
+}
+
+// The supported field types, every one round-trips without losing precision
+var FieldTypes = []FieldType{
+	{"int", "int", "int32", "c_int", "int"},
+	{"int64", "int64_t", "int64", "c_int64", "int"},
+	{"float", "double", "float64", "c_double", "float"},
+	{"string", "char*", "string", "c_char_p", "str"},
+	{"bool", "bool", "bool", "c_bool", "bool"},
+}
+
+// A field of a Struct
+type Field struct {
+	Name string
+	Type FieldType
+}
+
+// A struct that's defined in C, with a matching Go struct and python dataclass
+type Struct struct {
+	Name   string
+	Fields []Field
+}
+
+// The configuration of a new project
+type Config struct {
+	Name      string   // The python package name (i.e. "geometry")
+	Directory string   // The directory to create the project in
+	HelperDir string   // The helper module to copy (see FindHelper())
+	Structs   []Struct // The structs to generate, DefaultStruct if empty
+	Force     bool     // Overwrite the files of an existing project
+}
+
+// C, Go and python keywords, which can't be used as names in the generated code
+var keywords = []string{
+	// C
+	"auto", "char", "const", "double", "enum", "extern", "float", "int", "long", "register", "short", "signed",
+	"sizeof", "static", "struct", "typedef", "union", "unsigned", "void", "volatile", "while", "bool",
+	// python
+	"False", "None", "True", "and", "as", "assert", "async", "await", "class", "def", "del", "elif", "except",
+	"finally", "from", "global", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "try", "with", "yield",
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Checks that a name is an identifier in Go, C and python
+func checkIdentifier(kind, name string) error {
+	if !identifier.MatchString(name) || token.IsKeyword(name) || slices.Contains(keywords, name) {
+		return fmt.Errorf("%w: %s %q must be a letter or _ followed by letters, digits or _, and not a Go, C or python keyword", ErrInvalidName, kind, name)
+	}
+	return nil
+}
+
+// Parses a struct definition
+//
+// Parameters:
+//   - spec: The struct name, and it's fields as name=type (types are the names in FieldTypes), i.e. "Point:x=int,y=float,label=string".
+//
+// Returns:
+//   - The struct.
+//   - An error wrapping ErrInvalidSpec (or ErrInvalidName) if spec can't be parsed.
+func ParseStruct(spec string) (Struct, error) {
+	name, fields, found := strings.Cut(spec, ":")
+	if !found || strings.TrimSpace(fields) == "" {
+		return Struct{}, fmt.Errorf("%w: %q, expected Name:field=type,field=type", ErrInvalidSpec, spec)
+	}
+	result := Struct{Name: strings.TrimSpace(name)}
+	if err := checkIdentifier("struct", result.Name); err != nil {
+		return Struct{}, err
+	}
+	if !unicode.IsUpper(rune(result.Name[0])) {
+		return Struct{}, fmt.Errorf("%w: struct %q must start with an uppercase letter", ErrInvalidName, result.Name)
+	}
+	for _, field := range strings.Split(fields, ",") {
+		fieldName, typeName, found := strings.Cut(field, "=")
+		fieldName, typeName = strings.TrimSpace(fieldName), strings.TrimSpace(typeName)
+		if !found {
+			return Struct{}, fmt.Errorf("%w: field %q of %s, expected field=type", ErrInvalidSpec, field, result.Name)
+		}
+		if err := checkIdentifier("field", fieldName); err != nil {
+			return Struct{}, err
+		}
+		index := slices.IndexFunc(FieldTypes, func(t FieldType) bool { return t.Name == typeName })
+		if index == -1 {
+			return Struct{}, fmt.Errorf("%w: field %s of %s has unknown type %q", ErrInvalidSpec, fieldName, result.Name, typeName)
+		}
+		if slices.ContainsFunc(result.Fields, func(f Field) bool { return f.Name == fieldName }) {
+			return Struct{}, fmt.Errorf("%w: %s has two fields named %s", ErrInvalidSpec, result.Name, fieldName)
+		}
+		result.Fields = append(result.Fields, Field{fieldName, FieldTypes[index]})
+	}
+	return result, nil
+}
+
+// Finds the directory of the helper module, as resolved by the go.mod of the current directory
+// (which is the helper itself when run with go run ./cmd/cgohelper)
+//
+// Parameters:
+//   - ctx: Cancels go list.
+//
+// Returns:
+//   - The directory.
+//   - An error if the current module doesn't use the helper.
+func FindHelper(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{.Dir}}", HelperModule)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to find %s (pass the helper directory instead): %w: %s", HelperModule, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Whether a file of the helper is part of a copy of it (not tests or build outputs)
+func copied(name string) bool {
+	for _, pattern := range []string{"*_test.go", "test_*.py", "*.so", "*.dll", "*.dylib", "*.h", "*.build.json", "cgohelper", "cgohelper.exe"} {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Copies the helper into the helpers/ directory of a project
+func copyHelper(helperDir, destination string, written *[]string) error {
+	return filepath.WalkDir(helperDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == "__pycache__" || entry.Name() == "testdata" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !copied(entry.Name()) {
+			return nil
+		}
+		relative, err := filepath.Rel(helperDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relative)
+		if err := copyFile(path, target); err != nil {
+			return err
+		}
+		*written = append(*written, target)
+		return nil
+	})
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// Files in the module cache are read only, so don't keep their mode
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// The Go files of the helper's package main, which are copied into the project's package
+func helperGoFiles(helperDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(helperDir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, func(name string) bool { return strings.HasSuffix(name, "_test.go") })
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no Go files, is it the helper module?", helperDir)
+	}
+	return files, nil
+}
+
+var cTypedef = regexp.MustCompile(`}\s*([A-Za-z_][A-Za-z0-9_]*)\s*;|typedef\s+[^;{}]*?([A-Za-z_][A-Za-z0-9_]*)\s*;`)
+
+// The top level names (and C typedefs) declared by the helper, which structs can't reuse since they share a package
+func helperNames(files []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	fileSet := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fileSet, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				names[decl.Name.Name] = true
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						names[spec.Name.Name] = true
+					case *ast.ValueSpec:
+						for _, ident := range spec.Names {
+							names[ident.Name] = true
+						}
+					case *ast.ImportSpec:
+						if spec.Path.Value == `"C"` && decl.Doc != nil {
+							for _, match := range cTypedef.FindAllStringSubmatch(decl.Doc.Text(), -1) {
+								names[match[1]+match[2]] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// The data passed to the templates
+type templateData struct {
+	Name    string
+	Structs []Struct
+}
+
+var templateFunctions = template.FuncMap{
+	"lower": func(name string) string { return strings.ToLower(name[:1]) + name[1:] },
+	"snake": snakeCase,
+	// A value of each type for round-trip tests, chosen to catch truncation (i.e. int64 stored in a double, or non-ASCII text)
+	"sampleGo": func(f Field) string {
+		return map[string]string{"int": "-2147483648", "int64": "9007199254740993", "float": "0.1", "string": `"héllo, 世界"`, "bool": "true"}[f.Type.Name]
+	},
+	"samplePython": func(f Field) string {
+		return map[string]string{"int": "-2147483648", "int64": "9007199254740993", "float": "0.1", "string": `"héllo, 世界"`, "bool": "True"}[f.Type.Name]
+	},
+}
+
+// Converts a struct name to snake case (i.e. "HTTPRequest" to "http_request")
+func snakeCase(name string) string {
+	var result strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) && runes[i-1] != '_' {
+			result.WriteRune('_')
+		}
+		result.WriteRune(unicode.ToLower(r))
+	}
+	return result.String()
+}
+
+// Writes a new project
+//
+// Parameters:
+//   - c: The configuration, Name and HelperDir are required.
+//
+// Returns:
+//   - The files that were written.
+//   - An error wrapping ErrInvalidName if a name can't be used, ErrExists if the project exists and c.Force isn't set,
+//     or an error from reading the helper or writing the files.
+func Generate(c Config) ([]string, error) {
+	if err := checkIdentifier("project", c.Name); err != nil {
+		return nil, err
+	}
+	if slices.Contains([]string{"helpers", "go", "lib"}, c.Name) {
+		return nil, fmt.Errorf("%w: project %q is used by the generated files", ErrInvalidName, c.Name)
+	}
+	if c.HelperDir == "" {
+		return nil, errors.New("no helper directory")
+	}
+	structs := c.Structs
+	if len(structs) == 0 {
+		item, err := ParseStruct(DefaultStruct)
+		if err != nil {
+			return nil, err
+		}
+		structs = []Struct{item}
+	}
+
+	goFiles, err := helperGoFiles(c.HelperDir)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := helperNames(goFiles)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, s := range structs {
+		if reserved[s.Name] || seen[s.Name] {
+			return nil, fmt.Errorf("%w: struct %q is already declared by the helper or another struct", ErrInvalidName, s.Name)
+		}
+		seen[s.Name] = true
+	}
+
+	project := filepath.Join(c.Directory, c.Name)
+	if _, err := os.Stat(project); err == nil && !c.Force {
+		return nil, fmt.Errorf("%w: %s (use force to overwrite it)", ErrExists, project)
+	}
+
+	var written []string
+	if err := copyHelper(c.HelperDir, filepath.Join(project, "helpers"), &written); err != nil {
+		return written, err
+	}
+	for _, source := range goFiles {
+		target := filepath.Join(project, "go", "helpers_"+filepath.Base(source))
+		if err := copyFile(source, target); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	data := templateData{Name: c.Name, Structs: structs}
+	outputs := map[string]string{
+		"go.mod.tmpl":      filepath.Join(project, "go", "go.mod"),
+		"lib.go.tmpl":      filepath.Join(project, "go", "lib.go"),
+		"lib_test.go.tmpl": filepath.Join(project, "go", "lib_test.go"),
+		"init.py.tmpl":     filepath.Join(project, "__init__.py"),
+		"lib.py.tmpl":      filepath.Join(project, "lib.py"),
+		"test.py.tmpl":     filepath.Join(c.Directory, "test_"+c.Name+".py"),
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		contents, err := render(name, data)
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(outputs[name], contents, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, outputs[name])
+	}
+	return written, nil
+}
+
+// Renders a template, Go files are gofmt'ed
+func render(name string, data templateData) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(templateFunctions).ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	if err := tmpl.Execute(&output, data); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".go.tmpl") {
+		return output.Bytes(), nil
+	}
+	formatted, err := format.Source(output.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go from %s: %w", name, err)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,110 @@
+package scaffold
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStruct(t *testing.T) {
+	temp, err := ParseStruct("Point: x=int, y=float ,label=string,id=int64,visible=bool")
+	if err != nil {
+		t.Fatalf("TestParseStruct:ParseStruct(): %v", err)
+	}
+	expected := []string{"x int32", "y float64", "label string", "id int64", "visible bool"}
+	if temp.Name != "Point" || len(temp.Fields) != len(expected) {
+		t.Fatalf("TestParseStruct:ParseStruct(): %+v", temp)
+	}
+	for i, field := range temp.Fields {
+		if field.Name+" "+field.Type.Go != expected[i] {
+			t.Errorf("TestParseStruct:ParseStruct(): field %d is %s %s, expected %s", i, field.Name, field.Type.Go, expected[i])
+		}
+	}
+
+	for test_input, expected := range map[string]error{
+		"Point":                 ErrInvalidSpec,
+		"Point:":                ErrInvalidSpec,
+		"Point:x":               ErrInvalidSpec,
+		"Point:x=complex":       ErrInvalidSpec,
+		"Point:x=int,x=float":   ErrInvalidSpec,
+		"point:x=int":           ErrInvalidName,
+		"Po-int:x=int":          ErrInvalidName,
+		"Point:type=int":        ErrInvalidName,
+		"Point:class=int":       ErrInvalidName,
+		"Point:double=float":    ErrInvalidName,
+		"Point:x=int,1y=string": ErrInvalidName,
+	} {
+		if _, err := ParseStruct(test_input); !errors.Is(err, expected) {
+			t.Errorf("TestParseStruct:ParseStruct(%q): expected %v, got %v", test_input, expected, err)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for test_input, expected := range map[string]string{"Point": "point", "HTTPRequest": "http_request", "UserID": "user_id", "Site_Info": "site_info"} {
+		if temp := snakeCase(test_input); temp != expected {
+			t.Errorf("TestSnakeCase:snakeCase(%q): %q!=%q", test_input, temp, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	point, err := ParseStruct("Point:x=int,y=float,label=string,id=int64,visible=bool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Name: "geometry", Directory: dir, HelperDir: "..", Structs: []Struct{point}}
+	files, err := Generate(config)
+	if err != nil {
+		t.Fatalf("TestGenerate:Generate(): %v", err)
+	}
+	for _, name := range []string{"test_geometry.py", "geometry/__init__.py", "geometry/lib.py", "geometry/go/go.mod", "geometry/go/lib.go", "geometry/go/lib_test.go", "geometry/go/helpers_lib.go", "geometry/helpers/lib.py", "geometry/helpers/cmd/cgohelper/main.go"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("TestGenerate:Generate(): missing %s", name)
+		}
+	}
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") && !strings.HasSuffix(name, filepath.Join("go", "lib_test.go")) || strings.HasPrefix(filepath.Base(name), "test_lib") {
+			t.Errorf("TestGenerate:Generate(): copied the helper's tests (%s)", name)
+		}
+	}
+	library, _ := os.ReadFile(filepath.Join(dir, "geometry", "go", "lib.go"))
+	for _, expected := range []string{"\tdouble y;\n", "\tchar* label;\n", "} Point;", "//export round_trip_point", "//export free_point"} {
+		if !strings.Contains(string(library), expected) {
+			t.Errorf("TestGenerate:Generate(): lib.go doesn't contain %q", expected)
+		}
+	}
+
+	if _, err := Generate(config); !errors.Is(err, ErrExists) {
+		t.Errorf("TestGenerate:Generate(again): expected ErrExists, got %v", err)
+	}
+	config.Force = true
+	if _, err := Generate(config); err != nil {
+		t.Errorf("TestGenerate:Generate(force): %v", err)
+	}
+
+	// Structs share a package with the helper, so they can't reuse it's names
+	config.Structs = []Struct{{Name: "Timestamp", Fields: point.Fields}}
+	if _, err := Generate(config); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("TestGenerate:Generate(Timestamp): expected ErrInvalidName, got %v", err)
+	}
+	for _, name := range []string{"helpers", "my-lib", "import"} {
+		if _, err := Generate(Config{Name: name, Directory: dir, HelperDir: ".."}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("TestGenerate:Generate(%q): expected ErrInvalidName, got %v", name, err)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+	// The generated round-trip tests pass
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = filepath.Join(dir, "geometry", "go")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("TestGenerate:go test (generated): %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"unsafe"
+)
+
+// ======== Self-test ========
+
+// The values the caller writes into the self-test buffers before calling helper_self_test(), and reads back afterwards
+// (keep them in sync with SELF_TEST_INTS, SELF_TEST_FLOATS and SELF_TEST_TEXT in lib.py)
+var (
+	SelfTestInts   = []int{math.MinInt32, -1, 0, 1, math.MaxInt32}
+	SelfTestFloats = []float32{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.Copysign(0, -1)), math.MaxFloat32, math.SmallestNonzeroFloat32, -1.5}
+	SelfTestText   = "h\u00e9llo w\u00f6rld \u2764 \u65e5\u672c\u8a9e \U0001F40D"
+)
+
+// Memory supplied by the caller of RunSelfTest(), the round-trips are written into (and read back out of) it
+type SelfTestBuffers struct {
+	Ints          unsafe.Pointer // A C int array (*C.int), starting with SelfTestInts
+	IntCapacity   int            // The number of ints it can hold
+	Floats        unsafe.Pointer // A C float array (*C.float), starting with SelfTestFloats
+	FloatCapacity int            // The number of floats it can hold
+	Text          unsafe.Pointer // A C char buffer (*C.char), starting with SelfTestText and a null terminator
+	TextCapacity  int            // The number of bytes it can hold
+}
+
+// The outcome of one self-test case
+type SelfTestResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// The outcome of RunSelfTest()
+type SelfTestReport struct {
+	Passed   int              `json:"passed"`
+	Failed   int              `json:"failed"`
+	Results  []SelfTestResult `json:"results"`
+	Required map[string]int   `json:"required"` // The capacity each buffer needs for every case to run ("ints", "floats" and "text")
+}
+
+// Adds the result of a case to the report
+func (r *SelfTestReport) add(name string, err error) {
+	result := SelfTestResult{Name: name, Passed: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Passed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// A named self-test input
+type selfTestCase[T any] struct {
+	name  string
+	value T
+}
+
+// The int round-trip cases, positive and negative values (unlike the old tests that only ever tried negatives)
+func selfTestIntCases() []selfTestCase[[]int] {
+	signs := make([]int, 0, 101)
+	for i := -50; i <= 50; i++ {
+		signs = append(signs, i*40_000)
+	}
+	random := make([]int, 64)
+	for i := range random {
+		random[i] = int(int32(rand.Uint32()))
+	}
+	return []selfTestCase[[]int]{
+		{"empty", []int{}},
+		{"zero", []int{0}},
+		{"extremes", []int{math.MinInt32, math.MaxInt32, math.MinInt32 + 1, math.MaxInt32 - 1}},
+		{"signs", signs},
+		{"random", random},
+	}
+}
+
+// The float round-trip cases, compared bit for bit so NaN and -0 count
+func selfTestFloatCases() []selfTestCase[[]float32] {
+	return []selfTestCase[[]float32]{
+		{"empty", []float32{}},
+		{"zeros", []float32{0, float32(math.Copysign(0, -1))}},
+		{"special", []float32{float32(math.NaN()), float32(math.Inf(1)), float32(math.Inf(-1))}},
+		{"extremes", []float32{math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32, -math.SmallestNonzeroFloat32, 0x1p-126}},
+		{"signs", []float32{-1.5, 1.5, -1e10, 1e10, 0.1, -0.1}},
+	}
+}
+
+// The string round-trip cases
+func selfTestStringCases() []selfTestCase[string] {
+	return []selfTestCase[string]{
+		{"empty", ""},
+		{"ascii", "Hello World"},
+		{"control", "\n\t\r\x01\x7f"},
+		{"latin", "h\u00e9llo w\u00f6rld"},
+		{"cjk", "\u65e5\u672c\u8a9e\u306e\u30c6\u30ad\u30b9\u30c8"},
+		{"emoji", "\U0001F40D\u2764\ufe0f\U0001F469\u200d\U0001F469\u200d\U0001F467"},
+		{"combining", "e\u0301 a\u030a"},
+		{"zero width", "\ufeffzero\u200bwidth"},
+		{"invalid utf-8", "\xff\xfe\xc3("},
+		{"long ascii", strings.Repeat("abcdefghij", 100_000)},
+		{"long multibyte", strings.Repeat("\u65e5\u672c\u8a9e\U0001F40D\u00e9", 50_000)},
+	}
+}
+
+// Checks that a round-trip came back unchanged
+func selfTestCompare[T comparable](expected, actual []T) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("got %d elements, expected %d", len(actual), len(expected))
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			return fmt.Errorf("element %d is %v, expected %v", i, actual[i], expected[i])
+		}
+	}
+	return nil
+}
+
+// Converts floats to their bits, so NaN == NaN (whatever it's payload) and -0 != 0
+func selfTestFloatBits(values []float32) []uint32 {
+	result := make([]uint32, len(values))
+	for i, value := range values {
+		result[i] = math.Float32bits(value)
+		if value != value {
+			result[i] = 0x7fc00000
+		}
+	}
+	return result
+}
+
+// Checks that a caller buffer is big enough for a case
+func selfTestCapacity(buffer string, capacity, required int) error {
+	if required > capacity {
+		return fmt.Errorf("the %s buffer holds %d, the case needs %d", buffer, capacity, required)
+	}
+	return nil
+}
+
+// Reads the null terminated string at the start of the caller's text buffer
+func selfTestCallerText(buffers SelfTestBuffers) (string, error) {
+	if buffers.Text == nil || buffers.TextCapacity < 1 {
+		return "", errors.New("there is no text buffer")
+	}
+	end := bytes.IndexByte(unsafe.Slice((*byte)(buffers.Text), buffers.TextCapacity), 0)
+	if end < 0 {
+		return "", errors.New("the text buffer isn't null terminated")
+	}
+	return CStringToString(buffers.Text), nil
+}
+
+// Runs every conversion round-trip against the caller's buffers, so the ABI is checked with the caller's own memory
+// and compiler instead of only in go test
+//
+// Parameters:
+//   - buffers: Memory supplied by the caller, starting with SelfTestInts, SelfTestFloats and SelfTestText.
+//
+// Returns:
+//   - A report with a result for each case, the caller's values are checked first ("caller/*"), then each case is
+//     converted with the helper's own allocations and through the caller's buffers.
+//
+// Notes:
+//   - The buffers are overwritten by the cases, and refilled with SelfTestInts, SelfTestFloats and SelfTestText
+//     at the end so the caller can check Go's writes from its side
+//   - Cases that don't fit in a buffer fail, the report's Required has the capacities needed to run everything
+func RunSelfTest(buffers SelfTestBuffers) SelfTestReport {
+	report := SelfTestReport{Required: map[string]int{"ints": len(SelfTestInts), "floats": len(SelfTestFloats), "text": len(SelfTestText) + 1}}
+	intCases, floatCases, stringCases := selfTestIntCases(), selfTestFloatCases(), selfTestStringCases()
+	for _, c := range intCases {
+		report.Required["ints"] = max(report.Required["ints"], len(c.value))
+	}
+	for _, c := range floatCases {
+		report.Required["floats"] = max(report.Required["floats"], len(c.value))
+	}
+	for _, c := range stringCases {
+		report.Required["text"] = max(report.Required["text"], len(c.value)+1)
+	}
+
+	// The values written by the caller
+	report.add("caller/ints", func() error {
+		if buffers.Ints == nil {
+			return errors.New("there is no int buffer")
+		}
+		if err := selfTestCapacity("int", buffers.IntCapacity, len(SelfTestInts)); err != nil {
+			return err
+		}
+		return selfTestCompare(SelfTestInts, CIntArrayToSlice(buffers.Ints, len(SelfTestInts)))
+	}())
+	report.add("caller/floats", func() error {
+		if buffers.Floats == nil {
+			return errors.New("there is no float buffer")
+		}
+		if err := selfTestCapacity("float", buffers.FloatCapacity, len(SelfTestFloats)); err != nil {
+			return err
+		}
+		return selfTestCompare(selfTestFloatBits(SelfTestFloats), selfTestFloatBits(CFloatArrayToSlice(buffers.Floats, len(SelfTestFloats))))
+	}())
+	report.add("caller/text", func() error {
+		text, err := selfTestCallerText(buffers)
+		if err != nil {
+			return err
+		}
+		return selfTestCompare([]byte(SelfTestText), []byte(text))
+	}())
+
+	for _, c := range intCases {
+		report.add("ints/"+c.name, func() error {
+			result, err := IntSliceToCArray(c.value)
+			if err != nil {
+				return err
+			}
+			defer helper_free_int_array_result(unsafe.Pointer(result))
+			if err := selfTestCompare(c.value, CIntArrayToSlice(unsafe.Pointer(result.data), int(result.numberOfElements))); err != nil {
+				return fmt.Errorf("IntSliceToCArray(): %w", err)
+			}
+			if err := selfTestCapacity("int", buffers.IntCapacity, len(c.value)); err != nil {
+				return err
+			}
+			if _, err := FillIntBuffer(c.value, buffers.Ints, buffers.IntCapacity); err != nil {
+				return err
+			}
+			if err := selfTestCompare(c.value, CIntArrayToSlice(buffers.Ints, len(c.value))); err != nil {
+				return fmt.Errorf("FillIntBuffer(): %w", err)
+			}
+			return nil
+		}())
+	}
+	report.add("ints/overflow", func() error {
+		if math.MaxInt == math.MaxInt32 {
+			return nil // Every Go int fits in a C int
+		}
+		for _, value := range []int{math.MaxInt32 + 1, math.MinInt32 - 1} {
+			if _, err := IntSliceToCArray([]int{value}); !errors.Is(err, ErrIntegerOverflow) {
+				return fmt.Errorf("IntSliceToCArray([%d]) returned %v, expected ErrIntegerOverflow", value, err)
+			}
+		}
+		return nil
+	}())
+
+	for _, c := range floatCases {
+		report.add("floats/"+c.name, func() error {
+			result := FloatSliceToCArray(c.value)
+			defer helper_free_float_array_result(unsafe.Pointer(result))
+			if err := selfTestCompare(selfTestFloatBits(c.value), selfTestFloatBits(CFloatArrayToSlice(unsafe.Pointer(result.data), int(result.numberOfElements)))); err != nil {
+				return fmt.Errorf("FloatSliceToCArray(): %w", err)
+			}
+			if err := selfTestCapacity("float", buffers.FloatCapacity, len(c.value)); err != nil {
+				return err
+			}
+			FillFloatBuffer(c.value, buffers.Floats, buffers.FloatCapacity)
+			if err := selfTestCompare(selfTestFloatBits(c.value), selfTestFloatBits(CFloatArrayToSlice(buffers.Floats, len(c.value)))); err != nil {
+				return fmt.Errorf("FillFloatBuffer(): %w", err)
+			}
+			return nil
+		}())
+	}
+
+	values := make([]string, 0, len(stringCases))
+	for _, c := range stringCases {
+		values = append(values, c.value)
+		report.add("strings/"+c.name, func() error {
+			cString := StringToCString(c.value)
+			defer FreeCString(cString)
+			if actual := CStringToString(cString); actual != c.value {
+				return fmt.Errorf("StringToCString(): got %d bytes %.32q, expected %d bytes", len(actual), actual, len(c.value))
+			}
+			if err := selfTestCapacity("text", buffers.TextCapacity, len(c.value)+1); err != nil {
+				return err
+			}
+			FillStringBuffer(c.value, buffers.Text, buffers.TextCapacity)
+			if actual := CStringToString(buffers.Text); actual != c.value {
+				return fmt.Errorf("FillStringBuffer(): got %d bytes %.32q, expected %d bytes", len(actual), actual, len(c.value))
+			}
+			return nil
+		}())
+	}
+	for _, c := range []selfTestCase[[]string]{{"empty", []string{}}, {"one empty", []string{""}}, {"every string", values}} {
+		report.add("string arrays/"+c.name, func() error {
+			result := StringSliceToCArray(c.value)
+			defer helper_free_string_array_result(unsafe.Pointer(result))
+			return selfTestCompare(c.value, CStringArrayToSlice(unsafe.Pointer(result.data), int(result.numberOfElements)))
+		}())
+	}
+
+	// Leave the caller's values in the buffers, so it can check what Go wrote from it's side
+	FillIntBuffer(SelfTestInts, buffers.Ints, buffers.IntCapacity)
+	FillFloatBuffer(SelfTestFloats, buffers.Floats, buffers.FloatCapacity)
+	if buffers.Text != nil {
+		FillStringBuffer(SelfTestText, buffers.Text, buffers.TextCapacity)
+	}
+	return report
+}
+
+// Runs the conversion round-trips against buffers supplied by the caller, see RunSelfTest()
+//
+// Parameters:
+//   - ints: Pointer to a C int array (*C.int) starting with SelfTestInts.
+//   - intCapacity: The number of ints it can hold (C.size_t).
+//   - floats: Pointer to a C float array (*C.float) starting with SelfTestFloats.
+//   - floatCapacity: The number of floats it can hold (C.size_t).
+//   - text: Pointer to a C char buffer (*C.char) starting with SelfTestText and a null terminator.
+//   - textCapacity: The number of bytes it can hold (C.size_t).
+//
+// Returns:
+//   - Pointer to a C string with the JSON SelfTestReport (*C.char).
+//     Note: The caller is responsible for freeing the allocated memory using helper_free_c_string.
+//
+//export helper_self_test
+func helper_self_test(ints unsafe.Pointer, intCapacity C.size_t, floats unsafe.Pointer, floatCapacity C.size_t, text unsafe.Pointer, textCapacity C.size_t) unsafe.Pointer {
+	ExitIfForked()
+	buffers := SelfTestBuffers{Ints: ints, Floats: floats, Text: text}
+	if ints != nil {
+		buffers.IntCapacity = capacityToInt(intCapacity)
+	}
+	if floats != nil {
+		buffers.FloatCapacity = capacityToInt(floatCapacity)
+	}
+	if text != nil {
+		buffers.TextCapacity = capacityToInt(textCapacity)
+	}
+	encoded, err := json.Marshal(RunSelfTest(buffers))
+	if err != nil {
+		encoded, _ = json.Marshal(SelfTestReport{Failed: 1, Results: []SelfTestResult{{Name: "report", Error: err.Error()}}})
+	}
+	return StringToCString(string(encoded))
+}
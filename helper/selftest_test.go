@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// Allocates the self-test buffers with the caller's values in them, like the python bindings do
+func selfTestBuffers(ints, floats, text int) (SelfTestBuffers, []int32, []float32, []byte) {
+	intBuffer, floatBuffer, textBuffer := make([]int32, ints), make([]float32, floats), make([]byte, text)
+	for i, value := range SelfTestInts {
+		intBuffer[i] = int32(value)
+	}
+	copy(floatBuffer, SelfTestFloats)
+	copy(textBuffer, SelfTestText)
+	buffers := SelfTestBuffers{
+		Ints: unsafe.Pointer(&intBuffer[0]), IntCapacity: ints,
+		Floats: unsafe.Pointer(&floatBuffer[0]), FloatCapacity: floats,
+		Text: unsafe.Pointer(&textBuffer[0]), TextCapacity: text,
+	}
+	return buffers, intBuffer, floatBuffer, textBuffer
+}
+
+func TestRunSelfTest(t *testing.T) {
+	// Find out how big the buffers need to be
+	buffers, _, _, _ := selfTestBuffers(8, 8, 64)
+	report := RunSelfTest(buffers)
+	if report.Failed == 0 || report.Required["text"] < 1_000_000 {
+		t.Fatalf("TestRunSelfTest:RunSelfTest() with small buffers: %+v", report.Required)
+	}
+	for _, result := range report.Results {
+		if strings.HasPrefix(result.Name, "caller/") && !result.Passed {
+			t.Errorf("TestRunSelfTest:RunSelfTest(): %s failed: %s", result.Name, result.Error)
+		}
+	}
+
+	buffers, ints, floats, text := selfTestBuffers(report.Required["ints"], report.Required["floats"], report.Required["text"])
+	report = RunSelfTest(buffers)
+	if report.Failed != 0 || report.Passed != len(report.Results) || report.Passed < 25 {
+		t.Errorf("TestRunSelfTest:RunSelfTest(): %d passed, %d failed", report.Passed, report.Failed)
+	}
+	for _, result := range report.Results {
+		if !result.Passed {
+			t.Errorf("TestRunSelfTest:RunSelfTest(): %s failed: %s", result.Name, result.Error)
+		}
+	}
+
+	// The caller's values are written back
+	if ints[0] != -1<<31 || ints[4] != 1<<31-1 || floats[6] != -1.5 || string(text[:len(SelfTestText)+1]) != SelfTestText+"\x00" {
+		t.Errorf("TestRunSelfTest:RunSelfTest(): the buffers don't end with the caller's values")
+	}
+
+	// The caller's values are checked
+	ints[1], floats[0], text[0] = 2, 0, 'H'
+	report = RunSelfTest(buffers)
+	failed := map[string]bool{}
+	for _, result := range report.Results {
+		failed[result.Name] = !result.Passed
+	}
+	if !failed["caller/ints"] || !failed["caller/floats"] || !failed["caller/text"] || report.Failed != 3 {
+		t.Errorf("TestRunSelfTest:RunSelfTest() with the wrong values: %+v", report.Results[:3])
+	}
+}
+
+func TestSelfTestExport(t *testing.T) {
+	// Without buffers only the helper's own allocations are tested
+	result := helper_self_test(nil, 100, nil, 100, nil, 100)
+	defer FreeCString(result)
+
+	var report SelfTestReport
+	if err := json.Unmarshal([]byte(CStringToString(result)), &report); err != nil {
+		t.Fatalf("TestSelfTestExport:helper_self_test(): %v", err)
+	}
+	if report.Failed == 0 || len(report.Results) != report.Passed+report.Failed || report.Required["ints"] == 0 {
+		t.Errorf("TestSelfTestExport:helper_self_test(NULL): %+v", report)
+	}
+	if report.Results[0].Name != "caller/ints" || report.Results[0].Error != "there is no int buffer" {
+		t.Errorf("TestSelfTestExport:helper_self_test(NULL): %+v", report.Results[0])
+	}
+}
@@ -1,12 +1,13 @@
 import os
 import sys
 import random
+import shutil
 from platform import platform
-from ctypes import ArgumentError, cdll, c_char_p, c_int, POINTER, c_float
+from ctypes import ArgumentError, cdll, c_char_p, c_int, c_int32, c_int64, c_size_t, c_void_p, POINTER, c_float, Structure, addressof, create_string_buffer, sizeof
 sys.path.insert(0, os.path.abspath(os.path.dirname(__file__)))
 
 from lib import *
-from lib import _CStringArrayResult, _CIntArrayResult, _CFloatArrayResult
+from lib import _CStringArrayResult, _CIntArrayResult, _CFloatArrayResult, _CTimestamp, _CLibraryInfo, _CDecodeResult
 
 import pytest
 
@@ -17,36 +18,41 @@ else:
     lib = cdll.LoadLibrary(os.path.join(os.path.dirname(os.path.realpath(__file__)), "lib.so")) 
 
 # Setup CGo functions
-lib.print_string_array.argtypes =  [POINTER(c_char_p), c_int]
-lib.FreeStringArray.argtypes = [POINTER(c_char_p), c_int]
+lib.helper_print_string_array.argtypes =  [POINTER(c_char_p), c_size_t]
+lib.helper_free_string_array.argtypes = [POINTER(c_char_p), c_size_t]
 
-lib.print_int_array.argtypes =  [POINTER(c_int), c_int]
-lib.FreeIntArray.argtypes = [POINTER(c_int)]
+lib.helper_print_int_array.argtypes =  [POINTER(c_int), c_size_t]
+lib.helper_free_int_array.argtypes = [POINTER(c_int)]
 
-lib.print_float_array.argtypes =  [POINTER(c_float), c_int]
-lib.FreeFloatArray.argtypes =  [POINTER(c_float)]
+lib.helper_print_float_array.argtypes =  [POINTER(c_float), c_size_t]
+lib.helper_free_float_array.argtypes =  [POINTER(c_float)]
 
-lib.return_string.argtypes = [c_char_p]
-lib.return_string.restype = c_char_p
+lib.helper_return_string.argtypes = [c_char_p]
+lib.helper_return_string.restype = c_char_p
 
-lib.FreeCString.argtypes = [c_char_p]
+lib.helper_free_c_string.argtypes = [c_char_p]
 
-lib.print_string.argtypes = [c_char_p]
+lib.helper_print_string.argtypes = [c_char_p]
 
 ## Array-based functions
 
-lib.FreeStringArray.argtypes = [POINTER(c_char_p), c_int]
-lib.free_string_array_result.argtypes = [POINTER(_CStringArrayResult)]
-lib.return_string_array.argtypes = [POINTER(c_char_p), c_int] 
-lib.return_string_array.restype = POINTER(_CStringArrayResult)
+lib.helper_free_string_array.argtypes = [POINTER(c_char_p), c_size_t]
+lib.helper_free_string_array_result.argtypes = [POINTER(_CStringArrayResult)]
+lib.helper_return_string_array.argtypes = [POINTER(c_char_p), c_size_t] 
+lib.helper_return_string_array.restype = POINTER(_CStringArrayResult)
 
-lib.return_int_array.argtypes = [POINTER(c_int), c_int]
-lib.return_int_array.restype = POINTER(_CIntArrayResult)
-lib.free_int_array_result.argtypes = [POINTER(_CIntArrayResult)]
+lib.helper_return_int_array.argtypes = [POINTER(c_int), c_size_t]
+lib.helper_return_int_array.restype = POINTER(_CIntArrayResult)
+lib.helper_free_int_array_result.argtypes = [POINTER(_CIntArrayResult)]
 
-lib.return_float_array.argtypes = [POINTER(c_float), c_int]
-lib.return_float_array.restype = POINTER(_CFloatArrayResult)
-lib.free_float_array_result.argtypes = [POINTER(_CFloatArrayResult)]
+lib.helper_return_float_array.argtypes = [POINTER(c_float), c_size_t]
+lib.helper_return_float_array.restype = POINTER(_CFloatArrayResult)
+lib.helper_free_float_array_result.argtypes = [POINTER(_CFloatArrayResult)]
+
+## Versioned struct functions
+
+lib.helper_library_info.argtypes = [c_void_p]
+lib.helper_library_info.restype = c_void_p
 
 def cstring_checks(correct_content:str, data_to_test:c_char_p):
     """Checks that a c string is setup correctly"""
@@ -162,7 +168,7 @@ def test_internal_lib_functions():
         ["Reeeee"],
         []):
         result_c_array, result_number_of_items = prepare_string_array(test_input)
-        result:_CStringArrayResult = lib.return_string_array(result_c_array, result_number_of_items)
+        result:_CStringArrayResult = lib.helper_return_string_array(result_c_array, result_number_of_items)
 
         temp_data = result.contents
         assert len(test_input) == result_number_of_items == temp_data.numberOfElements
@@ -182,7 +188,7 @@ def test_internal_lib_functions():
         [b"Reeeee"],
         []):
         result_c_array, result_number_of_items = prepare_string_array(test_input)
-        result:_CStringArrayResult = lib.return_string_array(result_c_array, result_number_of_items)
+        result:_CStringArrayResult = lib.helper_return_string_array(result_c_array, result_number_of_items)
 
         temp_data = result.contents
         assert len(test_input) == result_number_of_items == temp_data.numberOfElements
@@ -195,7 +201,7 @@ def test_internal_lib_functions():
     n = 1000
     original_input = [random.randint(-1000, 1000) for _ in range(n)]
     c_array, number_of_items = prepare_int_array(original_input)
-    test_input = lib.return_int_array(c_array, number_of_items)
+    test_input = lib.helper_return_int_array(c_array, number_of_items)
     test_input_data = test_input.contents
     assert test_input_data.numberOfElements == number_of_items == len(original_input)
     for i in range(n):
@@ -206,7 +212,7 @@ def test_internal_lib_functions():
     test_input = ["A"]
     c_array, number_of_items = prepare_string_array(test_input)
     with pytest.raises(ArgumentError):
-        lib.return_int_array(c_array, number_of_items)
+        lib.helper_return_int_array(c_array, number_of_items)
         return_int_array(c_array, number_of_items)
     
     
@@ -214,7 +220,7 @@ def test_internal_lib_functions():
     n = 1000
     original_input = [random.randint(-1000, 1000) for _ in range(n)]
     c_array, number_of_items = prepare_float_array(original_input)
-    test_input = lib.return_float_array(c_array, number_of_items)
+    test_input = lib.helper_return_float_array(c_array, number_of_items)
     test_input_data = test_input.contents
     assert test_input_data.numberOfElements == number_of_items == len(original_input)
     for i in range(n):
@@ -249,7 +255,7 @@ def test_go_to_python_functions():
         ["Reeeee"],
         []):
         result_c_array, result_number_of_items = prepare_string_array(test_input)
-        intermediate:_CStringArrayResult = lib.return_string_array(result_c_array, result_number_of_items)
+        intermediate:_CStringArrayResult = lib.helper_return_string_array(result_c_array, result_number_of_items)
         result = string_array_result_to_list(intermediate)
 
         assert len(test_input) == result_number_of_items == len(result)
@@ -268,7 +274,7 @@ def test_go_to_python_functions():
         [b"Reeeee"],
         []):
         result_c_array, result_number_of_items = prepare_string_array(test_input)
-        intermediate:_CStringArrayResult = lib.return_string_array(result_c_array, result_number_of_items)
+        intermediate:_CStringArrayResult = lib.helper_return_string_array(result_c_array, result_number_of_items)
         result = string_array_result_to_list(intermediate)
 
         assert len(test_input) == result_number_of_items == len(result)
@@ -280,7 +286,7 @@ def test_go_to_python_functions():
     n = 1000
     original_input = [random.randint(-1000, 1000) for _ in range(n)]
     c_array, number_of_items = prepare_int_array(original_input)
-    temp = lib.return_int_array(c_array, number_of_items)
+    temp = lib.helper_return_int_array(c_array, number_of_items)
     
     test_input = int_array_result_to_list(temp)
     assert len(test_input) == number_of_items == len(original_input)
@@ -291,7 +297,7 @@ def test_go_to_python_functions():
     n = 1000
     original_input = [random.randint(-1000, 1000) for _ in range(n)]
     c_array, number_of_items = prepare_float_array(original_input)
-    temp = lib.return_float_array(c_array, number_of_items)
+    temp = lib.helper_return_float_array(c_array, number_of_items)
     test_input = float_array_result_to_list(temp)
     assert len(test_input) == number_of_items == len(original_input)
     for original_number, test_number in zip(original_input, test_input):
@@ -399,3 +405,792 @@ def test_debugging_functions(capsys:pytest.CaptureFixture[str]):
     n = 1000
     test_input = [random.uniform(-1000.00, 1000.00) for _ in range(n)]
     print_float_array(test_input)
+
+@pytest.mark.skipif(not platform().lower().startswith("linux"), reason="fork() scenario is only tested on Linux")
+def test_fork_safety():
+    # The process that loaded the library can use it
+    assert return_string("Hello World!") == "Hello World!"
+    assert lib.helper_is_forked_process() == 0
+
+    # A forked child should fail fast instead of calling into the broken runtime
+    pid = os.fork()
+    if pid == 0:
+        try:
+            return_string("Hello World!")
+        except ForkedProcessError:
+            os._exit(0)
+        except BaseException:
+            os._exit(2)
+        os._exit(1)
+    _, status = os.waitpid(pid, 0)
+    assert os.WIFEXITED(status)
+    assert os.WEXITSTATUS(status) == 0
+
+    # Calling the library without GoLibrary's check fails fast on the Go side (exits with ForkedExitCode)
+    pid = os.fork()
+    if pid == 0:
+        raw_library = cdll.LoadLibrary(dll_file)
+        raw_library.helper_return_string.argtypes = [c_char_p]
+        raw_library.helper_return_string(b"Hello World!")
+        os._exit(1)
+    _, status = os.waitpid(pid, 0)
+    assert os.WIFEXITED(status)
+    assert os.WEXITSTATUS(status) == 71
+
+    ## Re-loading the library in the child should also fail
+    pid = os.fork()
+    if pid == 0:
+        try:
+            get_library(dll_file)
+        except ForkedProcessError:
+            os._exit(0)
+        except BaseException:
+            os._exit(2)
+        os._exit(1)
+    _, status = os.waitpid(pid, 0)
+    assert os.WIFEXITED(status)
+    assert os.WEXITSTATUS(status) == 0
+
+    # Spawned workers are fresh interpreters, not forks
+    assert get_process_context().get_start_method() == "spawn"
+    with spawn_pool(1) as pool:
+        assert pool.apply(os.getpid) != os.getpid()
+
+def test_worker(tmp_path):
+    executable = os.path.join(tmp_path, "worker.exe" if platform().lower().startswith("windows") else "worker")
+    source = os.path.dirname(os.path.realpath(__file__))
+
+    with get_worker(executable, source, compile=True) as worker:
+        # Same results as the in-process functions
+        for test_input in ("","Hello World!", "!@$#^%!#@@%*!", "AWDsadfSA", "❤", "\x41", "\n"):
+            string_checks(test_input, worker.return_string(test_input))
+        test_input = [random.choice(["Lorem", "ipsum", "dolor", "sit", "amet"]) for _ in range(100)]
+        assert worker.return_string_array(test_input) == test_input
+        test_input = [random.randint(-1000, 1000) for _ in range(1000)]
+        assert worker.call("return_int_array", test_input) == test_input
+        assert worker.return_float_array([]) == []
+
+        # Errors in the worker don't kill it
+        with pytest.raises(WorkerError):
+            worker.does_not_exist()
+        with pytest.raises(WorkerError):
+            worker.return_string(1)
+
+        # A dead worker is restarted on the next call
+        first_pid = worker.worker_pid()
+        assert first_pid != os.getpid()
+        worker._process.kill()
+        worker._process.wait()
+        assert worker.worker_pid() != first_pid
+
+        # Unless restarting is disabled
+        worker.restart = False
+        worker._process.kill()
+        worker._process.wait()
+        with pytest.raises(WorkerCrashedError):
+            worker.return_string("Hello World!")
+
+    # A worker that fails to build is an error if there's no executable, otherwise the existing one is used
+    broken = os.path.join(tmp_path, "broken")
+    os.mkdir(broken)
+    with open(os.path.join(broken, "go.mod"), "w") as file:
+        file.write("module worker\n\ngo 1.22\n")
+    with open(os.path.join(broken, "main.go"), "w") as file:
+        file.write("package main\n\nfunc main() { return 1 }\n")
+    with pytest.raises(ValueError):
+        get_worker(os.path.join(tmp_path, "missing_worker"), broken, compile=True)
+    with get_worker(executable, broken, compile=True) as worker:
+        assert worker.return_string("Hello World!") == "Hello World!"
+
+    if not platform().lower().startswith("windows"):
+        with GoWorker(executable, socket_path=os.path.join(tmp_path, "worker.sock")) as worker:
+            assert worker.return_string("❤") == "❤"
+            assert worker.worker_pid() != os.getpid()
+
+        # A garbled response from a worker that's still running stops it, instead of waiting for it to exit
+        garbled = os.path.join(tmp_path, "garbled_worker")
+        with open(garbled, "w") as file:
+            file.write(f"#!{sys.executable}\nimport sys, time\nsys.stdout.buffer.write(b'\\x00\\x00\\x00\\x03abc')\nsys.stdout.flush()\ntime.sleep(60)\n")
+        os.chmod(garbled, 0o755)
+        with GoWorker(garbled) as worker:
+            with pytest.raises(WorkerCrashedError):
+                worker.call("return_string", "Hello World!")
+            assert not worker.running
+
+def test_host():
+    host = get_host(dll_file)
+    assert "return_string" in host.modules()["helper"]
+
+    # Namespaced calls give the same results as the in-process functions
+    for test_input in ("","Hello World!", "!@$#^%!#@@%*!", "AWDsadfSA", "❤", "\x41", "\n"):
+        string_checks(test_input, host.helper.return_string(test_input))
+    test_input = [random.randint(-1000, 1000) for _ in range(1000)]
+    assert host.helper.return_int_array(test_input) == test_input
+    assert host.call("helper", "return_string_array", ["Here", "are", "some", "strings"]) == ["Here", "are", "some", "strings"]
+
+    with pytest.raises(AttributeError):
+        host.does_not_exist
+    with pytest.raises(AttributeError):
+        host.helper.does_not_exist
+    with pytest.raises(HostError):
+        host.call("helper", "does_not_exist")
+    with pytest.raises(HostError):
+        host.helper.return_string(1)
+
+    # The host frees strings through it's own function object, instead of changing the library's argtypes
+    library = get_library(dll_file)
+    library.helper_free_c_string.argtypes = [c_char_p]
+    GoHost(library).modules()
+    assert list(library.helper_free_c_string.argtypes) == [c_char_p]
+
+def test_host_examples(tmp_path):
+    # The scraping and similarity examples built into one host (one Go runtime), see examples/host
+    host_directory = os.path.join(os.path.dirname(os.path.realpath(__file__)), "..", "examples", "host")
+    host = get_host(os.path.join(tmp_path, "host.so"), host_directory, compile=True)
+    modules = host.modules()
+    assert "parse_urls" in modules["scraping"] and "check" in modules["similarity"] and "return_string" in modules["helper"]
+
+    suggestion = host.similarity.check("almni", ["move", "alumni", "amni"])
+    assert suggestion["word"] == "alumni" and abs(suggestion["likelihood"] - 10 / 11) < 1e-6
+    assert host.similarity.check_levenstein("move", ["move", "mover"])["word"] == "move"
+    # Nothing listens on port 1, so each site is the default for it's URL (in order)
+    urls = ["http://127.0.0.1:1/first", "http://127.0.0.1:1/second"]
+    assert [site["url"] for site in host.scraping.parse_urls(urls)] == urls
+    with pytest.raises(HostError):
+        host.similarity.check("almni")
+
+def test_abi():
+    assert lib.helper_abi_version() == ABI_VERSION
+    check_abi(lib, dll_file, ABI_VERSION, REQUIRED_FEATURES | FEATURE_WORKER)
+
+    # Stale libraries are caught on load
+    with pytest.raises(ABIMismatchError):
+        check_abi(lib, dll_file, ABI_VERSION + 1)
+    with pytest.raises(ABIMismatchError):
+        check_abi(lib, dll_file, ABI_VERSION, 1 << 63)
+    with pytest.raises(ABIMismatchError):
+        get_library(dll_file, abi_version=ABI_VERSION + 1)
+
+def test_runtime_tuning():
+    runtime = GoRuntime(lib)
+
+    original = runtime.gomaxprocs
+    runtime.gomaxprocs = 1
+    assert runtime.gomaxprocs == 1
+    runtime.gomaxprocs = original
+    with pytest.raises(ValueError):
+        runtime.gomaxprocs = 0
+
+    original = runtime.gc_percent
+    runtime.gc_percent = 50
+    assert runtime.gc_percent == 50
+    runtime.gc_percent = original
+
+    original = runtime.memory_limit
+    runtime.memory_limit = 256 * 1024**2
+    assert runtime.memory_limit == 256 * 1024**2
+    runtime.memory_limit = None
+    assert runtime.memory_limit is None
+    with pytest.raises(ValueError):
+        runtime.memory_limit = -1
+    runtime.memory_limit = original
+
+    runtime.gc()
+    runtime.free_os_memory()
+
+    # The module level instance uses the helper's library
+    assert go_runtime.gomaxprocs >= 1
+
+def test_runtime_metrics():
+    metrics = GoRuntime(lib).metrics()
+    assert metrics["/sched/goroutines:goroutines"] >= 1
+    assert metrics["/memory/classes/heap/objects:bytes"] > 0
+    assert not any(name.startswith("/gc/pauses:seconds") for name in metrics) # Histograms are left out
+    assert "/cgo/go-to-c-calls:calls" in metrics
+
+    text = metrics_to_prometheus({"/gc/cycles/total:gc-cycles": 3, "/sched/goroutines:goroutines": 4}, labels={"library": 'he"lper'})
+    assert text == (
+        "# TYPE go_gc_cycles_total_gc_cycles counter\n"
+        'go_gc_cycles_total_gc_cycles{library="he\\"lper"} 3\n'
+        "# TYPE go_sched_goroutines_goroutines gauge\n"
+        'go_sched_goroutines_goroutines{library="he\\"lper"} 4\n'
+    )
+    assert "go_sched_goroutines_goroutines " in go_runtime.prometheus()
+
+    # Values keep their precision
+    text = metrics_to_prometheus({"/memory/classes/total:bytes": 123456789012, "/gc/gogc:percent": 0.1}, prefix="")
+    assert text == (
+        "# TYPE gc_gogc_percent gauge\n"
+        "gc_gogc_percent 0.1\n"
+        "# TYPE memory_classes_total_bytes counter\n"
+        "memory_classes_total_bytes 123456789012\n"
+    )
+    assert "pauses" not in go_runtime.prometheus()
+
+def test_worker_pool():
+    pool = GoPool(lib)
+
+    original = pool.size
+    pool.size = 3
+    assert pool.size == 3
+    with pytest.raises(ValueError):
+        pool.size = 0
+    assert pool.size == 3
+
+    stats = pool.stats()
+    assert stats["size"] == 3
+    assert stats["queued"] == stats["queued_low"] + stats["queued_normal"] + stats["queued_high"]
+    assert {"workers", "active", "submitted", "completed", "failed", "cancelled"} <= stats.keys()
+    pool.size = original
+
+    # The module level instance uses the helper's library
+    assert go_pool.size == original
+
+def test_buffer_pool():
+    pool = GoBufferPool(lib)
+    original = pool.cap
+
+    # Repeated round trips reuse the same blocks
+    pool.cap = 1024**2
+    return_int_array(*prepare_int_array(list(range(100))))
+    return_string_array(*prepare_string_array(["a", "b"]))
+    before = pool.stats()
+    for _ in range(10):
+        assert return_int_array(*prepare_int_array(list(range(100)))) == list(range(100))
+        assert return_string_array(*prepare_string_array(["a", "b"])) == ["a", "b"]
+    after = pool.stats()
+    assert after["misses"] == before["misses"]
+    assert after["hits"] - before["hits"] >= 40
+    assert after["in_use_blocks"] == before["in_use_blocks"]
+    assert after["idle_bytes"] > 0
+
+    assert pool.trim() > 0
+    assert pool.stats()["idle_bytes"] == 0
+    pool.cap = 0
+    assert pool.cap == 0
+    with pytest.raises(ValueError):
+        pool.cap = -1
+    pool.cap = original
+    assert go_buffer_pool.cap == original
+
+def test_self_test():
+    report = self_test()
+    failures = [r for r in report["results"] if not r["passed"]]
+    assert report["failed"] == 0, failures
+    assert report["passed"] == len(report["results"])
+    names = {r["name"] for r in report["results"]}
+    assert {"caller/ints", "caller/floats", "caller/text", "python/ints", "python/floats", "python/text"} <= names
+    assert {"ints/extremes", "floats/special", "strings/emoji", "strings/long multibyte", "string arrays/empty"} <= names
+    assert self_test(lib, check=True)["failed"] == 0
+
+def test_struct_layouts():
+    layouts = struct_layouts()
+    assert set(HELPER_STRUCTURES) <= set(layouts)
+    go_fields = [(f["name"], f["offset"], f["size"]) for f in layouts["IntArrayResult"]["fields"]]
+    assert go_fields == [(f["name"], f["offset"], f["size"]) for f in structure_layout(_CIntArrayResult)["fields"]]
+    assert layouts["IntArrayResult"]["fields"][1]["type"] == "int*"
+    assert compare_struct_layouts() == []
+    assert compare_struct_layouts(check=True) == []
+
+    # A packed Timestamp drops the padding cgo keeps after offsetSeconds, and a Structure Go doesn't know about
+    class PackedTimestamp(Structure):
+        _pack_ = 1
+        _fields_ = [("unixNanos", c_int64), ("offsetSeconds", c_int32), ("extra", c_int32)]
+
+    class Suggestion(Structure):
+        _fields_ = [("word", c_char_p), ("likelihood", c_float)]
+
+    mismatches = compare_struct_layouts({"Timestamp": PackedTimestamp, "Suggestion": Suggestion})
+    assert LayoutMismatch("Timestamp", None, "align", 8, 1) in mismatches
+    assert LayoutMismatch("Timestamp", "extra", "exists", False, True) in mismatches
+    assert LayoutMismatch("Suggestion", None, "exists", False, True) in mismatches
+    assert str(LayoutMismatch("Timestamp", "unixNanos", "offset", 0, 4)) == "Timestamp.unixNanos offset is 0 in Go but 4 in python"
+    with pytest.raises(StructLayoutError):
+        compare_struct_layouts({"Timestamp": PackedTimestamp}, lib, check=True)
+
+def test_hexdump():
+    text = create_string_buffer(b"Hello, World! This spans two lines")
+    dump = hexdump(text)
+    assert dump.startswith("00000000  48 65 6c 6c 6f 2c 20 57")
+    assert "|Hello, World! Th|" in dump
+    assert len(dump.splitlines()) == 3
+    assert hexdump(addressof(text), 5).rstrip().endswith("|Hello|")
+    assert hexdump(0, 10) == ""
+
+    info = _CLibraryInfo.new()
+    assert hexdump(info).startswith(f"00000000  {sizeof(info):02x} 00 00 00 02 00 00 00")
+    with pytest.raises(ValueError):
+        hexdump(addressof(text))
+
+def test_profiling(tmp_path):
+    runtime = GoRuntime(lib)
+
+    with runtime.cpu_profile(os.path.join(tmp_path, "cpu.pprof")) as path:
+        return_string_array(*prepare_string_array(["Lorem", "ipsum"] * 1000))
+    assert os.path.getsize(path) > 0
+
+    with runtime.trace(os.path.join(tmp_path, "trace.out")) as path:
+        return_int_array(*prepare_int_array(list(range(1000))))
+    assert os.path.getsize(path) > 0
+
+    with runtime.mutex_profile(os.path.join(tmp_path, "mutex.pprof")) as path:
+        pass
+    assert os.path.getsize(path) > 0
+
+    with runtime.block_profile(os.path.join(tmp_path, "block.pprof")) as path:
+        pass
+    assert os.path.getsize(path) > 0
+
+    for name in ("heap", "goroutine"):
+        runtime.write_profile(name, os.path.join(tmp_path, f"{name}.pprof"))
+        assert os.path.getsize(os.path.join(tmp_path, f"{name}.pprof")) > 0
+
+    # Errors from Go are raised in python
+    with pytest.raises(ProfilingError):
+        runtime.stop_cpu_profile()
+    with pytest.raises(ProfilingError):
+        runtime.stop_trace()
+    with pytest.raises(ProfilingError):
+        runtime.write_profile("does_not_exist", os.path.join(tmp_path, "x.pprof"))
+    with pytest.raises(ProfilingError):
+        runtime.start_cpu_profile(os.path.join(tmp_path, "missing", "cpu.pprof"))
+
+def test_fill_buffers():
+    # Buffers are reused across calls, and only the filled part is returned
+    buffer, capacity = prepare_int_buffer(10)
+    for test_input in ([-10_000, -1, 0, 1, 10_000], [42], []):
+        c_array, number_of_items = prepare_int_array(test_input)
+        assert fill_int_array(c_array, number_of_items, buffer, capacity) == test_input
+
+    float_buffer, float_capacity = prepare_float_buffer(3)
+    c_array, number_of_items = prepare_float_array([1.5, -2.25, 0.0])
+    assert fill_float_array(c_array, number_of_items, float_buffer, float_capacity) == [1.5, -2.25, 0.0]
+
+    byte_buffer, byte_capacity = prepare_byte_buffer(16)
+    for test_input in ["", "Hello World", "!@$#^%!#@@%*!", "❤", "\n"]:
+        assert fill_string(test_input, byte_buffer, byte_capacity) == test_input
+    assert fill_bytes(b"a\x00b", byte_buffer, byte_capacity) == b"a\x00b"
+
+    # Too small buffers report the size they need, and aren't written to
+    small_buffer, small_capacity = prepare_int_buffer(2)
+    c_array, number_of_items = prepare_int_array([1, 2, 3])
+    with pytest.raises(BufferTooSmallError) as error:
+        fill_int_array(c_array, number_of_items, small_buffer, small_capacity)
+    assert error.value.required == 3
+    assert list(small_buffer) == [0, 0]
+
+    small_buffer, small_capacity = prepare_byte_buffer(5)
+    with pytest.raises(BufferTooSmallError) as error:
+        fill_string("Hello", small_buffer, small_capacity)
+    assert error.value.required == 6
+    small_buffer, small_capacity = prepare_byte_buffer(error.value.required)
+    assert fill_string("Hello", small_buffer, small_capacity) == "Hello"
+
+def test_overflow_checks():
+    # ctypes would silently wrap these to 0 and -2147483648
+    for value in (2 ** 32, -(2 ** 31) - 1, 2 ** 63):
+        with pytest.raises(OverflowError):
+            prepare_int_array([1, value])
+    assert check_c_int(2 ** 31 - 1) == 2 ** 31 - 1
+    assert check_c_int(-(2 ** 31)) == -(2 ** 31)
+
+    # Lengths are size_t
+    assert _CIntArrayResult.numberOfElements.size == sizeof(c_size_t)
+    c_array, number_of_items = prepare_int_array([2 ** 31 - 1, -(2 ** 31)])
+    assert return_int_array(c_array, number_of_items) == [2 ** 31 - 1, -(2 ** 31)]
+
+def test_encodings():
+    # Valid input is the same under every policy
+    for test_input in ["", "Hello World", "!@$#^%!#@@%*!", "❤", "\n", "nul\x00byte"]:
+        for errors in ("strict", "replace", "surrogateescape"):
+            assert decode_utf8(test_input.encode(), errors) == (test_input, [])
+            assert decode_utf16(test_input.encode("utf-16-le"), errors) == (test_input, [])
+        assert decode_utf16(test_input.encode("utf-16-be"), big_endian=True).text == test_input
+
+    # Invalid bytes are reported, not silently replaced
+    test_input = b"ok\xffthen"
+    with pytest.raises(InvalidEncodingError) as error:
+        decode_utf8(test_input)
+    assert error.value.invalid_offsets == [2]
+    assert decode_utf8(test_input, "replace") == ("ok�then", [2])
+    decoded = decode_utf8(test_input, "surrogateescape")
+    assert decoded.text == test_input.decode(errors="surrogateescape")
+    assert decoded.text.encode(errors="surrogateescape") == test_input
+
+    # Unpaired surrogates in UTF-16
+    test_input = "a\ud83db".encode("utf-16-le", errors="surrogatepass")
+    with pytest.raises(InvalidEncodingError):
+        decode_utf16(test_input)
+    assert decode_utf16(test_input, "replace") == ("a�b", [1])
+    assert decode_utf16(test_input, "surrogateescape").text == "a\ud83db"
+    with pytest.raises(ValueError):
+        decode_utf16(b"abc")
+    # An unknown policy is a ValueError, like bytes.decode()
+    for decode in (decode_utf8, decode_utf16):
+        with pytest.raises(ValueError):
+            decode(b"ab", "ignore")
+    # A length whose size in bytes doesn't fit in a Go int is rejected before the buffer is read
+    lib.helper_decode_utf16.argtypes = [c_char_p, c_size_t, c_int, c_int]
+    lib.helper_decode_utf16.restype = POINTER(_CDecodeResult)
+    lib.helper_free_decode_result.argtypes = [POINTER(_CDecodeResult)]
+    result = lib.helper_decode_utf16(b"ab", 2**62, 0, ENCODING_STRICT)
+    assert b"integer overflow" in result.contents.error
+    lib.helper_free_decode_result(result)
+
+    assert decode_latin1("café ©ÿ".encode("latin-1")) == "café ©ÿ"
+
+def test_nullable_arrays():
+    # Missing values stay different from 0/""
+    for test_input in ([1, None, 0, None, None, None, None, None, -5], [], [None], [0]):
+        c_array, validity, number_of_items = prepare_nullable_int_array(test_input)
+        assert return_nullable_int_array(c_array, validity, number_of_items) == test_input
+
+    c_array, validity, number_of_items = prepare_nullable_float_array([None, 0.5, 0.0])
+    assert return_nullable_float_array(c_array, validity, number_of_items) == [None, 0.5, 0.0]
+
+    # No bitmap means every value is present
+    c_array, number_of_items = prepare_int_array([1, 2])
+    assert return_nullable_int_array(c_array, None, number_of_items) == [1, 2]
+
+    test_input = ["", None, "Hello", None]
+    c_array, number_of_items = prepare_string_array(test_input)
+    assert return_nullable_string_array(c_array, number_of_items) == test_input
+    assert return_string_array(c_array, number_of_items) == ["", "", "Hello", ""]
+
+    with pytest.raises(OverflowError):
+        prepare_nullable_int_array([None, 2 ** 40])
+
+    assert list(prepare_validity_bitmap([1, None, 1, None, None, None, None, None, 1])) == [0b101, 0b1]
+    assert is_valid(None, 3)
+
+def test_time_conversions():
+    from datetime import datetime, timedelta, timezone
+    for test_input in (
+        datetime(1970, 1, 1, tzinfo=timezone.utc),
+        datetime(2024, 3, 10, 12, 30, 15, 123456, tzinfo=timezone(timedelta(hours=-5))),
+        datetime(1969, 12, 31, 23, 59, 59, 1, tzinfo=timezone(timedelta(hours=5, minutes=30))),
+    ):
+        temp = return_timestamp(test_input)
+        assert temp == test_input
+        assert temp.utcoffset() == test_input.utcoffset()
+
+    # Naive datetimes are local time
+    naive = datetime(2024, 1, 1, 8, 0)
+    assert return_timestamp(naive) == naive.astimezone()
+
+    # Nanoseconds are rounded down to microseconds
+    assert timestamp_to_datetime(_CTimestamp(1_999, 0)) == datetime(1970, 1, 1, 0, 0, 0, 1, tzinfo=timezone.utc)
+    assert timestamp_to_datetime(_CTimestamp(0, 3600)).utcoffset() == timedelta(hours=1)
+
+    with pytest.raises(OverflowError):
+        prepare_timestamp(datetime(9999, 12, 31, tzinfo=timezone.utc))
+
+    for test_input in (timedelta(0), timedelta(microseconds=1), timedelta(seconds=-1.5), timedelta(days=3, minutes=90)):
+        assert return_duration(test_input) == test_input
+    assert prepare_duration(timedelta(milliseconds=250)) == 250_000_000
+    with pytest.raises(OverflowError):
+        prepare_duration(timedelta(days=365 * 300))
+
+    assert abs(go_now() - datetime.now(timezone.utc)) < timedelta(minutes=1)
+    assert go_now().tzinfo is not None
+
+def test_big_numbers():
+    from decimal import Decimal
+    from fractions import Fraction
+    from math import factorial
+
+    # 25! is past what the C int64 factorial can return
+    for test_input in (0, 1, -1, 127, 128, -128, -129, factorial(25), -factorial(25), 2 ** 1000 + 1):
+        assert return_big_int(test_input) == test_input
+        assert return_big_int_string(test_input) == test_input
+        assert int.from_bytes(prepare_big_int(test_input)[0], "big", signed=True) == test_input
+    assert prepare_big_int(-128) == (b"\x80", 1)
+    with pytest.raises(ValueError):
+        return_big_int_string("12abc")
+
+    assert format_big_int(-factorial(25)) == str(-factorial(25))
+
+    for test_input in ("0.1", "3.14159265358979323846264338327", "-1.5E+100", "Infinity"):
+        assert return_big_float(Decimal(test_input)) == Decimal(test_input)
+    # At float64 precision 0.1 can't be exact, but the shortest decimal is still 0.1
+    assert return_big_float("0.1", 53) == Decimal("0.1")
+    assert return_big_float("3.14159265358979323846264338327", 53) == Decimal("3.141592653589793")
+    with pytest.raises(ValueError):
+        return_big_float("NaN")
+
+    assert return_big_rat(Fraction(22, 7)) == Fraction(22, 7)
+    assert return_big_rat(Decimal("3.125")) == Fraction(25, 8)
+    with pytest.raises(ValueError):
+        return_big_rat("1/0")
+
+def test_versioned_structs():
+    from ctypes import c_int32, c_uint64, sizeof
+
+    info = library_info()
+    assert info.abi_version == ABI_VERSION
+    assert info.features & REQUIRED_FEATURES == REQUIRED_FEATURES
+    assert info.struct_version >= 2
+    assert info.gomaxprocs >= 1 and info.num_cpu >= 1
+
+    # Bindings written for version 1 only get the version 1 fields, and Go doesn't write past the struct
+    class LibraryInfoV1(VersionedStructure):
+        _fields_ = [("header", StructHeader), ("abiVersion", c_int32), ("features", c_uint64)]
+
+    class Guarded(Structure):
+        _fields_ = [("info", LibraryInfoV1), ("guard", c_uint64)]
+
+    guarded = Guarded(LibraryInfoV1.new(), 0xdeadbeef)
+    old = fill_versioned_struct(lib.helper_library_info, guarded.info)
+    assert old.header.size == sizeof(LibraryInfoV1) and old.header.version == info.struct_version
+    assert old.abiVersion == ABI_VERSION
+    assert guarded.guard == 0xdeadbeef
+
+    # Bindings newer than the library see the new fields as missing
+    class LibraryInfoV3(VersionedStructure):
+        _version_ = 3
+        _fields_ = [("header", StructHeader), ("abiVersion", c_int32), ("features", c_uint64), ("gomaxprocs", c_int32), ("numCPU", c_int32), ("future", c_uint64)]
+
+    newer = fill_versioned_struct(lib.helper_library_info, LibraryInfoV3.new())
+    assert newer.has_field("numCPU") and not newer.has_field("future")
+    assert newer.get("future") is None
+
+    with pytest.raises(StructVersionError):
+        fill_versioned_struct(lib.helper_library_info, LibraryInfoV1())  # header.size wasn't set
+
+def test_build_driver(tmp_path):
+    source = os.path.join(tmp_path, "answer")
+    os.mkdir(source)
+    def write_source(answer: int):
+        with open(os.path.join(source, "go.mod"), "w") as file:
+            file.write("module lib\n\ngo 1.22\n")
+        with open(os.path.join(source, "lib.go"), "w") as file:
+            file.write(f'package main\n\nimport "C"\n\n//export answer\nfunc answer() C.int {{ return {answer} }}\n\nfunc main() {{}}\n')
+    output = os.path.join(source, "lib.dll" if platform().lower().startswith("windows") else "lib.so")
+
+    write_source(42)
+    first = build_library(output, source)
+    assert first["rebuilt"] and os.path.exists(output)
+    assert os.path.exists(output + ".build.json")
+
+    # Only rebuilds when something that goes into the build changed
+    assert not build_library(output, source)["rebuilt"]
+    assert build_library(output, source, tags=["extra"])["rebuilt"]
+    write_source(43)
+    assert build_library(output, source, tags=["extra"])["rebuilt"]
+    assert build_library(output, source, tags=["extra"], force=True)["rebuilt"]
+
+    library = get_library(output, source, compile=True, tags=["extra"])
+    assert library.answer() == 43
+    unchecked = os.path.join(tmp_path, "unchecked", os.path.basename(output))
+    build_library(unchecked, source)
+
+    with pytest.raises(BuildError):
+        build_library(output, source, profile="fast")
+    with open(os.path.join(source, "lib.go"), "a") as file:
+        file.write("func broken() { return 1 }\n")
+    with pytest.raises(BuildError):
+        build_library(output, source)
+
+    # Without Go an existing library is used as is, quietly, instead of failing to check it on every load
+    import io
+    import contextlib
+    path = os.environ["PATH"]
+    os.environ["PATH"] = ""
+    try:
+        with contextlib.redirect_stdout(io.StringIO()) as printed:
+            assert get_library(unchecked, source, compile=True).answer() == 43
+    finally:
+        os.environ["PATH"] = path
+    assert printed.getvalue() == ""
+
+def test_new_project(tmp_path):
+    package = new_project("geometry", tmp_path, ["Point:x=int,y=float,label=string,id=int64,visible=bool"])
+    assert os.path.exists(os.path.join(package, "go", "lib.go"))
+    assert os.path.exists(os.path.join(tmp_path, "test_geometry.py"))
+    with pytest.raises(BuildError):
+        new_project("geometry", tmp_path)
+    with pytest.raises(BuildError):
+        new_project("other", tmp_path, ["Point:x=complex"])
+
+    # The generated round-trip test passes (importing it builds the new library)
+    sys.path.insert(0, str(tmp_path))
+    try:
+        import test_geometry
+        test_geometry.test_point_round_trip()
+    finally:
+        sys.path.remove(str(tmp_path))
+
+def test_doctor():
+    report = doctor()
+    assert [check["name"] for check in report["checks"]] == ["go", "go version", "cgo", "c compiler", "python", "c-shared"]
+    # This machine built the library, so nothing should fail
+    assert report["ok"], report
+    assert all(check["status"] in ("ok", "warning") for check in report["checks"])
+
+    report = doctor(cc="does-not-exist-cc")
+    compiler = report["checks"][3]
+    assert compiler["status"] in ("warning", "error") and compiler["fix"]
+
+def test_build_targets(tmp_path):
+    tag = platform_tag()
+    assert tag.count("-") == (2 if sys.platform.startswith("linux") else 1)
+    def write_source(name: str, answer: int) -> tuple[str, str]:
+        source = os.path.join(tmp_path, name)
+        os.makedirs(source, exist_ok=True)
+        with open(os.path.join(source, "go.mod"), "w") as file:
+            file.write("module lib\n\ngo 1.22\n")
+        with open(os.path.join(source, "lib.go"), "w") as file:
+            file.write(f'package main\n\nimport "C"\n\n//export answer\nfunc answer() C.int {{ return {answer} }}\n\nfunc main() {{}}\n')
+        return source, os.path.join(source, "lib.dll" if platform().lower().startswith("windows") else "lib.so")
+    source, dll_path = write_source("answer", 42)
+
+    with pytest.raises(BuildError):
+        build_targets(dll_path, source, ["darwin/arm64"])
+    if shutil.which("zig") is None:
+        with pytest.raises(BuildError):
+            build_targets(dll_path, source)
+    else:
+        results = build_targets(dll_path, source)
+        assert [r["target"]["goarch"] for r in results] == ["amd64", "arm64", "amd64", "arm64"]
+        assert all(os.path.exists(r["output"]) for r in results)
+
+    # The loader picks the library for this platform (built natively here, so it doesn't need zig)
+    assert find_platform_library(dll_path) is None
+    build_library(os.path.join(source, tag, os.path.basename(dll_path)), source)
+    assert find_platform_library(dll_path) == os.path.join(source, tag, os.path.basename(dll_path))
+    assert get_library(dll_path).answer() == 42
+
+    # And falls back to it when the library can't be built
+    source, dll_path = write_source("fallback", 7)
+    build_library(os.path.join(source, tag, os.path.basename(dll_path)), source)
+    with open(os.path.join(source, "lib.go"), "a") as file:
+        file.write("func broken() { return 1 }\n")
+    assert get_library(dll_path, source, compile=True).answer() == 7
+    assert not os.path.exists(dll_path)
+
+def test_wheel_backend(tmp_path):
+    import backend
+    import tarfile
+    import zipfile
+    project = os.path.join(tmp_path, "project")
+    os.makedirs(os.path.join(project, "answer", "go"))
+    with open(os.path.join(project, "pyproject.toml"), "w") as file:
+        file.write('[build-system]\nbuild-backend = "backend"\n\n[project]\nname = "answer-lib"\nversion = "1.0"\n\n'
+                   '[tool.cgohelper]\npackages = ["answer"]\nlibraries = [{source = "answer/go", output = "answer/go/lib"}]\n')
+    with open(os.path.join(project, "answer", "__init__.py"), "w") as file:
+        file.write("")
+    with open(os.path.join(project, "answer", "go", "go.mod"), "w") as file:
+        file.write("module lib\n\ngo 1.22\n")
+    with open(os.path.join(project, "answer", "go", "lib.go"), "w") as file:
+        file.write('package main\n\nimport "C"\n\n//export answer\nfunc answer() C.int { return 42 }\n\nfunc main() {}\n')
+
+    cwd = os.getcwd()
+    os.chdir(project)
+    try:
+        wheel_name = backend.build_wheel(str(tmp_path))
+        sdist_name = backend.build_sdist(str(tmp_path))
+    finally:
+        os.chdir(cwd)
+    assert wheel_name == f"answer_lib-1.0-py3-none-{backend.wheel_platform_tag()}.whl"
+
+    # The wheel has the library (but not the Go sources), and a RECORD with every file's hash
+    extension = backend._library_extension()
+    with zipfile.ZipFile(os.path.join(tmp_path, wheel_name)) as wheel:
+        names = wheel.namelist()
+        assert f"answer/go/lib{extension}" in names and f"answer/go/lib{extension}.bundled.json" in names
+        assert "answer/go/lib.go" not in names and "answer/go/go.mod" not in names
+        records = wheel.read("answer_lib-1.0.dist-info/RECORD").decode().splitlines()
+        for record in records:
+            path, digest, size = record.split(",")
+            if path.endswith("RECORD"):
+                continue
+            assert digest == backend._record_hash(wheel.read(path)) and int(size) == wheel.getinfo(path).file_size
+        assert "Tag: py3-none-" in wheel.read("answer_lib-1.0.dist-info/WHEEL").decode()
+        installed = os.path.join(tmp_path, "installed")
+        wheel.extractall(installed)
+    with tarfile.open(os.path.join(tmp_path, sdist_name)) as sdist:
+        assert "answer_lib-1.0/answer/go/lib.go" in sdist.getnames()
+
+    # An installed library loads without building it, even with compile=True and no Go sources
+    dll_path = os.path.join(installed, "answer", "go", f"lib{extension}")
+    assert is_bundled_library(dll_path)
+    assert not is_bundled_library(os.path.join(tmp_path, f"missing{extension}"))
+    assert get_library(dll_path, os.path.join(installed, "answer", "go"), compile=True).answer() == 42
+    assert not os.path.exists(dll_path + ".build.json")
+
+    with pytest.raises(backend.BackendError):
+        os.chdir(os.path.join(project, "answer"))
+        try:
+            backend.build_wheel(str(tmp_path))
+        finally:
+            os.chdir(cwd)
+
+def test_build_extension(tmp_path):
+    source = os.path.join(tmp_path, "go")
+    os.makedirs(source)
+    with open(os.path.join(source, "go.mod"), "w") as file:
+        file.write("module lib\n\ngo 1.22\n")
+    with open(os.path.join(source, "lib.go"), "w") as file:
+        file.write('package main\n\nimport "C"\nimport "unsafe"\n\n'
+                   '//export greet\nfunc greet(name *C.char) *C.char { return C.CString("hello " + C.GoString(name)) }\n\n'
+                   '//export sum\nfunc sum(values *C.int, count C.int) C.longlong {\n\ttotal := C.longlong(0)\n'
+                   '\tfor _, value := range unsafe.Slice(values, int(count)) {\n\t\ttotal += C.longlong(value)\n\t}\n\treturn total\n}\n\n'
+                   'func main() {}\n')
+    result = build_extension("lib_ext", os.path.join(tmp_path, "build"), source)
+    assert result["wrapped"] == ["greet", "sum"] and result["rebuilt"], result
+    assert not build_extension("lib_ext", os.path.join(tmp_path, "build"), source)["rebuilt"]
+    with pytest.raises(BuildError):
+        build_extension("lib-ext", os.path.join(tmp_path, "build"), source)
+
+    # The extension returns the same as ctypes, without the conversions
+    ext = load_extension(result["output"])
+    lib = get_library(os.path.join(source, "lib.dll" if platform().lower().startswith("windows") else "lib.so"), source, compile=True)
+    lib.greet.restype = c_char_p
+    lib.sum.restype = c_longlong
+    values, count = prepare_int_array([1, 2, 3])
+    assert ext.greet("go") == lib.greet(prepare_string("go")).decode() == "hello go"
+    assert ext.sum([1, 2, 3]) == lib.sum(values, count) == 6
+    with pytest.raises(OverflowError):
+        ext.sum([2**40])
+
+    latency = compare_latency({"ctypes": lambda: lib.sum(*prepare_int_array([1, 2, 3])), "extension": lambda: ext.sum([1, 2, 3])}, number=1000, repeat=3)
+    assert set(latency) == {"ctypes", "extension"} and all(seconds > 0 for seconds in latency.values())
+    print(latency)
+
+def test_generate_cffi(tmp_path):
+    source = os.path.join(tmp_path, "go")
+    os.makedirs(source)
+    with open(os.path.join(source, "go.mod"), "w") as file:
+        file.write("module lib\n\ngo 1.22\n")
+    with open(os.path.join(source, "lib.go"), "w") as file:
+        file.write('package main\n\n/*\n#include <stdlib.h>\n\ntypedef struct {\n\tsize_t numberOfElements;\n\tchar** data;\n} StringArrayResult;\n*/\nimport "C"\nimport "unsafe"\n\n'
+                   '//export repeat\nfunc repeat(word *C.char, count C.int) *C.StringArrayResult {\n'
+                   '\tresult := (*C.StringArrayResult)(C.malloc(C.size_t(unsafe.Sizeof(C.StringArrayResult{}))))\n'
+                   '\tresult.numberOfElements = C.size_t(count)\n\tresult.data = (**C.char)(C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(word))))\n'
+                   '\tfor i := range unsafe.Slice(result.data, int(count)) {\n\t\tunsafe.Slice(result.data, int(count))[i] = C.CString(C.GoString(word))\n\t}\n\treturn result\n}\n\n'
+                   '//export helper_free_string_array_result\nfunc helper_free_string_array_result(ptr unsafe.Pointer) {\n'
+                   '\tresult := (*C.StringArrayResult)(ptr)\n\tfor _, item := range unsafe.Slice(result.data, int(result.numberOfElements)) {\n\t\tC.free(unsafe.Pointer(item))\n\t}\n'
+                   '\tC.free(unsafe.Pointer(result.data))\n\tC.free(ptr)\n}\n\nfunc main() {}\n')
+    output = os.path.join(tmp_path, "binding")
+    result = generate_cffi("lib_cffi", output, source)
+    assert result["wrapped"] == ["repeat"] and result["rebuilt"] and not result["compiled"], result
+    assert [skipped["name"] for skipped in result["skipped"]] == ["helper_free_string_array_result"]
+    for path in (result["wrapper"], result["build_script"], result["cdef"]):
+        assert os.path.exists(path)
+    with open(result["wrapper"]) as file:
+        assert "lib.helper_free_string_array_result(pointer)" in file.read()
+    with pytest.raises(BuildError):
+        generate_cffi("lib-cffi", output, source)
+
+    try:
+        import cffi
+    except ImportError:
+        return # Compiling needs cffi
+    result = generate_cffi("lib_cffi", output, source, compile=True)
+    assert result["compiled"] and not generate_cffi("lib_cffi", output, source, compile=True)["compiled"]
+    sys.path.insert(0, output)
+    try:
+        import lib_cffi
+        assert lib_cffi.repeat("go", 3) == ["go", "go", "go"] and lib_cffi.repeat(None, 0) == []
+    finally:
+        sys.path.remove(output)
@@ -0,0 +1,117 @@
+package main
+
+/*
+#include <stdint.h>
+
+// An instant in time, the offset keeps the timezone the time was in (i.e. a Last-Modified header in GMT)
+typedef struct {
+    int64_t unixNanos;       // Nanoseconds since 1970-01-01T00:00:00Z
+    int32_t offsetSeconds;   // Offset of the timezone from UTC in seconds (east is positive)
+} Timestamp;
+
+// A duration in nanoseconds, the same representation as Go's time.Duration
+typedef int64_t Duration;
+*/
+import "C"
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+func init() {
+	RegisterStructLayout("Timestamp", C.Timestamp{})
+}
+
+// ======== Time Conversions ========
+
+// The range of instants a Timestamp can hold (Unix nanoseconds in an int64, roughly the years 1678 to 2262)
+var (
+	minTimestamp = time.Unix(0, math.MinInt64)
+	maxTimestamp = time.Unix(0, math.MaxInt64)
+)
+
+// Converts a time.Time to a C-compatible Timestamp, keeping the offset of its timezone
+//
+// Parameters:
+//   - t: The time to convert.
+//
+// Returns:
+//   - The C.Timestamp, the timezone name is dropped (only the offset at t is kept).
+//   - An error wrapping ErrIntegerOverflow if t is outside the range of Unix nanoseconds (i.e. a cert that expires in the year 9999).
+func TimeToCTimestamp(t time.Time) (C.Timestamp, error) {
+	if t.Before(minTimestamp) || t.After(maxTimestamp) {
+		return C.Timestamp{}, fmt.Errorf("%w: %s doesn't fit in a Timestamp", ErrIntegerOverflow, t.Format(time.RFC3339))
+	}
+	_, offset := t.Zone()
+	return C.Timestamp{unixNanos: C.int64_t(t.UnixNano()), offsetSeconds: C.int32_t(offset)}, nil
+}
+
+// Converts a C-compatible Timestamp to a time.Time
+//
+// Parameters:
+//   - timestamp: The C.Timestamp to convert.
+//
+// Returns:
+//   - The time.Time, in UTC if the offset is 0, otherwise in a fixed (unnamed) timezone with the offset.
+func CTimestampToTime(timestamp C.Timestamp) time.Time {
+	t := time.Unix(0, int64(timestamp.unixNanos))
+	if timestamp.offsetSeconds == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", int(timestamp.offsetSeconds)))
+}
+
+// Converts a time.Duration to a C-compatible Duration (nanoseconds)
+func DurationToCDuration(d time.Duration) C.Duration {
+	return C.Duration(d)
+}
+
+// Converts a C-compatible Duration (nanoseconds) to a time.Duration
+func CDurationToDuration(d C.Duration) time.Duration {
+	return time.Duration(d)
+}
+
+// Used to convert a C-compatible timestamp to a time.Time and back, good for debugging timezone issues
+//
+// Parameters:
+//   - timestamp: The C.Timestamp to convert.
+//
+// Returns:
+//   - The same instant and offset (C.Timestamp).
+//
+//export helper_return_timestamp
+func helper_return_timestamp(timestamp C.Timestamp) C.Timestamp {
+	ExitIfForked()
+	result, err := TimeToCTimestamp(CTimestampToTime(timestamp))
+	if err != nil {
+		return C.Timestamp{} // Can't happen, the time started out as a Timestamp
+	}
+	return result
+}
+
+// Used to convert a C-compatible duration to a time.Duration and back
+//
+// Parameters:
+//   - duration: The C.Duration to convert.
+//
+// Returns:
+//   - The same duration (C.Duration).
+//
+//export helper_return_duration
+func helper_return_duration(duration C.Duration) C.Duration {
+	ExitIfForked()
+	return DurationToCDuration(CDurationToDuration(duration))
+}
+
+// Returns the current time according to Go, in the local timezone of the process
+//
+// Returns:
+//   - The current time (C.Timestamp).
+//
+//export helper_now
+func helper_now() C.Timestamp {
+	ExitIfForked()
+	result, _ := TimeToCTimestamp(time.Now()) // time.Now() is always in range
+	return result
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimestampConversions(t *testing.T) {
+	for _, test_input := range []time.Time{
+		time.Unix(0, 0).UTC(),
+		time.Date(2024, 3, 10, 12, 30, 15, 123456789, time.FixedZone("EST", -5*60*60)),
+		time.Date(1969, 12, 31, 23, 59, 59, 1, time.FixedZone("IST", 5*60*60+30*60)),
+		time.Date(2262, 4, 11, 0, 0, 0, 0, time.UTC),
+	} {
+		ts, err := TimeToCTimestamp(test_input)
+		if err != nil {
+			t.Fatalf("TestTimestampConversions:TimeToCTimestamp(%v): %v", test_input, err)
+		}
+		temp := CTimestampToTime(ts)
+		if !temp.Equal(test_input) {
+			t.Errorf("TestTimestampConversions:CTimestampToTime(%v): %v!=%v", ts, temp, test_input)
+		}
+		_, expectedOffset := test_input.Zone()
+		if _, offset := temp.Zone(); offset != expectedOffset {
+			t.Errorf("TestTimestampConversions:CTimestampToTime(%v) offset: %d!=%d", ts, offset, expectedOffset)
+		}
+		if temp := helper_return_timestamp(ts); temp != ts {
+			t.Errorf("TestTimestampConversions:helper_return_timestamp(%v): %v!=%v", ts, temp, ts)
+		}
+	}
+
+	for _, test_input := range []time.Time{time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)} {
+		if _, err := TimeToCTimestamp(test_input); !errors.Is(err, ErrIntegerOverflow) {
+			t.Errorf("TestTimestampConversions:TimeToCTimestamp(%v): expected ErrIntegerOverflow, got %v", test_input, err)
+		}
+	}
+
+	if now := CTimestampToTime(helper_now()); time.Since(now).Abs() > time.Minute {
+		t.Errorf("TestTimestampConversions:helper_now(): %v is not close to %v", now, time.Now())
+	}
+}
+
+func TestDurationConversions(t *testing.T) {
+	for _, test_input := range []time.Duration{0, time.Nanosecond, -1500 * time.Millisecond, 90 * time.Minute} {
+		if temp := CDurationToDuration(helper_return_duration(DurationToCDuration(test_input))); temp != test_input {
+			t.Errorf("TestDurationConversions:helper_return_duration(%v): %v!=%v", test_input, temp, test_input)
+		}
+	}
+}
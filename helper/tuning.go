@@ -0,0 +1,125 @@
+package main
+
+import "C"
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+// ======== Runtime Tuning ========
+
+// Returns the current GOMAXPROCS (the number of OS threads that can run Go code at once)
+//
+// Returns:
+//   - The current value (C.int).
+//
+//export helper_get_gomaxprocs
+func helper_get_gomaxprocs() C.int {
+	ExitIfForked()
+	return C.int(runtime.GOMAXPROCS(0))
+}
+
+// Sets GOMAXPROCS (the number of OS threads that can run Go code at once)
+//
+// Parameters:
+//   - n: The new value, values < 1 leave the setting unchanged.
+//
+// Returns:
+//   - The previous value (C.int).
+//
+//export helper_set_gomaxprocs
+func helper_set_gomaxprocs(n C.int) C.int {
+	ExitIfForked()
+	return C.int(runtime.GOMAXPROCS(int(n)))
+}
+
+// Returns the current GC percent (how much the heap can grow since the last collection before the next one, GOGC)
+//
+// Returns:
+//   - The current value (C.int), a negative value means the GC is disabled.
+//
+//export helper_get_gc_percent
+func helper_get_gc_percent() C.int {
+	ExitIfForked()
+	return C.int(GCPercent())
+}
+
+// Reads the GC percent from runtime/metrics, reading it with debug.SetGCPercent() means setting it twice (each stops
+// the world), and a set from another goroutine in between would be undone
+//
+// Returns:
+//   - The current value, -1 means the GC is disabled (GOGC=off).
+func GCPercent() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	// The metric is a uint64, so off (-1) reads as math.MaxUint64
+	return int(int64(sample[0].Value.Uint64()))
+}
+
+// Sets the GC percent (the same as the GOGC environment variable)
+//
+// Parameters:
+//   - percent: The new value, a negative value disables the GC (until the memory limit is reached).
+//
+// Returns:
+//   - The previous value (C.int).
+//
+//export helper_set_gc_percent
+func helper_set_gc_percent(percent C.int) C.int {
+	ExitIfForked()
+	return C.int(debug.SetGCPercent(int(percent)))
+}
+
+// Returns the current soft memory limit of the Go runtime (GOMEMLIMIT)
+//
+// Returns:
+//   - The current limit in bytes (C.longlong), math.MaxInt64 means there is no limit.
+//
+//export helper_get_memory_limit
+func helper_get_memory_limit() C.longlong {
+	ExitIfForked()
+	return C.longlong(debug.SetMemoryLimit(-1))
+}
+
+// Sets the soft memory limit of the Go runtime (the same as the GOMEMLIMIT environment variable)
+//
+// Parameters:
+//   - limit: The new limit in bytes, negative values leave the limit unchanged.
+//
+// Returns:
+//   - The previous limit in bytes (C.longlong).
+//
+//export helper_set_memory_limit
+func helper_set_memory_limit(limit C.longlong) C.longlong {
+	ExitIfForked()
+	return C.longlong(debug.SetMemoryLimit(int64(limit)))
+}
+
+// Removes the soft memory limit of the Go runtime
+//
+// Returns:
+//   - The previous limit in bytes (C.longlong).
+//
+//export helper_clear_memory_limit
+func helper_clear_memory_limit() C.longlong {
+	ExitIfForked()
+	return C.longlong(debug.SetMemoryLimit(math.MaxInt64))
+}
+
+// Runs a garbage collection, blocking until it's complete
+//
+//export helper_gc
+func helper_gc() {
+	ExitIfForked()
+	runtime.GC()
+}
+
+// Runs a garbage collection, and returns as much memory to the operating system as possible
+//
+//export helper_free_os_memory
+func helper_free_os_memory() {
+	ExitIfForked()
+	debug.FreeOSMemory()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"testing"
+)
+
+func TestRuntimeTuning(t *testing.T) {
+	// GOMAXPROCS
+	original := helper_set_gomaxprocs(1)
+	if helper_get_gomaxprocs() != 1 {
+		t.Errorf("TestRuntimeTuning:helper_set_gomaxprocs(1): %d!=1", helper_get_gomaxprocs())
+	}
+	if previous := helper_set_gomaxprocs(0); previous != 1 || runtime.GOMAXPROCS(0) != 1 {
+		t.Errorf("TestRuntimeTuning:helper_set_gomaxprocs(0): should not change the value")
+	}
+	helper_set_gomaxprocs(original)
+
+	// GC percent
+	originalPercent := helper_set_gc_percent(50)
+	if helper_get_gc_percent() != 50 {
+		t.Errorf("TestRuntimeTuning:helper_set_gc_percent(50): %d!=50", helper_get_gc_percent())
+	}
+	helper_set_gc_percent(-1)
+	if helper_get_gc_percent() != -1 || GCPercent() != -1 {
+		t.Errorf("TestRuntimeTuning:helper_set_gc_percent(-1): %d!=-1", helper_get_gc_percent())
+	}
+	helper_set_gc_percent(50)
+	if previous := helper_set_gc_percent(originalPercent); previous != 50 {
+		t.Errorf("TestRuntimeTuning:helper_set_gc_percent(): previous %d!=50", previous)
+	}
+
+	// Memory limit
+	originalLimit := helper_set_memory_limit(64 << 20)
+	if helper_get_memory_limit() != 64<<20 {
+		t.Errorf("TestRuntimeTuning:helper_set_memory_limit(64MiB): %d!=%d", helper_get_memory_limit(), 64<<20)
+	}
+	if previous := helper_set_memory_limit(-1); previous != 64<<20 {
+		t.Errorf("TestRuntimeTuning:helper_set_memory_limit(-1): should not change the value")
+	}
+	if previous := helper_clear_memory_limit(); previous != 64<<20 || helper_get_memory_limit() != math.MaxInt64 {
+		t.Errorf("TestRuntimeTuning:helper_clear_memory_limit(): %d", helper_get_memory_limit())
+	}
+	helper_set_memory_limit(originalLimit)
+
+	// Make sure these don't crash
+	helper_gc()
+	helper_free_os_memory()
+}
@@ -0,0 +1,139 @@
+package main
+
+/*
+#include <stdint.h>
+
+// The first field of every versioned struct, like the cbSize field of a Windows API struct
+typedef struct {
+    uint32_t size;      // Set by the caller to sizeof() their version of the struct, Go sets it to the number of bytes it wrote
+    uint32_t version;   // Set by Go to the version of the struct it was built with
+} StructHeader;
+
+// Information about the loaded library, an example of a versioned struct (see helper_library_info())
+typedef struct {
+    StructHeader header;
+    // Version 1
+    int32_t abiVersion;     // ABIVersion
+    uint64_t features;      // ABIFeatures
+    // Version 2
+    int32_t gomaxprocs;     // The current GOMAXPROCS
+    int32_t numCPU;         // The number of CPUs Go can see
+} LibraryInfo;
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// The current version of C.LibraryInfo, bump it whenever a field is appended
+const LibraryInfoVersion = 2
+
+// Returned (wrapped) when the size in a struct header is too small to hold the header itself
+var ErrStructTooSmall = errors.New("versioned struct too small")
+
+// The header is registered on it's own too, a mismatch there shifts every field of every versioned struct
+func init() {
+	RegisterStructLayout("StructHeader", C.StructHeader{})
+	RegisterStructLayout("LibraryInfo", C.LibraryInfo{})
+}
+
+// ======== Versioned Structs ========
+//
+// A versioned struct starts with a C.StructHeader, and fields are only ever appended (never removed,
+// reordered or resized). The caller sets header.size to the sizeof() of the version of the struct
+// it was compiled with, so Go knows which fields it has room for:
+//   - Go -> caller: FillVersionedStruct() writes only the fields that fit, and sets header.size to the
+//     bytes written and header.version to Go's version.
+//   - caller -> Go: ReadVersionedStruct() reads only the fields the caller sent, the rest are zero.
+// So new bindings can use an old library (the new fields are missing) and old bindings can use a new
+// library (the new fields are never written), without bumping ABIVersion.
+
+// The size of a C.StructHeader in bytes
+const StructHeaderSize = int(unsafe.Sizeof(C.StructHeader{}))
+
+// Reads the size and version from the header of a versioned struct
+//
+// Parameters:
+//   - ptr: Pointer to the versioned struct (starting with a C.StructHeader).
+//
+// Returns:
+//   - The size in the header.
+//   - The version in the header.
+//   - An error wrapping ErrStructTooSmall if the size is too small to hold the header.
+func ReadStructHeader(ptr unsafe.Pointer) (int, int, error) {
+	header := (*C.StructHeader)(ptr)
+	size, version := int(header.size), int(header.version)
+	if size < StructHeaderSize {
+		return size, version, fmt.Errorf("%w: header.size is %d, the header alone is %d bytes (set it to sizeof() the struct)", ErrStructTooSmall, size, StructHeaderSize)
+	}
+	return size, version, nil
+}
+
+// Copies a versioned struct into a caller-allocated one, writing only the fields the caller's version has room for
+//
+// Parameters:
+//   - dst: Pointer to the caller's struct, with header.size set to its sizeof().
+//   - src: Pointer to Go's (complete) version of the struct, which must start with a C.StructHeader.
+//   - version: The version of src, written to header.version.
+//
+// Returns:
+//   - The number of bytes written (also written to header.size), the smaller of the two struct sizes.
+//   - An error wrapping ErrStructTooSmall if header.size is too small to hold the header (nothing is written).
+func FillVersionedStruct[T any](dst unsafe.Pointer, src *T, version int) (int, error) {
+	size, _, err := ReadStructHeader(dst)
+	if err != nil {
+		return 0, err
+	}
+	written := min(size, int(unsafe.Sizeof(*src)))
+	copy(unsafe.Slice((*byte)(dst), written)[StructHeaderSize:], unsafe.Slice((*byte)(unsafe.Pointer(src)), written)[StructHeaderSize:])
+	header := (*C.StructHeader)(dst)
+	header.size = C.uint32_t(written)
+	header.version = C.uint32_t(version)
+	return written, nil
+}
+
+// Reads a versioned struct sent by the caller, fields the caller's version doesn't have are left as zero values
+//
+// Parameters:
+//   - src: Pointer to the caller's struct, with header.size set to its sizeof().
+//
+// Returns:
+//   - Go's version of the struct (the header is copied as-is).
+//   - The version in the caller's header.
+//   - An error wrapping ErrStructTooSmall if header.size is too small to hold the header.
+func ReadVersionedStruct[T any](src unsafe.Pointer) (T, int, error) {
+	var result T
+	size, version, err := ReadStructHeader(src)
+	if err != nil {
+		return result, version, err
+	}
+	read := min(size, int(unsafe.Sizeof(result)))
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&result)), read), unsafe.Slice((*byte)(src), read))
+	return result, version, nil
+}
+
+// Fills a caller-allocated C.LibraryInfo with information about the library
+//
+// Parameters:
+//   - info: Pointer to the caller's LibraryInfo (*C.LibraryInfo), with header.size set to its sizeof().
+//
+// Returns:
+//   - NULL on success, otherwise a C string with the error (free with helper_free_c_string).
+//
+//export helper_library_info
+func helper_library_info(info unsafe.Pointer) unsafe.Pointer {
+	if err := CheckNotForked(); err != nil {
+		return errorToCString(err)
+	}
+	current := C.LibraryInfo{
+		abiVersion: C.int32_t(ABIVersion),
+		features:   C.uint64_t(ABIFeatures),
+		gomaxprocs: C.int32_t(runtime.GOMAXPROCS(0)),
+		numCPU:     C.int32_t(runtime.NumCPU()),
+	}
+	_, err := FillVersionedStruct(info, &current, LibraryInfoVersion)
+	return errorToCString(err)
+}
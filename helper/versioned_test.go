@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+// Go mirrors of the two versions of C.LibraryInfo, like an old and a new set of bindings would have
+type libraryInfoV1 struct {
+	size, version uint32
+	abiVersion    int32
+	features      uint64
+}
+
+type libraryInfoV2 struct {
+	libraryInfoV1
+	gomaxprocs, numCPU int32
+}
+
+func TestFillVersionedStruct(t *testing.T) {
+	// Old bindings only get the fields they know about, the bytes after are untouched
+	var buffer struct {
+		info  libraryInfoV1
+		guard uint64
+	}
+	buffer.info.size = uint32(unsafe.Sizeof(buffer.info))
+	buffer.guard = 0xdeadbeef
+	if err := helper_library_info(unsafe.Pointer(&buffer.info)); err != nil {
+		t.Fatalf("TestFillVersionedStruct:helper_library_info(v1): %s", CStringToString(err))
+	}
+	if buffer.info.size != 24 || buffer.info.version != LibraryInfoVersion || buffer.info.abiVersion != ABIVersion || buffer.info.features != ABIFeatures {
+		t.Errorf("TestFillVersionedStruct:helper_library_info(v1): %+v", buffer.info)
+	}
+	if buffer.guard != 0xdeadbeef {
+		t.Errorf("TestFillVersionedStruct:helper_library_info(v1): wrote past header.size (%x)", buffer.guard)
+	}
+
+	current := libraryInfoV2{}
+	current.size = uint32(unsafe.Sizeof(current))
+	if err := helper_library_info(unsafe.Pointer(&current)); err != nil {
+		t.Fatalf("TestFillVersionedStruct:helper_library_info(v2): %s", CStringToString(err))
+	}
+	if current.size != 32 || current.gomaxprocs != int32(runtime.GOMAXPROCS(0)) || current.numCPU != int32(runtime.NumCPU()) {
+		t.Errorf("TestFillVersionedStruct:helper_library_info(v2): %+v", current)
+	}
+
+	// Newer bindings than the library, only the fields Go knows about are written
+	var newer struct {
+		libraryInfoV2
+		future int64
+	}
+	newer.size = uint32(unsafe.Sizeof(newer))
+	newer.future = -1
+	written, err := FillVersionedStruct(unsafe.Pointer(&newer), &current, LibraryInfoVersion)
+	if err != nil || written != 32 || newer.size != 32 || newer.future != -1 {
+		t.Errorf("TestFillVersionedStruct:FillVersionedStruct(newer): %d, %v (%+v)", written, err, newer)
+	}
+
+	unset := libraryInfoV1{}
+	if _, err := FillVersionedStruct(unsafe.Pointer(&unset), &current, LibraryInfoVersion); !errors.Is(err, ErrStructTooSmall) {
+		t.Errorf("TestFillVersionedStruct:FillVersionedStruct(size 0): expected ErrStructTooSmall, got %v", err)
+	}
+	if unset != (libraryInfoV1{}) {
+		t.Errorf("TestFillVersionedStruct:FillVersionedStruct(size 0): wrote %+v", unset)
+	}
+}
+
+func TestReadVersionedStruct(t *testing.T) {
+	test_input := libraryInfoV1{size: 24, version: 1, abiVersion: 7, features: 3}
+	temp, version, err := ReadVersionedStruct[libraryInfoV2](unsafe.Pointer(&test_input))
+	if err != nil || version != 1 || temp.libraryInfoV1 != test_input || temp.gomaxprocs != 0 || temp.numCPU != 0 {
+		t.Errorf("TestReadVersionedStruct:ReadVersionedStruct(v1): %+v, %d, %v", temp, version, err)
+	}
+
+	test_input.size = 4
+	if _, _, err := ReadVersionedStruct[libraryInfoV2](unsafe.Pointer(&test_input)); !errors.Is(err, ErrStructTooSmall) {
+		t.Errorf("TestReadVersionedStruct:ReadVersionedStruct(size 4): expected ErrStructTooSmall, got %v", err)
+	}
+}
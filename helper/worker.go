@@ -0,0 +1,502 @@
+package main
+
+/*
+#include <unistd.h>
+*/
+import "C"
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/Descent098/cgo-python-helpers/registry"
+)
+
+// The largest frame the worker protocol will read, anything bigger is treated as a corrupt stream
+const MaxWorkerFrameSize = 256 << 20
+
+// Returned by ReadFrame when a frame header is larger than MaxWorkerFrameSize
+var ErrFrameTooLarge = errors.New("worker frame exceeds MaxWorkerFrameSize")
+
+// A function that can be called through the worker protocol (the same signature as registry.Function)
+//
+// Parameters:
+//   - args: The JSON encoded arguments sent by the caller, decode them with DecodeWorkerArgs.
+//
+// Returns:
+//   - Any JSON encodable value, and an error that is sent back to the caller as a string.
+type WorkerFunction = registry.Function
+
+// A single call sent to a worker
+type WorkerRequest struct {
+	ID       uint64            `json:"id"`       // Used to match responses to requests
+	Function string            `json:"function"` // The name the function was registered with, or "module.function" for the registry
+	Args     []json.RawMessage `json:"args"`     // The JSON encoded arguments
+}
+
+// The result of a single call sent back from a worker
+type WorkerResponse struct {
+	ID     uint64          `json:"id"`               // The ID of the request this responds to
+	Result json.RawMessage `json:"result,omitempty"` // The JSON encoded return value
+	Error  string          `json:"error,omitempty"`  // Set if the call failed
+}
+
+var (
+	workerFunctionsLock sync.RWMutex
+	workerFunctions     = map[string]WorkerFunction{}
+)
+
+// ======== Worker Registry ========
+
+// Registers a function so it can be called by name through a worker
+//
+// Parameters:
+//   - name: The name callers use for the function, use the same name as the exported function.
+//   - fn: The function to run.
+//
+// Usage:
+//
+//	func init() {
+//		RegisterWorkerFunction("parse_urls", func(args []json.RawMessage) (any, error) {
+//			var urls []string
+//			if err := DecodeWorkerArgs(args, &urls); err != nil {
+//				return nil, err
+//			}
+//			return ParseURLs(urls), nil
+//		})
+//	}
+func RegisterWorkerFunction(name string, fn WorkerFunction) {
+	workerFunctionsLock.Lock()
+	defer workerFunctionsLock.Unlock()
+	workerFunctions[name] = fn
+}
+
+// Decodes the arguments of a worker call into the provided pointers, in order
+//
+// Parameters:
+//   - args: The JSON encoded arguments from the request.
+//   - targets: Pointers to decode each argument into.
+//
+// Returns:
+//   - An error if the number of arguments doesn't match, or an argument can't be decoded.
+func DecodeWorkerArgs(args []json.RawMessage, targets ...any) error {
+	return registry.DecodeArgs(args, targets...)
+}
+
+// Finds a function registered with RegisterWorkerFunction, or in the registry for "module.function" names
+func lookupWorkerFunction(name string) (WorkerFunction, bool) {
+	workerFunctionsLock.RLock()
+	fn, ok := workerFunctions[name]
+	workerFunctionsLock.RUnlock()
+	if ok {
+		return fn, true
+	}
+	if module, function, found := strings.Cut(name, "."); found {
+		return registry.Lookup(module, function)
+	}
+	return nil, false
+}
+
+// Runs a registered function, recovering panics so they are reported instead of killing the worker
+func callWorkerFunction(request WorkerRequest) (response WorkerResponse) {
+	response.ID = request.ID
+
+	fn, ok := lookupWorkerFunction(request.Function)
+	if !ok {
+		response.Error = fmt.Sprintf("unknown function %q", request.Function)
+		return response
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			response.Result = nil
+			response.Error = fmt.Sprintf("%s panicked: %v", request.Function, r)
+		}
+	}()
+
+	result, err := fn(request.Args)
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+	response.Result = encoded
+	return response
+}
+
+// ======== Framing ========
+
+// Writes a single length-prefixed frame (4 byte big-endian length, then the payload)
+//
+// Parameters:
+//   - w: Where to write the frame.
+//   - payload: The bytes to send.
+//
+// Returns:
+//   - An error if the write fails.
+func WriteFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxWorkerFrameSize {
+		return ErrFrameTooLarge
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// Reads a single length-prefixed frame written by WriteFrame
+//
+// Parameters:
+//   - r: Where to read the frame from.
+//
+// Returns:
+//   - The payload of the frame, and io.EOF if the stream closed cleanly between frames.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxWorkerFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ======== Worker Server ========
+
+// Serves worker requests from r and writes responses to w until r is closed
+//
+// Parameters:
+//   - r: The stream requests are read from (i.e. os.Stdin or a socket).
+//   - w: The stream responses are written to (i.e. os.Stdout or a socket).
+//
+// Returns:
+//   - nil when r closes cleanly, otherwise the error that stopped the worker.
+func ServeWorker(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	for {
+		payload, err := ReadFrame(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request WorkerRequest
+		var response WorkerResponse
+		if err := json.Unmarshal(payload, &request); err != nil {
+			response.Error = fmt.Sprintf("malformed request: %v", err)
+		} else {
+			response = callWorkerFunction(request)
+		}
+
+		encoded, err := json.Marshal(response)
+		if err != nil {
+			return err
+		}
+		if err := WriteFrame(writer, encoded); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// Serves worker requests on a Unix socket, each connection is served concurrently
+//
+// Parameters:
+//   - socketPath: The path to create the socket at, an existing file at the path is removed.
+//
+// Returns:
+//   - The error that stopped the listener.
+func ServeWorkerSocket(socketPath string) error {
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			if err := ServeWorker(conn, conn); err != nil {
+				fmt.Fprintf(os.Stderr, "worker connection closed with error: %v\n", err)
+			}
+		}(conn)
+	}
+}
+
+// Entrypoint for a library built as a standalone worker executable (call it from main())
+//
+// Parameters:
+//   - args: The command line arguments (without the program name), pass -socket <path> to
+//     listen on a Unix socket, otherwise requests are read from stdin and written to stdout.
+//
+// Returns:
+//   - The error that stopped the worker.
+//
+// Notes:
+//   - When serving over stdout, file descriptor 1 is pointed at stderr (see detachStdout()) so prints don't corrupt the protocol
+func RunWorkerMain(args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ContinueOnError)
+	socketPath := flags.String("socket", "", "Serve on a Unix socket at this path instead of stdin/stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *socketPath != "" {
+		return ServeWorkerSocket(*socketPath)
+	}
+	stdout, err := detachStdout()
+	if err != nil {
+		return err
+	}
+	return ServeWorker(os.Stdin, stdout)
+}
+
+// Moves stdout to a new file descriptor and points file descriptor 1 at stderr, so anything that writes to stdout
+// (fmt.Println(), or C code calling printf()) ends up on stderr instead of in the protocol
+//
+// Returns:
+//   - The original stdout, only the protocol should be written to it.
+func detachStdout() (*os.File, error) {
+	fd, err := C.dup(1)
+	if fd < 0 {
+		return nil, fmt.Errorf("dup(1): %w", err)
+	}
+	if result, err := C.dup2(2, 1); result < 0 {
+		C.close(fd)
+		return nil, fmt.Errorf("dup2(2, 1): %w", err)
+	}
+	return os.NewFile(uintptr(fd), "stdout"), nil
+}
+
+// ======== Worker Client ========
+
+// A client for calling functions in a worker process
+type WorkerClient struct {
+	lock   sync.Mutex
+	reader *bufio.Reader
+	writer io.Writer
+	closer io.Closer
+	cmd    *exec.Cmd // Only set if the client started the worker
+	nextID uint64
+}
+
+// Creates a client that talks to a worker over an existing pair of streams
+//
+// Parameters:
+//   - r: The stream responses are read from.
+//   - w: The stream requests are written to.
+//
+// Returns:
+//   - A pointer to the new client.
+func NewWorkerClient(r io.Reader, w io.Writer) *WorkerClient {
+	client := &WorkerClient{reader: bufio.NewReader(r), writer: w}
+	if closer, ok := w.(io.Closer); ok {
+		client.closer = closer
+	}
+	return client
+}
+
+// Connects to a worker listening on a Unix socket
+//
+// Parameters:
+//   - socketPath: The path the worker is listening on.
+//
+// Returns:
+//   - A pointer to the new client, or an error if the connection fails.
+func DialWorker(socketPath string) (*WorkerClient, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewWorkerClient(conn, conn), nil
+}
+
+// Starts a worker executable and connects to it over stdin/stdout
+//
+// Parameters:
+//   - executable: The path to the worker executable.
+//   - args: Any additional arguments to pass to the worker.
+//
+// Returns:
+//   - A pointer to the new client, or an error if the worker couldn't be started.
+func StartWorker(executable string, args ...string) (*WorkerClient, error) {
+	cmd := exec.Command(executable, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	client := NewWorkerClient(stdout, stdin)
+	client.cmd = cmd
+	return client, nil
+}
+
+// Calls a function in the worker
+//
+// Parameters:
+//   - function: The name the function was registered with.
+//   - result: A pointer to decode the result into, or nil to discard it.
+//   - args: The arguments to the function, each must be JSON encodable.
+//
+// Returns:
+//   - An error if the call couldn't be sent, or the function returned an error.
+func (c *WorkerClient) Call(function string, result any, args ...any) error {
+	request := WorkerRequest{Function: function, Args: make([]json.RawMessage, len(args))}
+	for i, arg := range args {
+		encoded, err := json.Marshal(arg)
+		if err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+		request.Args[i] = encoded
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nextID++
+	request.ID = c.nextID
+
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	if err := WriteFrame(c.writer, encoded); err != nil {
+		return err
+	}
+	payload, err := ReadFrame(c.reader)
+	if err != nil {
+		return err
+	}
+
+	var response WorkerResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return err
+	}
+	if response.ID != request.ID {
+		return fmt.Errorf("response id %d does not match request id %d", response.ID, request.ID)
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	if result != nil && response.Result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
+
+// Closes the connection to the worker, and waits for it to exit if the client started it
+//
+// Returns:
+//   - An error if closing the connection or waiting on the worker fails.
+func (c *WorkerClient) Close() error {
+	var err error
+	if c.closer != nil {
+		err = c.closer.Close()
+	}
+	if c.cmd != nil {
+		if waitErr := c.cmd.Wait(); err == nil {
+			err = waitErr
+		}
+	}
+	return err
+}
+
+// ======== Built-in Worker Functions ========
+
+// The helper's own round-trip functions, run through the same C conversions as the exported versions
+//
+// Notes:
+//   - Each is also registered as the "helper" module, so they're available from a host library
+var builtinWorkerFunctions = map[string]WorkerFunction{
+	"return_string": func(args []json.RawMessage) (any, error) {
+		var data string
+		if err := DecodeWorkerArgs(args, &data); err != nil {
+			return nil, err
+		}
+		cString := StringToCString(data)
+		defer FreeCString(cString)
+		return CStringToString(cString), nil
+	},
+	"return_string_array": func(args []json.RawMessage) (any, error) {
+		var data []string
+		if err := DecodeWorkerArgs(args, &data); err != nil {
+			return nil, err
+		}
+		cArray := StringSliceToCArray(data)
+		defer helper_free_string_array_result(unsafe.Pointer(cArray))
+		return CStringArrayToSlice(unsafe.Pointer(cArray.data), int(cArray.numberOfElements)), nil
+	},
+	"return_int_array": func(args []json.RawMessage) (any, error) {
+		var data []int
+		if err := DecodeWorkerArgs(args, &data); err != nil {
+			return nil, err
+		}
+		cArray, err := IntSliceToCArray(data)
+		if err != nil {
+			return nil, err
+		}
+		defer helper_free_int_array_result(unsafe.Pointer(cArray))
+		return CIntArrayToSlice(unsafe.Pointer(cArray.data), int(cArray.numberOfElements)), nil
+	},
+	"return_float_array": func(args []json.RawMessage) (any, error) {
+		var data []float32
+		if err := DecodeWorkerArgs(args, &data); err != nil {
+			return nil, err
+		}
+		cArray := FloatSliceToCArray(data)
+		defer helper_free_float_array_result(unsafe.Pointer(cArray))
+		return CFloatArrayToSlice(unsafe.Pointer(cArray.data), int(cArray.numberOfElements)), nil
+	},
+}
+
+func init() {
+	RegisterWorkerFunction("worker_pid", func(args []json.RawMessage) (any, error) {
+		return os.Getpid(), nil
+	})
+	for name, fn := range builtinWorkerFunctions {
+		RegisterWorkerFunction(name, fn)
+		registry.Register("helper", name, fn)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+// Tests for the worker protocol, clients talk to ServeWorker
+// over in-memory pipes and a Unix socket in the same process
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFrames(t *testing.T) {
+	var buffer bytes.Buffer
+	for _, test_input := range [][]byte{{}, []byte("Hello World"), bytes.Repeat([]byte{0xff}, 70_000)} {
+		if err := WriteFrame(&buffer, test_input); err != nil {
+			t.Fatalf("TestFrames:WriteFrame(%d bytes): %v", len(test_input), err)
+		}
+		temp, err := ReadFrame(&buffer)
+		if err != nil {
+			t.Fatalf("TestFrames:ReadFrame(%d bytes): %v", len(test_input), err)
+		}
+		if !bytes.Equal(temp, test_input) {
+			t.Errorf("TestFrames:ReadFrame(%d bytes): got %d bytes back", len(test_input), len(temp))
+		}
+	}
+
+	// Clean close between frames vs. a truncated frame
+	if _, err := ReadFrame(&buffer); !errors.Is(err, io.EOF) {
+		t.Errorf("TestFrames:ReadFrame(empty): expected io.EOF, got %v", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{0, 0, 0, 5, 'a'})); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TestFrames:ReadFrame(truncated): expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := ReadFrame(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("TestFrames:ReadFrame(huge): expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+// Starts ServeWorker on in-memory pipes and returns a client connected to it
+func startPipeWorker(t *testing.T) *WorkerClient {
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	go func() {
+		ServeWorker(requestReader, responseWriter)
+		responseWriter.Close()
+	}()
+	client := NewWorkerClient(responseReader, requestWriter)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func checkWorkerRoundTrips(t *testing.T, client *WorkerClient) {
+	for _, test_input := range []string{"", "Hello World", "!@$#^%!#@@%*!", "❤", "\n"} {
+		var temp string
+		if err := client.Call("return_string", &temp, test_input); err != nil {
+			t.Fatalf("checkWorkerRoundTrips:return_string(%q): %v", test_input, err)
+		}
+		if temp != test_input {
+			t.Errorf("checkWorkerRoundTrips:return_string(%q): %q!=%q", test_input, test_input, temp)
+		}
+	}
+
+	for _, test_input := range [][]string{{"Here", "are", "some", "strings", "❤"}, {}} {
+		var temp []string
+		if err := client.Call("return_string_array", &temp, test_input); err != nil {
+			t.Fatalf("checkWorkerRoundTrips:return_string_array(%v): %v", test_input, err)
+		}
+		if !slices.Equal(temp, test_input) {
+			t.Errorf("checkWorkerRoundTrips:return_string_array(%v): %v!=%v", test_input, test_input, temp)
+		}
+	}
+
+	intInput := []int{-10_000, -1, 0, 1, 10_000}
+	var intResult []int
+	if err := client.Call("return_int_array", &intResult, intInput); err != nil {
+		t.Fatalf("checkWorkerRoundTrips:return_int_array(%v): %v", intInput, err)
+	}
+	if !slices.Equal(intResult, intInput) {
+		t.Errorf("checkWorkerRoundTrips:return_int_array(%v): %v!=%v", intInput, intInput, intResult)
+	}
+
+	floatInput := []float32{-790.5207, 0, 3.14159}
+	var floatResult []float32
+	if err := client.Call("return_float_array", &floatResult, floatInput); err != nil {
+		t.Fatalf("checkWorkerRoundTrips:return_float_array(%v): %v", floatInput, err)
+	}
+	if !slices.Equal(floatResult, floatInput) {
+		t.Errorf("checkWorkerRoundTrips:return_float_array(%v): %v!=%v", floatInput, floatInput, floatResult)
+	}
+}
+
+func TestWorkerPipes(t *testing.T) {
+	client := startPipeWorker(t)
+	checkWorkerRoundTrips(t, client)
+
+	// Errors and panics are reported, and the worker keeps serving afterwards
+	RegisterWorkerFunction("test_panic", func(args []json.RawMessage) (any, error) {
+		panic("oh no")
+	})
+	if err := client.Call("test_panic", nil); err == nil {
+		t.Errorf("TestWorkerPipes:test_panic(): expected an error")
+	}
+	if err := client.Call("does_not_exist", nil); err == nil {
+		t.Errorf("TestWorkerPipes:does_not_exist(): expected an error")
+	}
+	if err := client.Call("return_string", nil, 1, 2); err == nil {
+		t.Errorf("TestWorkerPipes:return_string(1, 2): expected an error")
+	}
+	if err := client.Call("return_int_array", nil, []int{1 << 40}); err == nil {
+		t.Errorf("TestWorkerPipes:return_int_array([1<<40]): expected an overflow error")
+	}
+	var pid int
+	if err := client.Call("worker_pid", &pid); err != nil || pid == 0 {
+		t.Errorf("TestWorkerPipes:worker_pid(): %d, %v", pid, err)
+	}
+}
+
+func TestDetachStdout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file descriptors are not tested on windows")
+	}
+	// Run in a child process, so the test binary's own stdout isn't moved
+	if os.Getenv("HELPER_TEST_DETACH_STDOUT") == "1" {
+		protocol, err := detachStdout()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print("fmt ")
+		syscall.Write(1, []byte("fd1 "))
+		protocol.WriteString("protocol")
+		os.Exit(0)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDetachStdout$")
+	cmd.Env = append(os.Environ(), "HELPER_TEST_DETACH_STDOUT=1")
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("TestDetachStdout: %v, %s", err, stderr.String())
+	}
+	if stdout.String() != "protocol" || stderr.String() != "fmt fd1 " {
+		t.Errorf("TestDetachStdout: stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestWorkerSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not tested on windows")
+	}
+	socketPath := filepath.Join(t.TempDir(), "worker.sock")
+	go ServeWorkerSocket(socketPath)
+
+	var client *WorkerClient
+	var err error
+	for range 50 {
+		if client, err = DialWorker(socketPath); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("TestWorkerSocket:DialWorker(%s): %v", socketPath, err)
+	}
+	defer client.Close()
+	checkWorkerRoundTrips(t, client)
+}